@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+	"internal/apierr"
+	"internal/database"
+	"net/http"
+	"time"
+)
+
+const (
+	statusActive = "active"
+	statusSuspended = "suspended"
+	statusBanned = "banned"
+)
+
+// the longest a moderator can suspend someone for, so a suspension can't be a ban in all but name
+// (only admins get to ban)
+const maxSuspendDays = 365
+
+// the status a user's account is actually in, since a suspension or ban that's run out leaves it active
+func accountStatus(user database.User) string {
+	if user.Status == "" || (user.StatusUntil.Valid && !time.Now().UTC().Before(user.StatusUntil.Time)) {
+		return statusActive
+	}
+	return user.Status
+}
+
+// why a user can't use their account, or "" if they can
+func accountBlocked(user database.User) string {
+	status := accountStatus(user)
+	if status == statusActive {
+		return ""
+	}
+	msg := fmt.Sprintf("Account is %s", status)
+	if user.StatusUntil.Valid {
+		msg += fmt.Sprintf(" until %v", user.StatusUntil.Time.Format(time.RFC3339))
+	}
+	if user.StatusReason != "" {
+		msg += ": " + user.StatusReason
+	}
+	return msg
+}
+
+// suspends or bans a user (or lifts it, for statusActive), logging them out everywhere
+// until is when it ends, or the zero time for never
+func setAccountStatus(ctx context.Context, apiCfg apiConfig, userID uuid.UUID, status string, reason string, until time.Time, hideChirps bool) (database.User, error) {
+	params := database.SetUserStatusParams{Status: status, ID: userID}
+	if status != statusActive {
+		params.StatusReason = reason
+		params.StatusUntil = sql.NullTime{Time: until, Valid: !until.IsZero()}
+		params.HideChirps = hideChirps
+	}
+	user, err := apiCfg.dbQueries.SetUserStatus(ctx, params)
+	if err != nil {
+		return database.User{}, err
+	}
+	if status != statusActive {
+		err = apiCfg.dbQueries.RevokeUserTokens(ctx, userID)
+		if err != nil {
+			return database.User{}, fmt.Errorf("revoking tokens: %w", err)
+		}
+	}
+	return user, nil
+}
+
+func accountStatusResponse(user database.User) accountStatusParam {
+	res := accountStatusParam{
+		UserID: user.ID,
+		Status: accountStatus(user),
+	}
+	if res.Status != statusActive {
+		res.Reason = user.StatusReason
+		res.HideChirps = user.HideChirps
+		if user.StatusUntil.Valid {
+			until := user.StatusUntil.Time
+			res.Until = &until
+		}
+	}
+	return res
+}
+
+// see whether a user is suspended or banned
+func getAccountStatus(wri http.ResponseWriter, req *http.Request, apiCfg apiConfig) {
+	user, ok := getPathUser(wri, req, apiCfg)
+	if !ok {
+		return
+	}
+	respondWithJSON(wri, 200, accountStatusResponse(user))
+}
+
+// suspend, ban or reinstate a user
+// moderators can suspend plain users, and only admins can ban anyone or touch other moderators
+func putAccountStatus(wri http.ResponseWriter, req *http.Request, apiCfg apiConfig) {
+	reqBody := struct {
+		Status string `json:"status"`
+		Reason string `json:"reason"`
+		Until *time.Time `json:"until"`
+		HideChirps bool `json:"hide_chirps"`
+	}{}
+	err := json.NewDecoder(req.Body).Decode(&reqBody)
+	if err != nil {
+		respondWithProblem(wri, errInvalidBody.Wrap(err))
+		return
+	}
+	if reqBody.Status != statusActive && reqBody.Status != statusSuspended && reqBody.Status != statusBanned {
+		respondWithError(wri, 400, "status must be active, suspended or banned")
+		return
+	}
+	until := time.Time{}
+	if reqBody.Until != nil {
+		until = reqBody.Until.UTC()
+		if !until.After(time.Now()) {
+			respondWithError(wri, 400, "until must be in the future")
+			return
+		}
+	}
+	user, ok := getPathUser(wri, req, apiCfg)
+	if !ok {
+		return
+	}
+	if user.ID == requestUser(req) {
+		respondWithError(wri, 400, "You can't change your own status")
+		return
+	}
+	if !hasRole(requestRole(req), roleAdmin) {
+		if reqBody.Status == statusBanned || accountStatus(user) == statusBanned || hasRole(user.Role, roleModerator) {
+			respondWithError(wri, 403, "Only admins can ban users or change the status of moderators")
+			return
+		}
+		if reqBody.Status == statusSuspended && (until.IsZero() || until.After(time.Now().AddDate(0, 0, maxSuspendDays))) {
+			msg := fmt.Sprintf("Moderators can only suspend someone for up to %d days", maxSuspendDays)
+			respondWithProblem(wri, apierr.Validation("invalid_suspension", msg, apierr.FieldError{Field: "until", Code: "out_of_range", Message: msg}))
+			return
+		}
+	}
+	user, err = setAccountStatus(req.Context(), apiCfg, user.ID, reqBody.Status, reqBody.Reason, until, reqBody.HideChirps)
+	if err != nil {
+		respondWithProblem(wri, fmt.Errorf("changing status: %w", err))
+		return
+	}
+	respondWithJSON(wri, 200, accountStatusResponse(user))
+}
@@ -8,45 +8,421 @@ import (
 	"encoding/json"
 	"internal/database"
 	"internal/auth"
+	"internal/moderation"
 	"time"
 	"database/sql"
-	"sort"
+	"errors"
+	"internal/apierr"
+	"context"
+	"strconv"
 )
 
-// get all chirps
+// get all chirps, a page at a time
 func getChirps(wri http.ResponseWriter, req *http.Request, apiCfg apiConfig) {
-	var chirps []database.Chirp
-	var err error
 	authorID := req.URL.Query().Get("author_id")
 	sortDir := req.URL.Query().Get("sort")
+	if sortDir != "" && sortDir != "asc" && sortDir != "desc" {
+		respondWithError(wri, 400, "sort must be asc or desc")
+		return
+	}
+	desc := sortDir == "desc"
+	start, limit, err := parsePage(req, desc)
+	if err != nil {
+		respondWithProblem(wri, err)
+		return
+	}
+	author := uuid.NullUUID{}
 	if authorID != "" {
-		auUU, _ := uuid.Parse(authorID)
-		chirps, err = apiCfg.dbQueries.GetChirpsByUser(req.Context(), auUU)
+		author.UUID, err = uuid.Parse(authorID)
 		if err != nil {
-			respondWithError(wri, 500, fmt.Sprintf("Error getting chirps: %v", err))
+			respondWithError(wri, 400, "Invalid author_id")
 			return
 		}
-	} else {
-		chirps, err = apiCfg.dbQueries.GetAllChirps(req.Context())
+		author.Valid = true
+	}
+
+	// ask for one extra so we know whether there's another page
+	entries, err := listChirps(req.Context(), apiCfg, author, start, desc, limit+1)
+	if err != nil {
+		respondWithProblem(wri, fmt.Errorf("getting chirps: %w", err))
+		return
+	}
+	if len(entries) > int(limit) {
+		entries = entries[:limit]
+		last := entries[len(entries)-1]
+		setNextLink(wri, req, cursor{CreatedAt: last.EntryAt, ID: last.EntryID})
+	}
+	output, err := timelineResponses(req, apiCfg, entries)
+	if err != nil {
+		respondWithProblem(wri, fmt.Errorf("getting chirps: %w", err))
+		return
+	}
+	respondWithJSON(wri, 200, output)
+}
+
+// turns a chirp into its response, without any of the counts filled in
+func chirpResponse(c database.Chirp) chirpParam {
+	res := chirpParam{
+		ID: c.ID,
+		CreatedAt: c.CreatedAt,
+		UpdatedAt: c.UpdatedAt,
+		Body: c.Body,
+		UserID: c.UserID,
+		Mentions: []mentionParam{},
+		Media: []mediaParam{},
+		Deleted: c.DeletedAt.Valid,
+	}
+	// a deleted chirp keeps its body until it's purged, but nobody gets to see it
+	if c.DeletedAt.Valid {
+		res.Body = ""
+	}
+	if c.InReplyTo.Valid {
+		parent := c.InReplyTo.UUID
+		res.InReplyTo = &parent
+	}
+	if c.QuoteOf.Valid {
+		quoted := c.QuoteOf.UUID
+		res.QuoteOf = &quoted
+	}
+	if c.EditedAt.Valid {
+		editedAt := c.EditedAt.Time
+		res.Edited = true
+		res.EditedAt = &editedAt
+	}
+	return res
+}
+
+// turns chirps into responses, with the counts looked up for all of them at once
+// if the request is from a logged in user, liked_by_me gets filled in for them too
+func chirpResponses(req *http.Request, apiCfg apiConfig, chirps []database.Chirp) ([]chirpParam, error) {
+	ctx := req.Context()
+	ids := []uuid.UUID{}
+	for _, c := range chirps {
+		ids = append(ids, c.ID)
+	}
+	counts, err := apiCfg.dbQueries.CountReplies(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	replyCounts := map[uuid.UUID]int64{}
+	for _, c := range counts {
+		replyCounts[c.InReplyTo.UUID] = c.ReplyCount
+	}
+	likes, err := apiCfg.dbQueries.CountLikes(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	likeCounts := map[uuid.UUID]int64{}
+	for _, l := range likes {
+		likeCounts[l.ChirpID] = l.LikeCount
+	}
+	rechirps, err := apiCfg.dbQueries.CountRechirps(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	rechirpCounts := map[uuid.UUID]int64{}
+	for _, r := range rechirps {
+		rechirpCounts[r.ChirpID] = r.RechirpCount
+	}
+	quotedIDs := []uuid.UUID{}
+	for _, c := range chirps {
+		if c.QuoteOf.Valid {
+			quotedIDs = append(quotedIDs, c.QuoteOf.UUID)
+		}
+	}
+	quotes, err := apiCfg.dbQueries.GetChirpsByIDs(ctx, quotedIDs)
+	if err != nil {
+		return nil, err
+	}
+	quoted := map[uuid.UUID]database.Chirp{}
+	for _, q := range quotes {
+		quoted[q.ID] = q
+	}
+	mentions, err := apiCfg.dbQueries.GetMentionsForChirps(ctx, append(ids, quotedIDs...))
+	if err != nil {
+		return nil, err
+	}
+	mentioned := map[uuid.UUID][]mentionParam{}
+	for _, m := range mentions {
+		mentioned[m.ChirpID] = append(mentioned[m.ChirpID], mentionParam{UserID: m.UserID, Handle: m.Handle.String})
+	}
+	media, err := apiCfg.dbQueries.GetMediaForChirps(ctx, append(ids, quotedIDs...))
+	if err != nil {
+		return nil, err
+	}
+	attached := map[uuid.UUID][]mediaParam{}
+	for _, m := range media {
+		attached[m.ChirpID.UUID] = append(attached[m.ChirpID.UUID], mediaResponse(m))
+	}
+	likedByMe := map[uuid.UUID]bool{}
+	if viewer, ok := optionalUser(req, apiCfg); ok {
+		liked, err := apiCfg.dbQueries.GetLikedByUser(ctx, database.GetLikedByUserParams{UserID: viewer, ChirpIds: ids})
 		if err != nil {
-			respondWithError(wri, 500, fmt.Sprintf("Error getting chirps: %v", err))
-			return
+			return nil, err
+		}
+		for _, id := range liked {
+			likedByMe[id] = true
 		}
 	}
 	output := []chirpParam{}
 	for _, c := range chirps {
-		output = append(output, chirpParam{
-			ID: c.ID,
-			CreatedAt: c.CreatedAt,
-			UpdatedAt: c.UpdatedAt,
-			Body: c.Body,
-			UserID: c.UserID,
+		res := chirpResponse(c)
+		res.ReplyCount = replyCounts[c.ID]
+		res.LikeCount = likeCounts[c.ID]
+		res.LikedByMe = likedByMe[c.ID]
+		res.RechirpCount = rechirpCounts[c.ID]
+		if m, ok := mentioned[c.ID]; ok && !c.DeletedAt.Valid {
+			res.Mentions = m
+		}
+		if m, ok := attached[c.ID]; ok && !c.DeletedAt.Valid {
+			res.Media = m
+		}
+		// a quote of something that's since been deleted still shows up, just without the quoted chirp
+		if c.QuoteOf.Valid {
+			res.QuotedChirp = &quotedChirpParam{ID: c.QuoteOf.UUID, Unavailable: true}
+			if q, ok := quoted[c.QuoteOf.UUID]; ok && !q.DeletedAt.Valid {
+				quotedRes := chirpResponse(q)
+				if m, ok := mentioned[q.ID]; ok {
+					quotedRes.Mentions = m
+				}
+				if m, ok := attached[q.ID]; ok {
+					quotedRes.Media = m
+				}
+				res.QuotedChirp.Unavailable = false
+				res.QuotedChirp.chirpParam = &quotedRes
+			}
+		}
+		output = append(output, res)
+	}
+	return output, nil
+}
+
+// looks up the chirp in the path, responding with a 404 if it's not there (or a 410 if it's been deleted)
+func getLiveChirp(wri http.ResponseWriter, req *http.Request, apiCfg apiConfig) (database.Chirp, bool) {
+	chirpID, _ := uuid.Parse(req.PathValue("chirpID"))
+	chirp, err := apiCfg.dbQueries.GetSingleChirp(req.Context(), chirpID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithProblem(wri, errChirpNotFound)
+		} else {
+			respondWithProblem(wri, fmt.Errorf("getting chirp: %w", err))
+		}
+		return database.Chirp{}, false
+	}
+	if chirp.DeletedAt.Valid {
+		respondWithProblem(wri, errChirpDeleted)
+		return database.Chirp{}, false
+	}
+	return chirp, true
+}
+
+// gets the user from the request's JWT
+func authenticate(req *http.Request, apiCfg apiConfig) (uuid.UUID, error) {
+	user, _, err := authenticateRole(req, apiCfg)
+	return user, err
+}
+
+// gets the user from the request's JWT, and their role
+// a JWT outlives its user being deleted, suspended, banned or demoted, so their account is checked every time
+// and the role is the one they have now, not the one in the JWT
+func authenticateRole(req *http.Request, apiCfg apiConfig) (uuid.UUID, string, error) {
+	bearer, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		return uuid.UUID{}, "", err
+	}
+	userID, _, err := auth.ParseJWT(bearer, apiCfg.secret)
+	if err != nil {
+		return uuid.UUID{}, "", err
+	}
+	user, err := apiCfg.dbQueries.GetUserByID(req.Context(), userID)
+	if err != nil {
+		return uuid.UUID{}, "", fmt.Errorf("Error getting user: %v", err)
+	}
+	if user.DeletedAt.Valid {
+		return uuid.UUID{}, "", errors.New("Account is deleted")
+	}
+	if blocked := accountBlocked(user); blocked != "" {
+		return uuid.UUID{}, "", fmt.Errorf("%s", blocked)
+	}
+	return userID, user.Role, nil
+}
+
+// the user in the request's JWT, going by its signature alone
+// that's cheap enough to do for every request, but doesn't check they're still allowed in,
+// so it's only good for counting and logging requests
+func tokenUser(req *http.Request, apiCfg apiConfig) (uuid.UUID, bool) {
+	bearer, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		return uuid.UUID{}, false
+	}
+	userID, _, err := auth.ParseJWT(bearer, apiCfg.secret)
+	return userID, err == nil
+}
+
+// the user from the request's JWT, if there's a valid one
+func optionalUser(req *http.Request, apiCfg apiConfig) (uuid.UUID, bool) {
+	user, err := authenticate(req, apiCfg)
+	return user, err == nil
+}
+
+// one entry on a timeline, either a chirp or someone rechirping one
+// (RechirpedBy is uuid.Nil for plain chirps, and EntryID is the rechirp's own id otherwise)
+type timelineEntry struct {
+	Chirp database.Chirp
+	EntryID uuid.UUID
+	EntryAt time.Time
+	RechirpedBy uuid.UUID
+}
+
+// picks the right pagination query for the author filter and sort direction
+func listChirps(ctx context.Context, apiCfg apiConfig, author uuid.NullUUID, start cursor, desc bool, limit int32) ([]timelineEntry, error) {
+	entries := []timelineEntry{}
+	if author.Valid && desc {
+		rows, err := apiCfg.dbQueries.ListChirpsByUserDesc(ctx, database.ListChirpsByUserDescParams{
+			UserID: author.UUID,
+			BeforeCreatedAt: start.CreatedAt,
+			BeforeID: start.ID,
+			PageLimit: limit,
+		})
+		for _, r := range rows {
+			entries = append(entries, timelineEntry(r))
+		}
+		return entries, err
+	}
+	if author.Valid {
+		rows, err := apiCfg.dbQueries.ListChirpsByUserAsc(ctx, database.ListChirpsByUserAscParams{
+			UserID: author.UUID,
+			AfterCreatedAt: start.CreatedAt,
+			AfterID: start.ID,
+			PageLimit: limit,
 		})
+		for _, r := range rows {
+			entries = append(entries, timelineEntry(r))
+		}
+		return entries, err
+	}
+	if desc {
+		rows, err := apiCfg.dbQueries.ListChirpsDesc(ctx, database.ListChirpsDescParams{
+			BeforeCreatedAt: start.CreatedAt,
+			BeforeID: start.ID,
+			PageLimit: limit,
+		})
+		for _, r := range rows {
+			entries = append(entries, timelineEntry(r))
+		}
+		return entries, err
+	}
+	rows, err := apiCfg.dbQueries.ListChirpsAsc(ctx, database.ListChirpsAscParams{
+		AfterCreatedAt: start.CreatedAt,
+		AfterID: start.ID,
+		PageLimit: limit,
+	})
+	for _, r := range rows {
+		entries = append(entries, timelineEntry(r))
+	}
+	return entries, err
+}
+
+// turns timeline entries into responses, with rechirps attributed to whoever rechirped them
+func timelineResponses(req *http.Request, apiCfg apiConfig, entries []timelineEntry) ([]chirpParam, error) {
+	chirps := []database.Chirp{}
+	for _, e := range entries {
+		chirps = append(chirps, e.Chirp)
+	}
+	output, err := chirpResponses(req, apiCfg, chirps)
+	if err != nil {
+		return nil, err
 	}
-	if sortDir == "desc" {
-		sort.Slice(output, func(i, j int) bool { return output[i].CreatedAt.Compare(output[j].CreatedAt) > 0})
-	} else if sortDir == "asc" || sortDir == "" {
-		sort.Slice(output, func(i, j int) bool { return output[i].CreatedAt.Compare(output[j].CreatedAt) < 0})
+	for i, e := range entries {
+		if e.RechirpedBy != uuid.Nil {
+			rechirpedBy, rechirpedAt := e.RechirpedBy, e.EntryAt
+			output[i].RechirpedBy = &rechirpedBy
+			output[i].RechirpedAt = &rechirpedAt
+		}
+	}
+	return output, nil
+}
+
+// search chirps
+func searchChirps(wri http.ResponseWriter, req *http.Request, apiCfg apiConfig) {
+	query := req.URL.Query()
+	params := database.SearchChirpsParams{
+		Query: strings.TrimSpace(query.Get("q")),
+		Since: firstCursor.CreatedAt,
+		Until: lastCursor.CreatedAt,
+		ByRelevance: true,
+		PageLimit: defaultPageLimit,
+	}
+	if params.Query == "" {
+		respondWithError(wri, 400, "q is required")
+		return
+	}
+	if authorID := query.Get("author_id"); authorID != "" {
+		author, err := uuid.Parse(authorID)
+		if err != nil {
+			respondWithError(wri, 400, "Invalid author_id")
+			return
+		}
+		params.AuthorID = uuid.NullUUID{UUID: author, Valid: true}
+	}
+	// date ranges are RFC 3339, since is inclusive and until isn't
+	var err error
+	if since := query.Get("since"); since != "" {
+		params.Since, err = time.Parse(time.RFC3339, since)
+		if err != nil {
+			respondWithError(wri, 400, "since must be an RFC 3339 timestamp")
+			return
+		}
+	}
+	if until := query.Get("until"); until != "" {
+		params.Until, err = time.Parse(time.RFC3339, until)
+		if err != nil {
+			respondWithError(wri, 400, "until must be an RFC 3339 timestamp")
+			return
+		}
+	}
+	switch query.Get("order") {
+	case "", "relevance":
+	case "recent":
+		params.ByRelevance = false
+	default:
+		respondWithError(wri, 400, "order must be relevance or recent")
+		return
+	}
+	if limit := query.Get("limit"); limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil || l < 1 || l > maxPageLimit {
+			respondWithError(wri, 400, fmt.Sprintf("limit must be between 1 and %d", maxPageLimit))
+			return
+		}
+		params.PageLimit = int32(l)
+	}
+	if offset := query.Get("offset"); offset != "" {
+		o, err := strconv.Atoi(offset)
+		if err != nil || o < 0 {
+			respondWithError(wri, 400, "offset must be 0 or more")
+			return
+		}
+		params.PageOffset = int32(o)
+	}
+
+	results, err := apiCfg.dbQueries.SearchChirps(req.Context(), params)
+	if err != nil {
+		respondWithProblem(wri, fmt.Errorf("searching chirps: %w", err))
+		return
+	}
+	chirps := []database.Chirp{}
+	for _, r := range results {
+		chirps = append(chirps, r.Chirp)
+	}
+	responses, err := chirpResponses(req, apiCfg, chirps)
+	if err != nil {
+		respondWithProblem(wri, fmt.Errorf("searching chirps: %w", err))
+		return
+	}
+	output := []searchResultParam{}
+	for i, r := range results {
+		output = append(output, searchResultParam{chirpParam: responses[i], Snippet: r.Snippet})
 	}
 	respondWithJSON(wri, 200, output)
 }
@@ -56,21 +432,24 @@ func getChirpByID(wri http.ResponseWriter, req *http.Request, apiCfg apiConfig)
 	chirpID, _ := uuid.Parse(req.PathValue("chirpID"))
 	chirp, err := apiCfg.dbQueries.GetSingleChirp(req.Context(), chirpID)
 	if err != nil {
-		if strings.Contains(fmt.Sprint(err), "no rows in result set") {
-			respondWithError(wri, 404, fmt.Sprint("Chirp not found"))
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithProblem(wri, errChirpNotFound)
 		} else {
-			respondWithError(wri, 500, fmt.Sprintf("Error getting chirp: %v", err))
+			respondWithProblem(wri, fmt.Errorf("getting chirp: %w", err))
 		}
 		return
 	}
-	resBody := chirpParam {
-		ID: chirp.ID,
-		CreatedAt: chirp.CreatedAt,
-		UpdatedAt: chirp.UpdatedAt,
-		Body: chirp.Body,
-		UserID: chirp.UserID,
+	resBody, err := chirpResponses(req, apiCfg, []database.Chirp{chirp})
+	if err != nil {
+		respondWithProblem(wri, fmt.Errorf("getting chirp: %w", err))
+		return
 	}
-	respondWithJSON(wri, 200, resBody)
+	// deleted chirps answer with their tombstone, so clients can tell them apart from ones that never existed
+	if chirp.DeletedAt.Valid {
+		respondWithJSON(wri, 410, resBody[0])
+		return
+	}
+	respondWithJSON(wri, 200, resBody[0])
 }
 
 // create a new chirp
@@ -78,6 +457,9 @@ func postChirp(wri http.ResponseWriter, req *http.Request, apiCfg apiConfig) {
 	type reqParam struct {
 		Body string `json:"body"`
 		UserID uuid.UUID `json:"user_id"`
+		InReplyTo *uuid.UUID `json:"in_reply_to"`
+		QuoteOf *uuid.UUID `json:"quote_of"`
+		MediaIDs []uuid.UUID `json:"media_ids"`
 	}
 	
 	// first decode the request
@@ -85,39 +467,72 @@ func postChirp(wri http.ResponseWriter, req *http.Request, apiCfg apiConfig) {
 	reqBody := reqParam{}
 	err := decoder.Decode(&reqBody)
 	if err != nil {
-		respondWithError(wri, 500, fmt.Sprintf("Error decoding request: %v", err))
-		return
-	}
-	if len(reqBody.Body) > 140 {
-		respondWithError(wri, 400, "Chirp is too long")
+		respondWithProblem(wri, errInvalidBody.Wrap(err))
 		return
 	}
-
 	// make sure the user is valid
-	bearer, err := auth.GetBearerToken(req.Header)
+	user, err := authenticate(req, apiCfg)
 	if err != nil {
-		respondWithError(wri, 500, fmt.Sprintf("Error getting bearer token: %v", err))
+		respondWithProblem(wri, errUnauthorized)
 		return
 	}
-	user, err := auth.ValidateJWT(bearer, apiCfg.secret)
-	if err != nil {
-		respondWithError(wri, 401, "Unauthorized")
+	
+	// replies have to be to a chirp that's still there
+	inReplyTo := uuid.NullUUID{}
+	if reqBody.InReplyTo != nil {
+		parent, err := apiCfg.dbQueries.GetSingleChirp(req.Context(), *reqBody.InReplyTo)
+		if err != nil || parent.DeletedAt.Valid {
+			respondWithError(wri, 400, "in_reply_to must be an existing chirp")
+			return
+		}
+		inReplyTo = uuid.NullUUID{UUID: parent.ID, Valid: true}
+	}
+	// and so do quotes
+	quoteOf := uuid.NullUUID{}
+	if reqBody.QuoteOf != nil {
+		quoted, err := apiCfg.dbQueries.GetSingleChirp(req.Context(), *reqBody.QuoteOf)
+		if err != nil || quoted.DeletedAt.Valid {
+			respondWithError(wri, 400, "quote_of must be an existing chirp")
+			return
+		}
+		quoteOf = uuid.NullUUID{UUID: quoted.ID, Valid: true}
+	}
+	if !validMedia(wri, req, apiCfg, reqBody.MediaIDs, user) {
+		return
+	}
+	res, ok := moderateChirp(wri, req, apiCfg, user, reqBody.Body)
+	if !ok {
 		return
 	}
 	
-	chirp, err := apiCfg.dbQueries.CreateChirp(req.Context(), database.CreateChirpParams{Body: profanityFilter(reqBody.Body), UserID: user,})
+	// held chirps wait in the review queue, and only go up once a moderator approves them
+	if res.Decision == moderation.Hold {
+		held, err := holdChirp(req.Context(), apiCfg, database.CreateHeldChirpParams{
+			UserID: user,
+			Body: res.Body,
+			InReplyTo: inReplyTo,
+			QuoteOf: quoteOf,
+			Stage: res.Stage,
+			Reason: res.Reason,
+		}, reqBody.MediaIDs)
+		if err != nil {
+			respondWithProblem(wri, fmt.Errorf("holding chirp: %w", err))
+			return
+		}
+		respondWithJSON(wri, 202, held)
+		return
+	}
+	chirp, err := publishChirp(req.Context(), apiCfg, database.CreateChirpParams{Body: res.Body, UserID: user, InReplyTo: inReplyTo, QuoteOf: quoteOf}, reqBody.MediaIDs, res.Flags)
 	if err != nil {
-		respondWithError(wri, 500, fmt.Sprintf("Error creating chirp: %v", err))
+		respondWithProblem(wri, fmt.Errorf("creating chirp: %w", err))
 		return
 	}
-	resBody := chirpParam{
-		ID: chirp.ID,
-		CreatedAt: chirp.CreatedAt,
-		UpdatedAt: chirp.CreatedAt,
-		Body: chirp.Body,
-		UserID: chirp.UserID,
+	resBody, err := chirpResponses(req, apiCfg, []database.Chirp{chirp})
+	if err != nil {
+		respondWithProblem(wri, fmt.Errorf("creating chirp: %w", err))
+		return
 	}
-	respondWithJSON(wri, 201, resBody)
+	respondWithJSON(wri, 201, resBody[0])
 }
 
 // create a new user
@@ -125,6 +540,7 @@ func postUser(wri http.ResponseWriter, req *http.Request, apiCfg apiConfig) {
 	type reqParam struct {
 		Email string `json:"email"`
 		Password string `json:"password"`
+		Handle string `json:"handle"`
 	}
 	
 	// first decode the request
@@ -132,17 +548,47 @@ func postUser(wri http.ResponseWriter, req *http.Request, apiCfg apiConfig) {
 	reqBody := reqParam{}
 	err := decoder.Decode(&reqBody)
 	if err != nil {
-		respondWithError(wri, 500, fmt.Sprintf("Error decoding request: %v", err))
+		respondWithProblem(wri, errInvalidBody.Wrap(err))
+		return
+	}
+
+	// say everything that's wrong at once, rather than one at a time
+	fields := []apierr.FieldError{}
+	if reqBody.Email == "" {
+		fields = append(fields, apierr.FieldError{Field: "email", Code: "required", Message: "Email is required"})
+	} else if !strings.Contains(reqBody.Email, "@") {
+		fields = append(fields, apierr.FieldError{Field: "email", Code: "invalid", Message: "That isn't an email address"})
+	}
+	if reqBody.Password == "" {
+		fields = append(fields, apierr.FieldError{Field: "password", Code: "required", Message: "Password is required"})
+	}
+	if len(fields) > 0 {
+		respondWithProblem(wri, apierr.Validation("invalid_user", "Some of the fields aren't right", fields...))
 		return
 	}
 
-	hashword, err := auth.HashPassword(reqBody.Password)
+	// a handle is optional, but you can't be mentioned without one
+	handle := sql.NullString{}
+	if reqBody.Handle != "" {
+		var ok bool
+		handle, ok = validHandle(wri, req, apiCfg, reqBody.Handle, uuid.Nil)
+		if !ok {
+			return
+		}
+	}
+
+	hashword, err := hashPassword(apiCfg, reqBody.Password)
 	if err != nil {
-		respondWithError(wri, 500, fmt.Sprintf("Error hashing password: %v", err))
+		respondWithProblem(wri, fmt.Errorf("hashing password: %w", err))
+		return
 	}
-	user, err := apiCfg.dbQueries.CreateUser(req.Context(), database.CreateUserParams{Email: reqBody.Email, HashedPassword: hashword})
+	user, err := apiCfg.dbQueries.CreateUser(req.Context(), database.CreateUserParams{Email: reqBody.Email, HashedPassword: hashword, Handle: handle})
 	if err != nil {
-		respondWithError(wri, 500, fmt.Sprintf("Error creating user: %v", err))
+		if apierr.IsUniqueViolation(err) {
+			respondWithProblem(wri, errEmailTaken.Wrap(err))
+		} else {
+			respondWithProblem(wri, fmt.Errorf("creating user: %w", err))
+		}
 		return
 	}
 	resBody := userParam{
@@ -150,7 +596,9 @@ func postUser(wri http.ResponseWriter, req *http.Request, apiCfg apiConfig) {
 		CreatedAt: user.CreatedAt,
 		UpdatedAt: user.CreatedAt,
 		Email: user.Email,
+		Handle: user.Handle.String,
 		IsChirpyRed: user.IsChirpyRed,
+		DMsFromFollowersOnly: user.DmsFromFollowersOnly,
 	}
 	respondWithJSON(wri, 201, resBody)
 }
@@ -167,25 +615,36 @@ func postLogin(wri http.ResponseWriter, req *http.Request, apiCfg apiConfig) {
 	reqBody := reqParam{}
 	err := decoder.Decode(&reqBody)
 	if err != nil {
-		respondWithError(wri, 500, fmt.Sprintf("Error decoding request: %v", err))
+		respondWithProblem(wri, errInvalidBody.Wrap(err))
 		return
 	}
 
 	// check authorization
 	user, err := apiCfg.dbQueries.GetUserByEmail(req.Context(), reqBody.Email)
-	if err != nil {
-		respondWithError(wri, 401, "Incorrect username or password")
+	// deleted accounts can only come back through an admin
+	if err != nil || user.DeletedAt.Valid {
+		apiCfg.metrics.loginFailures.Inc("unknown_user")
+		respondWithProblem(wri, errBadCredentials)
+		return
 	}
-	err = auth.CheckPasswordHash(reqBody.Password, user.HashedPassword)
+	err = checkPassword(apiCfg, reqBody.Password, user.HashedPassword)
 	if err != nil {
-		respondWithError(wri, 401, "Incorrect username or password")
+		apiCfg.metrics.loginFailures.Inc("wrong_password")
+		respondWithProblem(wri, errBadCredentials)
+		return
+	}
+	if blocked := accountBlocked(user); blocked != "" {
+		apiCfg.metrics.loginFailures.Inc("blocked")
+		respondWithProblem(wri, errAccountBlocked(blocked))
+		return
 	}
 
 	// get jwt token
 	dura, _ := time.ParseDuration(fmt.Sprintf("3600s"))
-	jwtToken, err := auth.MakeJWT(user.ID, apiCfg.secret, dura)
+	jwtToken, err := auth.MakeJWT(user.ID, user.Role, apiCfg.secret, dura)
 	if err != nil {
-		respondWithError(wri, 500, fmt.Sprintf("Error getting JWT token: %v", err))
+		respondWithProblem(wri, fmt.Errorf("getting JWT token: %w", err))
+		return
 	}
 	tokenStr := auth.MakeRefreshToken()
 	refDura, _ := time.ParseDuration(fmt.Sprintf("1440h"))
@@ -195,7 +654,8 @@ func postLogin(wri http.ResponseWriter, req *http.Request, apiCfg apiConfig) {
 		ExpiresAt: sql.NullTime{Time: time.Now().Add(refDura), Valid: true},
 	})
 	if err != nil {
-		respondWithError(wri, 500, fmt.Sprintf("Error getting refresh token: %v", err))
+		respondWithProblem(wri, fmt.Errorf("getting refresh token: %w", err))
+		return
 	}
 	
 	resBody := userParam{
@@ -203,7 +663,10 @@ func postLogin(wri http.ResponseWriter, req *http.Request, apiCfg apiConfig) {
 		CreatedAt: user.CreatedAt,
 		UpdatedAt: user.CreatedAt,
 		Email: user.Email,
+		Handle: user.Handle.String,
 		IsChirpyRed: user.IsChirpyRed,
+		DMsFromFollowersOnly: user.DmsFromFollowersOnly,
+		Role: user.Role,
 		Token: jwtToken,
 		RefreshToken: refreshToken.Token,
 	}
@@ -218,28 +681,49 @@ func refresh(wri http.ResponseWriter, req *http.Request, apiCfg apiConfig) {
 	}
 	bearer, err := auth.GetBearerToken(req.Header)
 	if err != nil {
-		respondWithError(wri, 500, fmt.Sprintf("Error getting bearer token: %v", err))
+		respondWithProblem(wri, errUnauthorized)
 		return
 	}
 	userWithExpiration, err := apiCfg.dbQueries.GetUserFromRefreshToken(req.Context(), bearer)
 	if err != nil {
-		respondWithError(wri, 401, fmt.Sprintf("Unauthorized: %v", err))
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithProblem(wri, apierr.Unauthorized("invalid_refresh_token", "Invalid refresh token"))
+		} else {
+			respondWithProblem(wri, fmt.Errorf("getting refresh token: %w", err))
+		}
 		return
 	}
 	if userWithExpiration.RevokedAt.Valid == true {
-		respondWithError(wri, 401, "Revoked")
+		respondWithProblem(wri, apierr.Unauthorized("refresh_token_revoked", "Revoked"))
 		return
 	}
 	now := time.Now()
 	if userWithExpiration.ExpiresAt.Time.Before(now) {
-		respondWithError(wri, 401, "Expired")
+		respondWithProblem(wri, apierr.Unauthorized("refresh_token_expired", "Expired"))
+		return
+	}
+	
+	// the role goes in the new token, so pick up any changes to it
+	user, err := apiCfg.dbQueries.GetUserByID(req.Context(), userWithExpiration.UserID)
+	if err != nil {
+		respondWithProblem(wri, fmt.Errorf("getting user: %w", err))
+		return
+	}
+	// deleting an account revokes its refresh tokens, but one could be in use at the same moment
+	if user.DeletedAt.Valid {
+		respondWithProblem(wri, errUnauthorized)
+		return
+	}
+	if blocked := accountBlocked(user); blocked != "" {
+		respondWithProblem(wri, errAccountBlocked(blocked))
 		return
 	}
 	
 	dura, _ := time.ParseDuration(fmt.Sprintf("3600s"))
-	jwtToken, err := auth.MakeJWT(userWithExpiration.UserID, apiCfg.secret, dura)
+	jwtToken, err := auth.MakeJWT(user.ID, user.Role, apiCfg.secret, dura)
 	if err != nil {
-		respondWithError(wri, 500, fmt.Sprintf("Error getting JWT token: %v", err))
+		respondWithProblem(wri, fmt.Errorf("getting JWT token: %w", err))
+		return
 	}
 	resBody := resParam{
 		Token: jwtToken,
@@ -251,33 +735,31 @@ func refresh(wri http.ResponseWriter, req *http.Request, apiCfg apiConfig) {
 func revoke(wri http.ResponseWriter, req *http.Request, apiCfg apiConfig) {
 	bearer, err := auth.GetBearerToken(req.Header)
 	if err != nil {
-		respondWithError(wri, 500, fmt.Sprintf("Error getting bearer token: %v", err))
+		respondWithProblem(wri, errUnauthorized)
 		return
 	}
 	err = apiCfg.dbQueries.RevokeToken(req.Context(), bearer)
 	if err != nil {
-		respondWithError(wri, 500, fmt.Sprintf("Error revoking token: %v", err))
+		respondWithProblem(wri, fmt.Errorf("revoking token: %w", err))
 		return
 	}
 	wri.WriteHeader(204)
 }
 
-// change user's email and password
+// change user's email, password, handle or DM setting
+// only what's sent is changed, so leaving out the email and password keeps them as they are
 func putUser(wri http.ResponseWriter, req *http.Request, apiCfg apiConfig) {
 	type reqParam struct {
-		Email string `json:"email"`
-		Password string `json:"password"`
+		Email *string `json:"email"`
+		Password *string `json:"password"`
+		Handle *string `json:"handle"`
+		DMsFromFollowersOnly *bool `json:"dms_from_followers_only"`
 	}
 
 	// validate the user
-	bearer, err := auth.GetBearerToken(req.Header)
+	user, err := authenticate(req, apiCfg)
 	if err != nil {
-		respondWithError(wri, 401, fmt.Sprintf("Error getting bearer token: %v", err))
-		return
-	}
-	user, err := auth.ValidateJWT(bearer, apiCfg.secret)
-	if err != nil {
-		respondWithError(wri, 401, "Unauthorized")
+		respondWithProblem(wri, errUnauthorized)
 		return
 	}
 
@@ -286,21 +768,87 @@ func putUser(wri http.ResponseWriter, req *http.Request, apiCfg apiConfig) {
 	reqBody := reqParam{}
 	err = decoder.Decode(&reqBody)
 	if err != nil {
-		respondWithError(wri, 500, fmt.Sprintf("Error decoding request: %v", err))
+		respondWithProblem(wri, errInvalidBody.Wrap(err))
 		return
 	}
 
-	hashword, err := auth.HashPassword(reqBody.Password)
-	if err != nil {
-		respondWithError(wri, 500, fmt.Sprintf("Error hashing password: %v", err))
+	// check everything before anything's written
+	fields := []apierr.FieldError{}
+	if reqBody.Email != nil && *reqBody.Email == "" {
+		fields = append(fields, apierr.FieldError{Field: "email", Code: "required", Message: "Email can't be empty"})
+	} else if reqBody.Email != nil && !strings.Contains(*reqBody.Email, "@") {
+		fields = append(fields, apierr.FieldError{Field: "email", Code: "invalid", Message: "That isn't an email address"})
+	}
+	if reqBody.Password != nil && *reqBody.Password == "" {
+		fields = append(fields, apierr.FieldError{Field: "password", Code: "required", Message: "Password can't be empty"})
+	}
+	if len(fields) > 0 {
+		respondWithProblem(wri, apierr.Validation("invalid_user", "Some of the fields aren't right", fields...))
+		return
 	}
-	updatedUser, err := apiCfg.dbQueries.UpdateEmailAndPassword(req.Context(), database.UpdateEmailAndPasswordParams{
-		ID: user,
-		Email: reqBody.Email,
-		HashedPassword: hashword,
+	var handle sql.NullString
+	if reqBody.Handle != nil {
+		var ok bool
+		handle, ok = validHandle(wri, req, apiCfg, *reqBody.Handle, user)
+		if !ok {
+			return
+		}
+	}
+	var hashword string
+	if reqBody.Password != nil {
+		hashword, err = hashPassword(apiCfg, *reqBody.Password)
+		if err != nil {
+			respondWithProblem(wri, fmt.Errorf("hashing password: %w", err))
+			return
+		}
+	}
+
+	// all or nothing, so a failure part way doesn't leave some of it changed
+	var updatedUser database.User
+	err = inTx(req.Context(), apiCfg, func(apiCfg apiConfig) error {
+		updatedUser, err = apiCfg.dbQueries.GetUserByID(req.Context(), user)
+		if err != nil {
+			return err
+		}
+		if reqBody.Email != nil || reqBody.Password != nil {
+			// whichever of the two wasn't sent stays as it is
+			params := database.UpdateEmailAndPasswordParams{ID: user, Email: updatedUser.Email, HashedPassword: updatedUser.HashedPassword}
+			if reqBody.Email != nil {
+				params.Email = *reqBody.Email
+			}
+			if reqBody.Password != nil {
+				params.HashedPassword = hashword
+			}
+			updatedUser, err = apiCfg.dbQueries.UpdateEmailAndPassword(req.Context(), params)
+			if apierr.IsUniqueViolation(err) {
+				return errEmailTaken.Wrap(err)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		if reqBody.Handle != nil {
+			updatedUser, err = apiCfg.dbQueries.UpdateHandle(req.Context(), database.UpdateHandleParams{ID: user, Handle: handle})
+			// someone could have taken it since it was checked
+			if apierr.IsUniqueViolation(err) {
+				return errHandleTaken.Wrap(err)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		if reqBody.DMsFromFollowersOnly != nil {
+			updatedUser, err = apiCfg.dbQueries.UpdateDMSetting(req.Context(), database.UpdateDMSettingParams{ID: user, DmsFromFollowersOnly: *reqBody.DMsFromFollowersOnly})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
 	})
 	if err != nil {
-		respondWithError(wri, 500, fmt.Sprintf("Error updating user: %v", err))
+		// the email and handle being taken come through as they are
+		respondWithProblem(wri, fmt.Errorf("updating user: %w", err))
+		return
 	}
 
 	resBody := userParam{
@@ -308,7 +856,9 @@ func putUser(wri http.ResponseWriter, req *http.Request, apiCfg apiConfig) {
 		CreatedAt: updatedUser.CreatedAt,
 		UpdatedAt: updatedUser.CreatedAt,
 		Email: updatedUser.Email,
+		Handle: updatedUser.Handle.String,
 		IsChirpyRed: updatedUser.IsChirpyRed,
+		DMsFromFollowersOnly: updatedUser.DmsFromFollowersOnly,
 		//Token: updatedUser.Token,
 		//RefreshToken: updatedUser.RefreshToken,
 	}
@@ -317,14 +867,9 @@ func putUser(wri http.ResponseWriter, req *http.Request, apiCfg apiConfig) {
 
 func deleteChirp(wri http.ResponseWriter, req *http.Request, apiCfg apiConfig) {
 	// validate the user
-	bearer, err := auth.GetBearerToken(req.Header)
+	user, err := authenticate(req, apiCfg)
 	if err != nil {
-		respondWithError(wri, 401, fmt.Sprintf("Error getting bearer token: %v", err))
-		return
-	}
-	user, err := auth.ValidateJWT(bearer, apiCfg.secret)
-	if err != nil {
-		respondWithError(wri, 403, "JWT is invalid")
+		respondWithProblem(wri, errUnauthorized)
 		return
 	}
 
@@ -332,24 +877,30 @@ func deleteChirp(wri http.ResponseWriter, req *http.Request, apiCfg apiConfig) {
 	chirpID, _ := uuid.Parse(req.PathValue("chirpID"))
 	chirp, err := apiCfg.dbQueries.GetSingleChirp(req.Context(), chirpID)
 	if err != nil {
-		if strings.Contains(fmt.Sprint(err), "no rows in result set") {
-			respondWithError(wri, 404, fmt.Sprint("Chirp not found"))
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithProblem(wri, errChirpNotFound)
 		} else {
-			respondWithError(wri, 404, fmt.Sprintf("Error getting chirp: %v", err))
+			respondWithProblem(wri, fmt.Errorf("getting chirp: %w", err))
 		}
 		return
 	}
+	if chirp.DeletedAt.Valid {
+		respondWithProblem(wri, errChirpDeleted)
+		return
+	}
 
 	// compare chirp owner to user
 	if chirp.UserID != user {
-		respondWithError(wri, 403, "Unauthorized")
+		respondWithProblem(wri, apierr.Forbidden("not_your_chirp", "That's not your chirp"))
 		return
 	}
 
 	// delete the chrip
-	err = apiCfg.dbQueries.DeleteSingleChirp(req.Context(), chirp.ID)
+	// it only gets marked as deleted here, the purge job gets rid of it once the grace period's up
+	err = apiCfg.dbQueries.SoftDeleteChirp(req.Context(), chirp.ID)
 	if err != nil {
-		respondWithError(wri, 500, fmt.Sprintf("Error deleting chirp: %v", err))
+		respondWithProblem(wri, fmt.Errorf("deleting chirp: %w", err))
+		return
 	}
 	wri.WriteHeader(204)
 }
@@ -359,11 +910,11 @@ func polkaWebhooks(wri http.ResponseWriter, req *http.Request, apiCfg apiConfig)
 	// first we need to verify the API Key
 	key, err := auth.GetAPIKey(req.Header)
 	if err != nil {
-		respondWithError(wri, 401, fmt.Sprintf("Error getting api key: %v", err))
+		respondWithProblem(wri, errUnauthorized)
 		return
 	}
 	if key != apiCfg.polka_key {
-		respondWithError(wri, 401, "Unauthorized")
+		respondWithProblem(wri, errUnauthorized)
 		return
 	}
 
@@ -379,7 +930,7 @@ func polkaWebhooks(wri http.ResponseWriter, req *http.Request, apiCfg apiConfig)
 	reqBody := reqParam{}
 	err = decoder.Decode(&reqBody)
 	if err != nil {
-		respondWithError(wri, 500, fmt.Sprintf("Error decoding request: %v", err))
+		respondWithProblem(wri, errInvalidBody.Wrap(err))
 		return
 	}
 
@@ -393,7 +944,7 @@ func polkaWebhooks(wri http.ResponseWriter, req *http.Request, apiCfg apiConfig)
 	userID, _ := uuid.Parse(reqBody.Data.UserID)
 	err = apiCfg.dbQueries.UpgradeToRed(req.Context(), userID)
 	if err != nil {
-		respondWithError(wri, 404, "User not found")
+		respondWithProblem(wri, errUserNotFound)
 		return
 	}
 
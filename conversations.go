@@ -0,0 +1,402 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"internal/database"
+	"net/http"
+	"slices"
+)
+
+// "small group" conversations, counting whoever starts it
+const maxConversationSize = 10
+const maxMessageLength = 1000
+
+// whether sender is allowed to message recipient
+// people who only take DMs from their followers only hear from people who follow them
+func canMessage(ctx context.Context, apiCfg apiConfig, sender uuid.UUID, recipient database.User) (bool, error) {
+	if !recipient.DmsFromFollowersOnly {
+		return true, nil
+	}
+	_, err := apiCfg.dbQueries.GetFollow(ctx, database.GetFollowParams{FollowerID: sender, FolloweeID: recipient.ID})
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// looks up the conversation in the path, responding with a 404 unless the user is (still) in it
+func getMyConversation(wri http.ResponseWriter, req *http.Request, apiCfg apiConfig, user uuid.UUID) (database.Conversation, bool) {
+	conversationID, err := uuid.Parse(req.PathValue("conversationID"))
+	if err != nil {
+		respondWithProblem(wri, errConversationNotFound)
+		return database.Conversation{}, false
+	}
+	participant, err := apiCfg.dbQueries.GetParticipant(req.Context(), database.GetParticipantParams{ConversationID: conversationID, UserID: user})
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && participant.LeftAt.Valid) {
+		respondWithProblem(wri, errConversationNotFound)
+		return database.Conversation{}, false
+	}
+	if err != nil {
+		respondWithProblem(wri, fmt.Errorf("getting conversation: %w", err))
+		return database.Conversation{}, false
+	}
+	conversation, err := apiCfg.dbQueries.GetConversation(req.Context(), conversationID)
+	if err != nil {
+		respondWithProblem(wri, fmt.Errorf("getting conversation: %w", err))
+		return database.Conversation{}, false
+	}
+	return conversation, true
+}
+
+// turns conversations into responses, with everyone's read markers looked up at once
+func conversationResponses(ctx context.Context, apiCfg apiConfig, conversations []database.Conversation, unread map[uuid.UUID]int64) ([]conversationParam, error) {
+	ids := []uuid.UUID{}
+	for _, c := range conversations {
+		ids = append(ids, c.ID)
+	}
+	participants, err := apiCfg.dbQueries.ListParticipants(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	byConversation := map[uuid.UUID][]participantParam{}
+	for _, p := range participants {
+		res := participantParam{UserID: p.UserID, JoinedAt: p.JoinedAt, Left: p.LeftAt.Valid}
+		if p.LastReadAt.Valid {
+			lastRead := p.LastReadAt.Time
+			res.LastReadAt = &lastRead
+		}
+		byConversation[p.ConversationID] = append(byConversation[p.ConversationID], res)
+	}
+	output := []conversationParam{}
+	for _, c := range conversations {
+		output = append(output, conversationParam{
+			ID: c.ID,
+			CreatedAt: c.CreatedAt,
+			UpdatedAt: c.UpdatedAt,
+			IsGroup: c.IsGroup,
+			Participants: byConversation[c.ID],
+			UnreadCount: unread[c.ID],
+		})
+	}
+	return output, nil
+}
+
+func messageResponse(m database.Message) messageParam {
+	return messageParam{
+		ID: m.ID,
+		CreatedAt: m.CreatedAt,
+		ConversationID: m.ConversationID,
+		SenderID: m.SenderID,
+		Body: m.Body,
+	}
+}
+
+// start a conversation with one or more people
+// asking for a one-to-one conversation you already have gets you the existing one back
+func postConversation(wri http.ResponseWriter, req *http.Request, apiCfg apiConfig) {
+	type reqParam struct {
+		ParticipantIDs []uuid.UUID `json:"participant_ids"`
+	}
+	user, err := authenticate(req, apiCfg)
+	if err != nil {
+		respondWithProblem(wri, errUnauthorized)
+		return
+	}
+	decoder := json.NewDecoder(req.Body)
+	reqBody := reqParam{}
+	err = decoder.Decode(&reqBody)
+	if err != nil {
+		respondWithProblem(wri, errInvalidBody.Wrap(err))
+		return
+	}
+
+	// you're always in your own conversations, so you don't need to list yourself
+	others := []uuid.UUID{}
+	for _, id := range reqBody.ParticipantIDs {
+		if id != user && !slices.Contains(others, id) {
+			others = append(others, id)
+		}
+	}
+	if len(others) == 0 {
+		respondWithError(wri, 400, "participant_ids must include someone other than you")
+		return
+	}
+	if len(others)+1 > maxConversationSize {
+		respondWithError(wri, 400, fmt.Sprintf("Conversations can have at most %d people in them", maxConversationSize))
+		return
+	}
+	for _, id := range others {
+		other, err := apiCfg.dbQueries.GetUserByID(req.Context(), id)
+		if errors.Is(err, sql.ErrNoRows) || (err == nil && other.DeletedAt.Valid) {
+			respondWithError(wri, 404, fmt.Sprintf("User %v not found", id))
+			return
+		}
+		if err != nil {
+			respondWithProblem(wri, fmt.Errorf("getting user: %w", err))
+			return
+		}
+		ok, err := canMessage(req.Context(), apiCfg, user, other)
+		if err != nil {
+			respondWithProblem(wri, fmt.Errorf("checking DM settings: %w", err))
+			return
+		}
+		if !ok {
+			respondWithError(wri, 403, fmt.Sprintf("User %v only accepts messages from their followers", id))
+			return
+		}
+	}
+
+	status := 201
+	var conversation database.Conversation
+	if len(others) == 1 {
+		conversation, err = apiCfg.dbQueries.FindDirectConversation(req.Context(), database.FindDirectConversationParams{UserID: user, OtherID: others[0]})
+		if err == nil {
+			status = 200
+		} else if !errors.Is(err, sql.ErrNoRows) {
+			respondWithProblem(wri, fmt.Errorf("getting conversation: %w", err))
+			return
+		}
+	}
+	if status == 201 {
+		// all or nothing, so there's never a conversation missing some of its people
+		err = inTx(req.Context(), apiCfg, func(apiCfg apiConfig) error {
+			conversation, err = apiCfg.dbQueries.CreateConversation(req.Context(), len(others) > 1)
+			if err != nil {
+				return err
+			}
+			for _, id := range append([]uuid.UUID{user}, others...) {
+				err = apiCfg.dbQueries.AddParticipant(req.Context(), database.AddParticipantParams{ConversationID: conversation.ID, UserID: id})
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			respondWithProblem(wri, fmt.Errorf("creating conversation: %w", err))
+			return
+		}
+	}
+	output, err := conversationResponses(req.Context(), apiCfg, []database.Conversation{conversation}, nil)
+	if err != nil {
+		respondWithProblem(wri, fmt.Errorf("getting conversation: %w", err))
+		return
+	}
+	respondWithJSON(wri, status, output[0])
+}
+
+// get the logged in user's conversations, most recently active first
+func getConversations(wri http.ResponseWriter, req *http.Request, apiCfg apiConfig) {
+	user, err := authenticate(req, apiCfg)
+	if err != nil {
+		respondWithProblem(wri, errUnauthorized)
+		return
+	}
+	start, limit, err := parsePage(req, true)
+	if err != nil {
+		respondWithProblem(wri, err)
+		return
+	}
+	rows, err := apiCfg.dbQueries.ListConversations(req.Context(), database.ListConversationsParams{
+		UserID: user,
+		BeforeUpdatedAt: start.CreatedAt,
+		BeforeID: start.ID,
+		PageLimit: limit + 1,
+	})
+	if err != nil {
+		respondWithProblem(wri, fmt.Errorf("getting conversations: %w", err))
+		return
+	}
+	// the cursor here is on when the conversation was last active
+	if len(rows) > int(limit) {
+		rows = rows[:limit]
+		last := rows[len(rows)-1].Conversation
+		setNextLink(wri, req, cursor{CreatedAt: last.UpdatedAt, ID: last.ID})
+	}
+	conversations := []database.Conversation{}
+	unread := map[uuid.UUID]int64{}
+	for _, r := range rows {
+		conversations = append(conversations, r.Conversation)
+		unread[r.Conversation.ID] = r.UnreadCount
+	}
+	output, err := conversationResponses(req.Context(), apiCfg, conversations, unread)
+	if err != nil {
+		respondWithProblem(wri, fmt.Errorf("getting conversations: %w", err))
+		return
+	}
+	respondWithJSON(wri, 200, output)
+}
+
+// send a message to a conversation you're in
+func postMessage(wri http.ResponseWriter, req *http.Request, apiCfg apiConfig) {
+	type reqParam struct {
+		Body string `json:"body"`
+	}
+	user, err := authenticate(req, apiCfg)
+	if err != nil {
+		respondWithProblem(wri, errUnauthorized)
+		return
+	}
+	conversation, ok := getMyConversation(wri, req, apiCfg, user)
+	if !ok {
+		return
+	}
+	decoder := json.NewDecoder(req.Body)
+	reqBody := reqParam{}
+	err = decoder.Decode(&reqBody)
+	if err != nil {
+		respondWithProblem(wri, errInvalidBody.Wrap(err))
+		return
+	}
+	if reqBody.Body == "" {
+		respondWithError(wri, 400, "Message is empty")
+		return
+	}
+	if len(reqBody.Body) > maxMessageLength {
+		respondWithError(wri, 400, "Message is too long")
+		return
+	}
+
+	// DM settings can change after a conversation starts, so check everyone still in it
+	participants, err := apiCfg.dbQueries.ListParticipants(req.Context(), []uuid.UUID{conversation.ID})
+	if err != nil {
+		respondWithProblem(wri, fmt.Errorf("getting conversation: %w", err))
+		return
+	}
+	for _, p := range participants {
+		if p.UserID == user || p.LeftAt.Valid {
+			continue
+		}
+		other, err := apiCfg.dbQueries.GetUserByID(req.Context(), p.UserID)
+		if err != nil {
+			respondWithProblem(wri, fmt.Errorf("getting user: %w", err))
+			return
+		}
+		// deleted users can't read it anyway
+		if other.DeletedAt.Valid {
+			continue
+		}
+		ok, err := canMessage(req.Context(), apiCfg, user, other)
+		if err != nil {
+			respondWithProblem(wri, fmt.Errorf("checking DM settings: %w", err))
+			return
+		}
+		if !ok {
+			respondWithError(wri, 403, fmt.Sprintf("User %v only accepts messages from their followers", other.ID))
+			return
+		}
+	}
+
+	message, err := apiCfg.dbQueries.CreateMessage(req.Context(), database.CreateMessageParams{
+		ConversationID: conversation.ID,
+		SenderID: user,
+		Body: reqBody.Body,
+	})
+	if err != nil {
+		respondWithProblem(wri, fmt.Errorf("sending message: %w", err))
+		return
+	}
+	// bump the conversation to the top of everyone's list, and you've obviously read your own message
+	err = apiCfg.dbQueries.TouchConversation(req.Context(), database.TouchConversationParams{ID: conversation.ID, UpdatedAt: message.CreatedAt})
+	if err != nil {
+		respondWithProblem(wri, fmt.Errorf("sending message: %w", err))
+		return
+	}
+	err = apiCfg.dbQueries.MarkConversationRead(req.Context(), database.MarkConversationReadParams{
+		ConversationID: conversation.ID,
+		UserID: user,
+		LastReadAt: sql.NullTime{Time: message.CreatedAt, Valid: true},
+	})
+	if err != nil {
+		respondWithProblem(wri, fmt.Errorf("sending message: %w", err))
+		return
+	}
+	respondWithJSON(wri, 201, messageResponse(message))
+}
+
+// get a conversation's messages, newest first
+func getMessages(wri http.ResponseWriter, req *http.Request, apiCfg apiConfig) {
+	user, err := authenticate(req, apiCfg)
+	if err != nil {
+		respondWithProblem(wri, errUnauthorized)
+		return
+	}
+	conversation, ok := getMyConversation(wri, req, apiCfg, user)
+	if !ok {
+		return
+	}
+	start, limit, err := parsePage(req, true)
+	if err != nil {
+		respondWithProblem(wri, err)
+		return
+	}
+	messages, err := apiCfg.dbQueries.ListMessages(req.Context(), database.ListMessagesParams{
+		ConversationID: conversation.ID,
+		BeforeCreatedAt: start.CreatedAt,
+		BeforeID: start.ID,
+		PageLimit: limit + 1,
+	})
+	if err != nil {
+		respondWithProblem(wri, fmt.Errorf("getting messages: %w", err))
+		return
+	}
+	if len(messages) > int(limit) {
+		messages = messages[:limit]
+		last := messages[len(messages)-1]
+		setNextLink(wri, req, cursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+	output := []messageParam{}
+	for _, m := range messages {
+		output = append(output, messageResponse(m))
+	}
+	respondWithJSON(wri, 200, output)
+}
+
+// move the logged in user's read marker up to the newest message
+func readConversation(wri http.ResponseWriter, req *http.Request, apiCfg apiConfig) {
+	user, err := authenticate(req, apiCfg)
+	if err != nil {
+		respondWithProblem(wri, errUnauthorized)
+		return
+	}
+	conversation, ok := getMyConversation(wri, req, apiCfg, user)
+	if !ok {
+		return
+	}
+	err = apiCfg.dbQueries.MarkConversationRead(req.Context(), database.MarkConversationReadParams{
+		ConversationID: conversation.ID,
+		UserID: user,
+		// the conversation's updated_at is the newest message's created_at, which comes from the same clock
+		// the unread count compares against, unlike this server's
+		LastReadAt: sql.NullTime{Time: conversation.UpdatedAt, Valid: true},
+	})
+	if err != nil {
+		respondWithProblem(wri, fmt.Errorf("marking conversation read: %w", err))
+		return
+	}
+	wri.WriteHeader(204)
+}
+
+// leave a conversation, which stops you seeing it or getting messages from it
+func leaveConversation(wri http.ResponseWriter, req *http.Request, apiCfg apiConfig) {
+	user, err := authenticate(req, apiCfg)
+	if err != nil {
+		respondWithProblem(wri, errUnauthorized)
+		return
+	}
+	conversation, ok := getMyConversation(wri, req, apiCfg, user)
+	if !ok {
+		return
+	}
+	err = apiCfg.dbQueries.LeaveConversation(req.Context(), database.LeaveConversationParams{ConversationID: conversation.ID, UserID: user})
+	if err != nil {
+		respondWithProblem(wri, fmt.Errorf("leaving conversation: %w", err))
+		return
+	}
+	wri.WriteHeader(204)
+}
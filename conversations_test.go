@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"internal/database"
+	"internal/store"
+	"testing"
+)
+
+func TestCanMessage(t *testing.T) {
+	ctx := context.Background()
+	apiCfg := apiConfig{dbQueries: store.NewMemory()}
+	sender, _ := apiCfg.dbQueries.CreateUser(ctx, database.CreateUserParams{Email: "sender@example.com"})
+	recipient, _ := apiCfg.dbQueries.CreateUser(ctx, database.CreateUserParams{Email: "recipient@example.com"})
+	recipient, _ = apiCfg.dbQueries.UpdateDMSetting(ctx, database.UpdateDMSettingParams{ID: recipient.ID, DmsFromFollowersOnly: true})
+
+	// the recipient following the sender isn't enough
+	apiCfg.dbQueries.FollowUser(ctx, database.FollowUserParams{FollowerID: recipient.ID, FolloweeID: sender.ID})
+	ok, err := canMessage(ctx, apiCfg, sender.ID, recipient)
+	if err != nil || ok {
+		t.Errorf("canMessage returned %v, %v when only the recipient follows the sender, expected false", ok, err)
+	}
+
+	// the sender has to be one of their followers
+	apiCfg.dbQueries.FollowUser(ctx, database.FollowUserParams{FollowerID: sender.ID, FolloweeID: recipient.ID})
+	ok, err = canMessage(ctx, apiCfg, sender.ID, recipient)
+	if err != nil || !ok {
+		t.Errorf("canMessage returned %v, %v when the sender follows the recipient, expected true", ok, err)
+	}
+
+	// and anyone can message someone who takes DMs from everybody
+	stranger, _ := apiCfg.dbQueries.CreateUser(ctx, database.CreateUserParams{Email: "stranger@example.com"})
+	ok, err = canMessage(ctx, apiCfg, stranger.ID, sender)
+	if err != nil || !ok {
+		t.Errorf("canMessage returned %v, %v for someone who takes DMs from everybody, expected true", ok, err)
+	}
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"internal/database"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// how long deleted chirps and users hang around (and can be restored) when DELETION_GRACE_PERIOD isn't set
+const defaultDeletionGracePeriod = 30 * 24 * time.Hour
+const purgeInterval = time.Hour
+
+// delete your own account
+// it's only marked as deleted for now, along with all its chirps, and every refresh token is revoked
+func deleteUser(wri http.ResponseWriter, req *http.Request, apiCfg apiConfig) {
+	userID, err := authenticate(req, apiCfg)
+	if err != nil {
+		respondWithProblem(wri, errUnauthorized)
+		return
+	}
+	// all in one go, so a deleted user never has chirps or tokens left over
+	err = inTx(req.Context(), apiCfg, func(apiCfg apiConfig) error {
+		user, err := apiCfg.dbQueries.SoftDeleteUser(req.Context(), userID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return errUserNotFound.Wrap(err)
+		}
+		if err != nil {
+			return fmt.Errorf("deleting user: %w", err)
+		}
+		// the chirps get the user's deleted_at, so a restore can bring back just these ones
+		err = apiCfg.dbQueries.SoftDeleteUserChirps(req.Context(), database.SoftDeleteUserChirpsParams{
+			DeletedAt: user.DeletedAt,
+			UserID: user.ID,
+		})
+		if err != nil {
+			return fmt.Errorf("deleting user's chirps: %w", err)
+		}
+		err = apiCfg.dbQueries.RevokeUserTokens(req.Context(), user.ID)
+		if err != nil {
+			return fmt.Errorf("revoking tokens: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		respondWithProblem(wri, err)
+		return
+	}
+	wri.WriteHeader(204)
+}
+
+// bring back a deleted chirp, as long as it hasn't been purged yet
+func restoreChirp(wri http.ResponseWriter, req *http.Request, apiCfg apiConfig) {
+	chirpID, _ := uuid.Parse(req.PathValue("chirpID"))
+	chirp, err := apiCfg.dbQueries.GetSingleChirp(req.Context(), chirpID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithProblem(wri, errChirpNotFound)
+		return
+	}
+	if err != nil {
+		respondWithProblem(wri, fmt.Errorf("getting chirp: %w", err))
+		return
+	}
+	if !chirp.DeletedAt.Valid {
+		respondWithProblem(wri, errChirpNotDeleted)
+		return
+	}
+	if chirp.PurgedAt.Valid {
+		respondWithProblem(wri, errChirpPurged)
+		return
+	}
+	// a deleted user's chirps come back with the user, not one at a time
+	author, err := apiCfg.dbQueries.GetUserByID(req.Context(), chirp.UserID)
+	if err != nil {
+		respondWithProblem(wri, fmt.Errorf("getting user: %w", err))
+		return
+	}
+	if author.DeletedAt.Valid {
+		respondWithProblem(wri, errAuthorDeleted)
+		return
+	}
+	chirp, err = apiCfg.dbQueries.RestoreChirp(req.Context(), chirp.ID)
+	if err != nil {
+		respondWithProblem(wri, fmt.Errorf("restoring chirp: %w", err))
+		return
+	}
+	resBody, err := chirpResponses(req, apiCfg, []database.Chirp{chirp})
+	if err != nil {
+		respondWithProblem(wri, fmt.Errorf("getting chirp: %w", err))
+		return
+	}
+	respondWithJSON(wri, 200, resBody[0])
+}
+
+// bring back a deleted user, and the chirps that were deleted along with them
+// chirps they'd deleted themselves before that stay deleted
+func restoreUser(wri http.ResponseWriter, req *http.Request, apiCfg apiConfig) {
+	userID, _ := uuid.Parse(req.PathValue("userID"))
+	user, err := apiCfg.dbQueries.GetUserByID(req.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithProblem(wri, errUserNotFound)
+		return
+	}
+	if err != nil {
+		respondWithProblem(wri, fmt.Errorf("getting user: %w", err))
+		return
+	}
+	if !user.DeletedAt.Valid {
+		respondWithProblem(wri, errUserNotDeleted)
+		return
+	}
+	deletedAt := user.DeletedAt
+	err = inTx(req.Context(), apiCfg, func(apiCfg apiConfig) error {
+		user, err = apiCfg.dbQueries.RestoreUser(req.Context(), user.ID)
+		if err != nil {
+			return fmt.Errorf("restoring user: %w", err)
+		}
+		err = apiCfg.dbQueries.RestoreUserChirps(req.Context(), database.RestoreUserChirpsParams{
+			UserID: user.ID,
+			DeletedAt: deletedAt,
+		})
+		if err != nil {
+			return fmt.Errorf("restoring user's chirps: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		respondWithProblem(wri, err)
+		return
+	}
+	resBody := userParam{
+		ID: user.ID,
+		CreatedAt: user.CreatedAt,
+		UpdatedAt: user.UpdatedAt,
+		Email: user.Email,
+		Handle: user.Handle.String,
+		IsChirpyRed: user.IsChirpyRed,
+		DMsFromFollowersOnly: user.DmsFromFollowersOnly,
+	}
+	respondWithJSON(wri, 200, resBody)
+}
+
+// gets rid of a deleted chirp for good
+// if anyone replied to it, a tombstone is left behind so the thread holds together
+func purgeChirp(ctx context.Context, apiCfg apiConfig, chirp database.Chirp) error {
+	replies, err := apiCfg.dbQueries.ListReplies(ctx, database.ListRepliesParams{
+		InReplyTo: uuid.NullUUID{UUID: chirp.ID, Valid: true},
+		AfterCreatedAt: firstCursor.CreatedAt,
+		AfterID: firstCursor.ID,
+		PageLimit: 1,
+	})
+	if err != nil {
+		return err
+	}
+	if len(replies) == 0 {
+		return apiCfg.dbQueries.DeleteSingleChirp(ctx, chirp.ID)
+	}
+	// what it used to say goes too, not just what it says now
+	err = apiCfg.dbQueries.TombstoneChirp(ctx, chirp.ID)
+	if err != nil {
+		return err
+	}
+	err = apiCfg.dbQueries.DeleteChirpRevisions(ctx, chirp.ID)
+	if err != nil {
+		return err
+	}
+	err = apiCfg.dbQueries.ClearHashtags(ctx, chirp.ID)
+	if err != nil {
+		return err
+	}
+	return apiCfg.dbQueries.ClearMentions(ctx, chirp.ID)
+}
+
+// purged users' tombstones are handed over to this placeholder account (see purgeUser)
+var placeholderUserID = uuid.Nil
+
+// gets rid of a deleted user for good, files and all
+// everything else of theirs cascades away with the row, so their chirps are purged first the way
+// purgeChirp does it, and the tombstones that leaves are handed over to the placeholder user to keep threads together
+func purgeUser(ctx context.Context, apiCfg apiConfig, user database.User) error {
+	media, err := apiCfg.dbQueries.ListUserMedia(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	err = inTx(ctx, apiCfg, func(apiCfg apiConfig) error {
+		chirps, err := apiCfg.dbQueries.ListUnpurgedUserChirps(ctx, user.ID)
+		if err != nil {
+			return err
+		}
+		for _, c := range chirps {
+			err = purgeChirp(ctx, apiCfg, c)
+			if err != nil {
+				return err
+			}
+		}
+		err = apiCfg.dbQueries.CreatePlaceholderUser(ctx, placeholderUserID)
+		if err != nil {
+			return err
+		}
+		err = apiCfg.dbQueries.ReassignTombstones(ctx, database.ReassignTombstonesParams{NewUserID: placeholderUserID, UserID: user.ID})
+		if err != nil {
+			return err
+		}
+		return apiCfg.dbQueries.DeleteUser(ctx, user.ID)
+	})
+	if err != nil {
+		return err
+	}
+	// the files only go once the rows have, so a purge that fails doesn't leave uploads without them
+	for _, m := range media {
+		err = deleteMediaFiles(ctx, apiCfg, m)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// purges every chirp and user that was deleted before the cutoff, returning how many of each went
+func purgeDeleted(ctx context.Context, apiCfg apiConfig, before time.Time) (int, int, error) {
+	const pageSize = 100
+	cutoff := sql.NullTime{Time: before, Valid: true}
+	chirps, users := 0, 0
+	for {
+		page, err := apiCfg.dbQueries.ListPurgeableChirps(ctx, database.ListPurgeableChirpsParams{DeletedBefore: cutoff, PageLimit: pageSize})
+		if err != nil {
+			return chirps, users, err
+		}
+		for _, c := range page {
+			err = purgeChirp(ctx, apiCfg, c)
+			if err != nil {
+				return chirps, users, err
+			}
+			chirps++
+		}
+		if len(page) < pageSize {
+			break
+		}
+	}
+	for {
+		page, err := apiCfg.dbQueries.ListPurgeableUsers(ctx, database.ListPurgeableUsersParams{DeletedBefore: cutoff, PageLimit: pageSize})
+		if err != nil {
+			return chirps, users, err
+		}
+		for _, u := range page {
+			err = purgeUser(ctx, apiCfg, u)
+			if err != nil {
+				return chirps, users, err
+			}
+			users++
+		}
+		if len(page) < pageSize {
+			return chirps, users, nil
+		}
+	}
+}
+
+// runs purgeDeleted every purgeInterval for as long as the server's up
+func purgeDeletedForever(apiCfg apiConfig) {
+	ticker := time.NewTicker(purgeInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		chirps, users, err := purgeDeleted(context.Background(), apiCfg, time.Now().UTC().Add(-apiCfg.deletionGracePeriod))
+		if err != nil {
+			slog.Error("purging deleted chirps and users failed", "error", err)
+		}
+		if chirps > 0 || users > 0 {
+			slog.Info("purged deleted chirps and users", "chirps", chirps, "users", users)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/google/uuid"
+	"internal/database"
+	"internal/store"
+	"testing"
+	"time"
+)
+
+func TestPurgeUserKeepsReplies(t *testing.T) {
+	ctx := context.Background()
+	apiCfg := apiConfig{dbQueries: store.NewMemory()}
+	gone, _ := apiCfg.dbQueries.CreateUser(ctx, database.CreateUserParams{Email: "gone@example.com"})
+	other, _ := apiCfg.dbQueries.CreateUser(ctx, database.CreateUserParams{Email: "other@example.com"})
+	replied, _ := apiCfg.dbQueries.CreateChirp(ctx, database.CreateChirpParams{Body: "anyone there?", UserID: gone.ID})
+	reply, _ := apiCfg.dbQueries.CreateChirp(ctx, database.CreateChirpParams{Body: "yes", UserID: other.ID, InReplyTo: uuid.NullUUID{UUID: replied.ID, Valid: true}})
+	lonely, _ := apiCfg.dbQueries.CreateChirp(ctx, database.CreateChirpParams{Body: "nobody answers this one", UserID: gone.ID})
+	apiCfg.dbQueries.SoftDeleteUser(ctx, gone.ID)
+	apiCfg.dbQueries.SoftDeleteUserChirps(ctx, database.SoftDeleteUserChirpsParams{UserID: gone.ID, DeletedAt: sql.NullTime{Time: time.Now(), Valid: true}})
+
+	err := purgeUser(ctx, apiCfg, gone)
+	if err != nil {
+		t.Fatalf("Error in purgeUser: %v", err)
+	}
+	_, err = apiCfg.dbQueries.GetUserByID(ctx, gone.ID)
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Expected the user to be gone, got %v", err)
+	}
+	_, err = apiCfg.dbQueries.GetSingleChirp(ctx, lonely.ID)
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Expected the chirp without replies to be gone, got %v", err)
+	}
+
+	// the one with a reply stays as a tombstone, and the reply still points at it
+	tombstone, err := apiCfg.dbQueries.GetSingleChirp(ctx, replied.ID)
+	if err != nil {
+		t.Fatalf("Expected the chirp with a reply to be left as a tombstone, got %v", err)
+	}
+	if !tombstone.PurgedAt.Valid || tombstone.Body != "" || tombstone.UserID != placeholderUserID {
+		t.Errorf("Expected a tombstone belonging to the placeholder user, got %+v", tombstone)
+	}
+	reply, _ = apiCfg.dbQueries.GetSingleChirp(ctx, reply.ID)
+	if reply.InReplyTo.UUID != replied.ID {
+		t.Errorf("Expected the reply to still be in reply to %v, got %v", replied.ID, reply.InReplyTo)
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"internal/apierr"
+)
+
+// the errors that come up all over the place, each with a code clients can count on
+var (
+	errUnauthorized = apierr.Unauthorized("unauthorized", "Unauthorized")
+	errForbidden = apierr.Forbidden("forbidden", "Forbidden")
+	errBadCredentials = apierr.Unauthorized("invalid_credentials", "Incorrect username or password")
+	errUserNotFound = apierr.NotFound("user_not_found", "User not found")
+	errChirpNotFound = apierr.NotFound("chirp_not_found", "Chirp not found")
+	errChirpDeleted = apierr.Gone("chirp_deleted", "Chirp has been deleted")
+	errChirpPurged = apierr.Gone("chirp_purged", "Chirp has already been purged")
+	errChirpNotDeleted = apierr.Conflict("chirp_not_deleted", "Chirp isn't deleted")
+	errUserNotDeleted = apierr.Conflict("user_not_deleted", "User isn't deleted")
+	errAuthorDeleted = apierr.Conflict("author_deleted", "Chirp's author is deleted, restore them instead")
+	errHeldAuthorDeleted = apierr.Conflict("author_deleted", "Held chirp's author is deleted")
+	errParentDeleted = apierr.Conflict("parent_deleted", "The chirp it replies to has been deleted")
+	errMediaNotFound = apierr.NotFound("media_not_found", "Media not found")
+	errMediaTaken = apierr.Conflict("media_taken", "One of the uploads has been attached to another chirp")
+	errListNotFound = apierr.NotFound("list_not_found", "List not found")
+	errReportNotFound = apierr.NotFound("report_not_found", "Report not found")
+	errHeldChirpNotFound = apierr.NotFound("held_chirp_not_found", "Held chirp not found")
+	errConversationNotFound = apierr.NotFound("conversation_not_found", "Conversation not found")
+	errReportResolved = apierr.Conflict("report_resolved", "Report has already been resolved")
+	errReportClaimed = apierr.Conflict("report_claimed", "Report has been claimed by another moderator")
+	errSelfReport = apierr.Validation("self_report", "You can't report yourself")
+	errSuspendModerator = apierr.Forbidden("suspend_moderator", "Only admins can suspend moderators")
+	errHandleTaken = apierr.Conflict("handle_taken", "That handle is taken")
+	errEmailTaken = apierr.Conflict("email_taken", "That email is already in use")
+	// these get what went wrong wrapped in them for the logs, since it's no use to the client
+	errInvalidBody = apierr.Validation("invalid_body", "Request body isn't valid JSON")
+	errInvalidUpload = apierr.Validation("invalid_upload", "Couldn't read the upload")
+	errInvalidImage = apierr.Validation("invalid_image", "Couldn't read the image")
+	errInvalidVideo = apierr.Validation("invalid_video", "Couldn't tell how long the video is")
+)
+
+// the error for a chirp the moderation pipeline turned away, saying why
+func errChirpRejected(reason string) *apierr.Error {
+	return apierr.Validation("chirp_rejected", reason)
+}
+
+// the error for a suspended or banned user, saying why (see accountBlocked)
+func errAccountBlocked(blocked string) *apierr.Error {
+	return apierr.Forbidden("account_blocked", blocked)
+}
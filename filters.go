@@ -0,0 +1,365 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"internal/contentfilter"
+	"internal/database"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// how often the word lists are read back out of the database,
+// so edits made through another server end up everywhere
+const filterReloadInterval = time.Minute
+
+// every word list in the database, along with its words
+func listFilterLists(ctx context.Context, apiCfg apiConfig) ([]filterListParam, error) {
+	lists, err := apiCfg.dbQueries.ListFilterLists(ctx)
+	if err != nil {
+		return nil, err
+	}
+	words, err := apiCfg.dbQueries.ListFilterWords(ctx)
+	if err != nil {
+		return nil, err
+	}
+	byList := map[string][]string{}
+	for _, w := range words {
+		byList[w.ListName] = append(byList[w.ListName], w.Word)
+	}
+	output := []filterListParam{}
+	for _, l := range lists {
+		res := filterListParam{
+			Name: l.Name,
+			Action: l.Action,
+			Words: byList[l.Name],
+			CreatedAt: l.CreatedAt,
+			UpdatedAt: l.UpdatedAt,
+		}
+		if res.Words == nil {
+			res.Words = []string{}
+		}
+		output = append(output, res)
+	}
+	return output, nil
+}
+
+// points the filter at whatever the database has in it right now
+func loadFilter(ctx context.Context, apiCfg apiConfig) error {
+	lists, err := listFilterLists(ctx, apiCfg)
+	if err != nil {
+		return err
+	}
+	filterLists := []contentfilter.List{}
+	for _, l := range lists {
+		filterLists = append(filterLists, contentfilter.List{Name: l.Name, Action: contentfilter.Action(l.Action), Words: l.Words})
+	}
+	apiCfg.filter.SetLists(filterLists)
+	return nil
+}
+
+// runs loadFilter every filterReloadInterval for as long as the server's up
+func reloadFilterForever(apiCfg apiConfig) {
+	ticker := time.NewTicker(filterReloadInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		err := loadFilter(context.Background(), apiCfg)
+		if err != nil {
+			slog.Error("reloading the content filter failed", "error", err)
+		}
+	}
+}
+
+// replaces a list in the database with the given action and words
+func saveFilterList(ctx context.Context, apiCfg apiConfig, name string, action contentfilter.Action, words []string) error {
+	_, err := apiCfg.dbQueries.UpsertFilterList(ctx, database.UpsertFilterListParams{Name: name, Action: string(action)})
+	if err != nil {
+		return err
+	}
+	err = apiCfg.dbQueries.ClearFilterWords(ctx, name)
+	if err != nil {
+		return err
+	}
+	return addFilterWords(ctx, apiCfg, name, words)
+}
+
+// words have to be normalized before they get here
+func addFilterWords(ctx context.Context, apiCfg apiConfig, name string, words []string) error {
+	for _, w := range words {
+		err := apiCfg.dbQueries.AddFilterWord(ctx, database.AddFilterWordParams{ListName: name, Word: w})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writes the lists in FILTER_FILE into the database, replacing any lists with the same names
+// lists that are only in the database are left alone
+func seedFilterFile(ctx context.Context, apiCfg apiConfig, path string) error {
+	lists, err := contentfilter.LoadFile(path)
+	if err != nil {
+		return err
+	}
+	for _, l := range lists {
+		words, err := normalizeWords(l.Words)
+		if err != nil {
+			return fmt.Errorf("reading %s: list %q: %w", path, l.Name, err)
+		}
+		err = saveFilterList(ctx, apiCfg, l.Name, l.Action, words)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func normalizeWords(words []string) ([]string, error) {
+	output := []string{}
+	for _, w := range words {
+		word, ok := contentfilter.NormalizeWord(w)
+		if !ok {
+			return nil, fmt.Errorf("invalid word %q", w)
+		}
+		output = append(output, word)
+	}
+	return output, nil
+}
+
+// records a flag on the chirp for every flagged word in it
+func flagChirp(ctx context.Context, apiCfg apiConfig, chirpID uuid.UUID, flagged []contentfilter.Match) error {
+	for _, m := range flagged {
+		err := apiCfg.dbQueries.CreateChirpFlag(ctx, database.CreateChirpFlagParams{ChirpID: chirpID, ListName: m.List, Word: m.Word})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// the name in the path, if it's a usable one
+func filterListName(wri http.ResponseWriter, req *http.Request) (string, bool) {
+	name := req.PathValue("name")
+	if !contentfilter.ValidName(name) {
+		respondWithError(wri, 400, "List names can only have lowercase letters, numbers, dashes and underscores, and up to 50 of them")
+		return "", false
+	}
+	return name, true
+}
+
+// a single list, after it's been changed
+func respondWithFilterList(wri http.ResponseWriter, req *http.Request, apiCfg apiConfig, name string) {
+	err := loadFilter(req.Context(), apiCfg)
+	if err != nil {
+		respondWithProblem(wri, fmt.Errorf("reloading the content filter: %w", err))
+		return
+	}
+	lists, err := listFilterLists(req.Context(), apiCfg)
+	if err != nil {
+		respondWithProblem(wri, fmt.Errorf("getting word lists: %w", err))
+		return
+	}
+	for _, l := range lists {
+		if l.Name == name {
+			respondWithJSON(wri, 200, l)
+			return
+		}
+	}
+	respondWithProblem(wri, errListNotFound)
+}
+
+// every word list, and what's on it
+func getFilterLists(wri http.ResponseWriter, req *http.Request, apiCfg apiConfig) {
+	lists, err := listFilterLists(req.Context(), apiCfg)
+	if err != nil {
+		respondWithProblem(wri, fmt.Errorf("getting word lists: %w", err))
+		return
+	}
+	respondWithJSON(wri, 200, lists)
+}
+
+// creates a list, or replaces everything about the one that's there
+func putFilterList(wri http.ResponseWriter, req *http.Request, apiCfg apiConfig) {
+	type reqParam struct {
+		Action contentfilter.Action `json:"action"`
+		Words []string `json:"words"`
+	}
+	name, ok := filterListName(wri, req)
+	if !ok {
+		return
+	}
+	decoder := json.NewDecoder(req.Body)
+	reqBody := reqParam{}
+	err := decoder.Decode(&reqBody)
+	if err != nil {
+		respondWithProblem(wri, errInvalidBody.Wrap(err))
+		return
+	}
+	if !reqBody.Action.Valid() {
+		respondWithError(wri, 400, "action must be mask, reject or flag")
+		return
+	}
+	words, err := normalizeWords(reqBody.Words)
+	if err != nil {
+		respondWithError(wri, 400, fmt.Sprint(err))
+		return
+	}
+	err = saveFilterList(req.Context(), apiCfg, name, reqBody.Action, words)
+	if err != nil {
+		respondWithProblem(wri, fmt.Errorf("saving word list: %w", err))
+		return
+	}
+	respondWithFilterList(wri, req, apiCfg, name)
+}
+
+func deleteFilterList(wri http.ResponseWriter, req *http.Request, apiCfg apiConfig) {
+	name, ok := filterListName(wri, req)
+	if !ok {
+		return
+	}
+	_, err := apiCfg.dbQueries.GetFilterList(req.Context(), name)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithProblem(wri, errListNotFound)
+		return
+	}
+	if err != nil {
+		respondWithProblem(wri, fmt.Errorf("getting word list: %w", err))
+		return
+	}
+	err = apiCfg.dbQueries.DeleteFilterList(req.Context(), name)
+	if err != nil {
+		respondWithProblem(wri, fmt.Errorf("deleting word list: %w", err))
+		return
+	}
+	err = loadFilter(req.Context(), apiCfg)
+	if err != nil {
+		respondWithProblem(wri, fmt.Errorf("reloading the content filter: %w", err))
+		return
+	}
+	wri.WriteHeader(204)
+}
+
+// adds words to a list that's already there
+func postFilterWords(wri http.ResponseWriter, req *http.Request, apiCfg apiConfig) {
+	type reqParam struct {
+		Words []string `json:"words"`
+	}
+	name, ok := filterListName(wri, req)
+	if !ok {
+		return
+	}
+	decoder := json.NewDecoder(req.Body)
+	reqBody := reqParam{}
+	err := decoder.Decode(&reqBody)
+	if err != nil {
+		respondWithProblem(wri, errInvalidBody.Wrap(err))
+		return
+	}
+	words, err := normalizeWords(reqBody.Words)
+	if err != nil {
+		respondWithError(wri, 400, fmt.Sprint(err))
+		return
+	}
+	_, err = apiCfg.dbQueries.GetFilterList(req.Context(), name)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithProblem(wri, errListNotFound)
+		return
+	}
+	if err != nil {
+		respondWithProblem(wri, fmt.Errorf("getting word list: %w", err))
+		return
+	}
+	err = addFilterWords(req.Context(), apiCfg, name, words)
+	if err != nil {
+		respondWithProblem(wri, fmt.Errorf("adding words: %w", err))
+		return
+	}
+	respondWithFilterList(wri, req, apiCfg, name)
+}
+
+// takes a word off a list (phrases need their spaces escaped in the path)
+func deleteFilterWord(wri http.ResponseWriter, req *http.Request, apiCfg apiConfig) {
+	name, ok := filterListName(wri, req)
+	if !ok {
+		return
+	}
+	word, ok := contentfilter.NormalizeWord(req.PathValue("word"))
+	if !ok {
+		respondWithError(wri, 400, "Invalid word")
+		return
+	}
+	_, err := apiCfg.dbQueries.GetFilterList(req.Context(), name)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithProblem(wri, errListNotFound)
+		return
+	}
+	if err != nil {
+		respondWithProblem(wri, fmt.Errorf("getting word list: %w", err))
+		return
+	}
+	err = apiCfg.dbQueries.RemoveFilterWord(req.Context(), database.RemoveFilterWordParams{ListName: name, Word: word})
+	if err != nil {
+		respondWithProblem(wri, fmt.Errorf("removing word: %w", err))
+		return
+	}
+	err = loadFilter(req.Context(), apiCfg)
+	if err != nil {
+		respondWithProblem(wri, fmt.Errorf("reloading the content filter: %w", err))
+		return
+	}
+	wri.WriteHeader(204)
+}
+
+// chirps that were flagged for review, newest first
+func getChirpFlags(wri http.ResponseWriter, req *http.Request, apiCfg apiConfig) {
+	start, limit, err := parsePage(req, true)
+	if err != nil {
+		respondWithProblem(wri, err)
+		return
+	}
+	rows, err := apiCfg.dbQueries.ListChirpFlags(req.Context(), database.ListChirpFlagsParams{
+		BeforeCreatedAt: start.CreatedAt,
+		BeforeID: start.ID,
+		PageLimit: limit + 1,
+	})
+	if err != nil {
+		respondWithProblem(wri, fmt.Errorf("getting flags: %w", err))
+		return
+	}
+	if len(rows) > int(limit) {
+		rows = rows[:limit]
+		last := rows[len(rows)-1]
+		setNextLink(wri, req, cursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+	output := []chirpFlagParam{}
+	for _, f := range rows {
+		output = append(output, chirpFlagParam{
+			ID: f.ID,
+			CreatedAt: f.CreatedAt,
+			ChirpID: f.ChirpID,
+			List: f.ListName,
+			Word: f.Word,
+		})
+	}
+	respondWithJSON(wri, 200, output)
+}
+
+// clears a flag once a moderator's looked at it
+func deleteChirpFlag(wri http.ResponseWriter, req *http.Request, apiCfg apiConfig) {
+	flagID, err := uuid.Parse(req.PathValue("flagID"))
+	if err != nil {
+		respondWithError(wri, 404, "Flag not found")
+		return
+	}
+	err = apiCfg.dbQueries.DeleteChirpFlag(req.Context(), flagID)
+	if err != nil {
+		respondWithProblem(wri, fmt.Errorf("deleting flag: %w", err))
+		return
+	}
+	wri.WriteHeader(204)
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"internal/database"
+	"net/http"
+)
+
+// looks up the user in the path, responding with a 404 if they're not there (or have been deleted)
+func getPathUser(wri http.ResponseWriter, req *http.Request, apiCfg apiConfig) (database.User, bool) {
+	userID, err := uuid.Parse(req.PathValue("userID"))
+	if err != nil {
+		respondWithProblem(wri, errUserNotFound)
+		return database.User{}, false
+	}
+	user, err := apiCfg.dbQueries.GetUserByID(req.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && user.DeletedAt.Valid) {
+		respondWithProblem(wri, errUserNotFound)
+		return database.User{}, false
+	}
+	if err != nil {
+		respondWithProblem(wri, fmt.Errorf("getting user: %w", err))
+		return database.User{}, false
+	}
+	return user, true
+}
+
+// follow a user (following them again does nothing)
+func followUser(wri http.ResponseWriter, req *http.Request, apiCfg apiConfig) {
+	follower, err := authenticate(req, apiCfg)
+	if err != nil {
+		respondWithProblem(wri, errUnauthorized)
+		return
+	}
+	followee, ok := getPathUser(wri, req, apiCfg)
+	if !ok {
+		return
+	}
+	// your own chirps are always on your timeline anyway
+	if followee.ID == follower {
+		respondWithError(wri, 400, "You can't follow yourself")
+		return
+	}
+	err = apiCfg.dbQueries.FollowUser(req.Context(), database.FollowUserParams{FollowerID: follower, FolloweeID: followee.ID})
+	if err != nil {
+		respondWithProblem(wri, fmt.Errorf("following user: %w", err))
+		return
+	}
+	err = notify(req.Context(), apiCfg, followee.ID, follower, notifyFollow, uuid.NullUUID{})
+	if err != nil {
+		respondWithProblem(wri, fmt.Errorf("notifying user: %w", err))
+		return
+	}
+	wri.WriteHeader(204)
+}
+
+// unfollow a user (unfollowing someone you don't follow does nothing)
+func unfollowUser(wri http.ResponseWriter, req *http.Request, apiCfg apiConfig) {
+	follower, err := authenticate(req, apiCfg)
+	if err != nil {
+		respondWithProblem(wri, errUnauthorized)
+		return
+	}
+	followee, ok := getPathUser(wri, req, apiCfg)
+	if !ok {
+		return
+	}
+	err = apiCfg.dbQueries.UnfollowUser(req.Context(), database.UnfollowUserParams{FollowerID: follower, FolloweeID: followee.ID})
+	if err != nil {
+		respondWithProblem(wri, fmt.Errorf("unfollowing user: %w", err))
+		return
+	}
+	wri.WriteHeader(204)
+}
+
+// get the people following a user, most recent first
+func getFollowers(wri http.ResponseWriter, req *http.Request, apiCfg apiConfig) {
+	user, ok := getPathUser(wri, req, apiCfg)
+	if !ok {
+		return
+	}
+	start, limit, err := parsePage(req, true)
+	if err != nil {
+		respondWithProblem(wri, err)
+		return
+	}
+	rows, err := apiCfg.dbQueries.ListFollowers(req.Context(), database.ListFollowersParams{
+		UserID: user.ID,
+		BeforeFollowedAt: start.CreatedAt,
+		BeforeID: start.ID,
+		PageLimit: limit + 1,
+	})
+	if err != nil {
+		respondWithProblem(wri, fmt.Errorf("getting followers: %w", err))
+		return
+	}
+	output := []followParam{}
+	for _, r := range rows {
+		output = append(output, followParam(r))
+	}
+	respondWithFollows(wri, req, output, limit)
+}
+
+// get the people a user follows, most recently followed first
+func getFollowing(wri http.ResponseWriter, req *http.Request, apiCfg apiConfig) {
+	user, ok := getPathUser(wri, req, apiCfg)
+	if !ok {
+		return
+	}
+	start, limit, err := parsePage(req, true)
+	if err != nil {
+		respondWithProblem(wri, err)
+		return
+	}
+	rows, err := apiCfg.dbQueries.ListFollowing(req.Context(), database.ListFollowingParams{
+		UserID: user.ID,
+		BeforeFollowedAt: start.CreatedAt,
+		BeforeID: start.ID,
+		PageLimit: limit + 1,
+	})
+	if err != nil {
+		respondWithProblem(wri, fmt.Errorf("getting followed users: %w", err))
+		return
+	}
+	output := []followParam{}
+	for _, r := range rows {
+		output = append(output, followParam(r))
+	}
+	respondWithFollows(wri, req, output, limit)
+}
+
+// trims off the extra row we asked for and uses it to set the next page link
+// (the cursor here is on when they followed, not when the user was created)
+func respondWithFollows(wri http.ResponseWriter, req *http.Request, follows []followParam, limit int32) {
+	if len(follows) > int(limit) {
+		follows = follows[:limit]
+		last := follows[len(follows)-1]
+		setNextLink(wri, req, cursor{CreatedAt: last.FollowedAt, ID: last.UserID})
+	}
+	respondWithJSON(wri, 200, follows)
+}
+
+// the logged in user's home timeline: their own chirps and rechirps plus those of everyone they follow, newest first
+func getTimeline(wri http.ResponseWriter, req *http.Request, apiCfg apiConfig) {
+	user, err := authenticate(req, apiCfg)
+	if err != nil {
+		respondWithProblem(wri, errUnauthorized)
+		return
+	}
+	start, limit, err := parsePage(req, true)
+	if err != nil {
+		respondWithProblem(wri, err)
+		return
+	}
+	rows, err := apiCfg.dbQueries.ListTimeline(req.Context(), database.ListTimelineParams{
+		UserID: user,
+		BeforeCreatedAt: start.CreatedAt,
+		BeforeID: start.ID,
+		PageLimit: limit + 1,
+	})
+	if err != nil {
+		respondWithProblem(wri, fmt.Errorf("getting timeline: %w", err))
+		return
+	}
+	entries := []timelineEntry{}
+	for _, r := range rows {
+		entries = append(entries, timelineEntry(r))
+	}
+	if len(entries) > int(limit) {
+		entries = entries[:limit]
+		last := entries[len(entries)-1]
+		setNextLink(wri, req, cursor{CreatedAt: last.EntryAt, ID: last.EntryID})
+	}
+	output, err := timelineResponses(req, apiCfg, entries)
+	if err != nil {
+		respondWithProblem(wri, fmt.Errorf("getting timeline: %w", err))
+		return
+	}
+	respondWithJSON(wri, 200, output)
+}
@@ -6,14 +6,79 @@ require internal/database v0.0.0
 
 require internal/auth v0.0.0
 
+require internal/store v0.0.0
+
+require internal/sqlitedb v0.0.0 // indirect
+
+require internal/migrate v0.0.0
+
+require internal/hashtags v0.0.0
+
+require internal/mentions v0.0.0
+
+require internal/blobstore v0.0.0
+
+require internal/imaging v0.0.0
+
+require internal/contentfilter v0.0.0
+
+require internal/moderation v0.0.0
+
+require internal/ratelimit v0.0.0
+
+require internal/metrics v0.0.0
+
+require internal/apierr v0.0.0
+
+require internal/video v0.0.0
+
 require (
+	github.com/google/uuid v1.6.0
+	github.com/joho/godotenv v1.5.1
+	github.com/lib/pq v1.10.9
+	modernc.org/sqlite v1.37.0
+)
+
+require (
+	github.com/dustin/go-humanize v1.0.1 // indirect
 	github.com/golang-jwt/jwt/v5 v5.2.2 // indirect
-	github.com/google/uuid v1.6.0 // indirect
-	github.com/joho/godotenv v1.5.1 // indirect
-	github.com/lib/pq v1.10.9 // indirect
+	github.com/mattn/go-isatty v0.0.20 // indirect
+	github.com/ncruces/go-strftime v0.1.9 // indirect
+	github.com/remyoudompheng/bigfft v0.0.0-20230129092748-24d4a6f8daec // indirect
 	golang.org/x/crypto v0.36.0 // indirect
+	golang.org/x/exp v0.0.0-20250305212735-054e65f0b394 // indirect
+	golang.org/x/sys v0.31.0 // indirect
+	modernc.org/libc v1.62.1 // indirect
+	modernc.org/mathutil v1.7.1 // indirect
+	modernc.org/memory v1.9.1 // indirect
 )
 
 replace internal/database => ./internal/database
 
 replace internal/auth => ./internal/auth
+
+replace internal/store => ./internal/store
+
+replace internal/sqlitedb => ./internal/sqlitedb
+
+replace internal/migrate => ./internal/migrate
+
+replace internal/hashtags => ./internal/hashtags
+
+replace internal/mentions => ./internal/mentions
+
+replace internal/blobstore => ./internal/blobstore
+
+replace internal/imaging => ./internal/imaging
+
+replace internal/contentfilter => ./internal/contentfilter
+
+replace internal/moderation => ./internal/moderation
+
+replace internal/ratelimit => ./internal/ratelimit
+
+replace internal/metrics => ./internal/metrics
+
+replace internal/apierr => ./internal/apierr
+
+replace internal/video => ./internal/video
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"internal/database"
+	"internal/hashtags"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// trends cover the last day, with each use counting for less as it gets older
+const trendWindow = 24 * time.Hour
+const trendDecay = 6 * time.Hour
+const defaultTrendLimit = 10
+const maxTrendLimit = 50
+
+// saves the hashtags in a chirp's body so they can be looked up later
+func tagChirp(ctx context.Context, apiCfg apiConfig, chirp database.Chirp) error {
+	for _, tag := range hashtags.Extract(chirp.Body) {
+		err := apiCfg.dbQueries.TagChirp(ctx, database.TagChirpParams{ChirpID: chirp.ID, Tag: tag, CreatedAt: chirp.CreatedAt})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// get the chirps with a hashtag, newest first
+func getHashtagChirps(wri http.ResponseWriter, req *http.Request, apiCfg apiConfig) {
+	tag, ok := hashtags.Normalize(req.PathValue("tag"))
+	if !ok {
+		respondWithError(wri, 400, "Invalid hashtag")
+		return
+	}
+	start, limit, err := parsePage(req, true)
+	if err != nil {
+		respondWithProblem(wri, err)
+		return
+	}
+	chirps, err := apiCfg.dbQueries.ListHashtagChirps(req.Context(), database.ListHashtagChirpsParams{
+		Tag: tag,
+		BeforeCreatedAt: start.CreatedAt,
+		BeforeID: start.ID,
+		PageLimit: limit + 1,
+	})
+	if err != nil {
+		respondWithProblem(wri, fmt.Errorf("getting chirps: %w", err))
+		return
+	}
+	if len(chirps) > int(limit) {
+		chirps = chirps[:limit]
+		last := chirps[len(chirps)-1]
+		setNextLink(wri, req, cursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+	output, err := chirpResponses(req, apiCfg, chirps)
+	if err != nil {
+		respondWithProblem(wri, fmt.Errorf("getting chirps: %w", err))
+		return
+	}
+	respondWithJSON(wri, 200, output)
+}
+
+// get the hashtags trending right now
+func getTrends(wri http.ResponseWriter, req *http.Request, apiCfg apiConfig) {
+	limit := defaultTrendLimit
+	if l := req.URL.Query().Get("limit"); l != "" {
+		var err error
+		limit, err = strconv.Atoi(l)
+		if err != nil || limit < 1 || limit > maxTrendLimit {
+			respondWithError(wri, 400, fmt.Sprintf("limit must be between 1 and %d", maxTrendLimit))
+			return
+		}
+	}
+	now := time.Now().UTC()
+	rows, err := apiCfg.dbQueries.TrendingHashtags(req.Context(), database.TrendingHashtagsParams{
+		Now: now,
+		DecaySeconds: trendDecay.Seconds(),
+		Since: now.Add(-trendWindow),
+		PageLimit: int32(limit),
+	})
+	if err != nil {
+		respondWithProblem(wri, fmt.Errorf("getting trends: %w", err))
+		return
+	}
+	output := []trendParam{}
+	for _, r := range rows {
+		output = append(output, trendParam(r))
+	}
+	respondWithJSON(wri, 200, output)
+}
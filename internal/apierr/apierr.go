@@ -0,0 +1,166 @@
+package apierr
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// what sort of thing went wrong, which decides the status code
+type Kind string
+
+const (
+	KindNotFound Kind = "not_found"
+	KindConflict Kind = "conflict"
+	KindValidation Kind = "validation"
+	KindUnauthorized Kind = "unauthorized"
+	KindForbidden Kind = "forbidden"
+	// for things that were there but have been taken away, like deleted chirps
+	KindGone Kind = "gone"
+	// anything that isn't the client's fault
+	KindInternal Kind = "internal"
+)
+
+func (k Kind) Status() int {
+	switch k {
+	case KindNotFound:
+		return http.StatusNotFound
+	case KindConflict:
+		return http.StatusConflict
+	case KindValidation:
+		return http.StatusBadRequest
+	case KindUnauthorized:
+		return http.StatusUnauthorized
+	case KindForbidden:
+		return http.StatusForbidden
+	case KindGone:
+		return http.StatusGone
+	}
+	return http.StatusInternalServerError
+}
+
+// what was wrong with one field of a request
+type FieldError struct {
+	Field string `json:"field"`
+	Code string `json:"code"`
+	Message string `json:"message"`
+}
+
+// An Error is something that went wrong in a way that can be told to the client
+// Code and Message are what they see, and Err (the cause) is only ever logged,
+// so an Error can be made from anything without leaking what was in it
+type Error struct {
+	Kind Kind
+	// a short name for the error that clients can check for, like "chirp_not_found"
+	// once it's out there it shouldn't change, even if the message does
+	Code string
+	Message string
+	// for validation errors, the fields that were wrong
+	Fields []FieldError
+	Err error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Wrap returns a copy of e with err as its cause
+func (e *Error) Wrap(err error) *Error {
+	wrapped := *e
+	wrapped.Err = err
+	return &wrapped
+}
+
+func NotFound(code string, message string) *Error {
+	return &Error{Kind: KindNotFound, Code: code, Message: message}
+}
+
+func Conflict(code string, message string) *Error {
+	return &Error{Kind: KindConflict, Code: code, Message: message}
+}
+
+// Validation is for requests that were wrong, with what was wrong with each field if it's down to them
+func Validation(code string, message string, fields ...FieldError) *Error {
+	return &Error{Kind: KindValidation, Code: code, Message: message, Fields: fields}
+}
+
+func Unauthorized(code string, message string) *Error {
+	return &Error{Kind: KindUnauthorized, Code: code, Message: message}
+}
+
+func Forbidden(code string, message string) *Error {
+	return &Error{Kind: KindForbidden, Code: code, Message: message}
+}
+
+func Gone(code string, message string) *Error {
+	return &Error{Kind: KindGone, Code: code, Message: message}
+}
+
+// Internal is for everything that's the server's fault
+// the client only ever hears that something went wrong
+func Internal(err error) *Error {
+	return &Error{Kind: KindInternal, Code: "internal_error", Message: "Something went wrong on our end", Err: err}
+}
+
+// the SQLSTATE codes for the database errors that are down to the request rather than the server
+// see https://www.postgresql.org/docs/current/errcodes-appendix.html
+var sqlStates = map[string]*Error{
+	"23505": Conflict("already_exists", "That already exists"),
+	"23503": Validation("invalid_reference", "That refers to something that doesn't exist"),
+	"23502": Validation("missing_value", "A required value is missing"),
+	"23514": Validation("invalid_value", "A value isn't allowed"),
+	"22001": Validation("value_too_long", "A value is too long"),
+	"22P02": Validation("invalid_value", "A value isn't in the right format"),
+}
+
+// From works out what kind of error err is
+// an *Error (anywhere in the chain) is returned as it is, sql.ErrNoRows is not found, database errors with
+// a SQLSTATE that's the request's fault are conflicts or validation errors, and everything else is internal
+// not found is as specific as it can be without knowing what was being looked for, so it's better to check for it
+// with errors.Is(err, sql.ErrNoRows) and say what wasn't found
+func From(err error) *Error {
+	if err == nil {
+		return nil
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return NotFound("not_found", "Not found").Wrap(err)
+	}
+	if state := SQLState(err); state != "" {
+		if mapped, ok := sqlStates[state]; ok {
+			return mapped.Wrap(err)
+		}
+		// the rest of class 23 is other integrity constraints
+		if strings.HasPrefix(state, "23") {
+			return Conflict("constraint_violation", "That conflicts with something that's already there").Wrap(err)
+		}
+	}
+	return Internal(err)
+}
+
+// SQLState is the SQLSTATE code of a database error, or "" if it doesn't have one
+// it's taken from a SQLState method, which lib/pq and pgx errors both have (as do the store's own constraint errors),
+// so nothing here has to depend on a particular driver
+func SQLState(err error) string {
+	var stater interface{ SQLState() string }
+	if errors.As(err, &stater) {
+		return stater.SQLState()
+	}
+	return ""
+}
+
+// IsUniqueViolation is whether err is from a unique constraint
+func IsUniqueViolation(err error) bool {
+	return SQLState(err) == "23505"
+}
@@ -0,0 +1,87 @@
+package apierr
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// like the errors from lib/pq, which have their SQLSTATE in them
+type driverError struct {
+	code string
+}
+
+func (e *driverError) Error() string {
+	return fmt.Sprintf("pq: error %s", e.code)
+}
+
+func (e *driverError) SQLState() string {
+	return e.code
+}
+
+func TestFrom(t *testing.T) {
+	cases := []struct {
+		err error
+		kind Kind
+		code string
+		status int
+	}{
+		{err: sql.ErrNoRows, kind: KindNotFound, code: "not_found", status: 404},
+		{err: fmt.Errorf("getting chirp: %w", sql.ErrNoRows), kind: KindNotFound, code: "not_found", status: 404},
+		{err: &driverError{code: "23505"}, kind: KindConflict, code: "already_exists", status: 409},
+		{err: fmt.Errorf("creating user: %w", &driverError{code: "23505"}), kind: KindConflict, code: "already_exists", status: 409},
+		{err: &driverError{code: "23503"}, kind: KindValidation, code: "invalid_reference", status: 400},
+		{err: &driverError{code: "22P02"}, kind: KindValidation, code: "invalid_value", status: 400},
+		{err: &driverError{code: "23P01"}, kind: KindConflict, code: "constraint_violation", status: 409},
+		{err: &driverError{code: "57014"}, kind: KindInternal, code: "internal_error", status: 500},
+		{err: errors.New("connection refused"), kind: KindInternal, code: "internal_error", status: 500},
+		{err: fmt.Errorf("deleting: %w", Forbidden("not_your_chirp", "That's not your chirp")), kind: KindForbidden, code: "not_your_chirp", status: 403},
+		{err: fmt.Errorf("getting chirp: %w", Gone("chirp_deleted", "Chirp has been deleted")), kind: KindGone, code: "chirp_deleted", status: 410},
+	}
+	for _, c := range cases {
+		e := From(c.err)
+		if e.Kind != c.kind || e.Code != c.code || e.Kind.Status() != c.status {
+			t.Errorf("From(%v) returned %v %q (%d), expected %v %q (%d)", c.err, e.Kind, e.Code, e.Kind.Status(), c.kind, c.code, c.status)
+		}
+	}
+	if From(nil) != nil {
+		t.Errorf("Expected From(nil) to be nil")
+	}
+}
+
+func TestInternalHidesCause(t *testing.T) {
+	cause := errors.New("pq: password authentication failed for user \"postgres\"")
+	e := From(fmt.Errorf("creating user: %w", cause))
+	if e.Message != "Something went wrong on our end" {
+		t.Errorf("Expected a generic message, got %q", e.Message)
+	}
+	// but it's still there for the logs
+	if !errors.Is(e, cause) {
+		t.Errorf("Expected the cause to be kept")
+	}
+}
+
+func TestWrap(t *testing.T) {
+	notFound := NotFound("chirp_not_found", "Chirp not found")
+	wrapped := notFound.Wrap(sql.ErrNoRows)
+	if notFound.Err != nil {
+		t.Errorf("Wrap changed the original")
+	}
+	if !errors.Is(wrapped, sql.ErrNoRows) || wrapped.Code != "chirp_not_found" {
+		t.Errorf("Wrap returned %+v", wrapped)
+	}
+	if wrapped.Error() != "Chirp not found: sql: no rows in result set" {
+		t.Errorf("Error() returned %q", wrapped.Error())
+	}
+}
+
+func TestValidationFields(t *testing.T) {
+	e := Validation("invalid_user", "Some fields are wrong",
+		FieldError{Field: "email", Code: "required", Message: "Email is required"},
+		FieldError{Field: "password", Code: "too_short", Message: "Password is too short"},
+	)
+	if e.Kind.Status() != 400 || len(e.Fields) != 2 || e.Fields[1].Field != "password" {
+		t.Errorf("Validation returned %+v", e)
+	}
+}
@@ -0,0 +1,3 @@
+module apierr
+
+go 1.24.1
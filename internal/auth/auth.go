@@ -23,32 +23,48 @@ func CheckPasswordHash(password, hash string) (error) {
 	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
 }
 
+// the claims in a chirpy jwt: the usual ones, plus the user's role
+type Claims struct {
+	Role string `json:"role"`
+	jwt.RegisteredClaims
+}
+
 // get a jwt token
-func MakeJWT(userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
-		Issuer: "chirpy",
-		IssuedAt: jwt.NewNumericDate(time.Now()),
-		ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiresIn)),
-		Subject: userID.String(),
+func MakeJWT(userID uuid.UUID, role string, tokenSecret string, expiresIn time.Duration) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{
+		Role: role,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer: "chirpy",
+			IssuedAt: jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiresIn)),
+			Subject: userID.String(),
+		},
 	})
 	return token.SignedString([]byte(tokenSecret))
 }
 
 // validate a jwt token
 func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
-	claims := jwt.RegisteredClaims{}
+	userID, _, err := ParseJWT(tokenString, tokenSecret)
+	return userID, err
+}
+
+// validate a jwt token, and get the role in it as well as the user
+// tokens from before roles were a thing have no role in them
+func ParseJWT(tokenString, tokenSecret string) (uuid.UUID, string, error) {
+	claims := Claims{}
 	token, err := jwt.ParseWithClaims(tokenString, &claims, func(token *jwt.Token) (interface{}, error) {
 		return []byte(tokenSecret), nil
 	})
 	if err != nil {
-		return uuid.UUID{}, fmt.Errorf("Error in ParseWithClaims: %v", err)
+		return uuid.UUID{}, "", fmt.Errorf("Error in ParseWithClaims: %v", err)
 	}
 	idString, err := token.Claims.GetSubject()
 	if err != nil {
-		return uuid.UUID{}, fmt.Errorf("Error in GetSubject: %v", err)
+		return uuid.UUID{}, "", fmt.Errorf("Error in GetSubject: %v", err)
 	}
 	idUU, err := uuid.Parse(idString)
-	return idUU, err
+	return idUU, claims.Role, err
 }
 
 // get a bearer token from the header
@@ -67,7 +67,7 @@ func TestJWT(t *testing.T) {
 	}
 	for _, c := range cases {
 		expireDuration, _ := time.ParseDuration(c.expiresIn)
-		jwt, err := MakeJWT(c.userID, c.tokenSecret, expireDuration)
+		jwt, err := MakeJWT(c.userID, "user", c.tokenSecret, expireDuration)
 		if err != nil {
 			t.Errorf("userID: %v\ntokenSecret: %v\nexpiresIn: %v\nError: %v", c.userID, c.tokenSecret, c.expiresIn, err)
 			continue
@@ -83,7 +83,7 @@ func TestJWT(t *testing.T) {
 	}
 	for _, f := range fails {
 		expireDuration, _ := time.ParseDuration(f.expiresIn)
-		jwt, err := MakeJWT(f.userID, f.tokenSecret, expireDuration)
+		jwt, err := MakeJWT(f.userID, "user", f.tokenSecret, expireDuration)
 		if err != nil {
 			continue
 		}
@@ -98,6 +98,21 @@ func TestJWT(t *testing.T) {
 	}
 }
 
+func TestJWTRole(t *testing.T) {
+	userID := uuid.New()
+	jwt, err := MakeJWT(userID, "moderator", "test", time.Minute)
+	if err != nil {
+		t.Fatalf("Error with MakeJWT: %v", err)
+	}
+	user, role, err := ParseJWT(jwt, "test")
+	if err != nil {
+		t.Fatalf("Error with ParseJWT: %v", err)
+	}
+	if user != userID || role != "moderator" {
+		t.Errorf("Expected %v with the moderator role, got %v with %q", userID, user, role)
+	}
+}
+
 func TestBearerToken(t *testing.T) {
 	goodTest := http.Header{}
 	goodTest.Add("Authorization", "Bearer test")
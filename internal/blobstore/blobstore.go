@@ -0,0 +1,26 @@
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// returned by Open when there's nothing stored under the key
+var ErrNotFound = errors.New("blob not found")
+
+// returned for keys that could escape the store, like ones with slashes or ..
+var ErrInvalidKey = errors.New("invalid blob key")
+
+// somewhere to keep uploaded files
+// keys are chosen by the caller and are just names, not paths
+type BlobStore interface {
+	// Put stores everything read from r under key, replacing anything already there,
+	// and returns how many bytes were written
+	// if reading r fails nothing is left behind
+	Put(ctx context.Context, key string, r io.Reader) (int64, error)
+	// Open returns a reader for the blob under key, or ErrNotFound
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the blob under key, and does nothing if it's already gone
+	Delete(ctx context.Context, key string) error
+}
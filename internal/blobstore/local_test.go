@@ -0,0 +1,78 @@
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestLocal(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	store, err := NewLocal(dir)
+	if err != nil {
+		t.Fatalf("Error in NewLocal: %v", err)
+	}
+
+	size, err := store.Put(ctx, "abc", strings.NewReader("hello"))
+	if err != nil || size != 5 {
+		t.Fatalf("Put returned %d, %v", size, err)
+	}
+	blob, err := store.Open(ctx, "abc")
+	if err != nil {
+		t.Fatalf("Error in Open: %v", err)
+	}
+	data, _ := io.ReadAll(blob)
+	blob.Close()
+	if string(data) != "hello" {
+		t.Errorf("Open returned %q, expected hello", data)
+	}
+
+	err = store.Delete(ctx, "abc")
+	if err != nil {
+		t.Errorf("Error in Delete: %v", err)
+	}
+	_, err = store.Open(ctx, "abc")
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound after deleting, got: %v", err)
+	}
+	err = store.Delete(ctx, "abc")
+	if err != nil {
+		t.Errorf("Deleting twice should do nothing, got: %v", err)
+	}
+}
+
+func TestLocalFailedPut(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	store, _ := NewLocal(dir)
+	_, err := store.Put(ctx, "abc", io.MultiReader(strings.NewReader("half"), errReader{}))
+	if err == nil {
+		t.Fatalf("Expected an error from a failing reader")
+	}
+	// nothing should be left behind, not even the temp file
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 0 {
+		t.Errorf("A failed Put left %d files behind", len(entries))
+	}
+}
+
+func TestLocalKeys(t *testing.T) {
+	ctx := context.Background()
+	store, _ := NewLocal(t.TempDir())
+	for _, key := range []string{"", ".", "..", "../escape", "a/b", `a\b`, ".tmp-123"} {
+		_, err := store.Put(ctx, key, strings.NewReader("x"))
+		if !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Put(%q) returned %v, expected ErrInvalidKey", key, err)
+		}
+	}
+}
+
+type errReader struct{}
+
+func (errReader) Read(p []byte) (int, error) {
+	return 0, errors.New("broken")
+}
@@ -0,0 +1,37 @@
+package contentfilter
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// LoadFile reads word lists from a JSON file shaped like
+// {"lists": [{"name": "slurs", "action": "reject", "words": ["..."]}]}
+func LoadFile(path string) ([]List, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	file := struct {
+		Lists []List `json:"lists"`
+	}{}
+	err = json.Unmarshal(data, &file)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	seen := map[string]bool{}
+	for _, l := range file.Lists {
+		if !ValidName(l.Name) {
+			return nil, fmt.Errorf("reading %s: invalid list name %q", path, l.Name)
+		}
+		if seen[l.Name] {
+			return nil, fmt.Errorf("reading %s: list %q is in there twice", path, l.Name)
+		}
+		seen[l.Name] = true
+		if !l.Action.Valid() {
+			return nil, fmt.Errorf("reading %s: list %q has invalid action %q (should be mask, reject or flag)", path, l.Name, l.Action)
+		}
+	}
+	return file.Lists, nil
+}
@@ -0,0 +1,335 @@
+package contentfilter
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// what happens to a chirp with a word from a list in it
+type Action string
+
+const (
+	// the word is replaced with ****
+	Mask Action = "mask"
+	// the whole chirp is turned away
+	Reject Action = "reject"
+	// the chirp goes up as is, but a moderator gets to look at it
+	Flag Action = "flag"
+)
+
+func (a Action) Valid() bool {
+	return a == Mask || a == Reject || a == Flag
+}
+
+// what masked words are replaced with
+const MaskText = "****"
+
+// entries longer than this are ignored
+const MaxWordLength = 100
+
+var listNamePattern = regexp.MustCompile(`^[a-z0-9_-]{1,50}$`)
+
+// ValidName reports whether name is a usable list name: 1 to 50 lowercase letters, numbers, dashes and underscores
+func ValidName(name string) bool {
+	return listNamePattern.MatchString(name)
+}
+
+// NormalizeWord turns a list entry into the form it's stored in: lowercase, with its spaces tidied up
+// it returns false if there's nothing in it to match on
+// entries can be phrases ("some phrase") as well as single words
+func NormalizeWord(word string) (string, bool) {
+	word = strings.ToLower(strings.Join(strings.Fields(word), " "))
+	if word == "" || utf8.RuneCountInString(word) > MaxWordLength {
+		return "", false
+	}
+	for _, r := range word {
+		if unicode.IsLetter(r) || unicode.IsNumber(r) {
+			return word, true
+		}
+	}
+	return "", false
+}
+
+// a named list of words that all get the same action
+type List struct {
+	Name string `json:"name"`
+	Action Action `json:"action"`
+	Words []string `json:"words"`
+}
+
+// a list entry found in some text
+type Match struct {
+	List string
+	Action Action
+	// the entry from the list
+	Word string
+	// the byte offsets of what matched it in the original text
+	Start int
+	End int
+}
+
+// what Check found
+type Result struct {
+	// the text with everything on a Mask list masked out
+	Text string
+	Matches []Match
+}
+
+// whether anything on a Reject list turned up
+func (r Result) Rejected() bool {
+	for _, m := range r.Matches {
+		if m.Action == Reject {
+			return true
+		}
+	}
+	return false
+}
+
+// the matches from Flag lists
+func (r Result) Flagged() []Match {
+	flagged := []Match{}
+	for _, m := range r.Matches {
+		if m.Action == Flag {
+			flagged = append(flagged, m)
+		}
+	}
+	return flagged
+}
+
+// one letter of an entry, and how many times in a row it has to appear
+// (fornax is f,o,r,n,a,x once each, so fooorrrnax still matches it but "as" doesn't match "ass")
+type run struct {
+	r rune
+	n int
+}
+
+// a list entry, compiled into words of runs
+type entry struct {
+	list string
+	action Action
+	word string
+	words [][]run
+}
+
+// A Filter checks text against word lists
+// matching is case-insensitive and sees through accents, lookalike letters from other scripts,
+// fullwidth letters, leetspeak (k3rfuffl3, $harbert), repeated letters (fooorrrnax),
+// zero-width characters and spaced out letters (k e r f u f f l e), but only ever matches whole words,
+// so a list with "ass" in it leaves "class" alone
+// it's safe to use from several goroutines at once, including while SetLists is changing it
+type Filter struct {
+	mu sync.RWMutex
+	// entries by the first letter they start with
+	entries map[rune][]entry
+}
+
+// New makes a filter for lists
+func New(lists []List) *Filter {
+	f := &Filter{}
+	f.SetLists(lists)
+	return f
+}
+
+// SetLists replaces every list the filter checks against
+// lists with an invalid action and entries that don't normalize are skipped
+func (f *Filter) SetLists(lists []List) {
+	entries := map[rune][]entry{}
+	for _, l := range lists {
+		if !l.Action.Valid() {
+			continue
+		}
+		for _, w := range l.Words {
+			word, ok := NormalizeWord(w)
+			if !ok {
+				continue
+			}
+			e := entry{list: l.Name, action: l.Action, word: word, words: compile(word)}
+			if len(e.words) == 0 {
+				continue
+			}
+			first := e.words[0][0].r
+			entries[first] = append(entries[first], e)
+		}
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.entries = entries
+}
+
+// folds an entry and splits it into words of runs
+func compile(word string) [][]run {
+	words := [][]run{}
+	current := []run{}
+	for _, c := range foldText(word) {
+		if c.kind == separator {
+			if len(current) > 0 {
+				words = append(words, current)
+				current = []run{}
+			}
+			continue
+		}
+		if len(current) > 0 && current[len(current)-1].r == c.r {
+			current[len(current)-1].n++
+		} else {
+			current = append(current, run{r: c.r, n: 1})
+		}
+	}
+	if len(current) > 0 {
+		words = append(words, current)
+	}
+	return words
+}
+
+// Check finds every list entry in text, and masks the ones from Mask lists
+func (f *Filter) Check(text string) Result {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	chars := foldText(text)
+	res := Result{Text: text, Matches: []Match{}}
+	// $$harbert matches from both of its $s, but it's only the one match
+	type found struct {
+		list string
+		word string
+		end int
+	}
+	seen := map[found]bool{}
+	for i, c := range chars {
+		// a word can only start where one could: not partway through another one
+		if c.kind == separator || (i > 0 && chars[i-1].kind == letter) {
+			continue
+		}
+		for _, e := range f.entries[c.r] {
+			end, ok := matchEntry(chars, i, e.words)
+			key := found{list: e.list, word: e.word, end: end}
+			if ok && !seen[key] {
+				seen[key] = true
+				res.Matches = append(res.Matches, Match{List: e.list, Action: e.action, Word: e.word, Start: c.start, End: chars[end-1].end})
+			}
+		}
+	}
+	res.Text = mask(text, res.Matches)
+	return res
+}
+
+// matches every word of an entry starting at chars[i], returning where the match ends
+// the words have to be separated by something, like they are in the entry
+func matchEntry(chars []char, i int, words [][]run) (int, bool) {
+	for w, runs := range words {
+		if w > 0 {
+			// skipping over anything trailing off the last word
+			for i < len(chars) && chars[i].kind == symbol {
+				i++
+			}
+			if i >= len(chars) || chars[i].kind != separator {
+				return 0, false
+			}
+			for i < len(chars) && chars[i].kind == separator {
+				i++
+			}
+		}
+		end, ok := matchWord(chars, i, runs)
+		if !ok {
+			return 0, false
+		}
+		i = end
+	}
+	return i, true
+}
+
+// matches one word starting at chars[i], either written normally or spaced out
+// it has to end where a word could, so kerfuffles isn't kerfuffle
+func matchWord(chars []char, i int, runs []run) (int, bool) {
+	letters := []int{}
+	for j := i; j < len(chars) && chars[j].kind != separator; j++ {
+		letters = append(letters, j)
+	}
+	end, ok := matchRuns(chars, letters, runs)
+	if ok && end == len(letters) {
+		return letters[end-1] + 1, true
+	}
+	if ok {
+		// symbols can trail off the end (kerfuffle!!!) without being part of the word
+		trailing := true
+		for _, j := range letters[end:] {
+			if chars[j].kind != symbol {
+				trailing = false
+			}
+		}
+		if trailing {
+			return letters[end-1] + 1, true
+		}
+	}
+	// spaced out, one letter at a time with a single separator between each (k e r f u f f l e, k.e.r.f.u.f.f.l.e)
+	// the whole spaced out stretch has to match, so "i m a s s i v e" doesn't hide an "ass"
+	if i+1 >= len(chars) || chars[i+1].kind != separator {
+		return 0, false
+	}
+	letters = []int{}
+	for j := i; j < len(chars) && chars[j].kind != separator; j += 2 {
+		letters = append(letters, j)
+		if j+1 >= len(chars) || chars[j+1].kind != separator || (j+2 < len(chars) && !spacedLetter(chars, j+2)) {
+			break
+		}
+	}
+	end, ok = matchRuns(chars, letters, runs)
+	if ok && end == len(letters) && len(letters) > 1 {
+		return letters[end-1] + 1, true
+	}
+	return 0, false
+}
+
+// whether chars[j] is a single letter on its own, like each of the letters in k e r f u f f l e
+func spacedLetter(chars []char, j int) bool {
+	return chars[j].kind != separator && (j+1 == len(chars) || chars[j+1].kind == separator)
+}
+
+// matches runs against the chars at positions, returning how many positions it used
+// each run has to be matched by at least as many of the same letter in a row, and takes all of them
+func matchRuns(chars []char, positions []int, runs []run) (int, bool) {
+	p := 0
+	for _, r := range runs {
+		n := 0
+		for p < len(positions) && chars[positions[p]].r == r.r {
+			n++
+			p++
+		}
+		if n < r.n {
+			return 0, false
+		}
+	}
+	return p, true
+}
+
+// replaces the text of every Mask match with MaskText, merging ones that overlap
+func mask(text string, matches []Match) string {
+	spans := [][2]int{}
+	for _, m := range matches {
+		if m.Action == Mask {
+			spans = append(spans, [2]int{m.Start, m.End})
+		}
+	}
+	if len(spans) == 0 {
+		return text
+	}
+	sort.Slice(spans, func(i, j int) bool {
+		return spans[i][0] < spans[j][0]
+	})
+	var b strings.Builder
+	last := 0
+	for i := 0; i < len(spans); i++ {
+		start, end := spans[i][0], spans[i][1]
+		for i+1 < len(spans) && spans[i+1][0] < end {
+			i++
+			end = max(end, spans[i][1])
+		}
+		b.WriteString(text[last:start])
+		b.WriteString(MaskText)
+		last = end
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
@@ -0,0 +1,138 @@
+package contentfilter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var testLists = []List{
+	{Name: "default", Action: Mask, Words: []string{"kerfuffle", "sharbert", "fornax"}},
+	{Name: "banned", Action: Reject, Words: []string{"ass", "bad  phrase"}},
+	{Name: "watch", Action: Flag, Words: []string{"scam"}},
+}
+
+func TestCheckMask(t *testing.T) {
+	cases := []struct{
+		input string
+		expected string
+	}{
+		{input: "nothing to see here", expected: "nothing to see here"},
+		// the cases the old filter already handled
+		{input: "what a kerfuffle today", expected: "what a **** today"},
+		{input: "Sharbert FORNAX", expected: "**** ****"},
+		// punctuation and whitespace around the word
+		{input: "Kerfuffle!", expected: "****!"},
+		{input: "kerfuffle\nnext line", expected: "****\nnext line"},
+		{input: "(fornax), \"sharbert.\"", expected: "(****), \"****.\""},
+		{input: "a kerfuffle's end", expected: "a ****'s end"},
+		// unicode tricks
+		{input: "kérfüffle", expected: "****"},
+		{input: "ｋｅｒｆｕｆｆｌｅ", expected: "****"},
+		{input: "kerf\u200buffle", expected: "****"},
+		{input: "ke\u0301rfuffle", expected: "****"},
+		{input: "fоrnаx", expected: "****"}, // Cyrillic о and а
+		// leetspeak and repeated letters
+		{input: "k3rfuffl3", expected: "****"},
+		{input: "$harbert", expected: "****"},
+		{input: "f0rn4x", expected: "****"},
+		{input: "kerfuff1e", expected: "****"},
+		{input: "fooorrrnaaax!!!", expected: "****!!!"},
+		// spaced out
+		{input: "k e r f u f f l e", expected: "****"},
+		{input: "what a k.e.r.f.u.f.f.l.e", expected: "what a ****"},
+		// whole words only
+		{input: "kerfuffles and fornaxes", expected: "kerfuffles and fornaxes"},
+		{input: "superkerfuffle", expected: "superkerfuffle"},
+		{input: "f o r n a x e s", expected: "f o r n a x e s"},
+	}
+	f := New(testLists)
+	for _, c := range cases {
+		res := f.Check(c.input)
+		if res.Text != c.expected {
+			t.Errorf("Input: %q\nExpected: %q\nOutput: %q", c.input, c.expected, res.Text)
+		}
+	}
+}
+
+func TestCheckActions(t *testing.T) {
+	cases := []struct{
+		input string
+		rejected bool
+		flagged int
+	}{
+		{input: "class is fine", rejected: false},
+		{input: "as is fine too", rejected: false},
+		{input: "i m a s s i v e", rejected: false},
+		{input: "you a$$", rejected: true},
+		{input: "aaassss", rejected: true},
+		{input: "a bad phrase!", rejected: true},
+		{input: "a bad, phrase", rejected: true},
+		{input: "bad phrases", rejected: false},
+		{input: "bad", rejected: false},
+		{input: "this is a $cam, a SCAM", flagged: 2},
+		{input: "scampi", flagged: 0},
+	}
+	f := New(testLists)
+	for _, c := range cases {
+		res := f.Check(c.input)
+		if res.Rejected() != c.rejected || len(res.Flagged()) != c.flagged {
+			t.Errorf("Input: %q\nExpected rejected %v with %d flagged\nOutput: %+v", c.input, c.rejected, c.flagged, res.Matches)
+		}
+		if res.Text != c.input {
+			t.Errorf("Only mask lists should change the text, %q became %q", c.input, res.Text)
+		}
+	}
+}
+
+func TestSetLists(t *testing.T) {
+	f := New(testLists)
+	f.SetLists([]List{{Name: "new", Action: Mask, Words: []string{"chirp"}}, {Name: "broken", Action: "explode", Words: []string{"fornax"}}})
+	res := f.Check("kerfuffle chirp fornax")
+	if res.Text != "kerfuffle **** fornax" {
+		t.Errorf("SetLists should replace the old lists and skip invalid ones, got %q", res.Text)
+	}
+}
+
+func TestNormalizeWord(t *testing.T) {
+	cases := []struct{
+		input string
+		expected string
+		ok bool
+	}{
+		{input: "Kerfuffle", expected: "kerfuffle", ok: true},
+		{input: "  Bad   Phrase ", expected: "bad phrase", ok: true},
+		{input: "a$$", expected: "a$$", ok: true},
+		{input: "", ok: false},
+		{input: "!!!", ok: false},
+	}
+	for _, c := range cases {
+		output, ok := NormalizeWord(c.input)
+		if output != c.expected || ok != c.ok {
+			t.Errorf("Input: %q\nExpected: %q, %v\nOutput: %q, %v", c.input, c.expected, c.ok, output, ok)
+		}
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	dir := t.TempDir()
+	good := filepath.Join(dir, "good.json")
+	os.WriteFile(good, []byte(`{"lists": [{"name": "default", "action": "mask", "words": ["kerfuffle"]}]}`), 0o644)
+	lists, err := LoadFile(good)
+	if err != nil || len(lists) != 1 || lists[0].Action != Mask || lists[0].Words[0] != "kerfuffle" {
+		t.Fatalf("LoadFile returned %+v, %v", lists, err)
+	}
+	for _, body := range []string{
+		`{"lists": [{"name": "Bad Name", "action": "mask"}]}`,
+		`{"lists": [{"name": "default", "action": "explode"}]}`,
+		`{"lists": [{"name": "twice", "action": "mask"}, {"name": "twice", "action": "flag"}]}`,
+		`not json`,
+	} {
+		bad := filepath.Join(dir, "bad.json")
+		os.WriteFile(bad, []byte(body), 0o644)
+		_, err = LoadFile(bad)
+		if err == nil {
+			t.Errorf("Expected an error loading %s", body)
+		}
+	}
+}
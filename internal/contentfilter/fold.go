@@ -0,0 +1,128 @@
+package contentfilter
+
+import (
+	"unicode"
+)
+
+// what a rune in the text is, as far as matching goes
+type kind int
+
+const (
+	// letters and numbers
+	letter kind = iota
+	// punctuation that's often swapped in for a letter, like the $ in $harbert
+	// it counts as that letter inside a word, but doesn't stop one from starting or ending
+	symbol
+	// everything else: spaces, punctuation, emoji and so on
+	separator
+)
+
+// stand-ins for letters, folded back to the letter they stand for
+// letters that look alike all go to the same one (l, 1 and | are all i), and since the word lists
+// are folded the same way, it doesn't matter which of them a list or a chirp used
+var leet = map[rune]rune{
+	'0': 'o',
+	'1': 'i',
+	'3': 'e',
+	'4': 'a',
+	'5': 's',
+	'7': 't',
+	'8': 'b',
+	'9': 'g',
+	'@': 'a',
+	'$': 's',
+	'!': 'i',
+	'|': 'i',
+	'+': 't',
+	'€': 'e',
+	'l': 'i',
+}
+
+// accented letters, and letters from other scripts that look just like Latin ones,
+// folded to the plain letter (all lowercase, since folding lowercases first)
+var lookalikes = map[rune]string{
+	'a': "àáâãäåāăąǎаα",
+	'b': "ƀ",
+	'c': "çćĉċčс",
+	'd': "ďđ",
+	'e': "èéêëēĕėęěеε",
+	'g': "ĝğġģ",
+	'h': "ĥħһ",
+	'i': "ìíîïĩīĭįıǐіι",
+	'j': "ĵј",
+	'k': "ķκ",
+	'l': "ĺļľŀł",
+	'n': "ñńņňŉ",
+	'o': "òóôõöøōŏőǒоο",
+	'p': "рρ",
+	'r': "ŕŗř",
+	's': "śŝşšßѕ",
+	't': "ţťŧτ",
+	'u': "ùúûüũūŭůűųǔ",
+	'v': "ν",
+	'w': "ŵ",
+	'x': "хχ",
+	'y': "ýÿŷу",
+	'z': "źżž",
+}
+
+var plain = map[rune]rune{}
+
+func init() {
+	for base, variants := range lookalikes {
+		for _, r := range variants {
+			plain[r] = base
+		}
+	}
+}
+
+// whether r is dropped entirely rather than splitting a word,
+// like combining accents and zero-width spaces (k​erfuffle is still kerfuffle)
+func invisible(r rune) bool {
+	return unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf)
+}
+
+// folds r down to what it's compared as, and says what kind of rune it is
+func fold(r rune) (rune, kind) {
+	r = unicode.ToLower(r)
+	// fullwidth forms (ｋｅｒｆｕｆｆｌｅ) are just ASCII in disguise
+	if r >= 0xFF01 && r <= 0xFF5E {
+		r = unicode.ToLower(r - 0xFEE0)
+	}
+	if p, ok := plain[r]; ok {
+		r = p
+	}
+	k := separator
+	if unicode.IsLetter(r) || unicode.IsNumber(r) {
+		k = letter
+	}
+	if l, ok := leet[r]; ok {
+		if k == separator {
+			k = symbol
+		}
+		r = l
+	}
+	return r, k
+}
+
+// one rune of folded text, and where it came from
+type char struct {
+	r rune
+	kind kind
+	// the byte offsets of the original rune
+	start int
+	end int
+}
+
+// folds every rune of text that isn't invisible
+func foldText(text string) []char {
+	chars := []char{}
+	for i, r := range text {
+		if invisible(r) {
+			continue
+		}
+		f, k := fold(r)
+		chars = append(chars, char{r: f, kind: k, start: i, end: i + len(string(r))})
+	}
+	return chars
+}
@@ -0,0 +1,3 @@
+module contentfilter
+
+go 1.24.1
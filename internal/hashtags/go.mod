@@ -0,0 +1,3 @@
+module hashtags
+
+go 1.24.1
@@ -0,0 +1,63 @@
+package hashtags
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// tags longer than this are ignored rather than cut short
+const MaxLength = 100
+
+// letters, numbers, combining marks (so tags in scripts like Devanagari work) and underscores
+func isTagRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsNumber(r) || unicode.Is(unicode.Mn, r) || unicode.Is(unicode.Mc, r) || r == '_'
+}
+
+// Normalize turns a tag (with or without its #) into the form it's stored in
+// tags are case-insensitive, so they're kept lowercase
+// it returns false if it isn't a valid tag, like #2024 with no letters in it
+func Normalize(tag string) (string, bool) {
+	tag = strings.TrimPrefix(tag, "#")
+	if tag == "" || utf8.RuneCountInString(tag) > MaxLength {
+		return "", false
+	}
+	hasLetter := false
+	for _, r := range tag {
+		if !isTagRune(r) {
+			return "", false
+		}
+		if unicode.IsLetter(r) {
+			hasLetter = true
+		}
+	}
+	if !hasLetter {
+		return "", false
+	}
+	return strings.ToLower(tag), true
+}
+
+// Extract finds the hashtags in a chirp, normalized and without duplicates, in the order they first appear
+// a # only starts a tag at the beginning of the chirp or after something that can't be part of one,
+// so "a#b" and "&#39;" don't count
+func Extract(body string) []string {
+	tags := []string{}
+	seen := map[string]bool{}
+	runes := []rune(body)
+	for i := 0; i < len(runes); i++ {
+		if runes[i] != '#' || (i > 0 && (isTagRune(runes[i-1]) || runes[i-1] == '#' || runes[i-1] == '&')) {
+			continue
+		}
+		end := i + 1
+		for end < len(runes) && isTagRune(runes[end]) {
+			end++
+		}
+		tag, ok := Normalize(string(runes[i+1:end]))
+		if ok && !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+		i = end - 1
+	}
+	return tags
+}
@@ -0,0 +1,68 @@
+package hashtags
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestExtract(t *testing.T) {
+	cases := []struct{
+		input string
+		expected []string
+	}{
+		{
+			input: "no tags here",
+			expected: []string{},
+		},
+		{
+			input: "#Go is fun #golang",
+			expected: []string{"go", "golang"},
+		},
+		{
+			input: "#GO #go #Go",
+			expected: []string{"go"},
+		},
+		{
+			input: "café #Café, #ÜBER!",
+			expected: []string{"café", "über"},
+		},
+		{
+			input: "#東京 #नमस्ते #日本語_2024",
+			expected: []string{"東京", "नमस्ते", "日本語_2024"},
+		},
+		{
+			input: "year #2024 and room#5 and &#39; and ##double",
+			expected: []string{},
+		},
+		{
+			input: "(#wrapped) #end.",
+			expected: []string{"wrapped", "end"},
+		},
+	}
+	for _, c := range cases {
+		output := Extract(c.input)
+		if !slices.Equal(output, c.expected) {
+			t.Errorf("Input: %v\nExpected: %v\nOutput: %v", c.input, c.expected, output)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	cases := []struct{
+		input string
+		expected string
+		ok bool
+	}{
+		{input: "#Chirpy", expected: "chirpy", ok: true},
+		{input: "Chirpy", expected: "chirpy", ok: true},
+		{input: "#", ok: false},
+		{input: "#123", ok: false},
+		{input: "two words", ok: false},
+	}
+	for _, c := range cases {
+		output, ok := Normalize(c.input)
+		if output != c.expected || ok != c.ok {
+			t.Errorf("Input: %v\nExpected: %v %v\nOutput: %v %v", c.input, c.expected, c.ok, output, ok)
+		}
+	}
+}
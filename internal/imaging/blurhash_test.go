@@ -0,0 +1,26 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+func TestBlurhash(t *testing.T) {
+	white := image.NewRGBA(image.Rect(0, 0, 64, 48))
+	draw.Draw(white, white.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	// L is 4x3 components, and TSUA is the average colour, 0xFFFFFF
+	hash := Blurhash(white, 4, 3)
+	if len(hash) != 28 || hash[0] != 'L' || hash[2:6] != "TSUA" {
+		t.Errorf("Blurhash returned %q, expected L?TSUA and 11 more pairs", hash)
+	}
+	if hash := Blurhash(white, 1, 1); hash != "00TSUA" {
+		t.Errorf("Blurhash with one component returned %q, expected 00TSUA", hash)
+	}
+	// red on the left and blue on the right shows up in the first horizontal component
+	twoTone := Blurhash(testImage(), 4, 3)
+	if twoTone[6:8] == hash[6:8] {
+		t.Errorf("Blurhash of a two-tone image returned %q", twoTone)
+	}
+}
@@ -0,0 +1,3 @@
+module imaging
+
+go 1.24.1
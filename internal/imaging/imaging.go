@@ -0,0 +1,235 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+)
+
+// uploads bigger than this (width times height, over every frame for GIFs) are turned away
+// before being decoded, so a small file can't ask for gigabytes of memory
+const MaxPixels = 40_000_000
+
+// thumbnails are cropped to a square this many pixels across
+const ThumbnailSize = 256
+
+const jpegQuality = 85
+
+var ErrTooLarge = errors.New("image is too large")
+var ErrUnsupported = errors.New("unsupported image type")
+
+// an upload after it's been cleaned up
+type Image struct {
+	// the image re-encoded, without any of the metadata it came with
+	Data []byte
+	Width int
+	Height int
+	// a few dozen characters that decode to a blurry preview, for showing while the real thing loads
+	// empty for images that couldn't be decoded (WebP)
+	Blurhash string
+	// ThumbnailSize square, in VariantType(contentType), or nil like Blurhash
+	Thumbnail []byte
+}
+
+// whether Process knows what to do with contentType
+func Supported(contentType string) bool {
+	switch contentType {
+	case "image/jpeg", "image/png", "image/gif", "image/webp":
+		return true
+	}
+	return false
+}
+
+// whether Resize can make smaller copies of contentType
+// GIFs would lose their animation and WebP can't be decoded without leaving the standard library,
+// so those are only ever served as uploaded
+func Resizable(contentType string) bool {
+	return contentType == "image/jpeg" || contentType == "image/png"
+}
+
+// the type thumbnails and resized copies of contentType are encoded as
+// photos stay JPEGs, everything else becomes a PNG so transparency survives
+func VariantType(contentType string) string {
+	if contentType == "image/jpeg" {
+		return "image/jpeg"
+	}
+	return "image/png"
+}
+
+// Process strips the metadata (EXIF, GPS, comments and so on) out of an uploaded image
+// JPEGs, PNGs and GIFs are decoded and re-encoded, which leaves everything but the pixels behind
+// (JPEGs are turned the right way up first, since their orientation lives in the EXIF being dropped)
+// WebP files just have their metadata chunks cut out
+func Process(data []byte, contentType string) (Image, error) {
+	switch contentType {
+	case "image/webp":
+		stripped, err := StripWebPMetadata(data)
+		return Image{Data: stripped}, err
+	case "image/gif":
+		return processGIF(data)
+	case "image/jpeg", "image/png":
+	default:
+		return Image{}, ErrUnsupported
+	}
+
+	img, err := decode(data, contentType)
+	if err != nil {
+		return Image{}, err
+	}
+	if contentType == "image/jpeg" {
+		img = orient(img, jpegOrientation(data))
+	}
+	encoded, err := encode(img, contentType)
+	if err != nil {
+		return Image{}, err
+	}
+	return finish(Image{Data: encoded}, img, contentType)
+}
+
+// GIFs keep every frame (and so their animation), the thumbnail and placeholder come from the first one
+func processGIF(data []byte) (Image, error) {
+	config, err := gif.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return Image{}, err
+	}
+	if config.Width*config.Height > MaxPixels {
+		return Image{}, ErrTooLarge
+	}
+	// DecodeAll allocates every frame before handing any of them back, so they're counted first
+	maxFrames := MaxPixels / max(config.Width*config.Height, 1)
+	if countGIFFrames(data, maxFrames) > maxFrames {
+		return Image{}, ErrTooLarge
+	}
+	g, err := gif.DecodeAll(bytes.NewReader(data))
+	if err != nil {
+		return Image{}, err
+	}
+	var buf bytes.Buffer
+	err = gif.EncodeAll(&buf, g)
+	if err != nil {
+		return Image{}, err
+	}
+	// frames can be smaller than the whole picture, so draw the first one onto a full-size canvas
+	first := image.NewRGBA(image.Rect(0, 0, config.Width, config.Height))
+	draw.Draw(first, g.Image[0].Bounds(), g.Image[0], g.Image[0].Bounds().Min, draw.Over)
+	return finish(Image{Data: buf.Bytes()}, first, "image/gif")
+}
+
+// how many frames a GIF has going by its blocks, without decoding any of them
+// stops once it's past limit, and leaves anything it can't make sense of for the decoder to complain about
+func countGIFFrames(data []byte, limit int) int {
+	// header and logical screen descriptor
+	if len(data) < 13 {
+		return 0
+	}
+	pos := 13
+	if data[10]&0x80 != 0 {
+		pos += 3 << (data[10]&0x07 + 1)
+	}
+	// image data and extensions are both a run of sub-blocks, ended by an empty one
+	skipSubBlocks := func() bool {
+		for pos < len(data) {
+			n := int(data[pos])
+			pos++
+			if n == 0 {
+				return true
+			}
+			pos += n
+		}
+		return false
+	}
+	frames := 0
+	for pos < len(data) {
+		switch data[pos] {
+		case 0x21:
+			// extension introducer and label
+			pos += 2
+		case 0x2C:
+			frames++
+			if frames > limit || pos+10 > len(data) {
+				return frames
+			}
+			flags := data[pos+9]
+			pos += 10
+			if flags&0x80 != 0 {
+				pos += 3 << (flags&0x07 + 1)
+			}
+			// LZW minimum code size
+			pos++
+		default:
+			// the trailer, or something that isn't a GIF
+			return frames
+		}
+		if !skipSubBlocks() {
+			return frames
+		}
+	}
+	return frames
+}
+
+// fills in the size, the placeholder and the thumbnail
+func finish(res Image, img image.Image, contentType string) (Image, error) {
+	res.Width = img.Bounds().Dx()
+	res.Height = img.Bounds().Dy()
+	res.Blurhash = Blurhash(img, 4, 3)
+	thumbnail, err := encode(cover(img, ThumbnailSize, ThumbnailSize), VariantType(contentType))
+	if err != nil {
+		return Image{}, err
+	}
+	res.Thumbnail = thumbnail
+	return res, nil
+}
+
+// Resize makes a copy of an already processed image width pixels wide, in VariantType(contentType)
+// images are only ever scaled down, asking for something wider than the original gets the original's size
+func Resize(data []byte, contentType string, width int) ([]byte, error) {
+	if !Resizable(contentType) {
+		return nil, ErrUnsupported
+	}
+	img, err := decode(data, contentType)
+	if err != nil {
+		return nil, err
+	}
+	bounds := img.Bounds()
+	if width < bounds.Dx() {
+		height := max(1, (bounds.Dy()*width+bounds.Dx()/2)/bounds.Dx())
+		img = scale(img, width, height)
+	}
+	return encode(img, VariantType(contentType))
+}
+
+// decodes a JPEG or PNG, checking its size before doing the expensive part
+func decode(data []byte, contentType string) (image.Image, error) {
+	var config image.Config
+	var err error
+	if contentType == "image/jpeg" {
+		config, err = jpeg.DecodeConfig(bytes.NewReader(data))
+	} else {
+		config, err = png.DecodeConfig(bytes.NewReader(data))
+	}
+	if err != nil {
+		return nil, err
+	}
+	if config.Width*config.Height > MaxPixels {
+		return nil, ErrTooLarge
+	}
+	if contentType == "image/jpeg" {
+		return jpeg.Decode(bytes.NewReader(data))
+	}
+	return png.Decode(bytes.NewReader(data))
+}
+
+func encode(img image.Image, contentType string) ([]byte, error) {
+	var buf bytes.Buffer
+	var err error
+	if contentType == "image/jpeg" {
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality})
+	} else {
+		err = png.Encode(&buf, img)
+	}
+	return buf.Bytes(), err
+}
@@ -0,0 +1,212 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+// a 40x20 image, red on the left and blue on the right
+func testImage() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 40, 20))
+	for y := 0; y < 20; y++ {
+		for x := 0; x < 40; x++ {
+			if x < 20 {
+				img.Set(x, y, color.RGBA{255, 0, 0, 255})
+			} else {
+				img.Set(x, y, color.RGBA{0, 0, 255, 255})
+			}
+		}
+	}
+	return img
+}
+
+// an APP1 segment with a big-endian EXIF block holding an orientation and a fake GPS note
+func exifSegment(orientation uint16) []byte {
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08")
+	tiff = binary.BigEndian.AppendUint16(tiff, 1)
+	tiff = binary.BigEndian.AppendUint16(tiff, 0x0112)
+	tiff = binary.BigEndian.AppendUint16(tiff, 3)
+	tiff = binary.BigEndian.AppendUint32(tiff, 1)
+	tiff = binary.BigEndian.AppendUint16(tiff, orientation)
+	tiff = append(tiff, 0, 0, 0, 0, 0, 0)
+	tiff = append(tiff, "GPS 51.5N 0.1W"...)
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	segment := []byte{0xFF, 0xE1}
+	segment = binary.BigEndian.AppendUint16(segment, uint16(len(payload)+2))
+	return append(segment, payload...)
+}
+
+func TestProcessJPEG(t *testing.T) {
+	var buf bytes.Buffer
+	jpeg.Encode(&buf, testImage(), nil)
+	// slip the EXIF in right after the start of image marker, where cameras put it
+	data := append([]byte{0xFF, 0xD8}, exifSegment(6)...)
+	data = append(data, buf.Bytes()[2:]...)
+	if jpegOrientation(data) != 6 {
+		t.Fatalf("jpegOrientation returned %d, expected 6", jpegOrientation(data))
+	}
+
+	res, err := Process(data, "image/jpeg")
+	if err != nil {
+		t.Fatalf("Error in Process: %v", err)
+	}
+	if bytes.Contains(res.Data, []byte("Exif")) || bytes.Contains(res.Data, []byte("GPS")) {
+		t.Errorf("Process left the EXIF in")
+	}
+	// orientation 6 is a quarter turn clockwise, so it comes out tall, red on top
+	if res.Width != 20 || res.Height != 40 {
+		t.Errorf("Process returned a %dx%d image, expected 20x40", res.Width, res.Height)
+	}
+	img, _ := jpeg.Decode(bytes.NewReader(res.Data))
+	r, _, b, _ := img.At(10, 5).RGBA()
+	if r < b {
+		t.Errorf("The top of the image should be red after turning it")
+	}
+	thumb, err := jpeg.Decode(bytes.NewReader(res.Thumbnail))
+	if err != nil || thumb.Bounds().Dx() != ThumbnailSize || thumb.Bounds().Dy() != ThumbnailSize {
+		t.Errorf("Expected a %dpx square JPEG thumbnail, got %v, %v", ThumbnailSize, thumb, err)
+	}
+	if len(res.Blurhash) != 28 {
+		t.Errorf("Expected a 28 character blurhash, got %q", res.Blurhash)
+	}
+}
+
+func TestOrient(t *testing.T) {
+	red := color.RGBA{255, 0, 0, 255}
+	blue := color.RGBA{0, 0, 255, 255}
+	img := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	img.Set(0, 0, red)
+	img.Set(1, 0, blue)
+	cases := []struct{
+		orientation int
+		expected []color.RGBA
+		tall bool
+	}{
+		{orientation: 1, expected: []color.RGBA{red, blue}},
+		{orientation: 2, expected: []color.RGBA{blue, red}},
+		{orientation: 3, expected: []color.RGBA{blue, red}},
+		{orientation: 4, expected: []color.RGBA{red, blue}},
+		{orientation: 5, expected: []color.RGBA{red, blue}, tall: true},
+		{orientation: 6, expected: []color.RGBA{red, blue}, tall: true},
+		{orientation: 7, expected: []color.RGBA{blue, red}, tall: true},
+		{orientation: 8, expected: []color.RGBA{blue, red}, tall: true},
+	}
+	for _, c := range cases {
+		out := orient(img, c.orientation)
+		second := image.Pt(1, 0)
+		if c.tall {
+			second = image.Pt(0, 1)
+		}
+		if out.At(0, 0) != c.expected[0] || out.At(second.X, second.Y) != c.expected[1] {
+			t.Errorf("orient(%d) put %v first and %v second", c.orientation, out.At(0, 0), out.At(second.X, second.Y))
+		}
+	}
+}
+
+func TestResize(t *testing.T) {
+	var buf bytes.Buffer
+	png.Encode(&buf, testImage())
+	smaller, err := Resize(buf.Bytes(), "image/png", 10)
+	if err != nil {
+		t.Fatalf("Error in Resize: %v", err)
+	}
+	img, _ := png.Decode(bytes.NewReader(smaller))
+	if img.Bounds().Dx() != 10 || img.Bounds().Dy() != 5 {
+		t.Errorf("Resize to 10 returned %v, expected 10x5", img.Bounds())
+	}
+	// never scaled up
+	same, _ := Resize(buf.Bytes(), "image/png", 400)
+	img, _ = png.Decode(bytes.NewReader(same))
+	if img.Bounds().Dx() != 40 {
+		t.Errorf("Resize to 400 returned %v, expected the original 40x20", img.Bounds())
+	}
+	_, err = Resize(buf.Bytes(), "image/gif", 10)
+	if err != ErrUnsupported {
+		t.Errorf("Expected ErrUnsupported for a GIF, got: %v", err)
+	}
+}
+
+func TestProcessTooLarge(t *testing.T) {
+	// just the header of a PNG claiming to be 10000x10000
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewGray(image.Rect(0, 0, 1, 1)))
+	data := buf.Bytes()
+	binary.BigEndian.PutUint32(data[16:], 10000)
+	binary.BigEndian.PutUint32(data[20:], 10000)
+	binary.BigEndian.PutUint32(data[29:], crc32.ChecksumIEEE(data[12:29]))
+	_, err := Process(data, "image/png")
+	if err != ErrTooLarge {
+		t.Errorf("Expected ErrTooLarge, got: %v", err)
+	}
+}
+
+func TestProcessGIFTooManyFrames(t *testing.T) {
+	// tiny frames, but every one of them is decoded at the size of the whole picture
+	g := &gif.GIF{Config: image.Config{Width: 1000, Height: 1000, ColorModel: color.Palette{color.Black, color.White}}}
+	for i := 0; i < MaxPixels/1_000_000+1; i++ {
+		g.Image = append(g.Image, image.NewPaletted(image.Rect(0, 0, 1, 1), color.Palette{color.Black, color.White}))
+		g.Delay = append(g.Delay, 10)
+	}
+	var buf bytes.Buffer
+	err := gif.EncodeAll(&buf, g)
+	if err != nil {
+		t.Fatalf("Error in EncodeAll: %v", err)
+	}
+	if n := countGIFFrames(buf.Bytes(), 1000); n != len(g.Image) {
+		t.Errorf("countGIFFrames returned %d, expected %d", n, len(g.Image))
+	}
+	_, err = Process(buf.Bytes(), "image/gif")
+	if err != ErrTooLarge {
+		t.Errorf("Expected ErrTooLarge, got: %v", err)
+	}
+}
+
+func chunk(fourCC string, payload []byte) []byte {
+	out := []byte(fourCC)
+	out = binary.LittleEndian.AppendUint32(out, uint32(len(payload)))
+	out = append(out, payload...)
+	if len(payload)%2 == 1 {
+		out = append(out, 0)
+	}
+	return out
+}
+
+func TestStripWebPMetadata(t *testing.T) {
+	body := []byte("WEBP")
+	body = append(body, chunk("VP8X", []byte{webpFlagEXIF | webpFlagXMP, 0, 0, 0, 0, 0, 0, 0, 0, 0})...)
+	body = append(body, chunk("VP8 ", []byte{1, 2, 3})...)
+	body = append(body, chunk("EXIF", []byte("GPS 51.5N 0.1W"))...)
+	body = append(body, chunk("XMP ", []byte("<x:xmpmeta/>"))...)
+	data := binary.LittleEndian.AppendUint32([]byte("RIFF"), uint32(len(body)))
+	data = append(data, body...)
+
+	stripped, err := StripWebPMetadata(data)
+	if err != nil {
+		t.Fatalf("Error in StripWebPMetadata: %v", err)
+	}
+	if strings.Contains(string(stripped), "GPS") || strings.Contains(string(stripped), "xmpmeta") {
+		t.Errorf("StripWebPMetadata left the metadata in")
+	}
+	if int(binary.LittleEndian.Uint32(stripped[4:])) != len(stripped)-8 {
+		t.Errorf("The RIFF size wasn't updated")
+	}
+	if stripped[20] != 0 {
+		t.Errorf("The VP8X metadata flags weren't cleared")
+	}
+	if !bytes.Contains(stripped, []byte{'V', 'P', '8', ' ', 3, 0, 0, 0, 1, 2, 3, 0}) {
+		t.Errorf("The image chunk should be left as it was")
+	}
+
+	_, err = StripWebPMetadata(data[:len(data)-3])
+	if err != ErrBadWebP {
+		t.Errorf("Expected ErrBadWebP for a cut off file, got: %v", err)
+	}
+}
@@ -0,0 +1,65 @@
+package imaging
+
+import (
+	"image"
+	"image/draw"
+)
+
+// scales img to w by h, averaging every source pixel that lands in each destination pixel
+// (a box filter, which is plenty for shrinking photos and needs nothing outside the standard library)
+// the averaging is done on premultiplied colours so transparent pixels don't bleed into their neighbours
+func scale(img image.Image, w, h int) *image.RGBA {
+	src := toRGBA(img)
+	sw, sh := src.Bounds().Dx(), src.Bounds().Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	for dy := 0; dy < h; dy++ {
+		y0 := dy * sh / h
+		y1 := max(y0+1, (dy+1)*sh/h)
+		for dx := 0; dx < w; dx++ {
+			x0 := dx * sw / w
+			x1 := max(x0+1, (dx+1)*sw/w)
+			var r, g, b, a, n uint64
+			for y := y0; y < y1; y++ {
+				row := src.Pix[y*src.Stride:]
+				for x := x0; x < x1; x++ {
+					p := row[x*4 : x*4+4]
+					r += uint64(p[0])
+					g += uint64(p[1])
+					b += uint64(p[2])
+					a += uint64(p[3])
+					n++
+				}
+			}
+			i := dy*dst.Stride + dx*4
+			dst.Pix[i] = uint8(r / n)
+			dst.Pix[i+1] = uint8(g / n)
+			dst.Pix[i+2] = uint8(b / n)
+			dst.Pix[i+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
+
+// crops img to w:h from the middle and scales it to w by h, like CSS's object-fit: cover
+func cover(img image.Image, w, h int) *image.RGBA {
+	bounds := img.Bounds()
+	cw, ch := bounds.Dx(), bounds.Dx()*h/w
+	if ch > bounds.Dy() {
+		cw, ch = bounds.Dy()*w/h, bounds.Dy()
+	}
+	x0 := bounds.Min.X + (bounds.Dx()-cw)/2
+	y0 := bounds.Min.Y + (bounds.Dy()-ch)/2
+	cropped := image.NewRGBA(image.Rect(0, 0, max(1, cw), max(1, ch)))
+	draw.Draw(cropped, cropped.Bounds(), img, image.Pt(x0, y0), draw.Src)
+	return scale(cropped, w, h)
+}
+
+// a copy of img as an *image.RGBA starting at (0, 0), unless it already is one
+func toRGBA(img image.Image) *image.RGBA {
+	if rgba, ok := img.(*image.RGBA); ok && rgba.Bounds().Min == (image.Point{}) {
+		return rgba
+	}
+	rgba := image.NewRGBA(image.Rect(0, 0, img.Bounds().Dx(), img.Bounds().Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, img.Bounds().Min, draw.Src)
+	return rgba
+}
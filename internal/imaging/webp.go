@@ -0,0 +1,51 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+)
+
+var ErrBadWebP = errors.New("malformed WebP file")
+
+// VP8X flags for the metadata chunks
+const webpFlagEXIF = 0x08
+const webpFlagXMP = 0x04
+
+// StripWebPMetadata cuts the EXIF and XMP chunks out of a WebP file, leaving the image itself alone
+// (a WebP file is a RIFF container, so this is just walking the chunks and skipping those two)
+func StripWebPMetadata(data []byte) ([]byte, error) {
+	if len(data) < 12 || string(data[:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return nil, ErrBadWebP
+	}
+	var out bytes.Buffer
+	out.Write(data[:12])
+	i := 12
+	for i < len(data) {
+		if i+8 > len(data) {
+			return nil, ErrBadWebP
+		}
+		fourCC := string(data[i : i+4])
+		size := int(binary.LittleEndian.Uint32(data[i+4:]))
+		// chunks are padded out to an even length
+		end := i + 8 + size + size%2
+		if size < 0 || end > len(data) {
+			return nil, ErrBadWebP
+		}
+		switch fourCC {
+		case "EXIF", "XMP ":
+		case "VP8X":
+			chunk := bytes.Clone(data[i:end])
+			if size > 0 {
+				chunk[8] &^= webpFlagEXIF | webpFlagXMP
+			}
+			out.Write(chunk)
+		default:
+			out.Write(data[i:end])
+		}
+		i = end
+	}
+	stripped := out.Bytes()
+	binary.LittleEndian.PutUint32(stripped[4:], uint32(len(stripped)-8))
+	return stripped, nil
+}
@@ -0,0 +1,3 @@
+module mentions
+
+go 1.24.1
@@ -0,0 +1,65 @@
+package mentions
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// handles are short and plain so they're easy to type after an @
+const MaxLength = 30
+
+func isHandleByte(b byte) bool {
+	return (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z') || (b >= '0' && b <= '9') || b == '_'
+}
+
+// handles are ASCII, but an @ in the middle of a word in any language still isn't a mention
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsNumber(r) || r == '_'
+}
+
+// Normalize turns a handle (with or without its @) into the form it's stored in
+// handles are case-insensitive, so they're kept lowercase
+// it returns false if it isn't a valid handle: 1 to 30 letters, numbers and underscores
+func Normalize(handle string) (string, bool) {
+	handle = strings.TrimPrefix(handle, "@")
+	if handle == "" || len(handle) > MaxLength {
+		return "", false
+	}
+	for i := 0; i < len(handle); i++ {
+		if !isHandleByte(handle[i]) {
+			return "", false
+		}
+	}
+	return strings.ToLower(handle), true
+}
+
+// Extract finds the @handles in a chirp, normalized and without duplicates, in the order they first appear
+// an @ only starts a mention at the beginning of the chirp or after something that can't be part of a handle,
+// so email addresses like someone@example.com don't count
+// likewise it has to end at something that can't be part of one, so @café isn't @caf
+func Extract(body string) []string {
+	handles := []string{}
+	seen := map[string]bool{}
+	for i := 0; i < len(body); i++ {
+		if body[i] != '@' {
+			continue
+		}
+		if before, _ := utf8.DecodeLastRuneInString(body[:i]); i > 0 && (isWordRune(before) || before == '@') {
+			continue
+		}
+		end := i + 1
+		for end < len(body) && isHandleByte(body[end]) {
+			end++
+		}
+		after, _ := utf8.DecodeRuneInString(body[end:])
+		// and @someone_with_a_really_long_name_here isn't @someone_with_a_really_long_n
+		handle, ok := Normalize(body[i+1:end])
+		if ok && !isWordRune(after) && !seen[handle] {
+			seen[handle] = true
+			handles = append(handles, handle)
+		}
+		i = end - 1
+	}
+	return handles
+}
@@ -0,0 +1,64 @@
+package mentions
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestExtract(t *testing.T) {
+	cases := []struct{
+		input string
+		expected []string
+	}{
+		{
+			input: "no mentions here",
+			expected: []string{},
+		},
+		{
+			input: "@Alice and @bob_2, meet @ALICE",
+			expected: []string{"alice", "bob_2"},
+		},
+		{
+			input: "email me at someone@example.com",
+			expected: []string{},
+		},
+		{
+			input: "(@wrapped) @end. @@double @",
+			expected: []string{"wrapped", "end"},
+		},
+		{
+			input: "@this_handle_is_much_too_long_to_be_real",
+			expected: []string{},
+		},
+		{
+			input: "café@nope @café @bob",
+			expected: []string{"bob"},
+		},
+	}
+	for _, c := range cases {
+		output := Extract(c.input)
+		if !slices.Equal(output, c.expected) {
+			t.Errorf("Input: %v\nExpected: %v\nOutput: %v", c.input, c.expected, output)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	cases := []struct{
+		input string
+		expected string
+		ok bool
+	}{
+		{input: "@Chirpy", expected: "chirpy", ok: true},
+		{input: "chirpy_fan_99", expected: "chirpy_fan_99", ok: true},
+		{input: "@", ok: false},
+		{input: "two words", ok: false},
+		{input: "dots.not.allowed", ok: false},
+	}
+	for _, c := range cases {
+		output, ok := Normalize(c.input)
+		if output != c.expected || ok != c.ok {
+			t.Errorf("Input: %v\nExpected: %v %v\nOutput: %v %v", c.input, c.expected, c.ok, output, ok)
+		}
+	}
+}
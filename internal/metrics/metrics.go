@@ -0,0 +1,209 @@
+package metrics
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// the kinds of metric, named the way the Prometheus text format names them
+type Type string
+
+const (
+	CounterType Type = "counter"
+	GaugeType Type = "gauge"
+	HistogramType Type = "histogram"
+)
+
+// buckets for timing requests, in seconds (the same ones Prometheus' client libraries default to)
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// A Registry holds metrics so they can all be written out together
+// metrics are registered up front and then updated from anywhere, so everything on them is safe to use concurrently
+type Registry struct {
+	mu sync.Mutex
+	families []*family
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// a metric and all its series, one for each combination of label values
+type family struct {
+	name string
+	help string
+	typ Type
+	labels []string
+	buckets []float64
+	// for metrics that are worked out when they're read, like the database pool's
+	fn func() float64
+
+	mu sync.Mutex
+	series map[string]*series
+}
+
+type series struct {
+	labelValues []string
+	// the count for counters, or the value for gauges
+	value float64
+	// for histograms, how many observations fell in each bucket (not cumulative), along with their sum and count
+	bucketCounts []uint64
+	sum float64
+	count uint64
+}
+
+func (r *Registry) register(f *family) *family {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, existing := range r.families {
+		if existing.name == f.name {
+			panic(fmt.Sprintf("metrics: %s registered twice", f.name))
+		}
+	}
+	f.clear()
+	r.families = append(r.families, f)
+	return f
+}
+
+// throws away every series
+// a metric without labels has just the one, which is always there (at 0 to start with)
+// f.mu has to be held, unless f isn't registered yet
+func (f *family) clear() {
+	f.series = map[string]*series{}
+	if len(f.labels) == 0 && f.fn == nil {
+		f.get(nil)
+	}
+}
+
+// the series for some label values, which is made the first time it's used
+func (f *family) get(labelValues []string) *series {
+	if len(labelValues) != len(f.labels) {
+		panic(fmt.Sprintf("metrics: %s takes %d label values, got %d", f.name, len(f.labels), len(labelValues)))
+	}
+	key := strings.Join(labelValues, "\xff")
+	s, ok := f.series[key]
+	if !ok {
+		s = &series{labelValues: slices.Clone(labelValues), bucketCounts: make([]uint64, len(f.buckets))}
+		f.series[key] = s
+	}
+	return s
+}
+
+// A Counter only goes up
+type Counter struct {
+	f *family
+}
+
+// Counter registers a counter, with a series for every combination of values for labels
+func (r *Registry) Counter(name string, help string, labels ...string) *Counter {
+	return &Counter{f: r.register(&family{name: name, help: help, typ: CounterType, labels: labels})}
+}
+
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add panics if v is negative, since counters can't go down
+func (c *Counter) Add(v float64, labelValues ...string) {
+	if v < 0 {
+		panic(fmt.Sprintf("metrics: %s can't go down", c.f.name))
+	}
+	c.f.mu.Lock()
+	defer c.f.mu.Unlock()
+	c.f.get(labelValues).value += v
+}
+
+func (c *Counter) Value(labelValues ...string) float64 {
+	c.f.mu.Lock()
+	defer c.f.mu.Unlock()
+	return c.f.get(labelValues).value
+}
+
+// Reset starts every series over from 0
+// Prometheus takes that the same way as the server restarting
+func (c *Counter) Reset() {
+	c.f.mu.Lock()
+	defer c.f.mu.Unlock()
+	c.f.clear()
+}
+
+// A Gauge can go up and down
+type Gauge struct {
+	f *family
+}
+
+func (r *Registry) Gauge(name string, help string, labels ...string) *Gauge {
+	return &Gauge{f: r.register(&family{name: name, help: help, typ: GaugeType, labels: labels})}
+}
+
+func (g *Gauge) Set(v float64, labelValues ...string) {
+	g.f.mu.Lock()
+	defer g.f.mu.Unlock()
+	g.f.get(labelValues).value = v
+}
+
+func (g *Gauge) Add(v float64, labelValues ...string) {
+	g.f.mu.Lock()
+	defer g.f.mu.Unlock()
+	g.f.get(labelValues).value += v
+}
+
+func (g *Gauge) Inc(labelValues ...string) {
+	g.Add(1, labelValues...)
+}
+
+func (g *Gauge) Dec(labelValues ...string) {
+	g.Add(-1, labelValues...)
+}
+
+func (g *Gauge) Value(labelValues ...string) float64 {
+	g.f.mu.Lock()
+	defer g.f.mu.Unlock()
+	return g.f.get(labelValues).value
+}
+
+// GaugeFunc registers a gauge that's worked out by calling fn whenever it's read
+func (r *Registry) GaugeFunc(name string, help string, fn func() float64) {
+	r.register(&family{name: name, help: help, typ: GaugeType, fn: fn})
+}
+
+// CounterFunc is the same for a counter, for things that are already counted somewhere else
+func (r *Registry) CounterFunc(name string, help string, fn func() float64) {
+	r.register(&family{name: name, help: help, typ: CounterType, fn: fn})
+}
+
+// A Histogram counts observations (usually how long something took) in buckets
+type Histogram struct {
+	f *family
+}
+
+// Histogram registers a histogram with buckets, which are their upper bounds in increasing order
+// (there's always a +Inf bucket on the end, so it doesn't need to be in there)
+func (r *Registry) Histogram(name string, help string, buckets []float64, labels ...string) *Histogram {
+	if !slices.IsSorted(buckets) {
+		panic(fmt.Sprintf("metrics: %s's buckets are out of order", name))
+	}
+	return &Histogram{f: r.register(&family{name: name, help: help, typ: HistogramType, labels: labels, buckets: buckets})}
+}
+
+func (h *Histogram) Observe(v float64, labelValues ...string) {
+	h.f.mu.Lock()
+	defer h.f.mu.Unlock()
+	s := h.f.get(labelValues)
+	i, _ := slices.BinarySearch(h.f.buckets, v)
+	if i < len(s.bucketCounts) {
+		s.bucketCounts[i]++
+	}
+	s.sum += v
+	s.count++
+}
+
+// how many observations there have been, and what they add up to
+func (h *Histogram) Count(labelValues ...string) (uint64, float64) {
+	h.f.mu.Lock()
+	defer h.f.mu.Unlock()
+	s := h.f.get(labelValues)
+	return s.count, s.sum
+}
@@ -0,0 +1,108 @@
+package metrics
+
+import (
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestWriteText(t *testing.T) {
+	r := NewRegistry()
+	requests := r.Counter("requests_total", "Requests served.", "route", "code")
+	inFlight := r.Gauge("in_flight", "Requests being served.")
+	latency := r.Histogram("latency_seconds", "How long requests took.", []float64{0.1, 1}, "route")
+	r.GaugeFunc("connections", "Open connections.", func() float64 { return 3 })
+	r.Counter("errors_total", "Errors.")
+
+	requests.Inc("GET /api/chirps", "200")
+	requests.Add(2, "GET /api/chirps", "200")
+	requests.Inc(`POST "weird"`+"\n", "500")
+	inFlight.Inc()
+	inFlight.Inc()
+	inFlight.Dec()
+	latency.Observe(0.05, "GET /api/chirps")
+	latency.Observe(0.1, "GET /api/chirps")
+	latency.Observe(0.5, "GET /api/chirps")
+	latency.Observe(3, "GET /api/chirps")
+
+	out := strings.Builder{}
+	err := r.WriteText(&out)
+	if err != nil {
+		t.Fatalf("Error in WriteText: %v", err)
+	}
+	expected := `# HELP requests_total Requests served.
+# TYPE requests_total counter
+requests_total{route="GET /api/chirps",code="200"} 3
+requests_total{route="POST \"weird\"\n",code="500"} 1
+# HELP in_flight Requests being served.
+# TYPE in_flight gauge
+in_flight 1
+# HELP latency_seconds How long requests took.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{route="GET /api/chirps",le="0.1"} 2
+latency_seconds_bucket{route="GET /api/chirps",le="1"} 3
+latency_seconds_bucket{route="GET /api/chirps",le="+Inf"} 4
+latency_seconds_sum{route="GET /api/chirps"} 3.65
+latency_seconds_count{route="GET /api/chirps"} 4
+# HELP connections Open connections.
+# TYPE connections gauge
+connections 3
+# HELP errors_total Errors.
+# TYPE errors_total counter
+errors_total 0
+`
+	if out.String() != expected {
+		t.Errorf("WriteText returned:\n%s\nexpected:\n%s", out.String(), expected)
+	}
+}
+
+func TestCounterReset(t *testing.T) {
+	r := NewRegistry()
+	hits := r.Counter("hits_total", "Hits.")
+	hits.Inc()
+	hits.Reset()
+	if hits.Value() != 0 {
+		t.Errorf("Expected 0 after Reset, got %v", hits.Value())
+	}
+	hits.Inc()
+	if hits.Value() != 1 {
+		t.Errorf("Expected 1, got %v", hits.Value())
+	}
+}
+
+func TestConcurrentUpdates(t *testing.T) {
+	r := NewRegistry()
+	requests := r.Counter("requests_total", "Requests served.", "code")
+	latency := r.Histogram("latency_seconds", "How long requests took.", DefaultBuckets)
+	wg := sync.WaitGroup{}
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				requests.Inc("200")
+				latency.Observe(0.2)
+				r.Gather()
+			}
+		}()
+	}
+	wg.Wait()
+	if requests.Value("200") != 5000 {
+		t.Errorf("Expected 5000 requests, got %v", requests.Value("200"))
+	}
+	count, _ := latency.Count()
+	if count != 5000 {
+		t.Errorf("Expected 5000 observations, got %d", count)
+	}
+}
+
+func TestRegisterTwice(t *testing.T) {
+	r := NewRegistry()
+	r.Counter("requests_total", "Requests served.")
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Expected registering the same name twice to panic")
+		}
+	}()
+	r.Gauge("requests_total", "Requests served.")
+}
@@ -0,0 +1,133 @@
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// the content type for WriteText's output, version 0.0.4 of the Prometheus text format
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+type Label struct {
+	Name string
+	Value string
+}
+
+// one line of output: a histogram has several for each series (its buckets, sum and count), everything else has one
+type Sample struct {
+	Name string
+	Labels []Label
+	Value float64
+}
+
+// a metric as it was when it was gathered
+type Family struct {
+	Name string
+	Help string
+	Type Type
+	Samples []Sample
+}
+
+// Gather reads every metric, in the order they were registered, with their series sorted by label values
+func (r *Registry) Gather() []Family {
+	r.mu.Lock()
+	families := slices.Clone(r.families)
+	r.mu.Unlock()
+	gathered := []Family{}
+	for _, f := range families {
+		gathered = append(gathered, f.gather())
+	}
+	return gathered
+}
+
+func (f *family) gather() Family {
+	out := Family{Name: f.name, Help: f.help, Type: f.typ, Samples: []Sample{}}
+	if f.fn != nil {
+		out.Samples = append(out.Samples, Sample{Name: f.name, Value: f.fn()})
+		return out
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	all := []*series{}
+	for _, s := range f.series {
+		all = append(all, s)
+	}
+	slices.SortFunc(all, func(a, b *series) int {
+		return slices.Compare(a.labelValues, b.labelValues)
+	})
+	for _, s := range all {
+		labels := []Label{}
+		for i, name := range f.labels {
+			labels = append(labels, Label{Name: name, Value: s.labelValues[i]})
+		}
+		if f.typ != HistogramType {
+			out.Samples = append(out.Samples, Sample{Name: f.name, Labels: labels, Value: s.value})
+			continue
+		}
+		// buckets in the output count everything up to their bound, so they add up as they go
+		cumulative := uint64(0)
+		for i, bound := range f.buckets {
+			cumulative += s.bucketCounts[i]
+			le := append(slices.Clone(labels), Label{Name: "le", Value: formatFloat(bound)})
+			out.Samples = append(out.Samples, Sample{Name: f.name + "_bucket", Labels: le, Value: float64(cumulative)})
+		}
+		le := append(slices.Clone(labels), Label{Name: "le", Value: "+Inf"})
+		out.Samples = append(out.Samples,
+			Sample{Name: f.name + "_bucket", Labels: le, Value: float64(s.count)},
+			Sample{Name: f.name + "_sum", Labels: labels, Value: s.sum},
+			Sample{Name: f.name + "_count", Labels: labels, Value: float64(s.count)},
+		)
+	}
+	return out
+}
+
+// WriteText writes every metric out in the Prometheus text format
+func (r *Registry) WriteText(w io.Writer) error {
+	buf := bufio.NewWriter(w)
+	for _, f := range r.Gather() {
+		buf.WriteString("# HELP " + f.Name + " " + escapeHelp(f.Help) + "\n")
+		buf.WriteString("# TYPE " + f.Name + " " + string(f.Type) + "\n")
+		for _, s := range f.Samples {
+			buf.WriteString(s.Name)
+			if len(s.Labels) > 0 {
+				buf.WriteString("{")
+				for i, l := range s.Labels {
+					if i > 0 {
+						buf.WriteString(",")
+					}
+					buf.WriteString(l.Name + `="` + escapeLabel(l.Value) + `"`)
+				}
+				buf.WriteString("}")
+			}
+			buf.WriteString(" " + formatFloat(s.Value) + "\n")
+		}
+	}
+	return buf.Flush()
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var helpEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+var labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+
+func escapeHelp(s string) string {
+	return helpEscaper.Replace(s)
+}
+
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
@@ -0,0 +1,54 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+)
+
+// the bits of sql that differ between databases
+type Dialect struct {
+	CreateTable string
+	InsertVersion string
+	Lock func(ctx context.Context, conn *sql.Conn) error
+	Unlock func(ctx context.Context, conn *sql.Conn) error
+}
+
+// an arbitrary key for pg_advisory_lock, shared by every replica
+const lockKey = 7236912875002154313
+
+var Postgres = Dialect{
+	CreateTable: `CREATE TABLE IF NOT EXISTS goose_db_version (
+    id SERIAL NOT NULL,
+    version_id BIGINT NOT NULL,
+    is_applied BOOLEAN NOT NULL,
+    tstamp TIMESTAMP NULL DEFAULT NOW(),
+    PRIMARY KEY(id)
+)`,
+	InsertVersion: "INSERT INTO goose_db_version (version_id, is_applied) VALUES ($1, $2)",
+	// advisory locks belong to the session, which is why everything runs on one *sql.Conn
+	Lock: func(ctx context.Context, conn *sql.Conn) error {
+		_, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", int64(lockKey))
+		return err
+	},
+	Unlock: func(ctx context.Context, conn *sql.Conn) error {
+		_, err := conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", int64(lockKey))
+		return err
+	},
+}
+
+// SQLite only ever lets one writer in at a time, so it doesn't need a lock of its own
+var SQLite = Dialect{
+	CreateTable: `CREATE TABLE IF NOT EXISTS goose_db_version (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    version_id INTEGER NOT NULL,
+    is_applied INTEGER NOT NULL,
+    tstamp TIMESTAMP DEFAULT (datetime('now'))
+)`,
+	InsertVersion: "INSERT INTO goose_db_version (version_id, is_applied) VALUES (?, ?)",
+	Lock: func(ctx context.Context, conn *sql.Conn) error {
+		return nil
+	},
+	Unlock: func(ctx context.Context, conn *sql.Conn) error {
+		return nil
+	},
+}
@@ -0,0 +1,304 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// a single numbered migration, split into its goose Up and Down halves
+type Migration struct {
+	Version int64
+	Name string
+	Up []string
+	Down []string
+}
+
+// where a migration stands in a particular database
+type Status struct {
+	Migration Migration
+	Applied bool
+	AppliedAt time.Time
+}
+
+// runs the migrations in a directory of goose-annotated sql files
+// versions are tracked in goose's own goose_db_version table,
+// so databases that were migrated by hand with goose are picked up as they are
+type Migrator struct {
+	db *sql.DB
+	dialect Dialect
+	migrations []Migration
+}
+
+// load the migrations in fsys and get ready to run them against db
+func New(db *sql.DB, dialect Dialect, fsys fs.FS) (*Migrator, error) {
+	migrations, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, dialect: dialect, migrations: migrations}, nil
+}
+
+// read every NNN_name.sql file at the top of fsys, in version order
+func Load(fsys fs.FS) ([]Migration, error) {
+	files, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+	migrations := []Migration{}
+	seen := map[int64]string{}
+	for _, file := range files {
+		prefix, _, found := strings.Cut(path.Base(file), "_")
+		if !found {
+			return nil, fmt.Errorf("Migration %v isn't named like NNN_name.sql", file)
+		}
+		version, err := strconv.ParseInt(prefix, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("Migration %v doesn't start with a version number", file)
+		}
+		if other, ok := seen[version]; ok {
+			return nil, fmt.Errorf("Migrations %v and %v have the same version", other, file)
+		}
+		seen[version] = file
+		contents, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+		up, down, err := parse(string(contents))
+		if err != nil {
+			return nil, fmt.Errorf("Error parsing %v: %v", file, err)
+		}
+		migrations = append(migrations, Migration{Version: version, Name: file, Up: up, Down: down})
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// split a migration file into its Up and Down statements
+// statements end with a semicolon at the end of a line, unless they're wrapped in
+// -- +goose StatementBegin / StatementEnd (for functions and the like)
+func parse(contents string) ([]string, []string, error) {
+	var up, down []string
+	var current *[]string
+	var statement strings.Builder
+	inBlock := false
+	for _, line := range strings.Split(contents, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "-- +goose") {
+			switch strings.TrimSpace(strings.TrimPrefix(trimmed, "-- +goose")) {
+			case "Up":
+				current = &up
+			case "Down":
+				current = &down
+			case "StatementBegin":
+				if current == nil {
+					return nil, nil, fmt.Errorf("StatementBegin before -- +goose Up or Down")
+				}
+				inBlock = true
+			case "StatementEnd":
+				if current == nil {
+					return nil, nil, fmt.Errorf("StatementEnd before -- +goose Up or Down")
+				}
+				inBlock = false
+				*current = append(*current, strings.TrimSpace(statement.String()))
+				statement.Reset()
+			}
+			continue
+		}
+		if current == nil || trimmed == "" || (!inBlock && strings.HasPrefix(trimmed, "--")) {
+			continue
+		}
+		statement.WriteString(line)
+		statement.WriteString("\n")
+		if !inBlock && strings.HasSuffix(trimmed, ";") {
+			*current = append(*current, strings.TrimSpace(statement.String()))
+			statement.Reset()
+		}
+	}
+	if current == nil {
+		return nil, nil, fmt.Errorf("no -- +goose Up annotation")
+	}
+	if inBlock {
+		return nil, nil, fmt.Errorf("StatementBegin without a StatementEnd")
+	}
+	// goose lets the last statement skip its semicolon
+	if rest := strings.TrimSpace(statement.String()); rest != "" {
+		*current = append(*current, rest)
+	}
+	return up, down, nil
+}
+
+// every migration this binary knows about
+func (m *Migrator) Migrations() []Migration {
+	return m.migrations
+}
+
+// applies everything that hasn't been applied yet, oldest first
+// the whole run happens under the dialect's lock, so replicas starting together take turns
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	applied := []Migration{}
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		done, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, mig := range m.migrations {
+			if _, ok := done[mig.Version]; ok {
+				continue
+			}
+			err = m.run(ctx, conn, mig.Version, mig.Up, true)
+			if err != nil {
+				return fmt.Errorf("Error applying %v: %v", mig.Name, err)
+			}
+			applied = append(applied, mig)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// rolls back the most recently applied migration
+// returns false if there was nothing to roll back
+func (m *Migrator) Down(ctx context.Context) (Migration, bool, error) {
+	var rolledBack Migration
+	found := false
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		done, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			mig := m.migrations[i]
+			if _, ok := done[mig.Version]; !ok {
+				continue
+			}
+			err = m.run(ctx, conn, mig.Version, mig.Down, false)
+			if err != nil {
+				return fmt.Errorf("Error rolling back %v: %v", mig.Name, err)
+			}
+			rolledBack = mig
+			found = true
+			return nil
+		}
+		return nil
+	})
+	return rolledBack, found, err
+}
+
+// where every migration stands
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	done, err := m.applied(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	statuses := []Status{}
+	for _, mig := range m.migrations {
+		at, ok := done[mig.Version]
+		statuses = append(statuses, Status{Migration: mig, Applied: ok, AppliedAt: at})
+	}
+	return statuses, nil
+}
+
+// the migrations that still need to be applied
+func (m *Migrator) Pending(ctx context.Context) ([]Migration, error) {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pending := []Migration{}
+	for _, s := range statuses {
+		if !s.Applied {
+			pending = append(pending, s.Migration)
+		}
+	}
+	return pending, nil
+}
+
+// runs fn on a single connection while holding the migration lock
+func (m *Migrator) locked(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	err = m.dialect.Lock(ctx, conn)
+	if err != nil {
+		return fmt.Errorf("Error taking the migration lock: %v", err)
+	}
+	defer m.dialect.Unlock(context.Background(), conn)
+	return fn(conn)
+}
+
+// which versions are currently applied, and when
+// goose never deletes rows, it adds an is_applied = false row when rolling back,
+// so the latest row for each version wins
+func (m *Migrator) applied(ctx context.Context, conn *sql.Conn) (map[int64]time.Time, error) {
+	_, err := conn.ExecContext(ctx, m.dialect.CreateTable)
+	if err != nil {
+		return nil, fmt.Errorf("Error creating goose_db_version: %v", err)
+	}
+	rows, err := conn.QueryContext(ctx, "SELECT version_id, is_applied, tstamp FROM goose_db_version ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	done := map[int64]time.Time{}
+	empty := true
+	for rows.Next() {
+		empty = false
+		var version int64
+		var isApplied bool
+		var tstamp sql.NullTime
+		err = rows.Scan(&version, &isApplied, &tstamp)
+		if err != nil {
+			return nil, err
+		}
+		if isApplied {
+			done[version] = tstamp.Time
+		} else {
+			delete(done, version)
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	// goose starts every fresh table off with a version 0 row
+	if empty {
+		_, err = conn.ExecContext(ctx, m.dialect.InsertVersion, 0, true)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return done, nil
+}
+
+// runs one half of a migration and records it, all in one transaction
+func (m *Migrator) run(ctx context.Context, conn *sql.Conn, version int64, statements []string, isApplied bool) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, stmt := range statements {
+		_, err = tx.ExecContext(ctx, stmt)
+		if err != nil {
+			return err
+		}
+	}
+	_, err = tx.ExecContext(ctx, m.dialect.InsertVersion, version, isApplied)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,77 @@
+package migrate
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestParse(t *testing.T) {
+	contents := `-- +goose Up
+CREATE TABLE users (
+    id UUID PRIMARY KEY
+);
+-- a comment that isn't a statement
+ALTER TABLE users ADD COLUMN email TEXT;
+
+-- +goose StatementBegin
+CREATE FUNCTION touch() RETURNS trigger AS $$
+BEGIN
+    NEW.updated_at = NOW();
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose Down
+DROP TABLE users`
+	up, down, err := parse(contents)
+	if err != nil {
+		t.Fatalf("Error in parse: %v", err)
+	}
+	if len(up) != 3 {
+		t.Fatalf("Expected 3 up statements, got %d: %q", len(up), up)
+	}
+	if up[0] != "CREATE TABLE users (\n    id UUID PRIMARY KEY\n);" {
+		t.Errorf("First up statement is wrong: %q", up[0])
+	}
+	if up[1] != "ALTER TABLE users ADD COLUMN email TEXT;" {
+		t.Errorf("Second up statement is wrong: %q", up[1])
+	}
+	if len(down) != 1 || down[0] != "DROP TABLE users" {
+		t.Errorf("Down statements are wrong: %q", down)
+	}
+
+	_, _, err = parse("CREATE TABLE nope (id INT);")
+	if err == nil {
+		t.Errorf("A file without -- +goose Up should fail")
+	}
+
+	_, _, err = parse("-- +goose StatementEnd\n-- +goose Up\nCREATE TABLE nope (id INT);")
+	if err == nil {
+		t.Errorf("A StatementEnd before -- +goose Up should fail")
+	}
+}
+
+func TestLoad(t *testing.T) {
+	fsys := fstest.MapFS{
+		"002_chirps.sql": {Data: []byte("-- +goose Up\nCREATE TABLE chirps (id INT);\n-- +goose Down\nDROP TABLE chirps;")},
+		"001_users.sql": {Data: []byte("-- +goose Up\nCREATE TABLE users (id INT);\n-- +goose Down\nDROP TABLE users;")},
+		"README.md": {Data: []byte("not a migration")},
+	}
+	migrations, err := Load(fsys)
+	if err != nil {
+		t.Fatalf("Error in Load: %v", err)
+	}
+	if len(migrations) != 2 {
+		t.Fatalf("Expected 2 migrations, got %d", len(migrations))
+	}
+	if migrations[0].Version != 1 || migrations[1].Version != 2 {
+		t.Errorf("Migrations are out of order: %v, %v", migrations[0].Name, migrations[1].Name)
+	}
+
+	fsys["001_duplicate.sql"] = &fstest.MapFile{Data: []byte("-- +goose Up\nSELECT 1;")}
+	_, err = Load(fsys)
+	if err == nil {
+		t.Errorf("Two migrations with the same version should fail")
+	}
+}
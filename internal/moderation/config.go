@@ -0,0 +1,128 @@
+package moderation
+
+import (
+	"encoding/json"
+	"fmt"
+	"internal/contentfilter"
+	"os"
+	"regexp"
+	"time"
+)
+
+// how long a chirp can be when the length stage doesn't say
+const DefaultMaxLength = 140
+
+// Default is the pipeline used without a config file: the length limit, then the content filter
+func Default(filter *contentfilter.Filter) *Pipeline {
+	return New(Length{Max: DefaultMaxLength}, Profanity{Filter: filter})
+}
+
+// the config for a stage, with the fields for every type of stage
+type stageConfig struct {
+	Type string `json:"type"`
+	// length
+	Max int `json:"max"`
+	// profanity
+	HoldFlagged bool `json:"hold_flagged"`
+	// links and spam
+	Blocked []string `json:"blocked"`
+	Action Decision `json:"action"`
+	MaxLinks int `json:"max_links"`
+	MaxMentions int `json:"max_mentions"`
+	MaxHashtags int `json:"max_hashtags"`
+	NewAccountAge string `json:"new_account_age"`
+	// rules
+	Rules []ruleConfig `json:"rules"`
+}
+
+type ruleConfig struct {
+	Pattern string `json:"pattern"`
+	Action Decision `json:"action"`
+	Replace string `json:"replace"`
+	Reason string `json:"reason"`
+}
+
+// LoadFile reads a pipeline from a JSON file listing its stages in order, like
+// {"stages": [{"type": "length", "max": 140}, {"type": "profanity"}, {"type": "links", "blocked": ["example.com"]}]}
+// the profanity stage checks chirps against filter
+func LoadFile(path string, filter *contentfilter.Filter) (*Pipeline, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	file := struct {
+		Stages []stageConfig `json:"stages"`
+	}{}
+	err = json.Unmarshal(data, &file)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	stages := []Stage{}
+	for i, c := range file.Stages {
+		stage, err := c.stage(filter)
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: stage %d: %w", path, i+1, err)
+		}
+		stages = append(stages, stage)
+	}
+	return New(stages...), nil
+}
+
+func (c stageConfig) stage(filter *contentfilter.Filter) (Stage, error) {
+	switch c.Type {
+	case "length":
+		if c.Max <= 0 {
+			c.Max = DefaultMaxLength
+		}
+		return Length{Max: c.Max}, nil
+	case "profanity":
+		return Profanity{Filter: filter, HoldFlagged: c.HoldFlagged}, nil
+	case "links":
+		action, err := blockAction(c.Action)
+		if err != nil {
+			return nil, err
+		}
+		return Links{Blocked: c.Blocked, Action: action}, nil
+	case "spam":
+		action, err := blockAction(c.Action)
+		if err != nil {
+			return nil, err
+		}
+		s := Spam{MaxLinks: c.MaxLinks, MaxMentions: c.MaxMentions, MaxHashtags: c.MaxHashtags, Action: action}
+		if c.NewAccountAge != "" {
+			s.NewAccountAge, err = time.ParseDuration(c.NewAccountAge)
+			if err != nil {
+				return nil, fmt.Errorf("invalid new_account_age %q", c.NewAccountAge)
+			}
+		}
+		return s, nil
+	case "rules":
+		rules := Rules{}
+		for j, r := range c.Rules {
+			pattern, err := regexp.Compile(r.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("rule %d: %w", j+1, err)
+			}
+			if r.Action != Modify && r.Action != Reject && r.Action != Hold {
+				return nil, fmt.Errorf("rule %d: invalid action %q (should be modify, reject or hold)", j+1, r.Action)
+			}
+			if r.Reason == "" {
+				r.Reason = "Chirp broke a rule"
+			}
+			rules = append(rules, Rule{Pattern: pattern, Action: r.Action, Replace: r.Replace, Reason: r.Reason})
+		}
+		return rules, nil
+	}
+	return nil, fmt.Errorf("unknown type %q (should be length, profanity, links, spam or rules)", c.Type)
+}
+
+// links and spam can reject or hold, and reject if they don't say
+func blockAction(action Decision) (Decision, error) {
+	if action == "" {
+		return Reject, nil
+	}
+	if action != Reject && action != Hold {
+		return "", fmt.Errorf("invalid action %q (should be reject or hold)", action)
+	}
+	return action, nil
+}
@@ -0,0 +1,15 @@
+module moderation
+
+go 1.24.1
+
+require internal/contentfilter v0.0.0
+
+require internal/hashtags v0.0.0
+
+require internal/mentions v0.0.0
+
+replace internal/contentfilter => ../contentfilter
+
+replace internal/hashtags => ../hashtags
+
+replace internal/mentions => ../mentions
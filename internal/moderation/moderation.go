@@ -0,0 +1,115 @@
+package moderation
+
+import (
+	"context"
+	"fmt"
+	"internal/contentfilter"
+	"time"
+)
+
+// what a stage (or the whole pipeline) decided to do with a chirp
+type Decision string
+
+const (
+	// the chirp goes up as is
+	Allow Decision = "allow"
+	// the chirp goes up, but with a different body
+	Modify Decision = "modify"
+	// the chirp is turned away
+	Reject Decision = "reject"
+	// the chirp waits in the review queue until a moderator approves it
+	Hold Decision = "hold"
+)
+
+func (d Decision) Valid() bool {
+	return d == Allow || d == Modify || d == Reject || d == Hold
+}
+
+// a chirp on its way in, and what the stages might want to know about who's posting it
+type Chirp struct {
+	Body string
+	AuthorCreatedAt time.Time
+}
+
+// what a single stage decided
+type Verdict struct {
+	Decision Decision
+	// the chirp's body after the stage
+	// it's only looked at for Modify and Hold, which have to set it even if they didn't change anything
+	Body string
+	// why it was modified, rejected or held, in a way that can be shown to whoever posted it
+	Reason string
+	// words from the content filter's flag lists, for a moderator to look at once it's up
+	Flags []contentfilter.Match
+}
+
+// a Stage is one step of the pipeline
+type Stage interface {
+	Name() string
+	Check(ctx context.Context, chirp Chirp) (Verdict, error)
+}
+
+// what the pipeline as a whole decided
+type Result struct {
+	// Allow if nothing changed the chirp, otherwise whatever the strictest stage said
+	Decision Decision
+	Body string
+	// the stage that rejected or held the chirp, and why
+	Stage string
+	Reason string
+	Flags []contentfilter.Match
+}
+
+// A Pipeline runs a chirp through its stages in order
+// each stage sees the body the stages before it left, so a stage that masks words
+// hands the masked body on to the next one
+// the first stage to reject a chirp ends it there; a held chirp still goes through the rest,
+// since a later stage could reject it outright
+type Pipeline struct {
+	stages []Stage
+}
+
+func New(stages ...Stage) *Pipeline {
+	return &Pipeline{stages: stages}
+}
+
+// the names of the stages, in order
+func (p *Pipeline) Stages() []string {
+	names := []string{}
+	for _, s := range p.stages {
+		names = append(names, s.Name())
+	}
+	return names
+}
+
+// Run decides what happens to chirp
+func (p *Pipeline) Run(ctx context.Context, chirp Chirp) (Result, error) {
+	original := chirp.Body
+	res := Result{Decision: Allow, Body: chirp.Body, Flags: []contentfilter.Match{}}
+	for _, s := range p.stages {
+		v, err := s.Check(ctx, chirp)
+		if err != nil {
+			return Result{}, fmt.Errorf("moderation stage %s: %w", s.Name(), err)
+		}
+		switch v.Decision {
+		case Allow:
+		case Reject:
+			return Result{Decision: Reject, Body: original, Stage: s.Name(), Reason: v.Reason, Flags: []contentfilter.Match{}}, nil
+		case Modify, Hold:
+			chirp.Body = v.Body
+			res.Body = v.Body
+			if v.Decision == Hold && res.Decision != Hold {
+				res.Decision = Hold
+				res.Stage = s.Name()
+				res.Reason = v.Reason
+			}
+		default:
+			return Result{}, fmt.Errorf("moderation stage %s: invalid decision %q", s.Name(), v.Decision)
+		}
+		res.Flags = append(res.Flags, v.Flags...)
+	}
+	if res.Decision == Allow && res.Body != original {
+		res.Decision = Modify
+	}
+	return res, nil
+}
@@ -0,0 +1,117 @@
+package moderation
+
+import (
+	"context"
+	"internal/contentfilter"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testFilter = contentfilter.New([]contentfilter.List{
+	{Name: "default", Action: contentfilter.Mask, Words: []string{"kerfuffle"}},
+	{Name: "banned", Action: contentfilter.Reject, Words: []string{"fornax"}},
+	{Name: "watch", Action: contentfilter.Flag, Words: []string{"scam"}},
+})
+
+func TestRun(t *testing.T) {
+	pipeline := New(
+		Length{Max: 140},
+		Profanity{Filter: testFilter},
+		Links{Blocked: []string{"example.com"}, Action: Reject},
+		Spam{MaxLinks: 2, MaxMentions: 3, NewAccountAge: 24 * time.Hour, Action: Hold},
+		Rules{
+			{Pattern: regexp.MustCompile(`(?i)buy now`), Action: Hold, Reason: "Sounds like an ad"},
+			{Pattern: regexp.MustCompile(`!{2,}`), Action: Modify, Replace: "!", Reason: "Calm down"},
+		},
+	)
+	old := time.Now().Add(-30 * 24 * time.Hour)
+	cases := []struct {
+		input string
+		new bool
+		decision Decision
+		body string
+		stage string
+		flags int
+	}{
+		{input: "hello world", decision: Allow, body: "hello world"},
+		{input: strings.Repeat("a", 141), decision: Reject, stage: "length"},
+		{input: "what a kerfuffle!!!", decision: Modify, body: "what a ****!"},
+		{input: "FORNAX", decision: Reject, stage: "profanity"},
+		{input: "this is a scam", decision: Allow, body: "this is a scam", flags: 1},
+		{input: "see https://www.Example.com/page", decision: Reject, stage: "links"},
+		{input: "see sub.example.com", decision: Reject, stage: "links"},
+		{input: "see notexample.com/page", decision: Allow, body: "see notexample.com/page"},
+		{input: "a.io/1 b.io/2 c.io/3", decision: Hold, body: "a.io/1 b.io/2 c.io/3", stage: "spam"},
+		{input: "@a @b @c @d", decision: Hold, body: "@a @b @c @d", stage: "spam"},
+		{input: "read file.txt", new: true, decision: Allow, body: "read file.txt"},
+		{input: "see https://a.io", new: true, decision: Hold, body: "see https://a.io", stage: "spam"},
+		// held chirps still get modified by the stages after the one that held them
+		{input: "Buy now kerfuffle!!", decision: Hold, body: "Buy now ****!", stage: "rules"},
+		// and can still be rejected by them
+		{input: "buy now at example.com", decision: Reject, stage: "links"},
+	}
+	for _, c := range cases {
+		created := old
+		if c.new {
+			created = time.Now()
+		}
+		res, err := pipeline.Run(context.Background(), Chirp{Body: c.input, AuthorCreatedAt: created})
+		if err != nil {
+			t.Fatalf("Run(%q) returned an error: %v", c.input, err)
+		}
+		if res.Decision != c.decision || (c.decision != Reject && res.Body != c.body) || res.Stage != c.stage || len(res.Flags) != c.flags {
+			t.Errorf("Input: %q\nExpected: %s %q from %q with %d flags\nOutput: %+v", c.input, c.decision, c.body, c.stage, c.flags, res)
+		}
+	}
+}
+
+func TestProfanityHoldFlagged(t *testing.T) {
+	pipeline := New(Profanity{Filter: testFilter, HoldFlagged: true})
+	res, _ := pipeline.Run(context.Background(), Chirp{Body: "a kerfuffle of a scam"})
+	if res.Decision != Hold || res.Body != "a **** of a scam" {
+		t.Errorf("Expected the chirp to be masked and held, got %+v", res)
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	dir := t.TempDir()
+	good := filepath.Join(dir, "good.json")
+	os.WriteFile(good, []byte(`{"stages": [
+		{"type": "length", "max": 100},
+		{"type": "profanity", "hold_flagged": true},
+		{"type": "links", "blocked": ["example.com"], "action": "hold"},
+		{"type": "spam", "max_links": 1, "new_account_age": "1h"},
+		{"type": "rules", "rules": [{"pattern": "(?i)buy now", "action": "reject"}]}
+	]}`), 0o644)
+	pipeline, err := LoadFile(good, testFilter)
+	if err != nil {
+		t.Fatalf("LoadFile returned an error: %v", err)
+	}
+	names := strings.Join(pipeline.Stages(), ",")
+	if names != "length,profanity,links,spam,rules" {
+		t.Errorf("Expected the stages in order, got %s", names)
+	}
+	res, _ := pipeline.Run(context.Background(), Chirp{Body: "BUY NOW", AuthorCreatedAt: time.Now()})
+	if res.Decision != Reject || res.Reason != "Chirp broke a rule" {
+		t.Errorf("Expected the rule to reject with the default reason, got %+v", res)
+	}
+	for _, body := range []string{
+		`{"stages": [{"type": "nope"}]}`,
+		`{"stages": [{"type": "links", "action": "modify"}]}`,
+		`{"stages": [{"type": "spam", "new_account_age": "a day"}]}`,
+		`{"stages": [{"type": "rules", "rules": [{"pattern": "(", "action": "hold"}]}]}`,
+		`{"stages": [{"type": "rules", "rules": [{"pattern": "a", "action": "allow"}]}]}`,
+		`not json`,
+	} {
+		bad := filepath.Join(dir, "bad.json")
+		os.WriteFile(bad, []byte(body), 0o644)
+		_, err = LoadFile(bad, testFilter)
+		if err == nil {
+			t.Errorf("Expected an error loading %s", body)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package moderation
+
+import (
+	"context"
+	"fmt"
+	"internal/contentfilter"
+	"internal/hashtags"
+	"internal/mentions"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Length turns away chirps longer than Max bytes
+type Length struct {
+	Max int
+}
+
+func (s Length) Name() string {
+	return "length"
+}
+
+func (s Length) Check(ctx context.Context, chirp Chirp) (Verdict, error) {
+	if len(chirp.Body) > s.Max {
+		return Verdict{Decision: Reject, Reason: "Chirp is too long"}, nil
+	}
+	return Verdict{Decision: Allow}, nil
+}
+
+// Profanity runs chirps through the content filter
+// words on mask lists are masked and words on reject lists get the chirp rejected
+// words on flag lists are passed along as flags, or hold the chirp if HoldFlagged is set
+type Profanity struct {
+	Filter *contentfilter.Filter
+	HoldFlagged bool
+}
+
+func (s Profanity) Name() string {
+	return "profanity"
+}
+
+func (s Profanity) Check(ctx context.Context, chirp Chirp) (Verdict, error) {
+	res := s.Filter.Check(chirp.Body)
+	if res.Rejected() {
+		return Verdict{Decision: Reject, Reason: "Chirp contains a disallowed word"}, nil
+	}
+	flagged := res.Flagged()
+	if s.HoldFlagged && len(flagged) > 0 {
+		return Verdict{Decision: Hold, Body: res.Text, Reason: "Chirp contains a word that needs a moderator to look at it"}, nil
+	}
+	v := Verdict{Decision: Allow, Flags: flagged}
+	if res.Text != chirp.Body {
+		v.Decision = Modify
+		v.Body = res.Text
+		v.Reason = "Chirp contained disallowed words"
+	}
+	return v, nil
+}
+
+// anything that looks like a domain name, with or without a scheme or path
+var hostPattern = regexp.MustCompile(`(?i)(https?://)?((?:[a-z0-9](?:[a-z0-9-]*[a-z0-9])?\.)+[a-z]{2,})(/)?`)
+
+// every domain name in body, lowercase
+func hosts(body string) []string {
+	found := []string{}
+	for _, m := range hostPattern.FindAllStringSubmatch(body, -1) {
+		found = append(found, strings.ToLower(m[2]))
+	}
+	return found
+}
+
+// how many links are in body
+// a bare domain only counts if it has a path after it (example.com/page), since plenty of
+// things that aren't links look like domains (file.txt, end of a sentence.no space)
+func countLinks(body string) int {
+	n := 0
+	for _, m := range hostPattern.FindAllStringSubmatch(body, -1) {
+		if m[1] != "" || m[3] != "" || strings.HasPrefix(strings.ToLower(m[2]), "www.") {
+			n++
+		}
+	}
+	return n
+}
+
+// Links turns away (or holds) chirps that mention a blocked domain or any subdomain of one
+type Links struct {
+	Blocked []string
+	// Reject or Hold
+	Action Decision
+}
+
+func (s Links) Name() string {
+	return "links"
+}
+
+func (s Links) Check(ctx context.Context, chirp Chirp) (Verdict, error) {
+	for _, host := range hosts(chirp.Body) {
+		for _, b := range s.Blocked {
+			b = strings.ToLower(b)
+			if host == b || strings.HasSuffix(host, "."+b) {
+				return Verdict{Decision: s.Action, Body: chirp.Body, Reason: "Chirp links to a blocked site"}, nil
+			}
+		}
+	}
+	return Verdict{Decision: Allow}, nil
+}
+
+// Spam turns away (or holds) chirps that look like spam
+// any of the limits left at 0 isn't checked
+type Spam struct {
+	MaxLinks int
+	MaxMentions int
+	MaxHashtags int
+	// accounts younger than this can't post links at all
+	NewAccountAge time.Duration
+	// Reject or Hold
+	Action Decision
+}
+
+func (s Spam) Name() string {
+	return "spam"
+}
+
+func (s Spam) Check(ctx context.Context, chirp Chirp) (Verdict, error) {
+	reasons := []string{}
+	links := countLinks(chirp.Body)
+	if s.MaxLinks > 0 && links > s.MaxLinks {
+		reasons = append(reasons, "too many links")
+	}
+	if s.MaxMentions > 0 && len(mentions.Extract(chirp.Body)) > s.MaxMentions {
+		reasons = append(reasons, "too many mentions")
+	}
+	if s.MaxHashtags > 0 && len(hashtags.Extract(chirp.Body)) > s.MaxHashtags {
+		reasons = append(reasons, "too many hashtags")
+	}
+	if s.NewAccountAge > 0 && links > 0 && time.Since(chirp.AuthorCreatedAt) < s.NewAccountAge {
+		reasons = append(reasons, "links from a new account")
+	}
+	if len(reasons) == 0 {
+		return Verdict{Decision: Allow}, nil
+	}
+	return Verdict{Decision: s.Action, Body: chirp.Body, Reason: fmt.Sprintf("Chirp looks like spam: %s", strings.Join(reasons, ", "))}, nil
+}
+
+// a custom rule: anything matching Pattern gets Action
+// for Modify, each match is replaced with Replace (which can use $1 and so on)
+type Rule struct {
+	Pattern *regexp.Regexp
+	Action Decision
+	Replace string
+	Reason string
+}
+
+// Rules checks a chirp against each rule in order
+// like the pipeline, a rejection ends it and a hold doesn't
+type Rules []Rule
+
+func (s Rules) Name() string {
+	return "rules"
+}
+
+func (s Rules) Check(ctx context.Context, chirp Chirp) (Verdict, error) {
+	v := Verdict{Decision: Allow, Body: chirp.Body}
+	for _, r := range s {
+		if !r.Pattern.MatchString(v.Body) {
+			continue
+		}
+		switch r.Action {
+		case Reject:
+			return Verdict{Decision: Reject, Reason: r.Reason}, nil
+		case Hold:
+			if v.Decision != Hold {
+				v.Decision = Hold
+				v.Reason = r.Reason
+			}
+		case Modify:
+			v.Body = r.Pattern.ReplaceAllString(v.Body, r.Replace)
+			if v.Decision == Allow {
+				v.Decision = Modify
+				v.Reason = r.Reason
+			}
+		}
+	}
+	return v, nil
+}
@@ -0,0 +1,115 @@
+package ratelimit
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// which policy goes with which route
+// routes are the patterns they're registered with on the ServeMux, like "POST /api/login"
+type Config struct {
+	// the policy for routes that aren't in Routes, if there is one
+	Default *Policy
+	// a nil policy means the route isn't limited, even if there's a default
+	Routes map[string]*Policy
+	// whether to take the client's IP from X-Forwarded-For, which is only safe behind a proxy that sets it
+	TrustForwardedFor bool
+}
+
+// Default is the config used without a config file: just the routes that are worth hammering
+func Default() Config {
+	return Config{
+		Routes: map[string]*Policy{
+			"POST /api/login": {Rate: 10, Per: time.Minute, Burst: 5},
+			"POST /api/users": {Rate: 5, Per: time.Hour, Burst: 5},
+			"POST /api/chirps": {Rate: 30, Per: time.Minute, Burst: 10},
+		},
+	}
+}
+
+// the policy for a route, if it's limited
+func (c Config) Policy(route string) (Policy, bool) {
+	policy, ok := c.Routes[route]
+	if !ok {
+		policy = c.Default
+	}
+	if policy == nil {
+		return Policy{}, false
+	}
+	return *policy, true
+}
+
+type policyConfig struct {
+	Rate int `json:"rate"`
+	Per string `json:"per"`
+	Burst int `json:"burst"`
+}
+
+// LoadFile reads a config from a JSON file like
+// {"default": {"rate": 60, "per": "1m"}, "routes": {"POST /api/login": {"rate": 5, "per": "1m", "burst": 3}, "GET /api/healthz": null}}
+// burst defaults to rate, and a route set to null isn't limited
+func LoadFile(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, err
+	}
+	file := struct {
+		Default *policyConfig `json:"default"`
+		Routes map[string]*policyConfig `json:"routes"`
+		TrustForwardedFor bool `json:"trust_forwarded_for"`
+	}{}
+	err = json.Unmarshal(data, &file)
+	if err != nil {
+		return Config{}, fmt.Errorf("reading %s: %w", path, err)
+	}
+	c := Config{Routes: map[string]*Policy{}, TrustForwardedFor: file.TrustForwardedFor}
+	if file.Default != nil {
+		c.Default, err = file.Default.policy()
+		if err != nil {
+			return Config{}, fmt.Errorf("reading %s: default: %w", path, err)
+		}
+	}
+	for route, p := range file.Routes {
+		if p == nil {
+			c.Routes[route] = nil
+			continue
+		}
+		c.Routes[route], err = p.policy()
+		if err != nil {
+			return Config{}, fmt.Errorf("reading %s: %s: %w", path, route, err)
+		}
+	}
+	return c, nil
+}
+
+func (p policyConfig) policy() (*Policy, error) {
+	if p.Rate <= 0 {
+		return nil, fmt.Errorf("rate must be more than 0")
+	}
+	per, err := time.ParseDuration(p.Per)
+	if err != nil || per <= 0 {
+		return nil, fmt.Errorf("invalid per %q", p.Per)
+	}
+	if p.Burst < 0 {
+		return nil, fmt.Errorf("burst can't be negative")
+	}
+	if p.Burst == 0 {
+		p.Burst = p.Rate
+	}
+	return &Policy{Rate: p.Rate, Per: per, Burst: p.Burst}, nil
+}
+
+// Open picks a backend based on a URL
+// "memory:" (or nothing) keeps the buckets in memory, and "redis://..." keeps them in Redis
+func Open(backendURL string) (Backend, error) {
+	if backendURL == "" || strings.HasPrefix(backendURL, "memory:") {
+		return NewMemory(), nil
+	}
+	if strings.HasPrefix(backendURL, "redis:") {
+		return NewRedis(backendURL)
+	}
+	return nil, fmt.Errorf("unknown rate limit backend %q (should be memory: or redis://)", backendURL)
+}
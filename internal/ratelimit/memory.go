@@ -0,0 +1,63 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// how often the memory backend looks for buckets it can forget about
+const sweepInterval = time.Minute
+
+// Memory keeps the buckets in a map, so they're only shared by one server
+// a bucket that's filled back up is the same as one that was never there,
+// so every so often the full ones are thrown away to keep the map from growing forever
+type Memory struct {
+	mu sync.Mutex
+	buckets map[string]memoryBucket
+	lastSweep time.Time
+	now func() time.Time
+}
+
+type memoryBucket struct {
+	bucket
+	// when it'll be full again
+	full time.Time
+}
+
+func NewMemory() *Memory {
+	return &Memory{
+		buckets: map[string]memoryBucket{},
+		now: time.Now,
+	}
+}
+
+func (m *Memory) Take(ctx context.Context, key string, policy Policy) (Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+	if now.Sub(m.lastSweep) >= sweepInterval {
+		m.sweep(now)
+	}
+	b, res := take(m.buckets[key].bucket, now, policy)
+	m.buckets[key] = memoryBucket{bucket: b, full: now.Add(res.Reset)}
+	return res, nil
+}
+
+// forgets the buckets that are full by now
+// m.mu has to be held
+func (m *Memory) sweep(now time.Time) {
+	for key, b := range m.buckets {
+		if !now.Before(b.full) {
+			delete(m.buckets, key)
+		}
+	}
+	m.lastSweep = now
+}
+
+// how many buckets are being kept
+func (m *Memory) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.buckets)
+}
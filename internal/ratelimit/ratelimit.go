@@ -0,0 +1,68 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// a token bucket: it holds up to Burst tokens, each request takes one,
+// and they come back at Rate every Per
+type Policy struct {
+	Rate int
+	Per time.Duration
+	Burst int
+}
+
+// how long it takes for one token to come back
+func (p Policy) interval() time.Duration {
+	return p.Per / time.Duration(p.Rate)
+}
+
+// what taking a token from a bucket came to
+type Result struct {
+	Allowed bool
+	// how many tokens the bucket holds when it's full
+	Limit int
+	// how many are left after this request
+	Remaining int
+	// how long until the bucket is full again
+	Reset time.Duration
+	// how long until there's a token to take, for requests that weren't allowed
+	RetryAfter time.Duration
+}
+
+// somewhere to keep the buckets
+// keys are chosen by the caller, and each key is its own bucket
+type Backend interface {
+	// Take takes a token from the bucket under key, filling it up first if it's new
+	Take(ctx context.Context, key string, policy Policy) (Result, error)
+}
+
+// the state of a bucket: how many tokens it had at a point in time
+type bucket struct {
+	tokens float64
+	at time.Time
+}
+
+// takes a token from b at now, returning what's left of it
+// a bucket with a zero at is new, and starts out full
+// the backends just store what comes out of here, so they all count the same way
+func take(b bucket, now time.Time, policy Policy) (bucket, Result) {
+	burst := float64(policy.Burst)
+	interval := policy.interval()
+	tokens := burst
+	if !b.at.IsZero() {
+		tokens = math.Min(burst, b.tokens+float64(now.Sub(b.at))/float64(interval))
+	}
+	res := Result{Limit: policy.Burst}
+	if tokens >= 1 {
+		tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = time.Duration((1 - tokens) * float64(interval))
+	}
+	res.Remaining = int(tokens)
+	res.Reset = time.Duration((burst - tokens) * float64(interval))
+	return bucket{tokens: tokens, at: now}, res
+}
@@ -0,0 +1,164 @@
+package ratelimit
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// a clock that only moves when it's told to
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+// checks that a backend counts like a token bucket, moving its clock with advance
+func testBackend(t *testing.T, backend Backend, advance func(time.Duration)) {
+	ctx := context.Background()
+	policy := Policy{Rate: 2, Per: time.Second, Burst: 3}
+
+	// a new bucket starts out full
+	for i := range 3 {
+		res, err := backend.Take(ctx, "alice", policy)
+		if err != nil {
+			t.Fatalf("Error in Take: %v", err)
+		}
+		if !res.Allowed || res.Limit != 3 || res.Remaining != 2-i {
+			t.Errorf("Take %d returned %+v", i+1, res)
+		}
+	}
+	res, _ := backend.Take(ctx, "alice", policy)
+	if res.Allowed || res.Remaining != 0 {
+		t.Errorf("Expected an empty bucket to turn the request away, got %+v", res)
+	}
+	if res.RetryAfter != 500*time.Millisecond || res.Reset != 1500*time.Millisecond {
+		t.Errorf("Expected to retry after 500ms and be full after 1.5s, got %v and %v", res.RetryAfter, res.Reset)
+	}
+
+	// other keys have their own buckets
+	res, _ = backend.Take(ctx, "bob", policy)
+	if !res.Allowed || res.Remaining != 2 {
+		t.Errorf("Expected bob's bucket to be full, got %+v", res)
+	}
+
+	// tokens come back at the rate, one every 500ms
+	advance(500 * time.Millisecond)
+	res, _ = backend.Take(ctx, "alice", policy)
+	if !res.Allowed || res.Remaining != 0 {
+		t.Errorf("Expected a token back after 500ms, got %+v", res)
+	}
+	res, _ = backend.Take(ctx, "alice", policy)
+	if res.Allowed {
+		t.Errorf("Expected only one token back after 500ms, got %+v", res)
+	}
+
+	// but never past the burst
+	advance(time.Hour)
+	for i := range 4 {
+		res, _ = backend.Take(ctx, "alice", policy)
+		if res.Allowed != (i < 3) {
+			t.Errorf("Take %d after an hour returned %+v", i+1, res)
+		}
+	}
+}
+
+func TestMemory(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	mem := NewMemory()
+	mem.now = clock.Now
+	testBackend(t, mem, clock.Advance)
+}
+
+func TestMemoryEviction(t *testing.T) {
+	ctx := context.Background()
+	clock := &fakeClock{now: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	mem := NewMemory()
+	mem.now = clock.Now
+	fast := Policy{Rate: 10, Per: time.Second, Burst: 10}
+	slow := Policy{Rate: 1, Per: time.Hour, Burst: 1}
+	mem.Take(ctx, "fast", fast)
+	mem.Take(ctx, "slow", slow)
+	if mem.Len() != 2 {
+		t.Fatalf("Expected 2 buckets, got %d", mem.Len())
+	}
+
+	// the fast bucket is full again long before the next sweep, and the slow one isn't
+	clock.Advance(sweepInterval)
+	mem.Take(ctx, "other", fast)
+	if mem.Len() != 2 {
+		t.Errorf("Expected the full bucket to be swept, leaving 2, got %d", mem.Len())
+	}
+	res, _ := mem.Take(ctx, "slow", slow)
+	if res.Allowed {
+		t.Errorf("Expected the slow bucket to still be empty")
+	}
+	res, _ = mem.Take(ctx, "fast", fast)
+	if !res.Allowed || res.Remaining != 9 {
+		t.Errorf("Expected a swept bucket to start out full, got %+v", res)
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ratelimit.json")
+	os.WriteFile(path, []byte(`{
+		"default": {"rate": 60, "per": "1m"},
+		"routes": {
+			"POST /api/login": {"rate": 5, "per": "1m", "burst": 3},
+			"GET /api/healthz": null
+		},
+		"trust_forwarded_for": true
+	}`), 0644)
+	c, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("Error in LoadFile: %v", err)
+	}
+	if !c.TrustForwardedFor {
+		t.Errorf("Expected trust_forwarded_for to be read")
+	}
+	cases := []struct {
+		route string
+		limited bool
+		policy Policy
+	}{
+		{route: "POST /api/login", limited: true, policy: Policy{Rate: 5, Per: time.Minute, Burst: 3}},
+		{route: "GET /api/chirps", limited: true, policy: Policy{Rate: 60, Per: time.Minute, Burst: 60}},
+		{route: "GET /api/healthz", limited: false},
+	}
+	for _, c2 := range cases {
+		policy, ok := c.Policy(c2.route)
+		if ok != c2.limited || policy != c2.policy {
+			t.Errorf("Policy(%q) returned %+v, %v", c2.route, policy, ok)
+		}
+	}
+
+	for _, bad := range []string{
+		`{"routes": {"POST /api/login": {"rate": 0, "per": "1m"}}}`,
+		`{"routes": {"POST /api/login": {"rate": 5, "per": "soon"}}}`,
+		`{"default": {"rate": 5, "per": "1m", "burst": -1}}`,
+	} {
+		os.WriteFile(path, []byte(bad), 0644)
+		_, err = LoadFile(path)
+		if err == nil {
+			t.Errorf("Expected an error loading %s", bad)
+		}
+	}
+}
+
+func TestDefault(t *testing.T) {
+	c := Default()
+	if _, ok := c.Policy("POST /api/login"); !ok {
+		t.Errorf("Expected logins to be limited by default")
+	}
+	if _, ok := c.Policy("GET /api/chirps"); ok {
+		t.Errorf("Expected reading chirps not to be limited by default")
+	}
+}
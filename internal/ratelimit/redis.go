@@ -0,0 +1,323 @@
+package ratelimit
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// how many times Take tries again when another server changes the bucket underneath it
+const redisRetries = 5
+
+// how long a Take can wait on Redis when its context doesn't say
+const redisTimeout = time.Second
+
+// returned when a bucket kept changing underneath Take
+var ErrContention = errors.New("rate limit bucket is too busy")
+
+// Redis keeps the buckets in Redis (or anything that talks like it, such as Valkey or KeyDB),
+// so servers sharing it share the limits too
+// buckets are updated in WATCH/MULTI/EXEC transactions and timed with the server's clock,
+// so servers can't double spend a token or disagree about when it comes back
+type Redis struct {
+	addr string
+	password string
+	db int
+	prefix string
+	idle chan *redisConn
+}
+
+// NewRedis connects to the Redis server at a URL like redis://:password@localhost:6379/0
+// the connection is checked straight away, so a bad URL fails at startup rather than on the first request
+func NewRedis(rawURL string) (*Redis, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "redis" {
+		return nil, fmt.Errorf("unsupported scheme %q (should be redis)", u.Scheme)
+	}
+	r := &Redis{
+		addr: u.Host,
+		prefix: "chirpy:ratelimit:",
+		idle: make(chan *redisConn, 8),
+	}
+	if u.Port() == "" {
+		r.addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if password, ok := u.User.Password(); ok {
+		r.password = password
+	}
+	if db := strings.Trim(u.Path, "/"); db != "" {
+		r.db, err = strconv.Atoi(db)
+		if err != nil {
+			return nil, fmt.Errorf("invalid database %q", db)
+		}
+	}
+	conn, err := r.get(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	r.put(conn, nil)
+	return r, nil
+}
+
+// Close closes the idle connections
+func (r *Redis) Close() error {
+	for {
+		select {
+		case conn := <-r.idle:
+			conn.Close()
+		default:
+			return nil
+		}
+	}
+}
+
+func (r *Redis) Take(ctx context.Context, key string, policy Policy) (res Result, err error) {
+	conn, err := r.get(ctx)
+	if err != nil {
+		return Result{}, err
+	}
+	defer func() { r.put(conn, err) }()
+	key = r.prefix + key
+	for range redisRetries {
+		replies, err := conn.do([]string{"WATCH", key}, []string{"GET", key}, []string{"TIME"})
+		if err != nil {
+			return Result{}, err
+		}
+		b, err := parseBucket(replies[1])
+		if err != nil {
+			return Result{}, err
+		}
+		now, err := parseTime(replies[2])
+		if err != nil {
+			return Result{}, err
+		}
+		b, res = take(b, now, policy)
+		// nothing needs saving when there wasn't a token to take, since the refill is worked out from the time
+		if !res.Allowed {
+			_, err = conn.do([]string{"UNWATCH"})
+			return res, err
+		}
+		// the bucket's full again once it expires, so there's no need to keep it any longer than that
+		ttl := max(res.Reset.Milliseconds(), 1)
+		value := fmt.Sprintf("%s %d", strconv.FormatFloat(b.tokens, 'g', -1, 64), b.at.UnixMicro())
+		replies, err = conn.do([]string{"MULTI"}, []string{"SET", key, value, "PX", strconv.FormatInt(ttl, 10)}, []string{"EXEC"})
+		if err != nil {
+			return Result{}, err
+		}
+		// EXEC comes back nil when the bucket changed after WATCH, so go around again
+		if replies[2] != nil {
+			return res, nil
+		}
+	}
+	return Result{}, ErrContention
+}
+
+// a bucket from how Take stores it, "tokens unix-microseconds"
+// nothing there is a new bucket
+func parseBucket(reply any) (bucket, error) {
+	if reply == nil {
+		return bucket{}, nil
+	}
+	value, ok := reply.(string)
+	tokens, at, found := strings.Cut(value, " ")
+	if !ok || !found {
+		return bucket{}, fmt.Errorf("invalid bucket %v", reply)
+	}
+	b := bucket{}
+	var err error
+	b.tokens, err = strconv.ParseFloat(tokens, 64)
+	if err != nil {
+		return bucket{}, fmt.Errorf("invalid bucket %q", value)
+	}
+	micros, err := strconv.ParseInt(at, 10, 64)
+	if err != nil {
+		return bucket{}, fmt.Errorf("invalid bucket %q", value)
+	}
+	b.at = time.UnixMicro(micros)
+	return b, nil
+}
+
+// the reply to TIME, which is the seconds and microseconds since the epoch
+func parseTime(reply any) (time.Time, error) {
+	parts, ok := reply.([]any)
+	if !ok || len(parts) != 2 {
+		return time.Time{}, fmt.Errorf("invalid TIME reply %v", reply)
+	}
+	seconds, _ := parts[0].(string)
+	micros, _ := parts[1].(string)
+	s, err1 := strconv.ParseInt(seconds, 10, 64)
+	us, err2 := strconv.ParseInt(micros, 10, 64)
+	if err1 != nil || err2 != nil {
+		return time.Time{}, fmt.Errorf("invalid TIME reply %v", reply)
+	}
+	return time.Unix(s, us*1000), nil
+}
+
+// an idle connection, or a new one if there aren't any
+func (r *Redis) get(ctx context.Context) (*redisConn, error) {
+	var conn *redisConn
+	select {
+	case conn = <-r.idle:
+	default:
+		dialer := net.Dialer{Timeout: redisTimeout}
+		c, err := dialer.DialContext(ctx, "tcp", r.addr)
+		if err != nil {
+			return nil, err
+		}
+		conn = &redisConn{conn: c, r: bufio.NewReader(c), w: bufio.NewWriter(c)}
+		setup := [][]string{}
+		if r.password != "" {
+			setup = append(setup, []string{"AUTH", r.password})
+		}
+		if r.db != 0 {
+			setup = append(setup, []string{"SELECT", strconv.Itoa(r.db)})
+		}
+		if len(setup) > 0 {
+			conn.setDeadline(ctx)
+			_, err = conn.do(setup...)
+			if err != nil {
+				conn.Close()
+				return nil, err
+			}
+		}
+	}
+	conn.setDeadline(ctx)
+	return conn, nil
+}
+
+// hands a connection back once a Take is done with it
+// one that had an error could be halfway through a reply (or a transaction), so it's closed instead
+func (r *Redis) put(conn *redisConn, err error) {
+	if err != nil && !errors.Is(err, ErrContention) {
+		conn.Close()
+		return
+	}
+	select {
+	case r.idle <- conn:
+	default:
+		conn.Close()
+	}
+}
+
+// a connection speaking RESP, the Redis protocol
+type redisConn struct {
+	conn net.Conn
+	r *bufio.Reader
+	w *bufio.Writer
+}
+
+// an error reply from the server
+type redisError string
+
+func (e redisError) Error() string {
+	return "redis: " + string(e)
+}
+
+func (c *redisConn) Close() error {
+	return c.conn.Close()
+}
+
+func (c *redisConn) setDeadline(ctx context.Context) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(redisTimeout)
+	}
+	c.conn.SetDeadline(deadline)
+}
+
+// sends the commands all at once and reads back their replies
+// the replies are strings, int64s, nil or []any, and the first error reply is returned as a redisError
+// (after reading the rest, so the connection is still usable)
+func (c *redisConn) do(commands ...[]string) ([]any, error) {
+	for _, args := range commands {
+		fmt.Fprintf(c.w, "*%d\r\n", len(args))
+		for _, arg := range args {
+			fmt.Fprintf(c.w, "$%d\r\n%s\r\n", len(arg), arg)
+		}
+	}
+	err := c.w.Flush()
+	if err != nil {
+		return nil, err
+	}
+	replies := []any{}
+	var replyErr error
+	for range commands {
+		reply, err := c.read()
+		if redisErr, ok := err.(redisError); ok {
+			if replyErr == nil {
+				replyErr = redisErr
+			}
+			reply, err = redisErr, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		replies = append(replies, reply)
+	}
+	return replies, replyErr
+}
+
+func (c *redisConn) read() (any, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("redis: empty reply")
+	}
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		size, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if size < 0 {
+			return nil, nil
+		}
+		data := make([]byte, size+2)
+		_, err = io.ReadFull(c.r, data)
+		if err != nil {
+			return nil, err
+		}
+		return string(data[:size]), nil
+	case '*':
+		size, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if size < 0 {
+			return nil, nil
+		}
+		items := []any{}
+		for range size {
+			item, err := c.read()
+			// errors inside an array (from EXEC, say) are just items in it
+			if redisErr, ok := err.(redisError); ok {
+				item, err = redisErr, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("redis: unexpected reply %q", line)
+}
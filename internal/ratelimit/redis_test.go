@@ -0,0 +1,281 @@
+package ratelimit
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// a stand-in for a Redis server, with just the commands the Redis backend uses
+// it keeps a version for every key so WATCH works like the real thing, and its clock only moves when it's told to
+type fakeRedis struct {
+	listener net.Listener
+	password string
+	mu sync.Mutex
+	now time.Time
+	values map[string]fakeValue
+	versions map[string]int
+}
+
+type fakeValue struct {
+	value string
+	expires time.Time
+}
+
+func newFakeRedis(t *testing.T, password string) *fakeRedis {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Error listening: %v", err)
+	}
+	f := &fakeRedis{
+		listener: listener,
+		password: password,
+		now: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		values: map[string]fakeValue{},
+		versions: map[string]int{},
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+	return f
+}
+
+func (f *fakeRedis) URL() string {
+	if f.password != "" {
+		return fmt.Sprintf("redis://:%s@%s/2", f.password, f.listener.Addr())
+	}
+	return "redis://" + f.listener.Addr().String()
+}
+
+func (f *fakeRedis) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = f.now.Add(d)
+}
+
+// changes a key behind everyone's back, like another server would
+func (f *fakeRedis) touch(key string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.versions[key]++
+}
+
+func (f *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	authed := f.password == ""
+	watched := map[string]int{}
+	var queued [][]string
+	inMulti := false
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		name := strings.ToUpper(args[0])
+		if !authed && name != "AUTH" {
+			io.WriteString(conn, "-NOAUTH Authentication required.\r\n")
+			continue
+		}
+		if inMulti && name != "EXEC" {
+			queued = append(queued, args)
+			io.WriteString(conn, "+QUEUED\r\n")
+			continue
+		}
+		f.mu.Lock()
+		switch name {
+		case "AUTH":
+			authed = args[1] == f.password
+			if authed {
+				io.WriteString(conn, "+OK\r\n")
+			} else {
+				io.WriteString(conn, "-WRONGPASS invalid password\r\n")
+			}
+		case "SELECT":
+			io.WriteString(conn, "+OK\r\n")
+		case "WATCH":
+			for _, key := range args[1:] {
+				watched[key] = f.versions[key]
+			}
+			io.WriteString(conn, "+OK\r\n")
+		case "UNWATCH":
+			watched = map[string]int{}
+			io.WriteString(conn, "+OK\r\n")
+		case "MULTI":
+			inMulti = true
+			io.WriteString(conn, "+OK\r\n")
+		case "EXEC":
+			changed := false
+			for key, version := range watched {
+				changed = changed || f.versions[key] != version
+			}
+			if changed {
+				io.WriteString(conn, "*-1\r\n")
+			} else {
+				fmt.Fprintf(conn, "*%d\r\n", len(queued))
+				for _, args := range queued {
+					io.WriteString(conn, f.run(args))
+				}
+			}
+			inMulti, queued, watched = false, nil, map[string]int{}
+		case "TIME":
+			io.WriteString(conn, "*2\r\n"+bulk(strconv.FormatInt(f.now.Unix(), 10))+bulk(strconv.Itoa(f.now.Nanosecond()/1000)))
+		default:
+			io.WriteString(conn, f.run(args))
+		}
+		f.mu.Unlock()
+	}
+}
+
+// runs a command that can be queued in a transaction, returning its reply
+// f.mu has to be held
+func (f *fakeRedis) run(args []string) string {
+	switch strings.ToUpper(args[0]) {
+	case "GET":
+		v, ok := f.values[args[1]]
+		if !ok || !f.now.Before(v.expires) {
+			return "$-1\r\n"
+		}
+		return bulk(v.value)
+	case "SET":
+		if len(args) != 5 || strings.ToUpper(args[3]) != "PX" {
+			return "-ERR syntax error\r\n"
+		}
+		ms, _ := strconv.Atoi(args[4])
+		f.values[args[1]] = fakeValue{value: args[2], expires: f.now.Add(time.Duration(ms) * time.Millisecond)}
+		f.versions[args[1]]++
+		return "+OK\r\n"
+	}
+	return fmt.Sprintf("-ERR unknown command '%s'\r\n", args[0])
+}
+
+func bulk(s string) string {
+	return fmt.Sprintf("$%d\r\n%s\r\n", len(s), s)
+}
+
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	count, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "*")))
+	if err != nil {
+		return nil, err
+	}
+	args := []string{}
+	for range count {
+		line, err = r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "$")))
+		if err != nil {
+			return nil, err
+		}
+		data := make([]byte, size+2)
+		_, err = io.ReadFull(r, data)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, string(data[:size]))
+	}
+	return args, nil
+}
+
+func TestRedis(t *testing.T) {
+	server := newFakeRedis(t, "hunter2")
+	backend, err := NewRedis(server.URL())
+	if err != nil {
+		t.Fatalf("Error in NewRedis: %v", err)
+	}
+	defer backend.Close()
+	testBackend(t, backend, server.Advance)
+
+	// buckets expire once they're full, so nothing's left lying around
+	server.Advance(time.Hour)
+	server.mu.Lock()
+	for key, v := range server.values {
+		if server.now.Before(v.expires) {
+			t.Errorf("Expected %s to have expired", key)
+		}
+	}
+	server.mu.Unlock()
+}
+
+func TestRedisWrongPassword(t *testing.T) {
+	server := newFakeRedis(t, "hunter2")
+	_, err := NewRedis(strings.Replace(server.URL(), "hunter2", "letmein", 1))
+	if err == nil {
+		t.Errorf("Expected an error with the wrong password")
+	}
+}
+
+func TestRedisContention(t *testing.T) {
+	ctx := context.Background()
+	server := newFakeRedis(t, "")
+	backend, _ := NewRedis(server.URL())
+	defer backend.Close()
+	policy := Policy{Rate: 1, Per: time.Hour, Burst: 20}
+
+	// lots of servers taking from the same bucket at once can't take more than it holds
+	wg := sync.WaitGroup{}
+	allowed := make(chan bool, 40)
+	for range 40 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res, err := backend.Take(ctx, "alice", policy)
+			allowed <- err == nil && res.Allowed
+		}()
+	}
+	wg.Wait()
+	close(allowed)
+	count := 0
+	for ok := range allowed {
+		if ok {
+			count++
+		}
+	}
+	if count > 20 {
+		t.Errorf("Expected at most 20 requests through, got %d", count)
+	}
+
+	// EXEC is aborted when the bucket changes after WATCH, and the next Take still works
+	conn, _ := backend.get(ctx)
+	conn.do([]string{"WATCH", "chirpy:ratelimit:bob"})
+	server.touch("chirpy:ratelimit:bob")
+	replies, err := conn.do([]string{"MULTI"}, []string{"SET", "chirpy:ratelimit:bob", "1 0", "PX", "1000"}, []string{"EXEC"})
+	if err != nil || replies[2] != nil {
+		t.Errorf("Expected EXEC to be aborted, got %v, %v", replies, err)
+	}
+	backend.put(conn, nil)
+	res, err := backend.Take(ctx, "bob", policy)
+	if err != nil || !res.Allowed {
+		t.Errorf("Take after an aborted transaction returned %+v, %v", res, err)
+	}
+}
+
+func TestOpen(t *testing.T) {
+	backend, err := Open("memory:")
+	if _, ok := backend.(*Memory); !ok || err != nil {
+		t.Errorf("Expected memory: to open the memory backend, got %T, %v", backend, err)
+	}
+	_, err = Open("memcached://localhost")
+	if err == nil {
+		t.Errorf("Expected an error for an unknown backend")
+	}
+}
@@ -0,0 +1,5 @@
+module sqlitedb
+
+go 1.24.1
+
+require github.com/google/uuid v1.6.0
@@ -0,0 +1,13 @@
+module store
+
+go 1.24.1
+
+require internal/database v0.0.0
+
+require internal/sqlitedb v0.0.0
+
+require github.com/google/uuid v1.6.0
+
+replace internal/database => ../database
+
+replace internal/sqlitedb => ../sqlitedb
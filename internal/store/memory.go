@@ -0,0 +1,2317 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"internal/database"
+	"github.com/google/uuid"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// an in-memory store with the same behaviour as the Postgres one
+// (unique emails, cascading deletes, sql.ErrNoRows when something isn't there)
+// good for tests and demos, everything is gone when the process stops
+type Memory struct {
+	mu sync.RWMutex
+	// held for the whole of a transaction, so there's only ever one at a time
+	txMu sync.Mutex
+	tables
+}
+
+// everything the memory store keeps, split out so a transaction can take a copy of it to roll back to
+type tables struct {
+	users map[uuid.UUID]database.User
+	emails map[string]uuid.UUID
+	handles map[string]uuid.UUID
+	chirps map[uuid.UUID]database.Chirp
+	tokens map[string]database.RefreshToken
+	likes map[like]time.Time
+	rechirps map[like]rechirp
+	follows map[follow]time.Time
+	hashtags map[hashtag]time.Time
+	mentions map[like]bool
+	notifications map[uuid.UUID]database.Notification
+	conversations map[uuid.UUID]database.Conversation
+	participants map[participant]database.ConversationParticipant
+	messages map[uuid.UUID]database.Message
+	media map[uuid.UUID]database.MediaUpload
+	revisions map[uuid.UUID]database.ChirpRevision
+	filterLists map[string]database.FilterList
+	filterWords map[filterWord]time.Time
+	flags map[uuid.UUID]database.ChirpFlag
+	held map[uuid.UUID]database.HeldChirp
+	heldMedia map[heldMedium]int32
+	reports map[uuid.UUID]database.Report
+	roleChanges map[uuid.UUID]database.RoleChange
+}
+
+// the primary key of the held_chirp_media table
+type heldMedium struct {
+	HeldChirpID uuid.UUID
+	MediaID uuid.UUID
+}
+
+// the primary key of the filter_words table
+type filterWord struct {
+	ListName string
+	Word string
+}
+
+// the primary key of the conversation_participants table
+type participant struct {
+	ConversationID uuid.UUID
+	UserID uuid.UUID
+}
+
+// the primary key of the chirp_hashtags table
+type hashtag struct {
+	ChirpID uuid.UUID
+	Tag string
+}
+
+// the primary key of the follows table
+type follow struct {
+	FollowerID uuid.UUID
+	FolloweeID uuid.UUID
+}
+
+// a rechirp has the same key as a like, plus an id of its own for the timeline cursors
+type rechirp struct {
+	ID uuid.UUID
+	CreatedAt time.Time
+}
+
+// the primary key of the likes table
+type like struct {
+	UserID uuid.UUID
+	ChirpID uuid.UUID
+}
+
+var _ Store = (*Memory)(nil)
+
+// make an empty in-memory store
+func NewMemory() *Memory {
+	m := &Memory{tables: tables{
+		users: map[uuid.UUID]database.User{},
+		emails: map[string]uuid.UUID{},
+		handles: map[string]uuid.UUID{},
+		chirps: map[uuid.UUID]database.Chirp{},
+		tokens: map[string]database.RefreshToken{},
+		likes: map[like]time.Time{},
+		rechirps: map[like]rechirp{},
+		follows: map[follow]time.Time{},
+		hashtags: map[hashtag]time.Time{},
+		mentions: map[like]bool{},
+		notifications: map[uuid.UUID]database.Notification{},
+		conversations: map[uuid.UUID]database.Conversation{},
+		participants: map[participant]database.ConversationParticipant{},
+		messages: map[uuid.UUID]database.Message{},
+		media: map[uuid.UUID]database.MediaUpload{},
+		revisions: map[uuid.UUID]database.ChirpRevision{},
+		filterLists: map[string]database.FilterList{},
+		filterWords: map[filterWord]time.Time{},
+		flags: map[uuid.UUID]database.ChirpFlag{},
+		held: map[uuid.UUID]database.HeldChirp{},
+		heldMedia: map[heldMedium]int32{},
+		reports: map[uuid.UUID]database.Report{},
+		roleChanges: map[uuid.UUID]database.RoleChange{},
+	}}
+	// the list the content filter migration starts everyone off with
+	t := now()
+	m.filterLists["default"] = database.FilterList{Name: "default", CreatedAt: t, UpdatedAt: t, Action: "mask"}
+	for _, w := range []string{"kerfuffle", "sharbert", "fornax"} {
+		m.filterWords[filterWord{ListName: "default", Word: w}] = t
+	}
+	return m
+}
+
+// transactions take a copy of every table first, and put it back if fn fails
+// only one runs at a time, but they aren't isolated from calls made outside of one,
+// and a rollback undoes those too (which is fine for tests and demos)
+func (m *Memory) InTx(ctx context.Context, fn func(Store) error) error {
+	m.txMu.Lock()
+	defer m.txMu.Unlock()
+	m.mu.RLock()
+	saved := m.tables.clone()
+	m.mu.RUnlock()
+	err := fn(memoryTx{m})
+	if err != nil {
+		m.mu.Lock()
+		m.tables = saved
+		m.mu.Unlock()
+	}
+	return err
+}
+
+// the store a memory transaction's fn gets, where InTx just carries on in the same transaction
+type memoryTx struct {
+	*Memory
+}
+
+func (t memoryTx) InTx(ctx context.Context, fn func(Store) error) error {
+	return fn(t)
+}
+
+// the rows are all plain values, so copying the maps copies everything
+func (t tables) clone() tables {
+	return tables{
+		users: maps.Clone(t.users),
+		emails: maps.Clone(t.emails),
+		handles: maps.Clone(t.handles),
+		chirps: maps.Clone(t.chirps),
+		tokens: maps.Clone(t.tokens),
+		likes: maps.Clone(t.likes),
+		rechirps: maps.Clone(t.rechirps),
+		follows: maps.Clone(t.follows),
+		hashtags: maps.Clone(t.hashtags),
+		mentions: maps.Clone(t.mentions),
+		notifications: maps.Clone(t.notifications),
+		conversations: maps.Clone(t.conversations),
+		participants: maps.Clone(t.participants),
+		messages: maps.Clone(t.messages),
+		media: maps.Clone(t.media),
+		revisions: maps.Clone(t.revisions),
+		filterLists: maps.Clone(t.filterLists),
+		filterWords: maps.Clone(t.filterWords),
+		flags: maps.Clone(t.flags),
+		held: maps.Clone(t.held),
+		heldMedia: maps.Clone(t.heldMedia),
+		reports: maps.Clone(t.reports),
+		roleChanges: maps.Clone(t.roleChanges),
+	}
+}
+
+// Postgres TIMESTAMP columns come back without a time zone, so keep everything in UTC
+func now() time.Time {
+	return time.Now().UTC()
+}
+
+// sorts chirps the way ORDER BY created_at does (with the id as a tiebreaker so it's stable)
+func sortChirps(chirps []database.Chirp) {
+	sort.Slice(chirps, func(i, j int) bool {
+		if chirps[i].CreatedAt.Equal(chirps[j].CreatedAt) {
+			return chirps[i].ID.String() < chirps[j].ID.String()
+		}
+		return chirps[i].CreatedAt.Before(chirps[j].CreatedAt)
+	})
+}
+
+func (m *Memory) CreateChirp(ctx context.Context, arg database.CreateChirpParams) (database.Chirp, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.users[arg.UserID]; !ok {
+		return database.Chirp{}, ErrForeignKeyViolation
+	}
+	if _, ok := m.chirps[arg.InReplyTo.UUID]; arg.InReplyTo.Valid && !ok {
+		return database.Chirp{}, ErrForeignKeyViolation
+	}
+	t := now()
+	chirp := database.Chirp{
+		ID: uuid.New(),
+		CreatedAt: t,
+		UpdatedAt: t,
+		Body: arg.Body,
+		UserID: arg.UserID,
+		InReplyTo: arg.InReplyTo,
+		QuoteOf: arg.QuoteOf,
+	}
+	m.chirps[chirp.ID] = chirp
+	return chirp, nil
+}
+
+func (m *Memory) GetAllChirps(ctx context.Context) ([]database.Chirp, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	chirps := []database.Chirp{}
+	for _, c := range m.chirps {
+		if !c.DeletedAt.Valid {
+			chirps = append(chirps, c)
+		}
+	}
+	sortChirps(chirps)
+	return chirps, nil
+}
+
+func (m *Memory) GetChirpsByUser(ctx context.Context, userID uuid.UUID) ([]database.Chirp, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	chirps := []database.Chirp{}
+	for _, c := range m.chirps {
+		if c.UserID == userID && !c.DeletedAt.Valid {
+			chirps = append(chirps, c)
+		}
+	}
+	sortChirps(chirps)
+	return chirps, nil
+}
+
+func (m *Memory) GetSingleChirp(ctx context.Context, id uuid.UUID) (database.Chirp, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	chirp, ok := m.chirps[id]
+	if !ok {
+		return database.Chirp{}, sql.ErrNoRows
+	}
+	return chirp, nil
+}
+
+// transactions already take turns here (see InTx), so there's nothing to lock
+func (m *Memory) GetChirpForUpdate(ctx context.Context, id uuid.UUID) (database.Chirp, error) {
+	return m.GetSingleChirp(ctx, id)
+}
+
+// missing ids are skipped, like they would be by = ANY
+func (m *Memory) GetChirpsByIDs(ctx context.Context, ids []uuid.UUID) ([]database.Chirp, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	chirps := []database.Chirp{}
+	for _, id := range ids {
+		if c, ok := m.chirps[id]; ok {
+			chirps = append(chirps, c)
+		}
+	}
+	return chirps, nil
+}
+
+func (m *Memory) DeleteSingleChirp(ctx context.Context, id uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.deleteChirp(id)
+	return nil
+}
+
+// replies to the deleted chirp lose their parent and its media goes back to being unattached, like ON DELETE SET NULL
+// the caller holds the lock
+func (m *Memory) deleteChirp(id uuid.UUID) {
+	delete(m.chirps, id)
+	for l := range m.likes {
+		if l.ChirpID == id {
+			delete(m.likes, l)
+		}
+	}
+	for r := range m.rechirps {
+		if r.ChirpID == id {
+			delete(m.rechirps, r)
+		}
+	}
+	for h := range m.hashtags {
+		if h.ChirpID == id {
+			delete(m.hashtags, h)
+		}
+	}
+	for mention := range m.mentions {
+		if mention.ChirpID == id {
+			delete(m.mentions, mention)
+		}
+	}
+	for _, n := range m.notifications {
+		if n.ChirpID.Valid && n.ChirpID.UUID == id {
+			delete(m.notifications, n.ID)
+		}
+	}
+	for _, r := range m.revisions {
+		if r.ChirpID == id {
+			delete(m.revisions, r.ID)
+		}
+	}
+	for _, f := range m.flags {
+		if f.ChirpID == id {
+			delete(m.flags, f.ID)
+		}
+	}
+	for _, media := range m.media {
+		if media.ChirpID.Valid && media.ChirpID.UUID == id {
+			media.ChirpID = uuid.NullUUID{}
+			m.media[media.ID] = media
+		}
+	}
+	for _, r := range m.reports {
+		if r.ChirpID.Valid && r.ChirpID.UUID == id {
+			m.deleteReport(r.ID)
+		}
+	}
+	// held replies go with it, since there's nothing left for them to reply to
+	for _, h := range m.held {
+		if h.InReplyTo.Valid && h.InReplyTo.UUID == id {
+			m.deleteHeldChirp(h.ID)
+		}
+	}
+	for _, c := range m.chirps {
+		if c.InReplyTo.Valid && c.InReplyTo.UUID == id {
+			c.InReplyTo = uuid.NullUUID{}
+			m.chirps[c.ID] = c
+		}
+	}
+}
+
+// deleting a chirp twice keeps the first deleted_at
+func (m *Memory) SoftDeleteChirp(ctx context.Context, id uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	chirp, ok := m.chirps[id]
+	if !ok || chirp.DeletedAt.Valid {
+		return nil
+	}
+	t := now()
+	chirp.DeletedAt = sql.NullTime{Time: t, Valid: true}
+	chirp.UpdatedAt = t
+	m.chirps[id] = chirp
+	return nil
+}
+
+func (m *Memory) TombstoneChirp(ctx context.Context, id uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	chirp, ok := m.chirps[id]
+	if !ok {
+		return nil
+	}
+	t := now()
+	chirp.Body = ""
+	if !chirp.DeletedAt.Valid {
+		chirp.DeletedAt = sql.NullTime{Time: t, Valid: true}
+	}
+	chirp.PurgedAt = sql.NullTime{Time: t, Valid: true}
+	chirp.UpdatedAt = t
+	m.chirps[id] = chirp
+	return nil
+}
+
+// sql.ErrNoRows unless the chirp is deleted but not yet purged
+func (m *Memory) RestoreChirp(ctx context.Context, id uuid.UUID) (database.Chirp, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	chirp, ok := m.chirps[id]
+	if !ok || !chirp.DeletedAt.Valid || chirp.PurgedAt.Valid {
+		return database.Chirp{}, sql.ErrNoRows
+	}
+	chirp.DeletedAt = sql.NullTime{}
+	chirp.UpdatedAt = now()
+	m.chirps[id] = chirp
+	return chirp, nil
+}
+
+// newest first, like the query
+func (m *Memory) ListUnpurgedUserChirps(ctx context.Context, userID uuid.UUID) ([]database.Chirp, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	chirps := []database.Chirp{}
+	for _, c := range m.chirps {
+		if c.UserID == userID && !c.PurgedAt.Valid {
+			chirps = append(chirps, c)
+		}
+	}
+	sort.Slice(chirps, func(i, j int) bool {
+		return chirpCompare(chirps[i], chirps[j].CreatedAt, chirps[j].ID) > 0
+	})
+	return chirps, nil
+}
+
+func (m *Memory) ReassignTombstones(ctx context.Context, arg database.ReassignTombstonesParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t := now()
+	for _, c := range m.chirps {
+		if c.UserID == arg.UserID && c.PurgedAt.Valid {
+			c.UserID = arg.NewUserID
+			c.UpdatedAt = t
+			m.chirps[c.ID] = c
+		}
+	}
+	return nil
+}
+
+func (m *Memory) SoftDeleteUserChirps(ctx context.Context, arg database.SoftDeleteUserChirpsParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t := now()
+	for _, c := range m.chirps {
+		if c.UserID == arg.UserID && !c.DeletedAt.Valid {
+			c.DeletedAt = arg.DeletedAt
+			c.UpdatedAt = t
+			m.chirps[c.ID] = c
+		}
+	}
+	return nil
+}
+
+// only the chirps deleted along with the user, the ones they deleted themselves stay deleted
+func (m *Memory) RestoreUserChirps(ctx context.Context, arg database.RestoreUserChirpsParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t := now()
+	for _, c := range m.chirps {
+		if c.UserID == arg.UserID && c.DeletedAt.Valid && c.DeletedAt.Time.Equal(arg.DeletedAt.Time) && !c.PurgedAt.Valid {
+			c.DeletedAt = sql.NullTime{}
+			c.UpdatedAt = t
+			m.chirps[c.ID] = c
+		}
+	}
+	return nil
+}
+
+// deleted before the cutoff and not purged yet, oldest deletion first
+func (m *Memory) ListPurgeableChirps(ctx context.Context, arg database.ListPurgeableChirpsParams) ([]database.Chirp, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	chirps := []database.Chirp{}
+	for _, c := range m.chirps {
+		if c.DeletedAt.Valid && c.DeletedAt.Time.Before(arg.DeletedBefore.Time) && !c.PurgedAt.Valid {
+			chirps = append(chirps, c)
+		}
+	}
+	sort.Slice(chirps, func(i, j int) bool {
+		return chirps[i].DeletedAt.Time.Before(chirps[j].DeletedAt.Time)
+	})
+	if len(chirps) > int(arg.PageLimit) {
+		chirps = chirps[:arg.PageLimit]
+	}
+	return chirps, nil
+}
+
+// compares chirps on (created_at, id), like the row comparisons in the pagination queries
+func chirpCompare(c database.Chirp, createdAt time.Time, id uuid.UUID) int {
+	if c.CreatedAt.Equal(createdAt) {
+		return strings.Compare(c.ID.String(), id.String())
+	}
+	if c.CreatedAt.Before(createdAt) {
+		return -1
+	}
+	return 1
+}
+
+// one page of the chirps that pass keep, oldest first or newest first
+func (m *Memory) pageChirps(keep func(database.Chirp) bool, desc bool, limit int32) []database.Chirp {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	chirps := []database.Chirp{}
+	for _, c := range m.chirps {
+		if keep(c) {
+			chirps = append(chirps, c)
+		}
+	}
+	sortChirps(chirps)
+	if desc {
+		slices.Reverse(chirps)
+	}
+	if len(chirps) > int(limit) {
+		chirps = chirps[:limit]
+	}
+	return chirps
+}
+
+// one entry on a timeline, shaped like the rows of the ListChirps queries
+// (RechirpedBy is uuid.Nil for plain chirps)
+type timelineRow struct {
+	Chirp database.Chirp
+	EntryID uuid.UUID
+	EntryAt time.Time
+	RechirpedBy uuid.UUID
+}
+
+// one page of chirps and rechirps mixed together, like the union in the ListChirps queries
+// only entries by authors that pass keep are included (everyone's, if it's nil)
+// with hide set, chirps by users with hide_chirps set are left out until their suspension or ban ends
+// the caller has to hold the lock
+func (m *Memory) pageTimeline(keep func(author uuid.UUID) bool, hide bool, start time.Time, startID uuid.UUID, desc bool, limit int32) []timelineRow {
+	if keep == nil {
+		keep = func(uuid.UUID) bool { return true }
+	}
+	hidden := func(c database.Chirp) bool {
+		u := m.users[c.UserID]
+		return hide && u.HideChirps && (!u.StatusUntil.Valid || u.StatusUntil.Time.After(now()))
+	}
+	rows := []timelineRow{}
+	for _, c := range m.chirps {
+		if !c.DeletedAt.Valid && keep(c.UserID) && !hidden(c) {
+			rows = append(rows, timelineRow{Chirp: c, EntryID: c.ID, EntryAt: c.CreatedAt})
+		}
+	}
+	for key, r := range m.rechirps {
+		c := m.chirps[key.ChirpID]
+		if !c.DeletedAt.Valid && keep(key.UserID) && !hidden(c) {
+			rows = append(rows, timelineRow{Chirp: c, EntryID: r.ID, EntryAt: r.CreatedAt, RechirpedBy: key.UserID})
+		}
+	}
+	// entries compare on (entry_at, entry_id), borrowing chirpCompare
+	entry := func(r timelineRow) database.Chirp {
+		return database.Chirp{CreatedAt: r.EntryAt, ID: r.EntryID}
+	}
+	rows = slices.DeleteFunc(rows, func(r timelineRow) bool {
+		cmp := chirpCompare(entry(r), start, startID)
+		return (desc && cmp >= 0) || (!desc && cmp <= 0)
+	})
+	sort.Slice(rows, func(i, j int) bool {
+		cmp := chirpCompare(entry(rows[i]), rows[j].EntryAt, rows[j].EntryID)
+		return (desc && cmp > 0) || (!desc && cmp < 0)
+	})
+	if len(rows) > int(limit) {
+		rows = rows[:limit]
+	}
+	return rows
+}
+
+func (m *Memory) ListChirpsAsc(ctx context.Context, arg database.ListChirpsAscParams) ([]database.ListChirpsAscRow, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	rows := []database.ListChirpsAscRow{}
+	for _, r := range m.pageTimeline(nil, true, arg.AfterCreatedAt, arg.AfterID, false, arg.PageLimit) {
+		rows = append(rows, database.ListChirpsAscRow(r))
+	}
+	return rows, nil
+}
+
+func (m *Memory) ListChirpsDesc(ctx context.Context, arg database.ListChirpsDescParams) ([]database.ListChirpsDescRow, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	rows := []database.ListChirpsDescRow{}
+	for _, r := range m.pageTimeline(nil, true, arg.BeforeCreatedAt, arg.BeforeID, true, arg.PageLimit) {
+		rows = append(rows, database.ListChirpsDescRow(r))
+	}
+	return rows, nil
+}
+
+func (m *Memory) ListChirpsByUserAsc(ctx context.Context, arg database.ListChirpsByUserAscParams) ([]database.ListChirpsByUserAscRow, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	rows := []database.ListChirpsByUserAscRow{}
+	author := func(id uuid.UUID) bool { return id == arg.UserID }
+	for _, r := range m.pageTimeline(author, true, arg.AfterCreatedAt, arg.AfterID, false, arg.PageLimit) {
+		rows = append(rows, database.ListChirpsByUserAscRow(r))
+	}
+	return rows, nil
+}
+
+func (m *Memory) ListChirpsByUserDesc(ctx context.Context, arg database.ListChirpsByUserDescParams) ([]database.ListChirpsByUserDescRow, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	rows := []database.ListChirpsByUserDescRow{}
+	author := func(id uuid.UUID) bool { return id == arg.UserID }
+	for _, r := range m.pageTimeline(author, true, arg.BeforeCreatedAt, arg.BeforeID, true, arg.PageLimit) {
+		rows = append(rows, database.ListChirpsByUserDescRow(r))
+	}
+	return rows, nil
+}
+
+// a substring scan over every chirp, see search.go
+func (m *Memory) SearchChirps(ctx context.Context, arg database.SearchChirpsParams) ([]database.SearchChirpsRow, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	chirps := []database.Chirp{}
+	for _, c := range m.chirps {
+		if !c.DeletedAt.Valid {
+			chirps = append(chirps, c)
+		}
+	}
+	return searchChirps(chirps, arg), nil
+}
+
+// tombstones are included, so threads keep their shape
+// tombstones can't be edited, so they come back as sql.ErrNoRows like a missing chirp does
+func (m *Memory) EditChirp(ctx context.Context, arg database.EditChirpParams) (database.Chirp, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	chirp, ok := m.chirps[arg.ID]
+	if !ok || chirp.DeletedAt.Valid {
+		return database.Chirp{}, sql.ErrNoRows
+	}
+	t := now()
+	chirp.Body = arg.Body
+	chirp.UpdatedAt = t
+	chirp.EditedAt = sql.NullTime{Time: t, Valid: true}
+	m.chirps[arg.ID] = chirp
+	return chirp, nil
+}
+
+func (m *Memory) CreateChirpRevision(ctx context.Context, arg database.CreateChirpRevisionParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.chirps[arg.ChirpID]; !ok {
+		return ErrForeignKeyViolation
+	}
+	revision := database.ChirpRevision{
+		ID: uuid.New(),
+		ChirpID: arg.ChirpID,
+		Body: arg.Body,
+		CreatedAt: arg.CreatedAt,
+		ReplacedAt: now(),
+	}
+	m.revisions[revision.ID] = revision
+	return nil
+}
+
+// oldest first
+func (m *Memory) ListChirpRevisions(ctx context.Context, chirpID uuid.UUID) ([]database.ChirpRevision, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	rows := []database.ChirpRevision{}
+	for _, r := range m.revisions {
+		if r.ChirpID == chirpID {
+			rows = append(rows, r)
+		}
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].ReplacedAt.Equal(rows[j].ReplacedAt) {
+			return rows[i].ID.String() < rows[j].ID.String()
+		}
+		return rows[i].ReplacedAt.Before(rows[j].ReplacedAt)
+	})
+	return rows, nil
+}
+
+func (m *Memory) DeleteChirpRevisions(ctx context.Context, chirpID uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, r := range m.revisions {
+		if r.ChirpID == chirpID {
+			delete(m.revisions, r.ID)
+		}
+	}
+	return nil
+}
+
+func (m *Memory) ListReplies(ctx context.Context, arg database.ListRepliesParams) ([]database.Chirp, error) {
+	return m.pageChirps(func(c database.Chirp) bool {
+		return c.InReplyTo.Valid && arg.InReplyTo.Valid && c.InReplyTo.UUID == arg.InReplyTo.UUID &&
+			chirpCompare(c, arg.AfterCreatedAt, arg.AfterID) > 0
+	}, false, arg.PageLimit), nil
+}
+
+func (m *Memory) GetRepliesToChirps(ctx context.Context, arg database.GetRepliesToChirpsParams) ([]database.Chirp, error) {
+	return m.pageChirps(func(c database.Chirp) bool {
+		return c.InReplyTo.Valid && slices.Contains(arg.ParentIds, c.InReplyTo.UUID)
+	}, false, arg.PageLimit), nil
+}
+
+// only chirps that actually have replies get a row, like the GROUP BY
+func (m *Memory) CountReplies(ctx context.Context, ids []uuid.UUID) ([]database.CountRepliesRow, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	counts := map[uuid.UUID]int64{}
+	for _, c := range m.chirps {
+		if c.InReplyTo.Valid && !c.DeletedAt.Valid && slices.Contains(ids, c.InReplyTo.UUID) {
+			counts[c.InReplyTo.UUID]++
+		}
+	}
+	rows := []database.CountRepliesRow{}
+	for id, count := range counts {
+		rows = append(rows, database.CountRepliesRow{InReplyTo: uuid.NullUUID{UUID: id, Valid: true}, ReplyCount: count})
+	}
+	return rows, nil
+}
+
+// liking something twice is a no-op, like ON CONFLICT DO NOTHING
+func (m *Memory) LikeChirp(ctx context.Context, arg database.LikeChirpParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.users[arg.UserID]; !ok {
+		return ErrForeignKeyViolation
+	}
+	if _, ok := m.chirps[arg.ChirpID]; !ok {
+		return ErrForeignKeyViolation
+	}
+	key := like{UserID: arg.UserID, ChirpID: arg.ChirpID}
+	if _, ok := m.likes[key]; !ok {
+		m.likes[key] = now()
+	}
+	return nil
+}
+
+func (m *Memory) UnlikeChirp(ctx context.Context, arg database.UnlikeChirpParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.likes, like{UserID: arg.UserID, ChirpID: arg.ChirpID})
+	return nil
+}
+
+func (m *Memory) CountLikes(ctx context.Context, ids []uuid.UUID) ([]database.CountLikesRow, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	counts := map[uuid.UUID]int64{}
+	for l := range m.likes {
+		if slices.Contains(ids, l.ChirpID) {
+			counts[l.ChirpID]++
+		}
+	}
+	rows := []database.CountLikesRow{}
+	for id, count := range counts {
+		rows = append(rows, database.CountLikesRow{ChirpID: id, LikeCount: count})
+	}
+	return rows, nil
+}
+
+func (m *Memory) GetLikedByUser(ctx context.Context, arg database.GetLikedByUserParams) ([]uuid.UUID, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	liked := []uuid.UUID{}
+	for _, id := range arg.ChirpIds {
+		if _, ok := m.likes[like{UserID: arg.UserID, ChirpID: id}]; ok {
+			liked = append(liked, id)
+		}
+	}
+	return liked, nil
+}
+
+// newest likes first
+func (m *Memory) ListUserLikes(ctx context.Context, arg database.ListUserLikesParams) ([]database.ListUserLikesRow, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	rows := []database.ListUserLikesRow{}
+	for l, likedAt := range m.likes {
+		chirp, ok := m.chirps[l.ChirpID]
+		if l.UserID != arg.UserID || !ok || chirp.DeletedAt.Valid {
+			continue
+		}
+		// compare on (liked_at, chirp_id) by borrowing chirpCompare
+		if chirpCompare(database.Chirp{CreatedAt: likedAt, ID: l.ChirpID}, arg.BeforeLikedAt, arg.BeforeID) >= 0 {
+			continue
+		}
+		rows = append(rows, database.ListUserLikesRow{Chirp: chirp, LikedAt: likedAt})
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		return chirpCompare(database.Chirp{CreatedAt: rows[i].LikedAt, ID: rows[i].Chirp.ID}, rows[j].LikedAt, rows[j].Chirp.ID) > 0
+	})
+	if len(rows) > int(arg.PageLimit) {
+		rows = rows[:arg.PageLimit]
+	}
+	return rows, nil
+}
+
+// rechirping something twice is a no-op, like ON CONFLICT DO NOTHING
+func (m *Memory) Rechirp(ctx context.Context, arg database.RechirpParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.users[arg.UserID]; !ok {
+		return ErrForeignKeyViolation
+	}
+	if _, ok := m.chirps[arg.ChirpID]; !ok {
+		return ErrForeignKeyViolation
+	}
+	key := like{UserID: arg.UserID, ChirpID: arg.ChirpID}
+	if _, ok := m.rechirps[key]; !ok {
+		m.rechirps[key] = rechirp{ID: uuid.New(), CreatedAt: now()}
+	}
+	return nil
+}
+
+func (m *Memory) UndoRechirp(ctx context.Context, arg database.UndoRechirpParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.rechirps, like{UserID: arg.UserID, ChirpID: arg.ChirpID})
+	return nil
+}
+
+func (m *Memory) CountRechirps(ctx context.Context, ids []uuid.UUID) ([]database.CountRechirpsRow, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	counts := map[uuid.UUID]int64{}
+	for r := range m.rechirps {
+		if slices.Contains(ids, r.ChirpID) {
+			counts[r.ChirpID]++
+		}
+	}
+	rows := []database.CountRechirpsRow{}
+	for id, count := range counts {
+		rows = append(rows, database.CountRechirpsRow{ChirpID: id, RechirpCount: count})
+	}
+	return rows, nil
+}
+
+// tagging a chirp twice is a no-op, like ON CONFLICT DO NOTHING
+func (m *Memory) TagChirp(ctx context.Context, arg database.TagChirpParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.chirps[arg.ChirpID]; !ok {
+		return ErrForeignKeyViolation
+	}
+	key := hashtag{ChirpID: arg.ChirpID, Tag: arg.Tag}
+	if _, ok := m.hashtags[key]; !ok {
+		m.hashtags[key] = arg.CreatedAt
+	}
+	return nil
+}
+
+func (m *Memory) ClearHashtags(ctx context.Context, chirpID uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for h := range m.hashtags {
+		if h.ChirpID == chirpID {
+			delete(m.hashtags, h)
+		}
+	}
+	return nil
+}
+
+// newest first, paged on the tag's copy of created_at
+func (m *Memory) ListHashtagChirps(ctx context.Context, arg database.ListHashtagChirpsParams) ([]database.Chirp, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	chirps := []database.Chirp{}
+	for h, createdAt := range m.hashtags {
+		chirp := m.chirps[h.ChirpID]
+		if h.Tag != arg.Tag || chirp.DeletedAt.Valid {
+			continue
+		}
+		if chirpCompare(database.Chirp{CreatedAt: createdAt, ID: h.ChirpID}, arg.BeforeCreatedAt, arg.BeforeID) < 0 {
+			chirps = append(chirps, chirp)
+		}
+	}
+	sortChirps(chirps)
+	slices.Reverse(chirps)
+	if len(chirps) > int(arg.PageLimit) {
+		chirps = chirps[:arg.PageLimit]
+	}
+	return chirps, nil
+}
+
+// scored in Go, see trends.go
+func (m *Memory) TrendingHashtags(ctx context.Context, arg database.TrendingHashtagsParams) ([]database.TrendingHashtagsRow, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	uses := []hashtagUse{}
+	for h, createdAt := range m.hashtags {
+		if !m.chirps[h.ChirpID].DeletedAt.Valid {
+			uses = append(uses, hashtagUse{Tag: h.Tag, CreatedAt: createdAt})
+		}
+	}
+	return scoreTrends(uses, arg), nil
+}
+
+// mentions have the same key as a like
+func (m *Memory) MentionUser(ctx context.Context, arg database.MentionUserParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.users[arg.UserID]; !ok {
+		return ErrForeignKeyViolation
+	}
+	if _, ok := m.chirps[arg.ChirpID]; !ok {
+		return ErrForeignKeyViolation
+	}
+	m.mentions[like{UserID: arg.UserID, ChirpID: arg.ChirpID}] = true
+	return nil
+}
+
+func (m *Memory) ClearMentions(ctx context.Context, chirpID uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for mention := range m.mentions {
+		if mention.ChirpID == chirpID {
+			delete(m.mentions, mention)
+		}
+	}
+	return nil
+}
+
+func (m *Memory) GetMentionsForChirps(ctx context.Context, chirpIds []uuid.UUID) ([]database.GetMentionsForChirpsRow, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	rows := []database.GetMentionsForChirpsRow{}
+	for mention := range m.mentions {
+		if slices.Contains(chirpIds, mention.ChirpID) {
+			rows = append(rows, database.GetMentionsForChirpsRow{
+				ChirpID: mention.ChirpID,
+				UserID: mention.UserID,
+				Handle: m.users[mention.UserID].Handle,
+			})
+		}
+	}
+	return rows, nil
+}
+
+// a second notification for the same thing is a no-op, like the unique index with ON CONFLICT DO NOTHING
+func (m *Memory) CreateNotification(ctx context.Context, arg database.CreateNotificationParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.users[arg.UserID]; !ok {
+		return ErrForeignKeyViolation
+	}
+	if _, ok := m.users[arg.ActorID]; !ok {
+		return ErrForeignKeyViolation
+	}
+	if _, ok := m.chirps[arg.ChirpID.UUID]; arg.ChirpID.Valid && !ok {
+		return ErrForeignKeyViolation
+	}
+	for _, n := range m.notifications {
+		if n.UserID == arg.UserID && n.ActorID == arg.ActorID && n.Kind == arg.Kind && n.ChirpID == arg.ChirpID && !n.ReportID.Valid {
+			return nil
+		}
+	}
+	n := database.Notification{
+		ID: uuid.New(),
+		CreatedAt: now(),
+		UserID: arg.UserID,
+		ActorID: arg.ActorID,
+		Kind: arg.Kind,
+		ChirpID: arg.ChirpID,
+	}
+	m.notifications[n.ID] = n
+	return nil
+}
+
+func (m *Memory) CreateReportNotification(ctx context.Context, arg database.CreateReportNotificationParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.users[arg.UserID]; !ok {
+		return ErrForeignKeyViolation
+	}
+	if _, ok := m.users[arg.ActorID]; !ok {
+		return ErrForeignKeyViolation
+	}
+	if _, ok := m.reports[arg.ReportID.UUID]; arg.ReportID.Valid && !ok {
+		return ErrForeignKeyViolation
+	}
+	for _, n := range m.notifications {
+		if n.UserID == arg.UserID && n.ActorID == arg.ActorID && n.Kind == arg.Kind && !n.ChirpID.Valid && n.ReportID == arg.ReportID {
+			return nil
+		}
+	}
+	n := database.Notification{
+		ID: uuid.New(),
+		CreatedAt: now(),
+		UserID: arg.UserID,
+		ActorID: arg.ActorID,
+		Kind: arg.Kind,
+		ReportID: arg.ReportID,
+	}
+	m.notifications[n.ID] = n
+	return nil
+}
+
+// newest first, leaving out the ones about tombstones
+func (m *Memory) pageNotifications(userID uuid.UUID, unreadOnly bool, before time.Time, beforeID uuid.UUID, limit int32) []database.Notification {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	rows := []database.Notification{}
+	for _, n := range m.notifications {
+		if n.UserID != userID || (unreadOnly && n.ReadAt.Valid) {
+			continue
+		}
+		if n.ChirpID.Valid && m.chirps[n.ChirpID.UUID].DeletedAt.Valid {
+			continue
+		}
+		// compare on (created_at, id) by borrowing chirpCompare
+		if chirpCompare(database.Chirp{CreatedAt: n.CreatedAt, ID: n.ID}, before, beforeID) < 0 {
+			rows = append(rows, n)
+		}
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		return chirpCompare(database.Chirp{CreatedAt: rows[i].CreatedAt, ID: rows[i].ID}, rows[j].CreatedAt, rows[j].ID) > 0
+	})
+	if len(rows) > int(limit) {
+		rows = rows[:limit]
+	}
+	return rows
+}
+
+func (m *Memory) ListNotifications(ctx context.Context, arg database.ListNotificationsParams) ([]database.Notification, error) {
+	return m.pageNotifications(arg.UserID, false, arg.BeforeCreatedAt, arg.BeforeID, arg.PageLimit), nil
+}
+
+func (m *Memory) ListUnreadNotifications(ctx context.Context, arg database.ListUnreadNotificationsParams) ([]database.Notification, error) {
+	return m.pageNotifications(arg.UserID, true, arg.BeforeCreatedAt, arg.BeforeID, arg.PageLimit), nil
+}
+
+// other people's notifications are left alone, even if their ids are passed in
+func (m *Memory) MarkNotificationsRead(ctx context.Context, arg database.MarkNotificationsReadParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t := now()
+	for _, id := range arg.Ids {
+		n, ok := m.notifications[id]
+		if ok && n.UserID == arg.UserID && !n.ReadAt.Valid {
+			n.ReadAt = sql.NullTime{Time: t, Valid: true}
+			m.notifications[id] = n
+		}
+	}
+	return nil
+}
+
+func (m *Memory) MarkAllNotificationsRead(ctx context.Context, userID uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t := now()
+	for id, n := range m.notifications {
+		if n.UserID == userID && !n.ReadAt.Valid {
+			n.ReadAt = sql.NullTime{Time: t, Valid: true}
+			m.notifications[id] = n
+		}
+	}
+	return nil
+}
+
+// following someone twice is a no-op, like ON CONFLICT DO NOTHING
+func (m *Memory) FollowUser(ctx context.Context, arg database.FollowUserParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.users[arg.FollowerID]; !ok {
+		return ErrForeignKeyViolation
+	}
+	if _, ok := m.users[arg.FolloweeID]; !ok {
+		return ErrForeignKeyViolation
+	}
+	key := follow{FollowerID: arg.FollowerID, FolloweeID: arg.FolloweeID}
+	if _, ok := m.follows[key]; !ok {
+		m.follows[key] = now()
+	}
+	return nil
+}
+
+func (m *Memory) GetFollow(ctx context.Context, arg database.GetFollowParams) (database.Follow, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	key := follow{FollowerID: arg.FollowerID, FolloweeID: arg.FolloweeID}
+	createdAt, ok := m.follows[key]
+	if !ok {
+		return database.Follow{}, sql.ErrNoRows
+	}
+	return database.Follow{FollowerID: key.FollowerID, FolloweeID: key.FolloweeID, CreatedAt: createdAt}, nil
+}
+
+func (m *Memory) UnfollowUser(ctx context.Context, arg database.UnfollowUserParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.follows, follow{FollowerID: arg.FollowerID, FolloweeID: arg.FolloweeID})
+	return nil
+}
+
+// one page of the other side of userID's follows, newest first
+// followers says which side userID is on
+func (m *Memory) pageFollows(userID uuid.UUID, followers bool, before time.Time, beforeID uuid.UUID, limit int32) []database.ListFollowersRow {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	rows := []database.ListFollowersRow{}
+	for f, followedAt := range m.follows {
+		self, other := f.FollowerID, f.FolloweeID
+		if followers {
+			self, other = f.FolloweeID, f.FollowerID
+		}
+		if self != userID || m.users[other].DeletedAt.Valid {
+			continue
+		}
+		// compare on (created_at, user_id) by borrowing chirpCompare
+		if chirpCompare(database.Chirp{CreatedAt: followedAt, ID: other}, before, beforeID) >= 0 {
+			continue
+		}
+		rows = append(rows, database.ListFollowersRow{UserID: other, FollowedAt: followedAt})
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		return chirpCompare(database.Chirp{CreatedAt: rows[i].FollowedAt, ID: rows[i].UserID}, rows[j].FollowedAt, rows[j].UserID) > 0
+	})
+	if len(rows) > int(limit) {
+		rows = rows[:limit]
+	}
+	return rows
+}
+
+func (m *Memory) ListFollowers(ctx context.Context, arg database.ListFollowersParams) ([]database.ListFollowersRow, error) {
+	return m.pageFollows(arg.UserID, true, arg.BeforeFollowedAt, arg.BeforeID, arg.PageLimit), nil
+}
+
+func (m *Memory) ListFollowing(ctx context.Context, arg database.ListFollowingParams) ([]database.ListFollowingRow, error) {
+	rows := []database.ListFollowingRow{}
+	for _, r := range m.pageFollows(arg.UserID, false, arg.BeforeFollowedAt, arg.BeforeID, arg.PageLimit) {
+		rows = append(rows, database.ListFollowingRow(r))
+	}
+	return rows, nil
+}
+
+// the user's own entries plus everyone they follow
+func (m *Memory) ListTimeline(ctx context.Context, arg database.ListTimelineParams) ([]database.ListTimelineRow, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	rows := []database.ListTimelineRow{}
+	authors := func(id uuid.UUID) bool {
+		_, following := m.follows[follow{FollowerID: arg.UserID, FolloweeID: id}]
+		return id == arg.UserID || following
+	}
+	for _, r := range m.pageTimeline(authors, false, arg.BeforeCreatedAt, arg.BeforeID, true, arg.PageLimit) {
+		rows = append(rows, database.ListTimelineRow(r))
+	}
+	return rows, nil
+}
+
+func (m *Memory) CreateConversation(ctx context.Context, isGroup bool) (database.Conversation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t := now()
+	conversation := database.Conversation{
+		ID: uuid.New(),
+		CreatedAt: t,
+		UpdatedAt: t,
+		IsGroup: isGroup,
+	}
+	m.conversations[conversation.ID] = conversation
+	return conversation, nil
+}
+
+func (m *Memory) GetConversation(ctx context.Context, id uuid.UUID) (database.Conversation, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	conversation, ok := m.conversations[id]
+	if !ok {
+		return database.Conversation{}, sql.ErrNoRows
+	}
+	return conversation, nil
+}
+
+// the oldest one-to-one conversation both users are still in
+func (m *Memory) FindDirectConversation(ctx context.Context, arg database.FindDirectConversationParams) (database.Conversation, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	found := database.Conversation{}
+	for _, c := range m.conversations {
+		if c.IsGroup || (found.ID != uuid.Nil && !c.CreatedAt.Before(found.CreatedAt)) {
+			continue
+		}
+		a, aOK := m.participants[participant{ConversationID: c.ID, UserID: arg.UserID}]
+		b, bOK := m.participants[participant{ConversationID: c.ID, UserID: arg.OtherID}]
+		if aOK && bOK && !a.LeftAt.Valid && !b.LeftAt.Valid {
+			found = c
+		}
+	}
+	if found.ID == uuid.Nil {
+		return database.Conversation{}, sql.ErrNoRows
+	}
+	return found, nil
+}
+
+func (m *Memory) TouchConversation(ctx context.Context, arg database.TouchConversationParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	conversation, ok := m.conversations[arg.ID]
+	if !ok {
+		return nil
+	}
+	conversation.UpdatedAt = arg.UpdatedAt
+	m.conversations[arg.ID] = conversation
+	return nil
+}
+
+// adding someone twice is a no-op, like ON CONFLICT DO NOTHING
+func (m *Memory) AddParticipant(ctx context.Context, arg database.AddParticipantParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.conversations[arg.ConversationID]; !ok {
+		return ErrForeignKeyViolation
+	}
+	if _, ok := m.users[arg.UserID]; !ok {
+		return ErrForeignKeyViolation
+	}
+	key := participant{ConversationID: arg.ConversationID, UserID: arg.UserID}
+	if _, ok := m.participants[key]; !ok {
+		m.participants[key] = database.ConversationParticipant{ConversationID: arg.ConversationID, UserID: arg.UserID, JoinedAt: now()}
+	}
+	return nil
+}
+
+func (m *Memory) GetParticipant(ctx context.Context, arg database.GetParticipantParams) (database.ConversationParticipant, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	p, ok := m.participants[participant(arg)]
+	if !ok {
+		return database.ConversationParticipant{}, sql.ErrNoRows
+	}
+	return p, nil
+}
+
+// in the order they joined
+func (m *Memory) ListParticipants(ctx context.Context, conversationIds []uuid.UUID) ([]database.ConversationParticipant, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	rows := []database.ConversationParticipant{}
+	for key, p := range m.participants {
+		if slices.Contains(conversationIds, key.ConversationID) {
+			rows = append(rows, p)
+		}
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].JoinedAt.Equal(rows[j].JoinedAt) {
+			return rows[i].UserID.String() < rows[j].UserID.String()
+		}
+		return rows[i].JoinedAt.Before(rows[j].JoinedAt)
+	})
+	return rows, nil
+}
+
+func (m *Memory) LeaveConversation(ctx context.Context, arg database.LeaveConversationParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := participant{ConversationID: arg.ConversationID, UserID: arg.UserID}
+	p, ok := m.participants[key]
+	if !ok || p.LeftAt.Valid {
+		return nil
+	}
+	p.LeftAt = sql.NullTime{Time: now(), Valid: true}
+	m.participants[key] = p
+	return nil
+}
+
+func (m *Memory) MarkConversationRead(ctx context.Context, arg database.MarkConversationReadParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := participant{ConversationID: arg.ConversationID, UserID: arg.UserID}
+	p, ok := m.participants[key]
+	if !ok {
+		return nil
+	}
+	p.LastReadAt = arg.LastReadAt
+	m.participants[key] = p
+	return nil
+}
+
+// most recently active first, with the unread counts worked out like the subquery does
+func (m *Memory) ListConversations(ctx context.Context, arg database.ListConversationsParams) ([]database.ListConversationsRow, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	rows := []database.ListConversationsRow{}
+	for key, p := range m.participants {
+		if key.UserID != arg.UserID || p.LeftAt.Valid {
+			continue
+		}
+		c := m.conversations[key.ConversationID]
+		// compare on (updated_at, id) by borrowing chirpCompare
+		if chirpCompare(database.Chirp{CreatedAt: c.UpdatedAt, ID: c.ID}, arg.BeforeUpdatedAt, arg.BeforeID) >= 0 {
+			continue
+		}
+		unread := int64(0)
+		for _, msg := range m.messages {
+			if msg.ConversationID == c.ID && msg.SenderID != arg.UserID && (!p.LastReadAt.Valid || msg.CreatedAt.After(p.LastReadAt.Time)) {
+				unread++
+			}
+		}
+		rows = append(rows, database.ListConversationsRow{Conversation: c, UnreadCount: unread})
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		return chirpCompare(database.Chirp{CreatedAt: rows[i].Conversation.UpdatedAt, ID: rows[i].Conversation.ID}, rows[j].Conversation.UpdatedAt, rows[j].Conversation.ID) > 0
+	})
+	if len(rows) > int(arg.PageLimit) {
+		rows = rows[:arg.PageLimit]
+	}
+	return rows, nil
+}
+
+func (m *Memory) CreateMessage(ctx context.Context, arg database.CreateMessageParams) (database.Message, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.conversations[arg.ConversationID]; !ok {
+		return database.Message{}, ErrForeignKeyViolation
+	}
+	if _, ok := m.users[arg.SenderID]; !ok {
+		return database.Message{}, ErrForeignKeyViolation
+	}
+	message := database.Message{
+		ID: uuid.New(),
+		CreatedAt: now(),
+		ConversationID: arg.ConversationID,
+		SenderID: arg.SenderID,
+		Body: arg.Body,
+	}
+	m.messages[message.ID] = message
+	return message, nil
+}
+
+// newest first
+func (m *Memory) ListMessages(ctx context.Context, arg database.ListMessagesParams) ([]database.Message, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	rows := []database.Message{}
+	for _, msg := range m.messages {
+		if msg.ConversationID != arg.ConversationID {
+			continue
+		}
+		if chirpCompare(database.Chirp{CreatedAt: msg.CreatedAt, ID: msg.ID}, arg.BeforeCreatedAt, arg.BeforeID) < 0 {
+			rows = append(rows, msg)
+		}
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		return chirpCompare(database.Chirp{CreatedAt: rows[i].CreatedAt, ID: rows[i].ID}, rows[j].CreatedAt, rows[j].ID) > 0
+	})
+	if len(rows) > int(arg.PageLimit) {
+		rows = rows[:arg.PageLimit]
+	}
+	return rows, nil
+}
+
+func (m *Memory) CreateMediaUpload(ctx context.Context, arg database.CreateMediaUploadParams) (database.MediaUpload, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.users[arg.UserID]; !ok {
+		return database.MediaUpload{}, ErrForeignKeyViolation
+	}
+	media := database.MediaUpload{
+		ID: uuid.New(),
+		CreatedAt: now(),
+		UserID: arg.UserID,
+		BlobKey: arg.BlobKey,
+		ContentType: arg.ContentType,
+		Size: arg.Size,
+		Width: arg.Width,
+		Height: arg.Height,
+		Blurhash: arg.Blurhash,
+		ThumbnailKey: arg.ThumbnailKey,
+	}
+	m.media[media.ID] = media
+	return media, nil
+}
+
+func (m *Memory) GetMediaUpload(ctx context.Context, id uuid.UUID) (database.MediaUpload, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	media, ok := m.media[id]
+	if !ok {
+		return database.MediaUpload{}, sql.ErrNoRows
+	}
+	return media, nil
+}
+
+// only claims uploads that aren't already on a chirp, returning how many it did (0 or 1)
+func (m *Memory) AttachMediaUpload(ctx context.Context, arg database.AttachMediaUploadParams) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	media, ok := m.media[arg.ID]
+	if !ok || media.ChirpID.Valid {
+		return 0, nil
+	}
+	if _, ok := m.chirps[arg.ChirpID.UUID]; arg.ChirpID.Valid && !ok {
+		return 0, ErrForeignKeyViolation
+	}
+	media.ChirpID = arg.ChirpID
+	media.Position = arg.Position
+	m.media[arg.ID] = media
+	return 1, nil
+}
+
+func (m *Memory) GetMediaForChirps(ctx context.Context, chirpIds []uuid.UUID) ([]database.MediaUpload, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	rows := []database.MediaUpload{}
+	for _, media := range m.media {
+		if media.ChirpID.Valid && slices.Contains(chirpIds, media.ChirpID.UUID) {
+			rows = append(rows, media)
+		}
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].ChirpID.UUID == rows[j].ChirpID.UUID {
+			return rows[i].Position < rows[j].Position
+		}
+		return rows[i].ChirpID.UUID.String() < rows[j].ChirpID.UUID.String()
+	})
+	return rows, nil
+}
+
+// unattached uploads, or ones whose chirp has been purged, oldest first
+func (m *Memory) ListOrphanedMedia(ctx context.Context, arg database.ListOrphanedMediaParams) ([]database.MediaUpload, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	rows := []database.MediaUpload{}
+	for _, media := range m.media {
+		if !media.CreatedAt.Before(arg.CreatedBefore) {
+			continue
+		}
+		if media.ChirpID.Valid && !m.chirps[media.ChirpID.UUID].PurgedAt.Valid {
+			continue
+		}
+		if m.isHeldMedia(media.ID) {
+			continue
+		}
+		rows = append(rows, media)
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		return rows[i].CreatedAt.Before(rows[j].CreatedAt)
+	})
+	if len(rows) > int(arg.PageLimit) {
+		rows = rows[:arg.PageLimit]
+	}
+	return rows, nil
+}
+
+func (m *Memory) ListUserMedia(ctx context.Context, userID uuid.UUID) ([]database.MediaUpload, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	rows := []database.MediaUpload{}
+	for _, media := range m.media {
+		if media.UserID == userID {
+			rows = append(rows, media)
+		}
+	}
+	return rows, nil
+}
+
+func (m *Memory) DeleteMediaUpload(ctx context.Context, id uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.deleteMediaUpload(id)
+	return nil
+}
+
+// the caller holds the lock
+func (m *Memory) deleteMediaUpload(id uuid.UUID) {
+	delete(m.media, id)
+	for key := range m.heldMedia {
+		if key.MediaID == id {
+			delete(m.heldMedia, key)
+		}
+	}
+}
+
+func (m *Memory) UpsertFilterList(ctx context.Context, arg database.UpsertFilterListParams) (database.FilterList, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t := now()
+	list, ok := m.filterLists[arg.Name]
+	if !ok {
+		list = database.FilterList{Name: arg.Name, CreatedAt: t}
+	}
+	list.Action = arg.Action
+	list.UpdatedAt = t
+	m.filterLists[arg.Name] = list
+	return list, nil
+}
+
+func (m *Memory) GetFilterList(ctx context.Context, name string) (database.FilterList, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	list, ok := m.filterLists[name]
+	if !ok {
+		return database.FilterList{}, sql.ErrNoRows
+	}
+	return list, nil
+}
+
+func (m *Memory) ListFilterLists(ctx context.Context) ([]database.FilterList, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	lists := []database.FilterList{}
+	for _, l := range m.filterLists {
+		lists = append(lists, l)
+	}
+	sort.Slice(lists, func(i, j int) bool {
+		return lists[i].Name < lists[j].Name
+	})
+	return lists, nil
+}
+
+// the list's words go with it
+func (m *Memory) DeleteFilterList(ctx context.Context, name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.filterLists, name)
+	for w := range m.filterWords {
+		if w.ListName == name {
+			delete(m.filterWords, w)
+		}
+	}
+	return nil
+}
+
+// adding a word twice is a no-op, like ON CONFLICT DO NOTHING
+func (m *Memory) AddFilterWord(ctx context.Context, arg database.AddFilterWordParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.filterLists[arg.ListName]; !ok {
+		return ErrForeignKeyViolation
+	}
+	key := filterWord{ListName: arg.ListName, Word: arg.Word}
+	if _, ok := m.filterWords[key]; !ok {
+		m.filterWords[key] = now()
+	}
+	return nil
+}
+
+func (m *Memory) RemoveFilterWord(ctx context.Context, arg database.RemoveFilterWordParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.filterWords, filterWord(arg))
+	return nil
+}
+
+func (m *Memory) ClearFilterWords(ctx context.Context, listName string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for w := range m.filterWords {
+		if w.ListName == listName {
+			delete(m.filterWords, w)
+		}
+	}
+	return nil
+}
+
+// sorted by list, then word
+func (m *Memory) ListFilterWords(ctx context.Context) ([]database.FilterWord, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	words := []database.FilterWord{}
+	for w, createdAt := range m.filterWords {
+		words = append(words, database.FilterWord{ListName: w.ListName, Word: w.Word, CreatedAt: createdAt})
+	}
+	sort.Slice(words, func(i, j int) bool {
+		if words[i].ListName == words[j].ListName {
+			return words[i].Word < words[j].Word
+		}
+		return words[i].ListName < words[j].ListName
+	})
+	return words, nil
+}
+
+func (m *Memory) CreateChirpFlag(ctx context.Context, arg database.CreateChirpFlagParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.chirps[arg.ChirpID]; !ok {
+		return ErrForeignKeyViolation
+	}
+	flag := database.ChirpFlag{
+		ID: uuid.New(),
+		CreatedAt: now(),
+		ChirpID: arg.ChirpID,
+		ListName: arg.ListName,
+		Word: arg.Word,
+	}
+	m.flags[flag.ID] = flag
+	return nil
+}
+
+// newest first
+func (m *Memory) ListChirpFlags(ctx context.Context, arg database.ListChirpFlagsParams) ([]database.ChirpFlag, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	rows := []database.ChirpFlag{}
+	for _, f := range m.flags {
+		// compare on (created_at, id) by borrowing chirpCompare
+		if chirpCompare(database.Chirp{CreatedAt: f.CreatedAt, ID: f.ID}, arg.BeforeCreatedAt, arg.BeforeID) < 0 {
+			rows = append(rows, f)
+		}
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		return chirpCompare(database.Chirp{CreatedAt: rows[i].CreatedAt, ID: rows[i].ID}, rows[j].CreatedAt, rows[j].ID) > 0
+	})
+	if len(rows) > int(arg.PageLimit) {
+		rows = rows[:arg.PageLimit]
+	}
+	return rows, nil
+}
+
+func (m *Memory) DeleteChirpFlag(ctx context.Context, id uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.flags, id)
+	return nil
+}
+
+func (m *Memory) CreateHeldChirp(ctx context.Context, arg database.CreateHeldChirpParams) (database.HeldChirp, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.users[arg.UserID]; !ok {
+		return database.HeldChirp{}, ErrForeignKeyViolation
+	}
+	if _, ok := m.chirps[arg.InReplyTo.UUID]; arg.InReplyTo.Valid && !ok {
+		return database.HeldChirp{}, ErrForeignKeyViolation
+	}
+	held := database.HeldChirp{
+		ID: uuid.New(),
+		CreatedAt: now(),
+		UserID: arg.UserID,
+		Body: arg.Body,
+		InReplyTo: arg.InReplyTo,
+		QuoteOf: arg.QuoteOf,
+		Stage: arg.Stage,
+		Reason: arg.Reason,
+	}
+	m.held[held.ID] = held
+	return held, nil
+}
+
+func (m *Memory) GetHeldChirp(ctx context.Context, id uuid.UUID) (database.HeldChirp, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	held, ok := m.held[id]
+	if !ok {
+		return database.HeldChirp{}, sql.ErrNoRows
+	}
+	return held, nil
+}
+
+func (m *Memory) ListHeldChirps(ctx context.Context, arg database.ListHeldChirpsParams) ([]database.HeldChirp, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	rows := []database.HeldChirp{}
+	for _, h := range m.held {
+		if chirpCompare(database.Chirp{CreatedAt: h.CreatedAt, ID: h.ID}, arg.AfterCreatedAt, arg.AfterID) > 0 {
+			rows = append(rows, h)
+		}
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		return chirpCompare(database.Chirp{CreatedAt: rows[i].CreatedAt, ID: rows[i].ID}, rows[j].CreatedAt, rows[j].ID) < 0
+	})
+	if len(rows) > int(arg.PageLimit) {
+		rows = rows[:arg.PageLimit]
+	}
+	return rows, nil
+}
+
+func (m *Memory) DeleteHeldChirp(ctx context.Context, id uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.deleteHeldChirp(id)
+	return nil
+}
+
+func (m *Memory) ClaimHeldChirp(ctx context.Context, id uuid.UUID) (database.HeldChirp, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	held, ok := m.held[id]
+	if !ok {
+		return database.HeldChirp{}, sql.ErrNoRows
+	}
+	m.deleteHeldChirp(id)
+	return held, nil
+}
+
+// the caller holds the lock
+func (m *Memory) deleteHeldChirp(id uuid.UUID) {
+	delete(m.held, id)
+	for key := range m.heldMedia {
+		if key.HeldChirpID == id {
+			delete(m.heldMedia, key)
+		}
+	}
+}
+
+func (m *Memory) AddHeldChirpMedia(ctx context.Context, arg database.AddHeldChirpMediaParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.held[arg.HeldChirpID]; !ok {
+		return ErrForeignKeyViolation
+	}
+	if _, ok := m.media[arg.MediaID]; !ok {
+		return ErrForeignKeyViolation
+	}
+	key := heldMedium{HeldChirpID: arg.HeldChirpID, MediaID: arg.MediaID}
+	if _, ok := m.heldMedia[key]; ok {
+		return ErrUniqueViolation
+	}
+	m.heldMedia[key] = arg.Position
+	return nil
+}
+
+func (m *Memory) ListHeldChirpMedia(ctx context.Context, heldChirpID uuid.UUID) ([]database.HeldChirpMedium, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	rows := []database.HeldChirpMedium{}
+	for key, position := range m.heldMedia {
+		if key.HeldChirpID == heldChirpID {
+			rows = append(rows, database.HeldChirpMedium{HeldChirpID: key.HeldChirpID, MediaID: key.MediaID, Position: position})
+		}
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		return rows[i].Position < rows[j].Position
+	})
+	return rows, nil
+}
+
+// whether an upload is waiting on a held chirp
+// the caller holds the lock
+func (m *Memory) isHeldMedia(id uuid.UUID) bool {
+	for key := range m.heldMedia {
+		if key.MediaID == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Memory) CreateReport(ctx context.Context, arg database.CreateReportParams) (database.Report, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.users[arg.ReporterID]; !ok {
+		return database.Report{}, ErrForeignKeyViolation
+	}
+	if _, ok := m.users[arg.UserID]; !ok {
+		return database.Report{}, ErrForeignKeyViolation
+	}
+	if _, ok := m.chirps[arg.ChirpID.UUID]; arg.ChirpID.Valid && !ok {
+		return database.Report{}, ErrForeignKeyViolation
+	}
+	t := now()
+	report := database.Report{
+		ID: uuid.New(),
+		CreatedAt: t,
+		UpdatedAt: t,
+		ReporterID: arg.ReporterID,
+		UserID: arg.UserID,
+		ChirpID: arg.ChirpID,
+		Category: arg.Category,
+		Details: arg.Details,
+		Status: "open",
+	}
+	m.reports[report.ID] = report
+	return report, nil
+}
+
+func (m *Memory) GetReport(ctx context.Context, id uuid.UUID) (database.Report, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	report, ok := m.reports[id]
+	if !ok {
+		return database.Report{}, sql.ErrNoRows
+	}
+	return report, nil
+}
+
+// the reports that pass keep, after the cursor and oldest first (or before it and newest first)
+func (m *Memory) pageReports(keep func(database.Report) bool, at time.Time, id uuid.UUID, desc bool, limit int32) []database.Report {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	rows := []database.Report{}
+	for _, r := range m.reports {
+		cmp := chirpCompare(database.Chirp{CreatedAt: r.CreatedAt, ID: r.ID}, at, id)
+		if keep(r) && ((desc && cmp < 0) || (!desc && cmp > 0)) {
+			rows = append(rows, r)
+		}
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		cmp := chirpCompare(database.Chirp{CreatedAt: rows[i].CreatedAt, ID: rows[i].ID}, rows[j].CreatedAt, rows[j].ID)
+		return (desc && cmp > 0) || (!desc && cmp < 0)
+	})
+	if len(rows) > int(limit) {
+		rows = rows[:limit]
+	}
+	return rows
+}
+
+func (m *Memory) ListReportsByStatus(ctx context.Context, arg database.ListReportsByStatusParams) ([]database.Report, error) {
+	keep := func(r database.Report) bool {
+		return r.Status == arg.Status
+	}
+	return m.pageReports(keep, arg.AfterCreatedAt, arg.AfterID, false, arg.PageLimit), nil
+}
+
+func (m *Memory) ListUnresolvedReports(ctx context.Context, arg database.ListUnresolvedReportsParams) ([]database.Report, error) {
+	keep := func(r database.Report) bool {
+		return r.Status != "resolved"
+	}
+	return m.pageReports(keep, arg.AfterCreatedAt, arg.AfterID, false, arg.PageLimit), nil
+}
+
+func (m *Memory) ListReportsByReporter(ctx context.Context, arg database.ListReportsByReporterParams) ([]database.Report, error) {
+	keep := func(r database.Report) bool {
+		return r.ReporterID == arg.ReporterID
+	}
+	return m.pageReports(keep, arg.BeforeCreatedAt, arg.BeforeID, true, arg.PageLimit), nil
+}
+
+// only open reports can be claimed
+func (m *Memory) ClaimReport(ctx context.Context, arg database.ClaimReportParams) (database.Report, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	report, ok := m.reports[arg.ID]
+	if !ok || report.Status != "open" {
+		return database.Report{}, sql.ErrNoRows
+	}
+	t := now()
+	report.Status = "claimed"
+	report.ClaimedBy = arg.ClaimedBy
+	report.ClaimedAt = sql.NullTime{Time: t, Valid: true}
+	report.UpdatedAt = t
+	m.reports[arg.ID] = report
+	return report, nil
+}
+
+func (m *Memory) ResolveReport(ctx context.Context, arg database.ResolveReportParams) (database.Report, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	report, ok := m.reports[arg.ID]
+	if !ok || report.Status == "resolved" {
+		return database.Report{}, sql.ErrNoRows
+	}
+	// nor can someone else's claim be
+	if report.Status == "claimed" && report.ClaimedBy != arg.ClaimedBy {
+		return database.Report{}, sql.ErrNoRows
+	}
+	t := now()
+	report.Status = "resolved"
+	report.ClaimedBy = arg.ClaimedBy
+	if !report.ClaimedAt.Valid {
+		report.ClaimedAt = sql.NullTime{Time: t, Valid: true}
+	}
+	report.ResolvedAt = sql.NullTime{Time: t, Valid: true}
+	report.UpdatedAt = t
+	report.Resolution = arg.Resolution
+	report.Note = arg.Note
+	m.reports[arg.ID] = report
+	return report, nil
+}
+
+// notifications about the report go with it
+// the caller holds the lock
+func (m *Memory) deleteReport(id uuid.UUID) {
+	delete(m.reports, id)
+	for _, n := range m.notifications {
+		if n.ReportID.Valid && n.ReportID.UUID == id {
+			delete(m.notifications, n.ID)
+		}
+	}
+}
+
+func (m *Memory) CreateRoleChange(ctx context.Context, arg database.CreateRoleChangeParams) (database.RoleChange, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.users[arg.UserID]; !ok {
+		return database.RoleChange{}, ErrForeignKeyViolation
+	}
+	if _, ok := m.users[arg.ChangedBy.UUID]; arg.ChangedBy.Valid && !ok {
+		return database.RoleChange{}, ErrForeignKeyViolation
+	}
+	change := database.RoleChange{
+		ID: uuid.New(),
+		CreatedAt: now(),
+		UserID: arg.UserID,
+		ChangedBy: arg.ChangedBy,
+		OldRole: arg.OldRole,
+		NewRole: arg.NewRole,
+		Reason: arg.Reason,
+	}
+	m.roleChanges[change.ID] = change
+	return change, nil
+}
+
+// the role changes keep lets through, newest first
+func (m *Memory) pageRoleChanges(keep func(database.RoleChange) bool, before time.Time, beforeID uuid.UUID, limit int32) []database.RoleChange {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	rows := []database.RoleChange{}
+	for _, c := range m.roleChanges {
+		if keep(c) && chirpCompare(database.Chirp{CreatedAt: c.CreatedAt, ID: c.ID}, before, beforeID) < 0 {
+			rows = append(rows, c)
+		}
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		return chirpCompare(database.Chirp{CreatedAt: rows[i].CreatedAt, ID: rows[i].ID}, rows[j].CreatedAt, rows[j].ID) > 0
+	})
+	if len(rows) > int(limit) {
+		rows = rows[:limit]
+	}
+	return rows
+}
+
+func (m *Memory) ListRoleChanges(ctx context.Context, arg database.ListRoleChangesParams) ([]database.RoleChange, error) {
+	keep := func(c database.RoleChange) bool {
+		return true
+	}
+	return m.pageRoleChanges(keep, arg.BeforeCreatedAt, arg.BeforeID, arg.PageLimit), nil
+}
+
+func (m *Memory) ListUserRoleChanges(ctx context.Context, arg database.ListUserRoleChangesParams) ([]database.RoleChange, error) {
+	keep := func(c database.RoleChange) bool {
+		return c.UserID == arg.UserID
+	}
+	return m.pageRoleChanges(keep, arg.BeforeCreatedAt, arg.BeforeID, arg.PageLimit), nil
+}
+
+func (m *Memory) CreateUser(ctx context.Context, arg database.CreateUserParams) (database.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.emails[arg.Email]; ok {
+		return database.User{}, ErrUniqueViolation
+	}
+	if _, ok := m.handles[arg.Handle.String]; arg.Handle.Valid && ok {
+		return database.User{}, ErrUniqueViolation
+	}
+	t := now()
+	user := database.User{
+		ID: uuid.New(),
+		CreatedAt: t,
+		UpdatedAt: t,
+		Email: arg.Email,
+		HashedPassword: arg.HashedPassword,
+		Handle: arg.Handle,
+		Role: "user",
+		Status: "active",
+	}
+	m.users[user.ID] = user
+	m.emails[user.Email] = user.ID
+	if user.Handle.Valid {
+		m.handles[user.Handle.String] = user.ID
+	}
+	return user, nil
+}
+
+func (m *Memory) GetUserByEmail(ctx context.Context, email string) (database.User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	id, ok := m.emails[email]
+	if !ok {
+		return database.User{}, sql.ErrNoRows
+	}
+	return m.users[id], nil
+}
+
+func (m *Memory) GetUserByID(ctx context.Context, id uuid.UUID) (database.User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	user, ok := m.users[id]
+	if !ok {
+		return database.User{}, sql.ErrNoRows
+	}
+	return user, nil
+}
+
+// missing handles are skipped, like they would be by = ANY
+func (m *Memory) GetUsersByHandles(ctx context.Context, handles []string) ([]database.User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	users := []database.User{}
+	for _, h := range handles {
+		if id, ok := m.handles[h]; ok {
+			users = append(users, m.users[id])
+		}
+	}
+	return users, nil
+}
+
+func (m *Memory) UpdateEmailAndPassword(ctx context.Context, arg database.UpdateEmailAndPasswordParams) (database.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	user, ok := m.users[arg.ID]
+	if !ok {
+		return database.User{}, sql.ErrNoRows
+	}
+	if owner, taken := m.emails[arg.Email]; taken && owner != user.ID {
+		return database.User{}, ErrUniqueViolation
+	}
+	delete(m.emails, user.Email)
+	user.Email = arg.Email
+	user.HashedPassword = arg.HashedPassword
+	user.UpdatedAt = now()
+	m.users[user.ID] = user
+	m.emails[user.Email] = user.ID
+	return user, nil
+}
+
+func (m *Memory) UpdateHandle(ctx context.Context, arg database.UpdateHandleParams) (database.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	user, ok := m.users[arg.ID]
+	if !ok {
+		return database.User{}, sql.ErrNoRows
+	}
+	if owner, taken := m.handles[arg.Handle.String]; arg.Handle.Valid && taken && owner != user.ID {
+		return database.User{}, ErrUniqueViolation
+	}
+	if user.Handle.Valid {
+		delete(m.handles, user.Handle.String)
+	}
+	user.Handle = arg.Handle
+	user.UpdatedAt = now()
+	m.users[user.ID] = user
+	if user.Handle.Valid {
+		m.handles[user.Handle.String] = user.ID
+	}
+	return user, nil
+}
+
+func (m *Memory) UpdateDMSetting(ctx context.Context, arg database.UpdateDMSettingParams) (database.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	user, ok := m.users[arg.ID]
+	if !ok {
+		return database.User{}, sql.ErrNoRows
+	}
+	user.DmsFromFollowersOnly = arg.DmsFromFollowersOnly
+	user.UpdatedAt = now()
+	m.users[user.ID] = user
+	return user, nil
+}
+
+// like the :exec query, upgrading a user that doesn't exist isn't an error
+func (m *Memory) UpgradeToRed(ctx context.Context, id uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	user, ok := m.users[id]
+	if !ok {
+		return nil
+	}
+	user.IsChirpyRed = true
+	user.UpdatedAt = now()
+	m.users[id] = user
+	return nil
+}
+
+// sql.ErrNoRows if they're already deleted
+func (m *Memory) SoftDeleteUser(ctx context.Context, id uuid.UUID) (database.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	user, ok := m.users[id]
+	if !ok || user.DeletedAt.Valid {
+		return database.User{}, sql.ErrNoRows
+	}
+	t := now()
+	user.DeletedAt = sql.NullTime{Time: t, Valid: true}
+	user.UpdatedAt = t
+	m.users[id] = user
+	return user, nil
+}
+
+func (m *Memory) RestoreUser(ctx context.Context, id uuid.UUID) (database.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	user, ok := m.users[id]
+	if !ok || !user.DeletedAt.Valid {
+		return database.User{}, sql.ErrNoRows
+	}
+	user.DeletedAt = sql.NullTime{}
+	user.UpdatedAt = now()
+	m.users[id] = user
+	return user, nil
+}
+
+func (m *Memory) SetUserStatus(ctx context.Context, arg database.SetUserStatusParams) (database.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	user, ok := m.users[arg.ID]
+	if !ok {
+		return database.User{}, sql.ErrNoRows
+	}
+	user.Status = arg.Status
+	user.StatusReason = arg.StatusReason
+	user.StatusUntil = arg.StatusUntil
+	user.HideChirps = arg.HideChirps
+	user.UpdatedAt = now()
+	m.users[arg.ID] = user
+	return user, nil
+}
+
+func (m *Memory) SetUserRole(ctx context.Context, arg database.SetUserRoleParams) (database.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	user, ok := m.users[arg.ID]
+	if !ok {
+		return database.User{}, sql.ErrNoRows
+	}
+	user.Role = arg.Role
+	user.UpdatedAt = now()
+	m.users[arg.ID] = user
+	return user, nil
+}
+
+// deleted before the cutoff, oldest deletion first
+func (m *Memory) ListPurgeableUsers(ctx context.Context, arg database.ListPurgeableUsersParams) ([]database.User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	users := []database.User{}
+	for _, u := range m.users {
+		if u.DeletedAt.Valid && u.DeletedAt.Time.Before(arg.DeletedBefore.Time) {
+			users = append(users, u)
+		}
+	}
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].DeletedAt.Time.Before(users[j].DeletedAt.Time)
+	})
+	if len(users) > int(arg.PageLimit) {
+		users = users[:arg.PageLimit]
+	}
+	return users, nil
+}
+
+// deletes the user and everything that cascades from them
+// like the query, it's only made if it isn't there already
+func (m *Memory) CreatePlaceholderUser(ctx context.Context, id uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.users[id]; ok {
+		return nil
+	}
+	t := now()
+	m.users[id] = database.User{
+		ID: id,
+		CreatedAt: t,
+		UpdatedAt: t,
+		Email: "(deleted)",
+		HashedPassword: "unset",
+		Role: "user",
+		Status: "banned",
+		StatusReason: "This account has been deleted",
+	}
+	m.emails["(deleted)"] = id
+	return nil
+}
+
+func (m *Memory) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	user, ok := m.users[id]
+	if !ok {
+		return nil
+	}
+	for _, c := range m.chirps {
+		if c.UserID == id {
+			m.deleteChirp(c.ID)
+		}
+	}
+	for key, tok := range m.tokens {
+		if tok.UserID == id {
+			delete(m.tokens, key)
+		}
+	}
+	for l := range m.likes {
+		if l.UserID == id {
+			delete(m.likes, l)
+		}
+	}
+	for r := range m.rechirps {
+		if r.UserID == id {
+			delete(m.rechirps, r)
+		}
+	}
+	for f := range m.follows {
+		if f.FollowerID == id || f.FolloweeID == id {
+			delete(m.follows, f)
+		}
+	}
+	for mention := range m.mentions {
+		if mention.UserID == id {
+			delete(m.mentions, mention)
+		}
+	}
+	for _, n := range m.notifications {
+		if n.UserID == id || n.ActorID == id {
+			delete(m.notifications, n.ID)
+		}
+	}
+	for key := range m.participants {
+		if key.UserID == id {
+			delete(m.participants, key)
+		}
+	}
+	for _, msg := range m.messages {
+		if msg.SenderID == id {
+			delete(m.messages, msg.ID)
+		}
+	}
+	for _, h := range m.held {
+		if h.UserID == id {
+			m.deleteHeldChirp(h.ID)
+		}
+	}
+	for _, r := range m.reports {
+		if r.ReporterID == id || r.UserID == id {
+			m.deleteReport(r.ID)
+		} else if r.ClaimedBy.Valid && r.ClaimedBy.UUID == id {
+			r.ClaimedBy = uuid.NullUUID{}
+			m.reports[r.ID] = r
+		}
+	}
+	for _, c := range m.roleChanges {
+		if c.UserID == id {
+			delete(m.roleChanges, c.ID)
+		} else if c.ChangedBy.Valid && c.ChangedBy.UUID == id {
+			c.ChangedBy = uuid.NullUUID{}
+			m.roleChanges[c.ID] = c
+		}
+	}
+	for _, media := range m.media {
+		if media.UserID == id {
+			m.deleteMediaUpload(media.ID)
+		}
+	}
+	delete(m.users, id)
+	delete(m.emails, user.Email)
+	if user.Handle.Valid {
+		delete(m.handles, user.Handle.String)
+	}
+	return nil
+}
+
+// deletes every user, and everything that cascades from them
+func (m *Memory) ResetUsers(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.users = map[uuid.UUID]database.User{}
+	m.emails = map[string]uuid.UUID{}
+	m.handles = map[string]uuid.UUID{}
+	m.chirps = map[uuid.UUID]database.Chirp{}
+	m.tokens = map[string]database.RefreshToken{}
+	m.likes = map[like]time.Time{}
+	m.rechirps = map[like]rechirp{}
+	m.follows = map[follow]time.Time{}
+	m.hashtags = map[hashtag]time.Time{}
+	m.mentions = map[like]bool{}
+	m.notifications = map[uuid.UUID]database.Notification{}
+	m.participants = map[participant]database.ConversationParticipant{}
+	m.messages = map[uuid.UUID]database.Message{}
+	m.media = map[uuid.UUID]database.MediaUpload{}
+	m.revisions = map[uuid.UUID]database.ChirpRevision{}
+	m.flags = map[uuid.UUID]database.ChirpFlag{}
+	m.held = map[uuid.UUID]database.HeldChirp{}
+	m.heldMedia = map[heldMedium]int32{}
+	m.reports = map[uuid.UUID]database.Report{}
+	m.roleChanges = map[uuid.UUID]database.RoleChange{}
+	return nil
+}
+
+func (m *Memory) CreateToken(ctx context.Context, arg database.CreateTokenParams) (database.RefreshToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.users[arg.UserID]; !ok {
+		return database.RefreshToken{}, ErrForeignKeyViolation
+	}
+	if _, ok := m.tokens[arg.Token]; ok {
+		return database.RefreshToken{}, ErrUniqueViolation
+	}
+	t := now()
+	token := database.RefreshToken{
+		Token: arg.Token,
+		CreatedAt: t,
+		UpdatedAt: t,
+		UserID: arg.UserID,
+		ExpiresAt: arg.ExpiresAt,
+	}
+	m.tokens[token.Token] = token
+	return token, nil
+}
+
+func (m *Memory) GetUserFromRefreshToken(ctx context.Context, token string) (database.GetUserFromRefreshTokenRow, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	tok, ok := m.tokens[token]
+	if !ok {
+		return database.GetUserFromRefreshTokenRow{}, sql.ErrNoRows
+	}
+	return database.GetUserFromRefreshTokenRow{
+		UserID: tok.UserID,
+		ExpiresAt: tok.ExpiresAt,
+		RevokedAt: tok.RevokedAt,
+	}, nil
+}
+
+func (m *Memory) RevokeToken(ctx context.Context, token string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	tok, ok := m.tokens[token]
+	if !ok {
+		return nil
+	}
+	t := now()
+	tok.UpdatedAt = t
+	tok.RevokedAt = sql.NullTime{Time: t, Valid: true}
+	m.tokens[token] = tok
+	return nil
+}
+
+func (m *Memory) RevokeUserTokens(ctx context.Context, userID uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t := now()
+	for key, tok := range m.tokens {
+		if tok.UserID == userID && !tok.RevokedAt.Valid {
+			tok.UpdatedAt = t
+			tok.RevokedAt = sql.NullTime{Time: t, Valid: true}
+			m.tokens[key] = tok
+		}
+	}
+	return nil
+}
@@ -0,0 +1,1042 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"internal/database"
+	"testing"
+	"time"
+	"github.com/google/uuid"
+)
+
+func TestMemoryUsers(t *testing.T) {
+	ctx := context.Background()
+	mem := NewMemory()
+	user, err := mem.CreateUser(ctx, database.CreateUserParams{Email: "a@example.com", HashedPassword: "hash"})
+	if err != nil {
+		t.Fatalf("Error in CreateUser: %v", err)
+	}
+	_, err = mem.CreateUser(ctx, database.CreateUserParams{Email: "a@example.com", HashedPassword: "hash"})
+	if !errors.Is(err, ErrUniqueViolation) {
+		t.Errorf("Creating a duplicate email should fail, got: %v", err)
+	}
+	found, err := mem.GetUserByEmail(ctx, "a@example.com")
+	if err != nil || found.ID != user.ID {
+		t.Errorf("GetUserByEmail returned %v, %v\nExpected: %v", found.ID, err, user.ID)
+	}
+	_, err = mem.GetUserByEmail(ctx, "nobody@example.com")
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Missing user should be sql.ErrNoRows, got: %v", err)
+	}
+
+	other, _ := mem.CreateUser(ctx, database.CreateUserParams{Email: "b@example.com", HashedPassword: "hash"})
+	_, err = mem.UpdateEmailAndPassword(ctx, database.UpdateEmailAndPasswordParams{ID: other.ID, Email: "a@example.com", HashedPassword: "new"})
+	if !errors.Is(err, ErrUniqueViolation) {
+		t.Errorf("Taking another user's email should fail, got: %v", err)
+	}
+	updated, err := mem.UpdateEmailAndPassword(ctx, database.UpdateEmailAndPasswordParams{ID: other.ID, Email: "c@example.com", HashedPassword: "new"})
+	if err != nil || updated.Email != "c@example.com" || updated.HashedPassword != "new" {
+		t.Errorf("UpdateEmailAndPassword returned %+v, %v", updated, err)
+	}
+	if _, err = mem.GetUserByEmail(ctx, "b@example.com"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("The old email should be free after an update, got: %v", err)
+	}
+
+	err = mem.UpgradeToRed(ctx, user.ID)
+	if err != nil {
+		t.Errorf("Error in UpgradeToRed: %v", err)
+	}
+	found, _ = mem.GetUserByEmail(ctx, "a@example.com")
+	if !found.IsChirpyRed {
+		t.Errorf("User should be Chirpy Red after UpgradeToRed")
+	}
+}
+
+func TestMemoryChirps(t *testing.T) {
+	ctx := context.Background()
+	mem := NewMemory()
+	_, err := mem.CreateChirp(ctx, database.CreateChirpParams{Body: "orphan", UserID: uuid.New()})
+	if !errors.Is(err, ErrForeignKeyViolation) {
+		t.Errorf("A chirp from an unknown user should fail, got: %v", err)
+	}
+
+	alice, _ := mem.CreateUser(ctx, database.CreateUserParams{Email: "alice@example.com"})
+	bob, _ := mem.CreateUser(ctx, database.CreateUserParams{Email: "bob@example.com"})
+	bodies := []string{"one", "two", "three"}
+	for _, b := range bodies {
+		_, err := mem.CreateChirp(ctx, database.CreateChirpParams{Body: b, UserID: alice.ID})
+		if err != nil {
+			t.Fatalf("Error in CreateChirp: %v", err)
+		}
+	}
+	bobChirp, _ := mem.CreateChirp(ctx, database.CreateChirpParams{Body: "four", UserID: bob.ID})
+
+	all, _ := mem.GetAllChirps(ctx)
+	if len(all) != 4 {
+		t.Errorf("GetAllChirps returned %d chirps, expected 4", len(all))
+	}
+	for i := 1; i < len(all); i++ {
+		if all[i].CreatedAt.Before(all[i-1].CreatedAt) {
+			t.Errorf("GetAllChirps isn't ordered by created_at")
+		}
+	}
+	alices, _ := mem.GetChirpsByUser(ctx, alice.ID)
+	if len(alices) != 3 {
+		t.Errorf("GetChirpsByUser returned %d chirps, expected 3", len(alices))
+	}
+
+	err = mem.DeleteSingleChirp(ctx, bobChirp.ID)
+	if err != nil {
+		t.Errorf("Error in DeleteSingleChirp: %v", err)
+	}
+	_, err = mem.GetSingleChirp(ctx, bobChirp.ID)
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("A deleted chirp should be sql.ErrNoRows, got: %v", err)
+	}
+
+	mem.ResetUsers(ctx)
+	all, _ = mem.GetAllChirps(ctx)
+	if len(all) != 0 {
+		t.Errorf("ResetUsers should cascade to chirps, %d left", len(all))
+	}
+}
+
+func TestMemoryTokens(t *testing.T) {
+	ctx := context.Background()
+	mem := NewMemory()
+	user, _ := mem.CreateUser(ctx, database.CreateUserParams{Email: "a@example.com"})
+	_, err := mem.CreateToken(ctx, database.CreateTokenParams{Token: "abc", UserID: user.ID})
+	if err != nil {
+		t.Fatalf("Error in CreateToken: %v", err)
+	}
+	row, err := mem.GetUserFromRefreshToken(ctx, "abc")
+	if err != nil || row.UserID != user.ID || row.RevokedAt.Valid {
+		t.Errorf("GetUserFromRefreshToken returned %+v, %v", row, err)
+	}
+	mem.RevokeToken(ctx, "abc")
+	row, _ = mem.GetUserFromRefreshToken(ctx, "abc")
+	if !row.RevokedAt.Valid {
+		t.Errorf("Token should be revoked")
+	}
+	_, err = mem.GetUserFromRefreshToken(ctx, "nope")
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Missing token should be sql.ErrNoRows, got: %v", err)
+	}
+}
+
+func TestMemoryPagination(t *testing.T) {
+	ctx := context.Background()
+	mem := NewMemory()
+	user, _ := mem.CreateUser(ctx, database.CreateUserParams{Email: "a@example.com"})
+	other, _ := mem.CreateUser(ctx, database.CreateUserParams{Email: "b@example.com"})
+	for i := 0; i < 5; i++ {
+		mem.CreateChirp(ctx, database.CreateChirpParams{Body: "mine", UserID: user.ID})
+		mem.CreateChirp(ctx, database.CreateChirpParams{Body: "theirs", UserID: other.ID})
+	}
+	all, _ := mem.GetChirpsByUser(ctx, user.ID)
+
+	// walk forwards two at a time
+	seen := []database.Chirp{}
+	after := database.ListChirpsByUserAscParams{UserID: user.ID, PageLimit: 2}
+	for {
+		page, err := mem.ListChirpsByUserAsc(ctx, after)
+		if err != nil {
+			t.Fatalf("Error in ListChirpsByUserAsc: %v", err)
+		}
+		if len(page) == 0 {
+			break
+		}
+		for _, r := range page {
+			seen = append(seen, r.Chirp)
+		}
+		after.AfterCreatedAt = page[len(page)-1].EntryAt
+		after.AfterID = page[len(page)-1].EntryID
+	}
+	if len(seen) != len(all) {
+		t.Fatalf("Paging forwards saw %d chirps, expected %d", len(seen), len(all))
+	}
+	for i := range all {
+		if seen[i].ID != all[i].ID {
+			t.Errorf("Chirp %d out of order paging forwards", i)
+		}
+	}
+
+	// and backwards from the end
+	page, _ := mem.ListChirpsDesc(ctx, database.ListChirpsDescParams{
+		BeforeCreatedAt: all[len(all)-1].CreatedAt.Add(time.Hour),
+		BeforeID: uuid.Max,
+		PageLimit: 3,
+	})
+	if len(page) != 3 {
+		t.Fatalf("ListChirpsDesc returned %d chirps, expected 3", len(page))
+	}
+	for i := 1; i < len(page); i++ {
+		if chirpCompare(page[i].Chirp, page[i-1].EntryAt, page[i-1].EntryID) >= 0 {
+			t.Errorf("ListChirpsDesc isn't newest first")
+		}
+	}
+}
+
+func TestMemoryReplies(t *testing.T) {
+	ctx := context.Background()
+	mem := NewMemory()
+	user, _ := mem.CreateUser(ctx, database.CreateUserParams{Email: "a@example.com"})
+	_, err := mem.CreateChirp(ctx, database.CreateChirpParams{Body: "reply", UserID: user.ID, InReplyTo: uuid.NullUUID{UUID: uuid.New(), Valid: true}})
+	if !errors.Is(err, ErrForeignKeyViolation) {
+		t.Errorf("Replying to a missing chirp should fail, got: %v", err)
+	}
+
+	root, _ := mem.CreateChirp(ctx, database.CreateChirpParams{Body: "root", UserID: user.ID})
+	parent := uuid.NullUUID{UUID: root.ID, Valid: true}
+	first, _ := mem.CreateChirp(ctx, database.CreateChirpParams{Body: "first", UserID: user.ID, InReplyTo: parent})
+	mem.CreateChirp(ctx, database.CreateChirpParams{Body: "second", UserID: user.ID, InReplyTo: parent})
+	mem.CreateChirp(ctx, database.CreateChirpParams{Body: "nested", UserID: user.ID, InReplyTo: uuid.NullUUID{UUID: first.ID, Valid: true}})
+
+	counts, _ := mem.CountReplies(ctx, []uuid.UUID{root.ID, first.ID})
+	expected := map[uuid.UUID]int64{root.ID: 2, first.ID: 1}
+	if len(counts) != 2 {
+		t.Fatalf("CountReplies returned %d rows, expected 2", len(counts))
+	}
+	for _, c := range counts {
+		if expected[c.InReplyTo.UUID] != c.ReplyCount {
+			t.Errorf("CountReplies for %v was %d, expected %d", c.InReplyTo.UUID, c.ReplyCount, expected[c.InReplyTo.UUID])
+		}
+	}
+
+	// tombstones stay in the thread but leave the listings
+	mem.TombstoneChirp(ctx, root.ID)
+	replies, _ := mem.ListReplies(ctx, database.ListRepliesParams{InReplyTo: parent, PageLimit: 10})
+	if len(replies) != 2 {
+		t.Errorf("A tombstone should keep its %d replies, got %d", 2, len(replies))
+	}
+	all, _ := mem.GetAllChirps(ctx)
+	if len(all) != 3 {
+		t.Errorf("GetAllChirps should skip tombstones, got %d chirps", len(all))
+	}
+
+	// and a hard delete orphans the replies like ON DELETE SET NULL
+	mem.DeleteSingleChirp(ctx, first.ID)
+	nested, _ := mem.GetRepliesToChirps(ctx, database.GetRepliesToChirpsParams{ParentIds: []uuid.UUID{first.ID}, PageLimit: 10})
+	if len(nested) != 0 {
+		t.Errorf("Replies to a deleted chirp should lose their parent")
+	}
+}
+
+func TestMemoryLikes(t *testing.T) {
+	ctx := context.Background()
+	mem := NewMemory()
+	alice, _ := mem.CreateUser(ctx, database.CreateUserParams{Email: "alice@example.com"})
+	bob, _ := mem.CreateUser(ctx, database.CreateUserParams{Email: "bob@example.com"})
+	chirp, _ := mem.CreateChirp(ctx, database.CreateChirpParams{Body: "like me", UserID: alice.ID})
+
+	// liking twice only counts once
+	for i := 0; i < 2; i++ {
+		err := mem.LikeChirp(ctx, database.LikeChirpParams{UserID: alice.ID, ChirpID: chirp.ID})
+		if err != nil {
+			t.Fatalf("Error in LikeChirp: %v", err)
+		}
+	}
+	mem.LikeChirp(ctx, database.LikeChirpParams{UserID: bob.ID, ChirpID: chirp.ID})
+	counts, _ := mem.CountLikes(ctx, []uuid.UUID{chirp.ID})
+	if len(counts) != 1 || counts[0].LikeCount != 2 {
+		t.Errorf("CountLikes returned %+v, expected 2 likes", counts)
+	}
+
+	mem.UnlikeChirp(ctx, database.UnlikeChirpParams{UserID: bob.ID, ChirpID: chirp.ID})
+	liked, _ := mem.GetLikedByUser(ctx, database.GetLikedByUserParams{UserID: bob.ID, ChirpIds: []uuid.UUID{chirp.ID}})
+	if len(liked) != 0 {
+		t.Errorf("Bob unliked the chirp, but GetLikedByUser still has it")
+	}
+	rows, _ := mem.ListUserLikes(ctx, database.ListUserLikesParams{UserID: alice.ID, BeforeLikedAt: time.Now().Add(time.Hour), BeforeID: uuid.Max, PageLimit: 10})
+	if len(rows) != 1 || rows[0].Chirp.ID != chirp.ID {
+		t.Errorf("ListUserLikes returned %+v", rows)
+	}
+
+	// likes go with the chirp
+	mem.DeleteSingleChirp(ctx, chirp.ID)
+	counts, _ = mem.CountLikes(ctx, []uuid.UUID{chirp.ID})
+	if len(counts) != 0 {
+		t.Errorf("Deleting a chirp should delete its likes")
+	}
+}
+
+func TestMemoryRechirps(t *testing.T) {
+	ctx := context.Background()
+	mem := NewMemory()
+	alice, _ := mem.CreateUser(ctx, database.CreateUserParams{Email: "alice@example.com"})
+	bob, _ := mem.CreateUser(ctx, database.CreateUserParams{Email: "bob@example.com"})
+	chirp, _ := mem.CreateChirp(ctx, database.CreateChirpParams{Body: "rechirp me", UserID: alice.ID})
+	mem.CreateChirp(ctx, database.CreateChirpParams{Body: "later", UserID: alice.ID})
+
+	// rechirping twice only counts once
+	for i := 0; i < 2; i++ {
+		err := mem.Rechirp(ctx, database.RechirpParams{UserID: bob.ID, ChirpID: chirp.ID})
+		if err != nil {
+			t.Fatalf("Error in Rechirp: %v", err)
+		}
+	}
+	counts, _ := mem.CountRechirps(ctx, []uuid.UUID{chirp.ID})
+	if len(counts) != 1 || counts[0].RechirpCount != 1 {
+		t.Errorf("CountRechirps returned %+v, expected 1 rechirp", counts)
+	}
+
+	// the rechirp shows up on bob's timeline as the newest entry
+	page, _ := mem.ListChirpsByUserDesc(ctx, database.ListChirpsByUserDescParams{UserID: bob.ID, BeforeCreatedAt: time.Now().Add(time.Hour), BeforeID: uuid.Max, PageLimit: 10})
+	if len(page) != 1 || page[0].Chirp.ID != chirp.ID || page[0].RechirpedBy != bob.ID {
+		t.Fatalf("Bob's timeline should have just the rechirp, got %+v", page)
+	}
+	if page[0].EntryID == chirp.ID {
+		t.Errorf("A rechirp should be paged by its own id")
+	}
+	all, _ := mem.ListChirpsDesc(ctx, database.ListChirpsDescParams{BeforeCreatedAt: time.Now().Add(time.Hour), BeforeID: uuid.Max, PageLimit: 10})
+	if len(all) != 3 || all[0].RechirpedBy != bob.ID {
+		t.Errorf("The full timeline should lead with the rechirp, got %+v", all)
+	}
+
+	mem.UndoRechirp(ctx, database.UndoRechirpParams{UserID: bob.ID, ChirpID: chirp.ID})
+	counts, _ = mem.CountRechirps(ctx, []uuid.UUID{chirp.ID})
+	if len(counts) != 0 {
+		t.Errorf("UndoRechirp should remove the rechirp")
+	}
+
+	// quotes don't keep the original around
+	quote, _ := mem.CreateChirp(ctx, database.CreateChirpParams{Body: "look", UserID: bob.ID, QuoteOf: uuid.NullUUID{UUID: chirp.ID, Valid: true}})
+	mem.DeleteSingleChirp(ctx, chirp.ID)
+	quoted, _ := mem.GetChirpsByIDs(ctx, []uuid.UUID{quote.QuoteOf.UUID})
+	if len(quoted) != 0 {
+		t.Errorf("GetChirpsByIDs found a deleted chirp")
+	}
+	if _, err := mem.GetSingleChirp(ctx, quote.ID); err != nil {
+		t.Errorf("Deleting the original shouldn't delete the quote: %v", err)
+	}
+}
+
+func TestMemoryFollows(t *testing.T) {
+	ctx := context.Background()
+	mem := NewMemory()
+	alice, _ := mem.CreateUser(ctx, database.CreateUserParams{Email: "alice@example.com"})
+	bob, _ := mem.CreateUser(ctx, database.CreateUserParams{Email: "bob@example.com"})
+	carol, _ := mem.CreateUser(ctx, database.CreateUserParams{Email: "carol@example.com"})
+
+	err := mem.FollowUser(ctx, database.FollowUserParams{FollowerID: alice.ID, FolloweeID: uuid.New()})
+	if !errors.Is(err, ErrForeignKeyViolation) {
+		t.Errorf("Following a missing user should fail, got: %v", err)
+	}
+	// following twice only counts once
+	for i := 0; i < 2; i++ {
+		mem.FollowUser(ctx, database.FollowUserParams{FollowerID: alice.ID, FolloweeID: bob.ID})
+	}
+	mem.FollowUser(ctx, database.FollowUserParams{FollowerID: carol.ID, FolloweeID: bob.ID})
+	followers, _ := mem.ListFollowers(ctx, database.ListFollowersParams{UserID: bob.ID, BeforeFollowedAt: time.Now().Add(time.Hour), BeforeID: uuid.Max, PageLimit: 10})
+	if len(followers) != 2 || followers[0].UserID != carol.ID {
+		t.Errorf("ListFollowers returned %+v, expected carol then alice", followers)
+	}
+	following, _ := mem.ListFollowing(ctx, database.ListFollowingParams{UserID: alice.ID, BeforeFollowedAt: time.Now().Add(time.Hour), BeforeID: uuid.Max, PageLimit: 10})
+	if len(following) != 1 || following[0].UserID != bob.ID {
+		t.Errorf("ListFollowing returned %+v, expected just bob", following)
+	}
+
+	// alice sees her own chirps and bob's (rechirps included), but not carol's
+	mine, _ := mem.CreateChirp(ctx, database.CreateChirpParams{Body: "mine", UserID: alice.ID})
+	mem.CreateChirp(ctx, database.CreateChirpParams{Body: "bob's", UserID: bob.ID})
+	carols, _ := mem.CreateChirp(ctx, database.CreateChirpParams{Body: "carol's", UserID: carol.ID})
+	mem.Rechirp(ctx, database.RechirpParams{UserID: bob.ID, ChirpID: carols.ID})
+	timeline := database.ListTimelineParams{UserID: alice.ID, BeforeCreatedAt: time.Now().Add(time.Hour), BeforeID: uuid.Max, PageLimit: 10}
+	rows, _ := mem.ListTimeline(ctx, timeline)
+	if len(rows) != 3 {
+		t.Fatalf("ListTimeline returned %d entries, expected 3", len(rows))
+	}
+	if rows[0].Chirp.ID != carols.ID || rows[0].RechirpedBy != bob.ID {
+		t.Errorf("The newest entry should be bob's rechirp, got %+v", rows[0])
+	}
+	if rows[2].Chirp.ID != mine.ID {
+		t.Errorf("The oldest entry should be alice's own chirp, got %+v", rows[2])
+	}
+
+	// and picks up where the last page left off
+	timeline.PageLimit = 2
+	page, _ := mem.ListTimeline(ctx, timeline)
+	timeline.BeforeCreatedAt, timeline.BeforeID = page[1].EntryAt, page[1].EntryID
+	page, _ = mem.ListTimeline(ctx, timeline)
+	if len(page) != 1 || page[0].Chirp.ID != mine.ID {
+		t.Errorf("The second page should be alice's own chirp, got %+v", page)
+	}
+
+	mem.UnfollowUser(ctx, database.UnfollowUserParams{FollowerID: alice.ID, FolloweeID: bob.ID})
+	timeline.BeforeCreatedAt, timeline.BeforeID = time.Now().Add(time.Hour), uuid.Max
+	rows, _ = mem.ListTimeline(ctx, timeline)
+	if len(rows) != 1 {
+		t.Errorf("After unfollowing bob alice should only see her own chirp, got %d entries", len(rows))
+	}
+}
+
+func TestMemoryHashtags(t *testing.T) {
+	ctx := context.Background()
+	mem := NewMemory()
+	user, _ := mem.CreateUser(ctx, database.CreateUserParams{Email: "a@example.com"})
+	first, _ := mem.CreateChirp(ctx, database.CreateChirpParams{Body: "#go", UserID: user.ID})
+	second, _ := mem.CreateChirp(ctx, database.CreateChirpParams{Body: "#go #rust", UserID: user.ID})
+	for _, c := range []database.Chirp{first, second} {
+		mem.TagChirp(ctx, database.TagChirpParams{ChirpID: c.ID, Tag: "go", CreatedAt: c.CreatedAt})
+	}
+	mem.TagChirp(ctx, database.TagChirpParams{ChirpID: second.ID, Tag: "rust", CreatedAt: second.CreatedAt})
+
+	page, _ := mem.ListHashtagChirps(ctx, database.ListHashtagChirpsParams{Tag: "go", BeforeCreatedAt: time.Now().Add(time.Hour), BeforeID: uuid.Max, PageLimit: 10})
+	if len(page) != 2 || page[0].ID != second.ID {
+		t.Errorf("ListHashtagChirps should be newest first, got %+v", page)
+	}
+
+	trends := database.TrendingHashtagsParams{Now: time.Now().UTC(), DecaySeconds: 3600, Since: time.Now().Add(-time.Hour), PageLimit: 10}
+	rows, _ := mem.TrendingHashtags(ctx, trends)
+	if len(rows) != 2 || rows[0].Tag != "go" || rows[0].Uses != 2 {
+		t.Errorf("TrendingHashtags returned %+v, expected go first with 2 uses", rows)
+	}
+
+	// deleting a chirp takes it out of the counts, whether it's a tombstone or gone entirely
+	mem.TombstoneChirp(ctx, first.ID)
+	mem.DeleteSingleChirp(ctx, second.ID)
+	rows, _ = mem.TrendingHashtags(ctx, trends)
+	if len(rows) != 0 {
+		t.Errorf("Deleted chirps are still trending: %+v", rows)
+	}
+}
+
+func TestMemoryNotifications(t *testing.T) {
+	ctx := context.Background()
+	mem := NewMemory()
+	alice, _ := mem.CreateUser(ctx, database.CreateUserParams{Email: "alice@example.com", Handle: sql.NullString{String: "alice", Valid: true}})
+	bob, _ := mem.CreateUser(ctx, database.CreateUserParams{Email: "bob@example.com"})
+	_, err := mem.CreateUser(ctx, database.CreateUserParams{Email: "carol@example.com", Handle: sql.NullString{String: "alice", Valid: true}})
+	if !errors.Is(err, ErrUniqueViolation) {
+		t.Errorf("Handles should be unique, got: %v", err)
+	}
+	users, _ := mem.GetUsersByHandles(ctx, []string{"alice", "nobody"})
+	if len(users) != 1 || users[0].ID != alice.ID {
+		t.Errorf("GetUsersByHandles returned %+v, expected just alice", users)
+	}
+
+	chirp, _ := mem.CreateChirp(ctx, database.CreateChirpParams{Body: "hi @alice", UserID: bob.ID})
+	mem.MentionUser(ctx, database.MentionUserParams{ChirpID: chirp.ID, UserID: alice.ID})
+	mentions, _ := mem.GetMentionsForChirps(ctx, []uuid.UUID{chirp.ID})
+	if len(mentions) != 1 || mentions[0].Handle.String != "alice" {
+		t.Errorf("GetMentionsForChirps returned %+v", mentions)
+	}
+
+	// the same thing twice only notifies once
+	about := uuid.NullUUID{UUID: chirp.ID, Valid: true}
+	for i := 0; i < 2; i++ {
+		err = mem.CreateNotification(ctx, database.CreateNotificationParams{UserID: alice.ID, ActorID: bob.ID, Kind: "mention", ChirpID: about})
+		if err != nil {
+			t.Fatalf("Error in CreateNotification: %v", err)
+		}
+	}
+	mem.CreateNotification(ctx, database.CreateNotificationParams{UserID: alice.ID, ActorID: bob.ID, Kind: "follow"})
+	mem.CreateNotification(ctx, database.CreateNotificationParams{UserID: alice.ID, ActorID: bob.ID, Kind: "follow"})
+	page := database.ListNotificationsParams{UserID: alice.ID, BeforeCreatedAt: time.Now().Add(time.Hour), BeforeID: uuid.Max, PageLimit: 10}
+	all, _ := mem.ListNotifications(ctx, page)
+	if len(all) != 2 || all[0].Kind != "follow" {
+		t.Fatalf("ListNotifications returned %+v, expected the follow then the mention", all)
+	}
+
+	mem.MarkNotificationsRead(ctx, database.MarkNotificationsReadParams{UserID: alice.ID, Ids: []uuid.UUID{all[0].ID}})
+	unread, _ := mem.ListUnreadNotifications(ctx, database.ListUnreadNotificationsParams(page))
+	if len(unread) != 1 || unread[0].Kind != "mention" {
+		t.Errorf("ListUnreadNotifications returned %+v, expected just the mention", unread)
+	}
+	// bob can't mark alice's notifications
+	mem.MarkAllNotificationsRead(ctx, bob.ID)
+	unread, _ = mem.ListUnreadNotifications(ctx, database.ListUnreadNotificationsParams(page))
+	if len(unread) != 1 {
+		t.Errorf("Marking bob's notifications read touched alice's")
+	}
+
+	// notifications go when their chirp does, even as a tombstone
+	mem.TombstoneChirp(ctx, chirp.ID)
+	all, _ = mem.ListNotifications(ctx, page)
+	if len(all) != 1 || all[0].Kind != "follow" {
+		t.Errorf("A tombstoned chirp's notifications should be hidden, got %+v", all)
+	}
+	mem.DeleteSingleChirp(ctx, chirp.ID)
+	mentions, _ = mem.GetMentionsForChirps(ctx, []uuid.UUID{chirp.ID})
+	if len(mentions) != 0 || len(mem.notifications) != 1 {
+		t.Errorf("Deleting a chirp should delete its mentions and notifications")
+	}
+}
+
+func TestMemoryConversations(t *testing.T) {
+	ctx := context.Background()
+	mem := NewMemory()
+	alice, _ := mem.CreateUser(ctx, database.CreateUserParams{Email: "alice@example.com"})
+	bob, _ := mem.CreateUser(ctx, database.CreateUserParams{Email: "bob@example.com"})
+
+	direct, _ := mem.CreateConversation(ctx, false)
+	mem.AddParticipant(ctx, database.AddParticipantParams{ConversationID: direct.ID, UserID: alice.ID})
+	mem.AddParticipant(ctx, database.AddParticipantParams{ConversationID: direct.ID, UserID: bob.ID})
+	found, err := mem.FindDirectConversation(ctx, database.FindDirectConversationParams{UserID: bob.ID, OtherID: alice.ID})
+	if err != nil || found.ID != direct.ID {
+		t.Fatalf("FindDirectConversation returned %+v, %v", found, err)
+	}
+	err = mem.AddParticipant(ctx, database.AddParticipantParams{ConversationID: uuid.New(), UserID: alice.ID})
+	if !errors.Is(err, ErrForeignKeyViolation) {
+		t.Errorf("Expected ErrForeignKeyViolation for a missing conversation, got: %v", err)
+	}
+
+	// your own messages never count as unread
+	mem.CreateMessage(ctx, database.CreateMessageParams{ConversationID: direct.ID, SenderID: alice.ID, Body: "hi"})
+	mem.CreateMessage(ctx, database.CreateMessageParams{ConversationID: direct.ID, SenderID: alice.ID, Body: "you there?"})
+	page := database.ListConversationsParams{UserID: bob.ID, BeforeUpdatedAt: time.Now().Add(time.Hour), BeforeID: uuid.Max, PageLimit: 10}
+	rows, _ := mem.ListConversations(ctx, page)
+	if len(rows) != 1 || rows[0].UnreadCount != 2 {
+		t.Fatalf("ListConversations returned %+v, expected 2 unread for bob", rows)
+	}
+	page.UserID = alice.ID
+	rows, _ = mem.ListConversations(ctx, page)
+	if len(rows) != 1 || rows[0].UnreadCount != 0 {
+		t.Errorf("ListConversations returned %+v, expected nothing unread for alice", rows)
+	}
+	mem.MarkConversationRead(ctx, database.MarkConversationReadParams{ConversationID: direct.ID, UserID: bob.ID, LastReadAt: sql.NullTime{Time: time.Now().Add(time.Minute), Valid: true}})
+	page.UserID = bob.ID
+	rows, _ = mem.ListConversations(ctx, page)
+	if rows[0].UnreadCount != 0 {
+		t.Errorf("Unread count should be 0 after reading, got %d", rows[0].UnreadCount)
+	}
+
+	// leaving hides the conversation and means it's no longer the direct one
+	mem.LeaveConversation(ctx, database.LeaveConversationParams{ConversationID: direct.ID, UserID: bob.ID})
+	rows, _ = mem.ListConversations(ctx, page)
+	if len(rows) != 0 {
+		t.Errorf("A left conversation should be hidden, got %+v", rows)
+	}
+	_, err = mem.FindDirectConversation(ctx, database.FindDirectConversationParams{UserID: alice.ID, OtherID: bob.ID})
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Expected sql.ErrNoRows after leaving, got: %v", err)
+	}
+
+	_, err = mem.GetFollow(ctx, database.GetFollowParams{FollowerID: bob.ID, FolloweeID: alice.ID})
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Expected sql.ErrNoRows from GetFollow, got: %v", err)
+	}
+	mem.FollowUser(ctx, database.FollowUserParams{FollowerID: bob.ID, FolloweeID: alice.ID})
+	if _, err = mem.GetFollow(ctx, database.GetFollowParams{FollowerID: bob.ID, FolloweeID: alice.ID}); err != nil {
+		t.Errorf("Error in GetFollow: %v", err)
+	}
+}
+
+func TestMemoryMedia(t *testing.T) {
+	ctx := context.Background()
+	mem := NewMemory()
+	alice, _ := mem.CreateUser(ctx, database.CreateUserParams{Email: "alice@example.com"})
+	first, _ := mem.CreateMediaUpload(ctx, database.CreateMediaUploadParams{UserID: alice.ID, BlobKey: "a", ContentType: "image/png", Size: 10})
+	second, _ := mem.CreateMediaUpload(ctx, database.CreateMediaUploadParams{UserID: alice.ID, BlobKey: "b", ContentType: "image/png", Size: 10})
+	chirp, _ := mem.CreateChirp(ctx, database.CreateChirpParams{Body: "look", UserID: alice.ID})
+	attachedTo := uuid.NullUUID{UUID: chirp.ID, Valid: true}
+	mem.AttachMediaUpload(ctx, database.AttachMediaUploadParams{ChirpID: attachedTo, Position: 1, ID: first.ID})
+	mem.AttachMediaUpload(ctx, database.AttachMediaUploadParams{ChirpID: attachedTo, Position: 0, ID: second.ID})
+	media, _ := mem.GetMediaForChirps(ctx, []uuid.UUID{chirp.ID})
+	if len(media) != 2 || media[0].ID != second.ID {
+		t.Fatalf("GetMediaForChirps returned %+v, expected both in position order", media)
+	}
+
+	// an upload can only be claimed once
+	other, _ := mem.CreateChirp(ctx, database.CreateChirpParams{Body: "mine now", UserID: alice.ID})
+	attached, _ := mem.AttachMediaUpload(ctx, database.AttachMediaUploadParams{ChirpID: uuid.NullUUID{UUID: other.ID, Valid: true}, ID: first.ID})
+	media, _ = mem.GetMediaForChirps(ctx, []uuid.UUID{other.ID})
+	if attached != 0 || len(media) != 0 {
+		t.Errorf("Attaching an already attached upload should do nothing, got %+v", media)
+	}
+
+	later := database.ListOrphanedMediaParams{CreatedBefore: time.Now().Add(time.Hour), PageLimit: 10}
+	orphans, _ := mem.ListOrphanedMedia(ctx, later)
+	if len(orphans) != 0 {
+		t.Errorf("Attached media shouldn't be orphaned, got %+v", orphans)
+	}
+	orphans, _ = mem.ListOrphanedMedia(ctx, database.ListOrphanedMediaParams{CreatedBefore: time.Now().Add(-time.Hour), PageLimit: 10})
+	if len(orphans) != 0 {
+		t.Errorf("Media newer than the cutoff shouldn't be orphaned, got %+v", orphans)
+	}
+
+	// tombstoned or deleted chirps leave their media behind to be collected
+	mem.TombstoneChirp(ctx, chirp.ID)
+	orphans, _ = mem.ListOrphanedMedia(ctx, later)
+	if len(orphans) != 2 {
+		t.Errorf("A tombstoned chirp's media should be orphaned, got %+v", orphans)
+	}
+	mem.DeleteSingleChirp(ctx, chirp.ID)
+	orphans, _ = mem.ListOrphanedMedia(ctx, later)
+	if len(orphans) != 2 || orphans[0].ChirpID.Valid {
+		t.Errorf("A deleted chirp's media should be unattached, got %+v", orphans)
+	}
+	mem.DeleteMediaUpload(ctx, first.ID)
+	_, err := mem.GetMediaUpload(ctx, first.ID)
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Expected sql.ErrNoRows after deleting, got: %v", err)
+	}
+}
+
+func TestMemoryRevisions(t *testing.T) {
+	ctx := context.Background()
+	mem := NewMemory()
+	alice, _ := mem.CreateUser(ctx, database.CreateUserParams{Email: "alice@example.com", Handle: sql.NullString{String: "alice", Valid: true}})
+	chirp, _ := mem.CreateChirp(ctx, database.CreateChirpParams{Body: "first #draft @alice", UserID: alice.ID})
+	mem.TagChirp(ctx, database.TagChirpParams{ChirpID: chirp.ID, Tag: "draft", CreatedAt: chirp.CreatedAt})
+	mem.MentionUser(ctx, database.MentionUserParams{ChirpID: chirp.ID, UserID: alice.ID})
+
+	mem.CreateChirpRevision(ctx, database.CreateChirpRevisionParams{ChirpID: chirp.ID, Body: chirp.Body, CreatedAt: chirp.CreatedAt})
+	edited, err := mem.EditChirp(ctx, database.EditChirpParams{ID: chirp.ID, Body: "second"})
+	if err != nil || edited.Body != "second" || !edited.EditedAt.Valid {
+		t.Fatalf("EditChirp returned %+v, %v", edited, err)
+	}
+	mem.ClearHashtags(ctx, chirp.ID)
+	mem.ClearMentions(ctx, chirp.ID)
+	if len(mem.hashtags) != 0 || len(mem.mentions) != 0 {
+		t.Errorf("ClearHashtags and ClearMentions should leave nothing behind")
+	}
+	mem.CreateChirpRevision(ctx, database.CreateChirpRevisionParams{ChirpID: chirp.ID, Body: edited.Body, CreatedAt: edited.EditedAt.Time})
+	mem.EditChirp(ctx, database.EditChirpParams{ID: chirp.ID, Body: "third"})
+	revisions, _ := mem.ListChirpRevisions(ctx, chirp.ID)
+	if len(revisions) != 2 || revisions[0].Body != "first #draft @alice" || revisions[1].Body != "second" {
+		t.Fatalf("ListChirpRevisions returned %+v, expected first then second", revisions)
+	}
+
+	// tombstones can't be edited
+	mem.TombstoneChirp(ctx, chirp.ID)
+	_, err = mem.EditChirp(ctx, database.EditChirpParams{ID: chirp.ID, Body: "fourth"})
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Expected sql.ErrNoRows editing a tombstone, got: %v", err)
+	}
+	mem.DeleteSingleChirp(ctx, chirp.ID)
+	revisions, _ = mem.ListChirpRevisions(ctx, chirp.ID)
+	if len(revisions) != 0 {
+		t.Errorf("Deleting a chirp should delete its revisions")
+	}
+}
+
+func TestMemorySoftDelete(t *testing.T) {
+	ctx := context.Background()
+	mem := NewMemory()
+	alice, _ := mem.CreateUser(ctx, database.CreateUserParams{Email: "alice@example.com", Handle: sql.NullString{String: "alice", Valid: true}})
+	bob, _ := mem.CreateUser(ctx, database.CreateUserParams{Email: "bob@example.com"})
+	mem.FollowUser(ctx, database.FollowUserParams{FollowerID: bob.ID, FolloweeID: alice.ID})
+	mine, _ := mem.CreateChirp(ctx, database.CreateChirpParams{Body: "deleted by me", UserID: alice.ID})
+	kept, _ := mem.CreateChirp(ctx, database.CreateChirpParams{Body: "deleted with me", UserID: alice.ID})
+	reply, _ := mem.CreateChirp(ctx, database.CreateChirpParams{Body: "a reply", UserID: bob.ID, InReplyTo: uuid.NullUUID{UUID: kept.ID, Valid: true}})
+	mem.CreateToken(ctx, database.CreateTokenParams{Token: "alice-token", UserID: alice.ID})
+
+	// soft deleting keeps the body, and doing it twice keeps the first deleted_at
+	mem.SoftDeleteChirp(ctx, mine.ID)
+	deleted, _ := mem.GetSingleChirp(ctx, mine.ID)
+	if !deleted.DeletedAt.Valid || deleted.PurgedAt.Valid || deleted.Body != "deleted by me" {
+		t.Fatalf("SoftDeleteChirp left %+v", deleted)
+	}
+	mem.SoftDeleteChirp(ctx, mine.ID)
+	again, _ := mem.GetSingleChirp(ctx, mine.ID)
+	if !again.DeletedAt.Time.Equal(deleted.DeletedAt.Time) {
+		t.Errorf("Deleting a chirp twice moved its deleted_at")
+	}
+
+	user, err := mem.SoftDeleteUser(ctx, alice.ID)
+	if err != nil || !user.DeletedAt.Valid {
+		t.Fatalf("SoftDeleteUser returned %+v, %v", user, err)
+	}
+	_, err = mem.SoftDeleteUser(ctx, alice.ID)
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Expected sql.ErrNoRows deleting a deleted user, got: %v", err)
+	}
+	mem.SoftDeleteUserChirps(ctx, database.SoftDeleteUserChirpsParams{DeletedAt: user.DeletedAt, UserID: alice.ID})
+	mem.RevokeUserTokens(ctx, alice.ID)
+	row, _ := mem.GetUserFromRefreshToken(ctx, "alice-token")
+	if !row.RevokedAt.Valid {
+		t.Errorf("RevokeUserTokens should revoke the user's tokens")
+	}
+	following, _ := mem.ListFollowing(ctx, database.ListFollowingParams{UserID: bob.ID, BeforeFollowedAt: now().Add(time.Hour), BeforeID: uuid.Max, PageLimit: 10})
+	if len(following) != 0 {
+		t.Errorf("Deleted users should be left out of ListFollowing, got %+v", following)
+	}
+
+	// restoring the user only brings back the chirps deleted along with them
+	_, err = mem.RestoreUser(ctx, alice.ID)
+	if err != nil {
+		t.Fatalf("Error restoring user: %v", err)
+	}
+	mem.RestoreUserChirps(ctx, database.RestoreUserChirpsParams{UserID: alice.ID, DeletedAt: user.DeletedAt})
+	chirps, _ := mem.GetChirpsByUser(ctx, alice.ID)
+	if len(chirps) != 1 || chirps[0].ID != kept.ID {
+		t.Fatalf("Expected only the chirp deleted with the user back, got %+v", chirps)
+	}
+	restored, err := mem.RestoreChirp(ctx, mine.ID)
+	if err != nil || restored.DeletedAt.Valid {
+		t.Fatalf("RestoreChirp returned %+v, %v", restored, err)
+	}
+	_, err = mem.RestoreChirp(ctx, mine.ID)
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Expected sql.ErrNoRows restoring a chirp that isn't deleted, got: %v", err)
+	}
+
+	// only chirps deleted before the cutoff are purgeable, and purged ones can't come back
+	mem.SoftDeleteChirp(ctx, kept.ID)
+	purgeable, _ := mem.ListPurgeableChirps(ctx, database.ListPurgeableChirpsParams{DeletedBefore: sql.NullTime{Time: now().Add(-time.Hour), Valid: true}, PageLimit: 10})
+	if len(purgeable) != 0 {
+		t.Errorf("Nothing was deleted an hour ago, got %+v", purgeable)
+	}
+	purgeable, _ = mem.ListPurgeableChirps(ctx, database.ListPurgeableChirpsParams{DeletedBefore: sql.NullTime{Time: now().Add(time.Hour), Valid: true}, PageLimit: 10})
+	if len(purgeable) != 1 || purgeable[0].ID != kept.ID {
+		t.Fatalf("Expected the deleted chirp to be purgeable, got %+v", purgeable)
+	}
+	mem.TombstoneChirp(ctx, kept.ID)
+	_, err = mem.RestoreChirp(ctx, kept.ID)
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Expected sql.ErrNoRows restoring a purged chirp, got: %v", err)
+	}
+
+	// purging the user takes everything of theirs with them
+	mem.SoftDeleteUser(ctx, alice.ID)
+	users, _ := mem.ListPurgeableUsers(ctx, database.ListPurgeableUsersParams{DeletedBefore: sql.NullTime{Time: now().Add(time.Hour), Valid: true}, PageLimit: 10})
+	if len(users) != 1 || users[0].ID != alice.ID {
+		t.Fatalf("Expected alice to be purgeable, got %+v", users)
+	}
+	mem.DeleteUser(ctx, alice.ID)
+	if _, err = mem.GetUserByEmail(ctx, "alice@example.com"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Expected sql.ErrNoRows for a purged user's email, got: %v", err)
+	}
+	if len(mem.chirps) != 1 || len(mem.follows) != 0 || len(mem.tokens) != 0 || len(mem.handles) != 0 {
+		t.Errorf("DeleteUser should cascade, left chirps %v, follows %v, tokens %v, handles %v", mem.chirps, mem.follows, mem.tokens, mem.handles)
+	}
+	orphan, _ := mem.GetSingleChirp(ctx, reply.ID)
+	if orphan.InReplyTo.Valid {
+		t.Errorf("Replies to a purged user's chirps should lose their parent")
+	}
+}
+
+func TestMemoryFilters(t *testing.T) {
+	ctx := context.Background()
+	mem := NewMemory()
+	alice, _ := mem.CreateUser(ctx, database.CreateUserParams{Email: "alice@example.com"})
+
+	// the default list from the migration is already there
+	lists, _ := mem.ListFilterLists(ctx)
+	if len(lists) != 1 || lists[0].Name != "default" || lists[0].Action != "mask" {
+		t.Fatalf("Expected the default list, got %+v", lists)
+	}
+
+	// upserting keeps created_at and changes the action
+	created, _ := mem.UpsertFilterList(ctx, database.UpsertFilterListParams{Name: "watch", Action: "mask"})
+	updated, _ := mem.UpsertFilterList(ctx, database.UpsertFilterListParams{Name: "watch", Action: "flag"})
+	if updated.Action != "flag" || !updated.CreatedAt.Equal(created.CreatedAt) {
+		t.Errorf("UpsertFilterList returned %+v", updated)
+	}
+	mem.AddFilterWord(ctx, database.AddFilterWordParams{ListName: "watch", Word: "scam"})
+	mem.AddFilterWord(ctx, database.AddFilterWordParams{ListName: "watch", Word: "scam"})
+	err := mem.AddFilterWord(ctx, database.AddFilterWordParams{ListName: "nope", Word: "scam"})
+	if err == nil {
+		t.Errorf("Adding a word to a list that isn't there should fail")
+	}
+	words, _ := mem.ListFilterWords(ctx)
+	if len(words) != 4 || words[3].ListName != "watch" || words[3].Word != "scam" {
+		t.Errorf("Expected the default words then scam, got %+v", words)
+	}
+
+	// flags go newest first, and go away with their chirp
+	chirp, _ := mem.CreateChirp(ctx, database.CreateChirpParams{Body: "a scam", UserID: alice.ID})
+	mem.CreateChirpFlag(ctx, database.CreateChirpFlagParams{ChirpID: chirp.ID, ListName: "watch", Word: "scam"})
+	flags, _ := mem.ListChirpFlags(ctx, database.ListChirpFlagsParams{BeforeCreatedAt: now().Add(time.Hour), BeforeID: uuid.Max, PageLimit: 10})
+	if len(flags) != 1 || flags[0].ChirpID != chirp.ID {
+		t.Fatalf("Expected one flag, got %+v", flags)
+	}
+	mem.DeleteSingleChirp(ctx, chirp.ID)
+	flags, _ = mem.ListChirpFlags(ctx, database.ListChirpFlagsParams{BeforeCreatedAt: now().Add(time.Hour), BeforeID: uuid.Max, PageLimit: 10})
+	if len(flags) != 0 {
+		t.Errorf("Deleting a chirp should delete its flags, got %+v", flags)
+	}
+
+	// deleting a list takes its words with it
+	mem.DeleteFilterList(ctx, "watch")
+	_, err = mem.GetFilterList(ctx, "watch")
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Expected sql.ErrNoRows getting a deleted list, got: %v", err)
+	}
+	words, _ = mem.ListFilterWords(ctx)
+	if len(words) != 3 {
+		t.Errorf("Deleting a list should delete its words, got %+v", words)
+	}
+}
+
+func TestMemoryHeldChirps(t *testing.T) {
+	ctx := context.Background()
+	mem := NewMemory()
+	alice, _ := mem.CreateUser(ctx, database.CreateUserParams{Email: "alice@example.com"})
+	parent, _ := mem.CreateChirp(ctx, database.CreateChirpParams{Body: "parent", UserID: alice.ID})
+	upload, _ := mem.CreateMediaUpload(ctx, database.CreateMediaUploadParams{UserID: alice.ID, BlobKey: "a", ContentType: "image/png", Size: 10})
+
+	first, err := mem.CreateHeldChirp(ctx, database.CreateHeldChirpParams{UserID: alice.ID, Body: "first", Stage: "spam", Reason: "too many links"})
+	if err != nil {
+		t.Fatalf("CreateHeldChirp returned an error: %v", err)
+	}
+	reply, _ := mem.CreateHeldChirp(ctx, database.CreateHeldChirpParams{UserID: alice.ID, Body: "reply", InReplyTo: uuid.NullUUID{UUID: parent.ID, Valid: true}, Stage: "rules", Reason: "an ad"})
+	_, err = mem.CreateHeldChirp(ctx, database.CreateHeldChirpParams{UserID: uuid.New(), Body: "nobody"})
+	if !errors.Is(err, ErrForeignKeyViolation) {
+		t.Errorf("Expected ErrForeignKeyViolation holding a chirp for nobody, got: %v", err)
+	}
+
+	// held media isn't orphaned
+	mem.AddHeldChirpMedia(ctx, database.AddHeldChirpMediaParams{HeldChirpID: first.ID, MediaID: upload.ID, Position: 0})
+	later := database.ListOrphanedMediaParams{CreatedBefore: time.Now().Add(time.Hour), PageLimit: 10}
+	orphans, _ := mem.ListOrphanedMedia(ctx, later)
+	if len(orphans) != 0 {
+		t.Errorf("Media on a held chirp shouldn't be orphaned, got %+v", orphans)
+	}
+	media, _ := mem.ListHeldChirpMedia(ctx, first.ID)
+	if len(media) != 1 || media[0].MediaID != upload.ID {
+		t.Errorf("Expected the upload on the held chirp, got %+v", media)
+	}
+
+	// the queue is oldest first
+	queue, _ := mem.ListHeldChirps(ctx, database.ListHeldChirpsParams{AfterCreatedAt: time.Time{}, AfterID: uuid.Nil, PageLimit: 10})
+	if len(queue) != 2 || queue[0].ID != first.ID || queue[1].ID != reply.ID {
+		t.Fatalf("Expected both held chirps oldest first, got %+v", queue)
+	}
+
+	// held replies go with their parent
+	mem.DeleteSingleChirp(ctx, parent.ID)
+	_, err = mem.GetHeldChirp(ctx, reply.ID)
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Deleting a chirp should delete held replies to it, got: %v", err)
+	}
+
+	// and once a held chirp's gone its media is orphaned
+	claimed, err := mem.ClaimHeldChirp(ctx, first.ID)
+	if err != nil || claimed.ID != first.ID {
+		t.Errorf("ClaimHeldChirp returned %+v, %v", claimed, err)
+	}
+	orphans, _ = mem.ListOrphanedMedia(ctx, later)
+	if len(orphans) != 1 {
+		t.Errorf("Media from a deleted held chirp should be orphaned, got %+v", orphans)
+	}
+	// only the first claim gets it
+	_, err = mem.ClaimHeldChirp(ctx, first.ID)
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Expected sql.ErrNoRows claiming a held chirp twice, got: %v", err)
+	}
+}
+
+func TestMemoryReports(t *testing.T) {
+	ctx := context.Background()
+	mem := NewMemory()
+	alice, _ := mem.CreateUser(ctx, database.CreateUserParams{Email: "alice@example.com"})
+	bob, _ := mem.CreateUser(ctx, database.CreateUserParams{Email: "bob@example.com"})
+	mod, _ := mem.CreateUser(ctx, database.CreateUserParams{Email: "mod@example.com"})
+	chirp, _ := mem.CreateChirp(ctx, database.CreateChirpParams{Body: "rude", UserID: bob.ID})
+
+	first, err := mem.CreateReport(ctx, database.CreateReportParams{ReporterID: alice.ID, UserID: bob.ID, ChirpID: uuid.NullUUID{UUID: chirp.ID, Valid: true}, Category: "harassment"})
+	if err != nil {
+		t.Fatalf("CreateReport returned an error: %v", err)
+	}
+	if first.Status != "open" {
+		t.Errorf("Expected a new report to be open, got %q", first.Status)
+	}
+	second, _ := mem.CreateReport(ctx, database.CreateReportParams{ReporterID: alice.ID, UserID: bob.ID, Category: "spam"})
+
+	// only open reports can be claimed
+	mine := uuid.NullUUID{UUID: mod.ID, Valid: true}
+	claimed, err := mem.ClaimReport(ctx, database.ClaimReportParams{ClaimedBy: mine, ID: first.ID})
+	if err != nil || claimed.Status != "claimed" || claimed.ClaimedBy != mine {
+		t.Fatalf("Expected the report to be claimed, got %+v (%v)", claimed, err)
+	}
+	_, err = mem.ClaimReport(ctx, database.ClaimReportParams{ClaimedBy: uuid.NullUUID{UUID: alice.ID, Valid: true}, ID: first.ID})
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Claiming a claimed report should fail, got: %v", err)
+	}
+
+	// the queue is oldest first
+	queue, _ := mem.ListUnresolvedReports(ctx, database.ListUnresolvedReportsParams{AfterCreatedAt: time.Time{}, AfterID: uuid.Nil, PageLimit: 10})
+	if len(queue) != 2 || queue[0].ID != first.ID || queue[1].ID != second.ID {
+		t.Fatalf("Expected both reports oldest first, got %+v", queue)
+	}
+	open, _ := mem.ListReportsByStatus(ctx, database.ListReportsByStatusParams{Status: "open", AfterCreatedAt: time.Time{}, AfterID: uuid.Nil, PageLimit: 10})
+	if len(open) != 1 || open[0].ID != second.ID {
+		t.Errorf("Expected just the unclaimed report to be open, got %+v", open)
+	}
+
+	// resolving a report claims it too, and it can only be resolved once
+	resolved, err := mem.ResolveReport(ctx, database.ResolveReportParams{ClaimedBy: mine, Resolution: sql.NullString{String: "dismiss", Valid: true}, Note: "fine", ID: second.ID})
+	if err != nil || resolved.Status != "resolved" || !resolved.ClaimedAt.Valid || !resolved.ResolvedAt.Valid {
+		t.Fatalf("Expected the report to be resolved, got %+v (%v)", resolved, err)
+	}
+	_, err = mem.ResolveReport(ctx, database.ResolveReportParams{ClaimedBy: mine, Resolution: sql.NullString{String: "remove", Valid: true}, ID: second.ID})
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Resolving a resolved report should fail, got: %v", err)
+	}
+	mem.CreateReportNotification(ctx, database.CreateReportNotificationParams{UserID: alice.ID, ActorID: bob.ID, Kind: "report_resolved", ReportID: uuid.NullUUID{UUID: second.ID, Valid: true}})
+	mem.CreateReportNotification(ctx, database.CreateReportNotificationParams{UserID: alice.ID, ActorID: bob.ID, Kind: "report_resolved", ReportID: uuid.NullUUID{UUID: first.ID, Valid: true}})
+	notifications, _ := mem.ListNotifications(ctx, database.ListNotificationsParams{UserID: alice.ID, BeforeCreatedAt: time.Now().Add(time.Hour), BeforeID: uuid.Max, PageLimit: 10})
+	if len(notifications) != 2 {
+		t.Errorf("Expected a notification for each report, got %+v", notifications)
+	}
+
+	made, _ := mem.ListReportsByReporter(ctx, database.ListReportsByReporterParams{ReporterID: alice.ID, BeforeCreatedAt: time.Now().Add(time.Hour), BeforeID: uuid.Max, PageLimit: 10})
+	if len(made) != 2 || made[0].ID != second.ID {
+		t.Errorf("Expected alice's reports newest first, got %+v", made)
+	}
+
+	// chirp reports go with the chirp, and their notifications go with them
+	mem.DeleteSingleChirp(ctx, chirp.ID)
+	_, err = mem.GetReport(ctx, first.ID)
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Deleting a chirp should delete reports about it, got: %v", err)
+	}
+	notifications, _ = mem.ListNotifications(ctx, database.ListNotificationsParams{UserID: alice.ID, BeforeCreatedAt: time.Now().Add(time.Hour), BeforeID: uuid.Max, PageLimit: 10})
+	if len(notifications) != 1 {
+		t.Errorf("Expected the deleted report's notification to go too, got %+v", notifications)
+	}
+}
+
+func TestMemoryRoles(t *testing.T) {
+	ctx := context.Background()
+	mem := NewMemory()
+	alice, _ := mem.CreateUser(ctx, database.CreateUserParams{Email: "alice@example.com"})
+	bob, _ := mem.CreateUser(ctx, database.CreateUserParams{Email: "bob@example.com"})
+	if alice.Role != "user" {
+		t.Errorf("Expected new users to be plain users, got %q", alice.Role)
+	}
+
+	bob, err := mem.SetUserRole(ctx, database.SetUserRoleParams{Role: "moderator", ID: bob.ID})
+	if err != nil || bob.Role != "moderator" {
+		t.Fatalf("Expected bob to be a moderator, got %+v (%v)", bob, err)
+	}
+	_, err = mem.SetUserRole(ctx, database.SetUserRoleParams{Role: "admin", ID: uuid.New()})
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Expected sql.ErrNoRows for a missing user, got: %v", err)
+	}
+
+	by := uuid.NullUUID{UUID: alice.ID, Valid: true}
+	first, err := mem.CreateRoleChange(ctx, database.CreateRoleChangeParams{UserID: bob.ID, ChangedBy: by, OldRole: "user", NewRole: "moderator"})
+	if err != nil {
+		t.Fatalf("CreateRoleChange returned an error: %v", err)
+	}
+	second, _ := mem.CreateRoleChange(ctx, database.CreateRoleChangeParams{UserID: alice.ID, OldRole: "user", NewRole: "admin", Reason: "listed in ADMINS"})
+	_, err = mem.CreateRoleChange(ctx, database.CreateRoleChangeParams{UserID: uuid.New(), OldRole: "user", NewRole: "admin"})
+	if !errors.Is(err, ErrForeignKeyViolation) {
+		t.Errorf("Expected ErrForeignKeyViolation changing nobody's role, got: %v", err)
+	}
+
+	later := time.Now().Add(time.Hour)
+	all, _ := mem.ListRoleChanges(ctx, database.ListRoleChangesParams{BeforeCreatedAt: later, BeforeID: uuid.Max, PageLimit: 10})
+	if len(all) != 2 || all[0].ID != second.ID || all[1].ID != first.ID {
+		t.Fatalf("Expected both changes newest first, got %+v", all)
+	}
+	bobs, _ := mem.ListUserRoleChanges(ctx, database.ListUserRoleChangesParams{UserID: bob.ID, BeforeCreatedAt: later, BeforeID: uuid.Max, PageLimit: 10})
+	if len(bobs) != 1 || bobs[0].ID != first.ID {
+		t.Errorf("Expected just bob's change, got %+v", bobs)
+	}
+
+	// the trail outlives whoever made the change, but not who it was made to
+	mem.DeleteUser(ctx, alice.ID)
+	all, _ = mem.ListRoleChanges(ctx, database.ListRoleChangesParams{BeforeCreatedAt: later, BeforeID: uuid.Max, PageLimit: 10})
+	if len(all) != 1 || all[0].ID != first.ID || all[0].ChangedBy.Valid {
+		t.Errorf("Expected bob's change to be left with no changed_by, got %+v", all)
+	}
+}
+
+func TestMemoryAccountStatus(t *testing.T) {
+	ctx := context.Background()
+	mem := NewMemory()
+	alice, _ := mem.CreateUser(ctx, database.CreateUserParams{Email: "alice@example.com"})
+	bob, _ := mem.CreateUser(ctx, database.CreateUserParams{Email: "bob@example.com"})
+	if alice.Status != "active" {
+		t.Errorf("Expected new users to be active, got %q", alice.Status)
+	}
+	first, _ := mem.CreateChirp(ctx, database.CreateChirpParams{Body: "first", UserID: alice.ID})
+	mem.CreateChirp(ctx, database.CreateChirpParams{Body: "second", UserID: bob.ID})
+	mem.Rechirp(ctx, database.RechirpParams{UserID: alice.ID, ChirpID: first.ID})
+	mem.FollowUser(ctx, database.FollowUserParams{FollowerID: bob.ID, FolloweeID: alice.ID})
+	page := database.ListChirpsAscParams{AfterCreatedAt: time.Time{}, AfterID: uuid.Nil, PageLimit: 10}
+
+	// a suspension without hide_chirps leaves the chirps where they are
+	until := sql.NullTime{Time: time.Now().Add(time.Hour), Valid: true}
+	alice, err := mem.SetUserStatus(ctx, database.SetUserStatusParams{Status: "suspended", StatusReason: "spam", StatusUntil: until, ID: alice.ID})
+	if err != nil || alice.Status != "suspended" || alice.StatusReason != "spam" {
+		t.Fatalf("Expected alice to be suspended, got %+v (%v)", alice, err)
+	}
+	rows, _ := mem.ListChirpsAsc(ctx, page)
+	if len(rows) != 3 {
+		t.Errorf("Expected every chirp and rechirp, got %d", len(rows))
+	}
+
+	// with it, both her chirps and rechirps of them go, but only from the chirp listings
+	mem.SetUserStatus(ctx, database.SetUserStatusParams{Status: "banned", HideChirps: true, ID: alice.ID})
+	rows, _ = mem.ListChirpsAsc(ctx, page)
+	if len(rows) != 1 || rows[0].Chirp.UserID != bob.ID {
+		t.Errorf("Expected just bob's chirp, got %+v", rows)
+	}
+	byAlice, _ := mem.ListChirpsByUserDesc(ctx, database.ListChirpsByUserDescParams{UserID: alice.ID, BeforeCreatedAt: time.Now().Add(time.Hour), BeforeID: uuid.Max, PageLimit: 10})
+	if len(byAlice) != 0 {
+		t.Errorf("Expected none of alice's chirps, got %+v", byAlice)
+	}
+	timeline, _ := mem.ListTimeline(ctx, database.ListTimelineParams{UserID: bob.ID, BeforeCreatedAt: time.Now().Add(time.Hour), BeforeID: uuid.Max, PageLimit: 10})
+	if len(timeline) != 3 {
+		t.Errorf("Expected bob's timeline to be left alone, got %d entries", len(timeline))
+	}
+
+	// and they come back once it's over
+	over := sql.NullTime{Time: time.Now().Add(-time.Minute), Valid: true}
+	mem.SetUserStatus(ctx, database.SetUserStatusParams{Status: "suspended", StatusUntil: over, HideChirps: true, ID: alice.ID})
+	rows, _ = mem.ListChirpsAsc(ctx, page)
+	if len(rows) != 3 {
+		t.Errorf("Expected alice's chirps back after her suspension, got %d", len(rows))
+	}
+
+	_, err = mem.SetUserStatus(ctx, database.SetUserStatusParams{Status: "banned", ID: uuid.New()})
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Expected sql.ErrNoRows for a missing user, got: %v", err)
+	}
+}
+
+func TestConstraintErrorStates(t *testing.T) {
+	cases := map[error]string{ErrUniqueViolation: "23505", ErrForeignKeyViolation: "23503"}
+	for err, state := range cases {
+		var stater interface{ SQLState() string }
+		if !errors.As(fmt.Errorf("wrapped: %w", err), &stater) || stater.SQLState() != state {
+			t.Errorf("Expected %v to have SQLSTATE %s", err, state)
+		}
+	}
+}
+
+func TestMemoryTransactions(t *testing.T) {
+	ctx := context.Background()
+	mem := NewMemory()
+	user, _ := mem.CreateUser(ctx, database.CreateUserParams{Email: "a@example.com", HashedPassword: "hash"})
+
+	// a failed transaction takes everything it did with it, even from a nested one
+	failed := errors.New("failed")
+	err := mem.InTx(ctx, func(q Store) error {
+		_, err := q.CreateChirp(ctx, database.CreateChirpParams{Body: "rolled back", UserID: user.ID})
+		if err != nil {
+			return err
+		}
+		return q.InTx(ctx, func(q Store) error {
+			_, err := q.SoftDeleteUser(ctx, user.ID)
+			if err != nil {
+				return err
+			}
+			return failed
+		})
+	})
+	if !errors.Is(err, failed) {
+		t.Errorf("InTx returned %v, expected the error from fn", err)
+	}
+	chirps, _ := mem.GetChirpsByUser(ctx, user.ID)
+	found, _ := mem.GetUserByID(ctx, user.ID)
+	if len(chirps) != 0 || found.DeletedAt.Valid {
+		t.Errorf("Expected the transaction to be rolled back, got %d chirps and deleted %v", len(chirps), found.DeletedAt.Valid)
+	}
+
+	err = mem.InTx(ctx, func(q Store) error {
+		_, err := q.CreateChirp(ctx, database.CreateChirpParams{Body: "committed", UserID: user.ID})
+		return err
+	})
+	chirps, _ = mem.GetChirpsByUser(ctx, user.ID)
+	if err != nil || len(chirps) != 1 {
+		t.Errorf("Expected the transaction to be committed, got %d chirps and %v", len(chirps), err)
+	}
+}
@@ -0,0 +1,48 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"internal/database"
+)
+
+// the Postgres store is just the sqlc generated code
+type Postgres struct {
+	*database.Queries
+	db *sql.DB
+	// set when this is the store for a transaction
+	tx *sql.Tx
+}
+
+var _ Store = (*Postgres)(nil)
+
+// wrap an open Postgres connection
+func NewPostgres(db *sql.DB) *Postgres {
+	return &Postgres{
+		Queries: database.New(db),
+		db: db,
+	}
+}
+
+// how the connection pool's doing
+func (p *Postgres) Stats() sql.DBStats {
+	return p.db.Stats()
+}
+
+// ts_headline marks the matches but doesn't escape anything, so that's done here
+func (p *Postgres) SearchChirps(ctx context.Context, arg database.SearchChirpsParams) ([]database.SearchChirpsRow, error) {
+	rows, err := p.Queries.SearchChirps(ctx, arg)
+	for i := range rows {
+		rows[i].Snippet = markSnippet(rows[i].Snippet)
+	}
+	return rows, err
+}
+
+func (p *Postgres) InTx(ctx context.Context, fn func(Store) error) error {
+	if p.tx != nil {
+		return fn(p)
+	}
+	return runTx(ctx, p.db, func(tx *sql.Tx) error {
+		return fn(&Postgres{Queries: p.Queries.WithTx(tx), db: p.db, tx: tx})
+	})
+}
@@ -0,0 +1,168 @@
+package store
+
+import (
+	"html"
+	"internal/database"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// the stores without Postgres' full-text search fall back to plain substring matching
+// it understands the same basic syntax as websearch_to_tsquery:
+// bare words, "quoted phrases" and -excluded words, all case-insensitive
+type textQuery struct {
+	include []string
+	exclude []string
+}
+
+func parseTextQuery(q string) textQuery {
+	query := textQuery{}
+	for len(q) > 0 {
+		q = strings.TrimLeftFunc(q, unicode.IsSpace)
+		if q == "" {
+			break
+		}
+		exclude := false
+		if q[0] == '-' {
+			exclude = true
+			q = q[1:]
+		}
+		var term string
+		if strings.HasPrefix(q, "\"") {
+			phrase, rest, _ := strings.Cut(q[1:], "\"")
+			term, q = phrase, rest
+		} else {
+			end := strings.IndexFunc(q, unicode.IsSpace)
+			if end == -1 {
+				end = len(q)
+			}
+			term, q = q[:end], q[end:]
+		}
+		term = strings.ToLower(strings.TrimSpace(term))
+		if term == "" {
+			continue
+		}
+		if exclude {
+			query.exclude = append(query.exclude, term)
+		} else {
+			query.include = append(query.include, term)
+		}
+	}
+	return query
+}
+
+// the longest term, which makes the best LIKE pattern for narrowing things down
+func (q textQuery) longest() string {
+	longest := ""
+	for _, term := range q.include {
+		if len(term) > len(longest) {
+			longest = term
+		}
+	}
+	return longest
+}
+
+// whether body matches, and how well (just the number of times the terms turn up)
+func (q textQuery) match(body string) (bool, float32) {
+	if len(q.include) == 0 {
+		return false, 0
+	}
+	lower := strings.ToLower(body)
+	for _, term := range q.exclude {
+		if strings.Contains(lower, term) {
+			return false, 0
+		}
+	}
+	var rank float32
+	for _, term := range q.include {
+		count := strings.Count(lower, term)
+		if count == 0 {
+			return false, 0
+		}
+		rank += float32(count)
+	}
+	return true, rank
+}
+
+// wraps every match in <mark> tags, the same way the Postgres query's ts_headline does
+func (q textQuery) highlight(body string) string {
+	body = stripMarkers.Replace(body)
+	lower := strings.ToLower(body)
+	// a few letters change length when lowercased, and then the offsets don't line up
+	if len(lower) != len(body) {
+		return markSnippet(body)
+	}
+	marked := make([]bool, len(body))
+	for _, term := range q.include {
+		for start := 0; ; {
+			i := strings.Index(lower[start:], term)
+			if i == -1 {
+				break
+			}
+			for j := start + i; j < start+i+len(term); j++ {
+				marked[j] = true
+			}
+			start += i + len(term)
+		}
+	}
+	var out strings.Builder
+	for i := 0; i < len(body); i++ {
+		if marked[i] && (i == 0 || !marked[i-1]) {
+			out.WriteString(markStart)
+		}
+		out.WriteByte(body[i])
+		if marked[i] && (i == len(body)-1 || !marked[i+1]) {
+			out.WriteString(markStop)
+		}
+	}
+	return markSnippet(out.String())
+}
+
+// snippets are HTML, so matches are wrapped in these while it's still plain text
+// they're stripped from the chirp first (see stripMarkers) so a chirp can't add marks of its own
+const (
+	markStart = "\x01"
+	markStop = "\x02"
+)
+
+var stripMarkers = strings.NewReplacer(markStart, "", markStop, "")
+
+// escapes the rest of the chirp, so the <mark> tags are the only markup in a snippet
+func markSnippet(snippet string) string {
+	snippet = html.EscapeString(snippet)
+	return strings.NewReplacer(markStart, "<mark>", markStop, "</mark>").Replace(snippet)
+}
+
+// filters, ranks, sorts and pages chirps the way the SearchChirps query does
+func searchChirps(chirps []database.Chirp, arg database.SearchChirpsParams) []database.SearchChirpsRow {
+	query := parseTextQuery(arg.Query)
+	rows := []database.SearchChirpsRow{}
+	for _, c := range chirps {
+		if arg.AuthorID.Valid && c.UserID != arg.AuthorID.UUID {
+			continue
+		}
+		if c.CreatedAt.Before(arg.Since) || !c.CreatedAt.Before(arg.Until) {
+			continue
+		}
+		ok, rank := query.match(c.Body)
+		if !ok {
+			continue
+		}
+		rows = append(rows, database.SearchChirpsRow{Chirp: c, Rank: rank, Snippet: query.highlight(c.Body)})
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if arg.ByRelevance && rows[i].Rank != rows[j].Rank {
+			return rows[i].Rank > rows[j].Rank
+		}
+		return chirpCompare(rows[i].Chirp, rows[j].Chirp.CreatedAt, rows[j].Chirp.ID) > 0
+	})
+	if int(arg.PageOffset) >= len(rows) {
+		return []database.SearchChirpsRow{}
+	}
+	rows = rows[arg.PageOffset:]
+	if len(rows) > int(arg.PageLimit) {
+		rows = rows[:arg.PageLimit]
+	}
+	return rows
+}
@@ -0,0 +1,71 @@
+package store
+
+import (
+	"testing"
+)
+
+func TestTextQuery(t *testing.T) {
+	cases := []struct{
+		query string
+		body string
+		match bool
+		snippet string
+	}{
+		{
+			query: "hello",
+			body: "Hello world",
+			match: true,
+			snippet: "<mark>Hello</mark> world",
+		},
+		{
+			query: "hello moon",
+			body: "Hello world",
+			match: false,
+		},
+		{
+			query: "\"big dog\"",
+			body: "That's a big dog!",
+			match: true,
+			snippet: "That&#39;s a <mark>big dog</mark>!",
+		},
+		{
+			query: "\"big dog\"",
+			body: "A dog that is big",
+			match: false,
+		},
+		{
+			query: "dog -cat",
+			body: "dog and cat",
+			match: false,
+		},
+		{
+			query: "dog -cat",
+			body: "dog and dog",
+			match: true,
+			snippet: "<mark>dog</mark> and <mark>dog</mark>",
+		},
+		{
+			// the chirp's own markup is escaped, and only the matches are marked up
+			query: "alert",
+			body: "<script>alert(1)</script> \x01<img src=x onerror=alert(2)>\x02",
+			match: true,
+			snippet: "&lt;script&gt;<mark>alert</mark>(1)&lt;/script&gt; &lt;img src=x onerror=<mark>alert</mark>(2)&gt;",
+		},
+		{
+			query: "   ",
+			body: "anything",
+			match: false,
+		},
+	}
+	for _, c := range cases {
+		q := parseTextQuery(c.query)
+		ok, _ := q.match(c.body)
+		if ok != c.match {
+			t.Errorf("Query: %q\nBody: %q\nExpected match: %v", c.query, c.body, c.match)
+			continue
+		}
+		if ok && q.highlight(c.body) != c.snippet {
+			t.Errorf("Query: %q\nSnippet: %q\nExpected: %q", c.query, q.highlight(c.body), c.snippet)
+		}
+	}
+}
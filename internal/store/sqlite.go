@@ -0,0 +1,1154 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"internal/database"
+	"internal/sqlitedb"
+	"github.com/google/uuid"
+	"strings"
+)
+
+// the SQLite store wraps the code sqlc generates for the sqlite engine
+// SQLite can't make uuids or timestamps for us, so those get filled in here instead
+// most of the generated rows have the same columns as the Postgres ones, so they convert straight across
+type SQLite struct {
+	q *sqlitedb.Queries
+	db *sql.DB
+	// set when this is the store for a transaction
+	tx *sql.Tx
+}
+
+var _ Store = (*SQLite)(nil)
+
+// wrap an open SQLite connection
+func NewSQLite(db *sql.DB) *SQLite {
+	return &SQLite{
+		q: sqlitedb.New(db),
+		db: db,
+	}
+}
+
+// how the connection pool's doing
+func (s *SQLite) Stats() sql.DBStats {
+	return s.db.Stats()
+}
+
+func (s *SQLite) InTx(ctx context.Context, fn func(Store) error) error {
+	if s.tx != nil {
+		return fn(s)
+	}
+	return runTx(ctx, s.db, func(tx *sql.Tx) error {
+		return fn(&SQLite{q: s.q.WithTx(tx), db: s.db, tx: tx})
+	})
+}
+
+// turns SQLite's constraint errors into the same ones the memory store uses
+func sqliteErr(err error) error {
+	if err == nil {
+		return nil
+	}
+	msg := err.Error()
+	if strings.Contains(msg, "UNIQUE constraint failed") {
+		return ErrUniqueViolation
+	}
+	if strings.Contains(msg, "FOREIGN KEY constraint failed") {
+		return ErrForeignKeyViolation
+	}
+	return err
+}
+
+func sqliteChirp(c sqlitedb.Chirp) database.Chirp {
+	return database.Chirp{
+		ID: c.ID,
+		CreatedAt: c.CreatedAt,
+		UpdatedAt: c.UpdatedAt,
+		Body: c.Body,
+		UserID: c.UserID,
+		InReplyTo: c.InReplyTo,
+		DeletedAt: c.DeletedAt,
+		QuoteOf: c.QuoteOf,
+		EditedAt: c.EditedAt,
+		PurgedAt: c.PurgedAt,
+	}
+}
+
+func sqliteChirps(rows []sqlitedb.Chirp) []database.Chirp {
+	chirps := make([]database.Chirp, 0, len(rows))
+	for _, r := range rows {
+		chirps = append(chirps, sqliteChirp(r))
+	}
+	return chirps
+}
+
+func (s *SQLite) CreateChirp(ctx context.Context, arg database.CreateChirpParams) (database.Chirp, error) {
+	t := now()
+	chirp, err := s.q.CreateChirp(ctx, sqlitedb.CreateChirpParams{
+		ID: uuid.New(),
+		CreatedAt: t,
+		UpdatedAt: t,
+		Body: arg.Body,
+		UserID: arg.UserID,
+		InReplyTo: arg.InReplyTo,
+		QuoteOf: arg.QuoteOf,
+	})
+	return sqliteChirp(chirp), sqliteErr(err)
+}
+
+func (s *SQLite) GetAllChirps(ctx context.Context) ([]database.Chirp, error) {
+	rows, err := s.q.GetAllChirps(ctx)
+	return sqliteChirps(rows), err
+}
+
+func (s *SQLite) GetChirpsByUser(ctx context.Context, userID uuid.UUID) ([]database.Chirp, error) {
+	rows, err := s.q.GetChirpsByUser(ctx, userID)
+	return sqliteChirps(rows), err
+}
+
+func (s *SQLite) GetSingleChirp(ctx context.Context, id uuid.UUID) (database.Chirp, error) {
+	chirp, err := s.q.GetSingleChirp(ctx, id)
+	return sqliteChirp(chirp), err
+}
+
+func (s *SQLite) GetChirpForUpdate(ctx context.Context, id uuid.UUID) (database.Chirp, error) {
+	chirp, err := s.q.GetChirpForUpdate(ctx, id)
+	return sqliteChirp(chirp), err
+}
+
+func (s *SQLite) GetChirpsByIDs(ctx context.Context, ids []uuid.UUID) ([]database.Chirp, error) {
+	rows, err := s.q.GetChirpsByIDs(ctx, ids)
+	return sqliteChirps(rows), err
+}
+
+func (s *SQLite) DeleteSingleChirp(ctx context.Context, id uuid.UUID) error {
+	return s.q.DeleteSingleChirp(ctx, id)
+}
+
+func (s *SQLite) ListChirpsAsc(ctx context.Context, arg database.ListChirpsAscParams) ([]database.ListChirpsAscRow, error) {
+	rows, err := s.q.ListChirpsAsc(ctx, sqlitedb.ListChirpsAscParams{
+		AfterCreatedAt: arg.AfterCreatedAt,
+		AfterID: arg.AfterID,
+		Now: now(),
+		PageLimit: int64(arg.PageLimit),
+	})
+	entries := make([]database.ListChirpsAscRow, 0, len(rows))
+	for _, r := range rows {
+		entries = append(entries, database.ListChirpsAscRow{Chirp: sqliteChirp(r.Chirp), EntryID: r.EntryID, EntryAt: r.EntryAt, RechirpedBy: r.RechirpedBy})
+	}
+	return entries, err
+}
+
+func (s *SQLite) ListChirpsDesc(ctx context.Context, arg database.ListChirpsDescParams) ([]database.ListChirpsDescRow, error) {
+	rows, err := s.q.ListChirpsDesc(ctx, sqlitedb.ListChirpsDescParams{
+		BeforeCreatedAt: arg.BeforeCreatedAt,
+		BeforeID: arg.BeforeID,
+		Now: now(),
+		PageLimit: int64(arg.PageLimit),
+	})
+	entries := make([]database.ListChirpsDescRow, 0, len(rows))
+	for _, r := range rows {
+		entries = append(entries, database.ListChirpsDescRow{Chirp: sqliteChirp(r.Chirp), EntryID: r.EntryID, EntryAt: r.EntryAt, RechirpedBy: r.RechirpedBy})
+	}
+	return entries, err
+}
+
+func (s *SQLite) ListChirpsByUserAsc(ctx context.Context, arg database.ListChirpsByUserAscParams) ([]database.ListChirpsByUserAscRow, error) {
+	rows, err := s.q.ListChirpsByUserAsc(ctx, sqlitedb.ListChirpsByUserAscParams{
+		UserID: arg.UserID,
+		AfterCreatedAt: arg.AfterCreatedAt,
+		AfterID: arg.AfterID,
+		Now: now(),
+		PageLimit: int64(arg.PageLimit),
+	})
+	entries := make([]database.ListChirpsByUserAscRow, 0, len(rows))
+	for _, r := range rows {
+		entries = append(entries, database.ListChirpsByUserAscRow{Chirp: sqliteChirp(r.Chirp), EntryID: r.EntryID, EntryAt: r.EntryAt, RechirpedBy: r.RechirpedBy})
+	}
+	return entries, err
+}
+
+func (s *SQLite) ListChirpsByUserDesc(ctx context.Context, arg database.ListChirpsByUserDescParams) ([]database.ListChirpsByUserDescRow, error) {
+	rows, err := s.q.ListChirpsByUserDesc(ctx, sqlitedb.ListChirpsByUserDescParams{
+		UserID: arg.UserID,
+		BeforeCreatedAt: arg.BeforeCreatedAt,
+		BeforeID: arg.BeforeID,
+		Now: now(),
+		PageLimit: int64(arg.PageLimit),
+	})
+	entries := make([]database.ListChirpsByUserDescRow, 0, len(rows))
+	for _, r := range rows {
+		entries = append(entries, database.ListChirpsByUserDescRow{Chirp: sqliteChirp(r.Chirp), EntryID: r.EntryID, EntryAt: r.EntryAt, RechirpedBy: r.RechirpedBy})
+	}
+	return entries, err
+}
+
+// LIKE narrows things down to chirps containing the longest term, then search.go does the rest
+func (s *SQLite) SearchChirps(ctx context.Context, arg database.SearchChirpsParams) ([]database.SearchChirpsRow, error) {
+	escaper := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+	rows, err := s.q.SearchChirpsCandidates(ctx, sqlitedb.SearchChirpsCandidatesParams{
+		Pattern: "%" + escaper.Replace(parseTextQuery(arg.Query).longest()) + "%",
+		Since: arg.Since,
+		Until: arg.Until,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return searchChirps(sqliteChirps(rows), arg), nil
+}
+
+func (s *SQLite) SoftDeleteChirp(ctx context.Context, id uuid.UUID) error {
+	return s.q.SoftDeleteChirp(ctx, sqlitedb.SoftDeleteChirpParams{
+		DeletedAt: sql.NullTime{Time: now(), Valid: true},
+		ID: id,
+	})
+}
+
+func (s *SQLite) TombstoneChirp(ctx context.Context, id uuid.UUID) error {
+	t := now()
+	return s.q.TombstoneChirp(ctx, sqlitedb.TombstoneChirpParams{
+		PurgedAt: sql.NullTime{Time: t, Valid: true},
+		UpdatedAt: t,
+		ID: id,
+	})
+}
+
+func (s *SQLite) RestoreChirp(ctx context.Context, id uuid.UUID) (database.Chirp, error) {
+	chirp, err := s.q.RestoreChirp(ctx, sqlitedb.RestoreChirpParams{UpdatedAt: now(), ID: id})
+	return sqliteChirp(chirp), err
+}
+
+func (s *SQLite) ListUnpurgedUserChirps(ctx context.Context, userID uuid.UUID) ([]database.Chirp, error) {
+	rows, err := s.q.ListUnpurgedUserChirps(ctx, userID)
+	return sqliteChirps(rows), err
+}
+
+func (s *SQLite) ReassignTombstones(ctx context.Context, arg database.ReassignTombstonesParams) error {
+	return s.q.ReassignTombstones(ctx, sqlitedb.ReassignTombstonesParams{NewUserID: arg.NewUserID, UpdatedAt: now(), UserID: arg.UserID})
+}
+
+func (s *SQLite) SoftDeleteUserChirps(ctx context.Context, arg database.SoftDeleteUserChirpsParams) error {
+	return s.q.SoftDeleteUserChirps(ctx, sqlitedb.SoftDeleteUserChirpsParams(arg))
+}
+
+func (s *SQLite) RestoreUserChirps(ctx context.Context, arg database.RestoreUserChirpsParams) error {
+	return s.q.RestoreUserChirps(ctx, sqlitedb.RestoreUserChirpsParams{
+		UpdatedAt: now(),
+		UserID: arg.UserID,
+		DeletedAt: arg.DeletedAt,
+	})
+}
+
+func (s *SQLite) ListPurgeableChirps(ctx context.Context, arg database.ListPurgeableChirpsParams) ([]database.Chirp, error) {
+	rows, err := s.q.ListPurgeableChirps(ctx, sqlitedb.ListPurgeableChirpsParams{
+		DeletedBefore: arg.DeletedBefore,
+		PageLimit: int64(arg.PageLimit),
+	})
+	return sqliteChirps(rows), err
+}
+
+func (s *SQLite) EditChirp(ctx context.Context, arg database.EditChirpParams) (database.Chirp, error) {
+	chirp, err := s.q.EditChirp(ctx, sqlitedb.EditChirpParams{
+		Body: arg.Body,
+		EditedAt: sql.NullTime{Time: now(), Valid: true},
+		ID: arg.ID,
+	})
+	return sqliteChirp(chirp), err
+}
+
+func (s *SQLite) CreateChirpRevision(ctx context.Context, arg database.CreateChirpRevisionParams) error {
+	err := s.q.CreateChirpRevision(ctx, sqlitedb.CreateChirpRevisionParams{
+		ID: uuid.New(),
+		ChirpID: arg.ChirpID,
+		Body: arg.Body,
+		CreatedAt: arg.CreatedAt,
+		ReplacedAt: now(),
+	})
+	return sqliteErr(err)
+}
+
+func (s *SQLite) ListChirpRevisions(ctx context.Context, chirpID uuid.UUID) ([]database.ChirpRevision, error) {
+	rows, err := s.q.ListChirpRevisions(ctx, chirpID)
+	revisions := make([]database.ChirpRevision, 0, len(rows))
+	for _, r := range rows {
+		revisions = append(revisions, database.ChirpRevision(r))
+	}
+	return revisions, err
+}
+
+func (s *SQLite) DeleteChirpRevisions(ctx context.Context, chirpID uuid.UUID) error {
+	return s.q.DeleteChirpRevisions(ctx, chirpID)
+}
+
+func (s *SQLite) ListReplies(ctx context.Context, arg database.ListRepliesParams) ([]database.Chirp, error) {
+	rows, err := s.q.ListReplies(ctx, sqlitedb.ListRepliesParams{
+		InReplyTo: arg.InReplyTo,
+		AfterCreatedAt: arg.AfterCreatedAt,
+		AfterID: arg.AfterID,
+		PageLimit: int64(arg.PageLimit),
+	})
+	return sqliteChirps(rows), err
+}
+
+// in_reply_to is nullable, so sqlc wants the ids as NullUUIDs here
+func nullUUIDs(ids []uuid.UUID) []uuid.NullUUID {
+	nulls := make([]uuid.NullUUID, 0, len(ids))
+	for _, id := range ids {
+		nulls = append(nulls, uuid.NullUUID{UUID: id, Valid: true})
+	}
+	return nulls
+}
+
+func (s *SQLite) GetRepliesToChirps(ctx context.Context, arg database.GetRepliesToChirpsParams) ([]database.Chirp, error) {
+	rows, err := s.q.GetRepliesToChirps(ctx, sqlitedb.GetRepliesToChirpsParams{
+		ParentIds: nullUUIDs(arg.ParentIds),
+		PageLimit: int64(arg.PageLimit),
+	})
+	return sqliteChirps(rows), err
+}
+
+func (s *SQLite) CountReplies(ctx context.Context, ids []uuid.UUID) ([]database.CountRepliesRow, error) {
+	rows, err := s.q.CountReplies(ctx, nullUUIDs(ids))
+	counts := make([]database.CountRepliesRow, 0, len(rows))
+	for _, r := range rows {
+		counts = append(counts, database.CountRepliesRow(r))
+	}
+	return counts, err
+}
+
+func (s *SQLite) LikeChirp(ctx context.Context, arg database.LikeChirpParams) error {
+	err := s.q.LikeChirp(ctx, sqlitedb.LikeChirpParams{
+		UserID: arg.UserID,
+		ChirpID: arg.ChirpID,
+		CreatedAt: now(),
+	})
+	return sqliteErr(err)
+}
+
+func (s *SQLite) UnlikeChirp(ctx context.Context, arg database.UnlikeChirpParams) error {
+	return s.q.UnlikeChirp(ctx, sqlitedb.UnlikeChirpParams(arg))
+}
+
+func (s *SQLite) CountLikes(ctx context.Context, ids []uuid.UUID) ([]database.CountLikesRow, error) {
+	rows, err := s.q.CountLikes(ctx, ids)
+	counts := make([]database.CountLikesRow, 0, len(rows))
+	for _, r := range rows {
+		counts = append(counts, database.CountLikesRow(r))
+	}
+	return counts, err
+}
+
+func (s *SQLite) GetLikedByUser(ctx context.Context, arg database.GetLikedByUserParams) ([]uuid.UUID, error) {
+	return s.q.GetLikedByUser(ctx, sqlitedb.GetLikedByUserParams(arg))
+}
+
+func (s *SQLite) ListUserLikes(ctx context.Context, arg database.ListUserLikesParams) ([]database.ListUserLikesRow, error) {
+	rows, err := s.q.ListUserLikes(ctx, sqlitedb.ListUserLikesParams{
+		UserID: arg.UserID,
+		BeforeLikedAt: arg.BeforeLikedAt,
+		BeforeID: arg.BeforeID,
+		PageLimit: int64(arg.PageLimit),
+	})
+	likes := make([]database.ListUserLikesRow, 0, len(rows))
+	for _, r := range rows {
+		likes = append(likes, database.ListUserLikesRow{Chirp: sqliteChirp(r.Chirp), LikedAt: r.LikedAt})
+	}
+	return likes, err
+}
+
+func (s *SQLite) Rechirp(ctx context.Context, arg database.RechirpParams) error {
+	err := s.q.Rechirp(ctx, sqlitedb.RechirpParams{
+		ID: uuid.New(),
+		CreatedAt: now(),
+		UserID: arg.UserID,
+		ChirpID: arg.ChirpID,
+	})
+	return sqliteErr(err)
+}
+
+func (s *SQLite) UndoRechirp(ctx context.Context, arg database.UndoRechirpParams) error {
+	return s.q.UndoRechirp(ctx, sqlitedb.UndoRechirpParams(arg))
+}
+
+func (s *SQLite) CountRechirps(ctx context.Context, ids []uuid.UUID) ([]database.CountRechirpsRow, error) {
+	rows, err := s.q.CountRechirps(ctx, ids)
+	counts := make([]database.CountRechirpsRow, 0, len(rows))
+	for _, r := range rows {
+		counts = append(counts, database.CountRechirpsRow(r))
+	}
+	return counts, err
+}
+
+func (s *SQLite) TagChirp(ctx context.Context, arg database.TagChirpParams) error {
+	return sqliteErr(s.q.TagChirp(ctx, sqlitedb.TagChirpParams(arg)))
+}
+
+func (s *SQLite) ClearHashtags(ctx context.Context, chirpID uuid.UUID) error {
+	return s.q.ClearHashtags(ctx, chirpID)
+}
+
+func (s *SQLite) ListHashtagChirps(ctx context.Context, arg database.ListHashtagChirpsParams) ([]database.Chirp, error) {
+	rows, err := s.q.ListHashtagChirps(ctx, sqlitedb.ListHashtagChirpsParams{
+		Tag: arg.Tag,
+		BeforeCreatedAt: arg.BeforeCreatedAt,
+		BeforeID: arg.BeforeID,
+		PageLimit: int64(arg.PageLimit),
+	})
+	return sqliteChirps(rows), err
+}
+
+// the scoring happens in trends.go, see the query
+func (s *SQLite) TrendingHashtags(ctx context.Context, arg database.TrendingHashtagsParams) ([]database.TrendingHashtagsRow, error) {
+	rows, err := s.q.ListHashtagUses(ctx, arg.Since)
+	if err != nil {
+		return nil, err
+	}
+	uses := make([]hashtagUse, 0, len(rows))
+	for _, r := range rows {
+		uses = append(uses, hashtagUse(r))
+	}
+	return scoreTrends(uses, arg), nil
+}
+
+func (s *SQLite) FollowUser(ctx context.Context, arg database.FollowUserParams) error {
+	err := s.q.FollowUser(ctx, sqlitedb.FollowUserParams{
+		FollowerID: arg.FollowerID,
+		FolloweeID: arg.FolloweeID,
+		CreatedAt: now(),
+	})
+	return sqliteErr(err)
+}
+
+func (s *SQLite) GetFollow(ctx context.Context, arg database.GetFollowParams) (database.Follow, error) {
+	follow, err := s.q.GetFollow(ctx, sqlitedb.GetFollowParams(arg))
+	return database.Follow(follow), err
+}
+
+func (s *SQLite) UnfollowUser(ctx context.Context, arg database.UnfollowUserParams) error {
+	return s.q.UnfollowUser(ctx, sqlitedb.UnfollowUserParams(arg))
+}
+
+func (s *SQLite) ListFollowers(ctx context.Context, arg database.ListFollowersParams) ([]database.ListFollowersRow, error) {
+	rows, err := s.q.ListFollowers(ctx, sqlitedb.ListFollowersParams{
+		UserID: arg.UserID,
+		BeforeFollowedAt: arg.BeforeFollowedAt,
+		BeforeID: arg.BeforeID,
+		PageLimit: int64(arg.PageLimit),
+	})
+	followers := make([]database.ListFollowersRow, 0, len(rows))
+	for _, r := range rows {
+		followers = append(followers, database.ListFollowersRow(r))
+	}
+	return followers, err
+}
+
+func (s *SQLite) ListFollowing(ctx context.Context, arg database.ListFollowingParams) ([]database.ListFollowingRow, error) {
+	rows, err := s.q.ListFollowing(ctx, sqlitedb.ListFollowingParams{
+		UserID: arg.UserID,
+		BeforeFollowedAt: arg.BeforeFollowedAt,
+		BeforeID: arg.BeforeID,
+		PageLimit: int64(arg.PageLimit),
+	})
+	following := make([]database.ListFollowingRow, 0, len(rows))
+	for _, r := range rows {
+		following = append(following, database.ListFollowingRow(r))
+	}
+	return following, err
+}
+
+func (s *SQLite) ListTimeline(ctx context.Context, arg database.ListTimelineParams) ([]database.ListTimelineRow, error) {
+	rows, err := s.q.ListTimeline(ctx, sqlitedb.ListTimelineParams{
+		UserID: arg.UserID,
+		BeforeCreatedAt: arg.BeforeCreatedAt,
+		BeforeID: arg.BeforeID,
+		PageLimit: int64(arg.PageLimit),
+	})
+	entries := make([]database.ListTimelineRow, 0, len(rows))
+	for _, r := range rows {
+		entries = append(entries, database.ListTimelineRow{Chirp: sqliteChirp(r.Chirp), EntryID: r.EntryID, EntryAt: r.EntryAt, RechirpedBy: r.RechirpedBy})
+	}
+	return entries, err
+}
+
+func (s *SQLite) MentionUser(ctx context.Context, arg database.MentionUserParams) error {
+	return sqliteErr(s.q.MentionUser(ctx, sqlitedb.MentionUserParams(arg)))
+}
+
+func (s *SQLite) ClearMentions(ctx context.Context, chirpID uuid.UUID) error {
+	return s.q.ClearMentions(ctx, chirpID)
+}
+
+func (s *SQLite) GetMentionsForChirps(ctx context.Context, chirpIds []uuid.UUID) ([]database.GetMentionsForChirpsRow, error) {
+	rows, err := s.q.GetMentionsForChirps(ctx, chirpIds)
+	mentions := make([]database.GetMentionsForChirpsRow, 0, len(rows))
+	for _, r := range rows {
+		mentions = append(mentions, database.GetMentionsForChirpsRow(r))
+	}
+	return mentions, err
+}
+
+func (s *SQLite) CreateNotification(ctx context.Context, arg database.CreateNotificationParams) error {
+	err := s.q.CreateNotification(ctx, sqlitedb.CreateNotificationParams{
+		ID: uuid.New(),
+		CreatedAt: now(),
+		UserID: arg.UserID,
+		ActorID: arg.ActorID,
+		Kind: arg.Kind,
+		ChirpID: arg.ChirpID,
+	})
+	return sqliteErr(err)
+}
+
+func (s *SQLite) CreateReportNotification(ctx context.Context, arg database.CreateReportNotificationParams) error {
+	err := s.q.CreateReportNotification(ctx, sqlitedb.CreateReportNotificationParams{
+		ID: uuid.New(),
+		CreatedAt: now(),
+		UserID: arg.UserID,
+		ActorID: arg.ActorID,
+		Kind: arg.Kind,
+		ReportID: arg.ReportID,
+	})
+	return sqliteErr(err)
+}
+
+func sqliteNotifications(rows []sqlitedb.Notification) []database.Notification {
+	notifications := make([]database.Notification, 0, len(rows))
+	for _, r := range rows {
+		notifications = append(notifications, database.Notification(r))
+	}
+	return notifications
+}
+
+func (s *SQLite) ListNotifications(ctx context.Context, arg database.ListNotificationsParams) ([]database.Notification, error) {
+	rows, err := s.q.ListNotifications(ctx, sqlitedb.ListNotificationsParams{
+		UserID: arg.UserID,
+		BeforeCreatedAt: arg.BeforeCreatedAt,
+		BeforeID: arg.BeforeID,
+		PageLimit: int64(arg.PageLimit),
+	})
+	return sqliteNotifications(rows), err
+}
+
+func (s *SQLite) ListUnreadNotifications(ctx context.Context, arg database.ListUnreadNotificationsParams) ([]database.Notification, error) {
+	rows, err := s.q.ListUnreadNotifications(ctx, sqlitedb.ListUnreadNotificationsParams{
+		UserID: arg.UserID,
+		BeforeCreatedAt: arg.BeforeCreatedAt,
+		BeforeID: arg.BeforeID,
+		PageLimit: int64(arg.PageLimit),
+	})
+	return sqliteNotifications(rows), err
+}
+
+func (s *SQLite) MarkNotificationsRead(ctx context.Context, arg database.MarkNotificationsReadParams) error {
+	return s.q.MarkNotificationsRead(ctx, sqlitedb.MarkNotificationsReadParams{
+		ReadAt: now(),
+		UserID: arg.UserID,
+		Ids: arg.Ids,
+	})
+}
+
+func (s *SQLite) MarkAllNotificationsRead(ctx context.Context, userID uuid.UUID) error {
+	return s.q.MarkAllNotificationsRead(ctx, sqlitedb.MarkAllNotificationsReadParams{
+		ReadAt: now(),
+		UserID: userID,
+	})
+}
+
+func (s *SQLite) CreateConversation(ctx context.Context, isGroup bool) (database.Conversation, error) {
+	t := now()
+	conversation, err := s.q.CreateConversation(ctx, sqlitedb.CreateConversationParams{
+		ID: uuid.New(),
+		CreatedAt: t,
+		UpdatedAt: t,
+		IsGroup: isGroup,
+	})
+	return database.Conversation(conversation), err
+}
+
+func (s *SQLite) GetConversation(ctx context.Context, id uuid.UUID) (database.Conversation, error) {
+	conversation, err := s.q.GetConversation(ctx, id)
+	return database.Conversation(conversation), err
+}
+
+func (s *SQLite) FindDirectConversation(ctx context.Context, arg database.FindDirectConversationParams) (database.Conversation, error) {
+	conversation, err := s.q.FindDirectConversation(ctx, sqlitedb.FindDirectConversationParams(arg))
+	return database.Conversation(conversation), err
+}
+
+func (s *SQLite) TouchConversation(ctx context.Context, arg database.TouchConversationParams) error {
+	return s.q.TouchConversation(ctx, sqlitedb.TouchConversationParams{
+		UpdatedAt: arg.UpdatedAt,
+		ID: arg.ID,
+	})
+}
+
+func (s *SQLite) AddParticipant(ctx context.Context, arg database.AddParticipantParams) error {
+	err := s.q.AddParticipant(ctx, sqlitedb.AddParticipantParams{
+		ConversationID: arg.ConversationID,
+		UserID: arg.UserID,
+		JoinedAt: now(),
+	})
+	return sqliteErr(err)
+}
+
+func (s *SQLite) GetParticipant(ctx context.Context, arg database.GetParticipantParams) (database.ConversationParticipant, error) {
+	participant, err := s.q.GetParticipant(ctx, sqlitedb.GetParticipantParams(arg))
+	return database.ConversationParticipant(participant), err
+}
+
+func (s *SQLite) ListParticipants(ctx context.Context, conversationIds []uuid.UUID) ([]database.ConversationParticipant, error) {
+	rows, err := s.q.ListParticipants(ctx, conversationIds)
+	participants := make([]database.ConversationParticipant, 0, len(rows))
+	for _, r := range rows {
+		participants = append(participants, database.ConversationParticipant(r))
+	}
+	return participants, err
+}
+
+func (s *SQLite) LeaveConversation(ctx context.Context, arg database.LeaveConversationParams) error {
+	return s.q.LeaveConversation(ctx, sqlitedb.LeaveConversationParams{
+		LeftAt: sql.NullTime{Time: now(), Valid: true},
+		ConversationID: arg.ConversationID,
+		UserID: arg.UserID,
+	})
+}
+
+func (s *SQLite) MarkConversationRead(ctx context.Context, arg database.MarkConversationReadParams) error {
+	return s.q.MarkConversationRead(ctx, sqlitedb.MarkConversationReadParams{
+		LastReadAt: arg.LastReadAt,
+		ConversationID: arg.ConversationID,
+		UserID: arg.UserID,
+	})
+}
+
+func (s *SQLite) ListConversations(ctx context.Context, arg database.ListConversationsParams) ([]database.ListConversationsRow, error) {
+	rows, err := s.q.ListConversations(ctx, sqlitedb.ListConversationsParams{
+		UserID: arg.UserID,
+		BeforeUpdatedAt: arg.BeforeUpdatedAt,
+		BeforeID: arg.BeforeID,
+		PageLimit: int64(arg.PageLimit),
+	})
+	conversations := make([]database.ListConversationsRow, 0, len(rows))
+	for _, r := range rows {
+		conversations = append(conversations, database.ListConversationsRow{Conversation: database.Conversation(r.Conversation), UnreadCount: r.UnreadCount})
+	}
+	return conversations, err
+}
+
+func (s *SQLite) CreateMessage(ctx context.Context, arg database.CreateMessageParams) (database.Message, error) {
+	message, err := s.q.CreateMessage(ctx, sqlitedb.CreateMessageParams{
+		ID: uuid.New(),
+		CreatedAt: now(),
+		ConversationID: arg.ConversationID,
+		SenderID: arg.SenderID,
+		Body: arg.Body,
+	})
+	return database.Message(message), sqliteErr(err)
+}
+
+func (s *SQLite) ListMessages(ctx context.Context, arg database.ListMessagesParams) ([]database.Message, error) {
+	rows, err := s.q.ListMessages(ctx, sqlitedb.ListMessagesParams{
+		ConversationID: arg.ConversationID,
+		BeforeCreatedAt: arg.BeforeCreatedAt,
+		BeforeID: arg.BeforeID,
+		PageLimit: int64(arg.PageLimit),
+	})
+	messages := make([]database.Message, 0, len(rows))
+	for _, r := range rows {
+		messages = append(messages, database.Message(r))
+	}
+	return messages, err
+}
+
+// position, width and height are INTEGERs, which come out of SQLite as int64s
+func sqliteMediaUpload(m sqlitedb.MediaUpload) database.MediaUpload {
+	return database.MediaUpload{
+		ID: m.ID,
+		CreatedAt: m.CreatedAt,
+		UserID: m.UserID,
+		BlobKey: m.BlobKey,
+		ContentType: m.ContentType,
+		Size: m.Size,
+		ChirpID: m.ChirpID,
+		Position: int32(m.Position),
+		Width: int32(m.Width),
+		Height: int32(m.Height),
+		Blurhash: m.Blurhash,
+		ThumbnailKey: m.ThumbnailKey,
+	}
+}
+
+func sqliteMediaUploads(rows []sqlitedb.MediaUpload) []database.MediaUpload {
+	media := make([]database.MediaUpload, 0, len(rows))
+	for _, r := range rows {
+		media = append(media, sqliteMediaUpload(r))
+	}
+	return media
+}
+
+func (s *SQLite) CreateMediaUpload(ctx context.Context, arg database.CreateMediaUploadParams) (database.MediaUpload, error) {
+	media, err := s.q.CreateMediaUpload(ctx, sqlitedb.CreateMediaUploadParams{
+		ID: uuid.New(),
+		CreatedAt: now(),
+		UserID: arg.UserID,
+		BlobKey: arg.BlobKey,
+		ContentType: arg.ContentType,
+		Size: arg.Size,
+		Width: int64(arg.Width),
+		Height: int64(arg.Height),
+		Blurhash: arg.Blurhash,
+		ThumbnailKey: arg.ThumbnailKey,
+	})
+	return sqliteMediaUpload(media), sqliteErr(err)
+}
+
+func (s *SQLite) GetMediaUpload(ctx context.Context, id uuid.UUID) (database.MediaUpload, error) {
+	media, err := s.q.GetMediaUpload(ctx, id)
+	return sqliteMediaUpload(media), err
+}
+
+func (s *SQLite) AttachMediaUpload(ctx context.Context, arg database.AttachMediaUploadParams) (int64, error) {
+	rows, err := s.q.AttachMediaUpload(ctx, sqlitedb.AttachMediaUploadParams{
+		ChirpID: arg.ChirpID,
+		Position: int64(arg.Position),
+		ID: arg.ID,
+	})
+	return rows, sqliteErr(err)
+}
+
+func (s *SQLite) GetMediaForChirps(ctx context.Context, chirpIds []uuid.UUID) ([]database.MediaUpload, error) {
+	rows, err := s.q.GetMediaForChirps(ctx, chirpIds)
+	return sqliteMediaUploads(rows), err
+}
+
+func (s *SQLite) ListOrphanedMedia(ctx context.Context, arg database.ListOrphanedMediaParams) ([]database.MediaUpload, error) {
+	rows, err := s.q.ListOrphanedMedia(ctx, sqlitedb.ListOrphanedMediaParams{
+		CreatedBefore: arg.CreatedBefore,
+		PageLimit: int64(arg.PageLimit),
+	})
+	return sqliteMediaUploads(rows), err
+}
+
+func (s *SQLite) ListUserMedia(ctx context.Context, userID uuid.UUID) ([]database.MediaUpload, error) {
+	rows, err := s.q.ListUserMedia(ctx, userID)
+	return sqliteMediaUploads(rows), err
+}
+
+func (s *SQLite) DeleteMediaUpload(ctx context.Context, id uuid.UUID) error {
+	return s.q.DeleteMediaUpload(ctx, id)
+}
+
+func (s *SQLite) UpsertFilterList(ctx context.Context, arg database.UpsertFilterListParams) (database.FilterList, error) {
+	list, err := s.q.UpsertFilterList(ctx, sqlitedb.UpsertFilterListParams{
+		Name: arg.Name,
+		CreatedAt: now(),
+		Action: arg.Action,
+	})
+	return database.FilterList(list), err
+}
+
+func (s *SQLite) GetFilterList(ctx context.Context, name string) (database.FilterList, error) {
+	list, err := s.q.GetFilterList(ctx, name)
+	return database.FilterList(list), err
+}
+
+func (s *SQLite) ListFilterLists(ctx context.Context) ([]database.FilterList, error) {
+	rows, err := s.q.ListFilterLists(ctx)
+	lists := make([]database.FilterList, 0, len(rows))
+	for _, r := range rows {
+		lists = append(lists, database.FilterList(r))
+	}
+	return lists, err
+}
+
+func (s *SQLite) DeleteFilterList(ctx context.Context, name string) error {
+	return s.q.DeleteFilterList(ctx, name)
+}
+
+func (s *SQLite) AddFilterWord(ctx context.Context, arg database.AddFilterWordParams) error {
+	err := s.q.AddFilterWord(ctx, sqlitedb.AddFilterWordParams{
+		ListName: arg.ListName,
+		Word: arg.Word,
+		CreatedAt: now(),
+	})
+	return sqliteErr(err)
+}
+
+func (s *SQLite) RemoveFilterWord(ctx context.Context, arg database.RemoveFilterWordParams) error {
+	return s.q.RemoveFilterWord(ctx, sqlitedb.RemoveFilterWordParams(arg))
+}
+
+func (s *SQLite) ClearFilterWords(ctx context.Context, listName string) error {
+	return s.q.ClearFilterWords(ctx, listName)
+}
+
+func (s *SQLite) ListFilterWords(ctx context.Context) ([]database.FilterWord, error) {
+	rows, err := s.q.ListFilterWords(ctx)
+	words := make([]database.FilterWord, 0, len(rows))
+	for _, r := range rows {
+		words = append(words, database.FilterWord(r))
+	}
+	return words, err
+}
+
+func (s *SQLite) CreateChirpFlag(ctx context.Context, arg database.CreateChirpFlagParams) error {
+	err := s.q.CreateChirpFlag(ctx, sqlitedb.CreateChirpFlagParams{
+		ID: uuid.New(),
+		CreatedAt: now(),
+		ChirpID: arg.ChirpID,
+		ListName: arg.ListName,
+		Word: arg.Word,
+	})
+	return sqliteErr(err)
+}
+
+func (s *SQLite) ListChirpFlags(ctx context.Context, arg database.ListChirpFlagsParams) ([]database.ChirpFlag, error) {
+	rows, err := s.q.ListChirpFlags(ctx, sqlitedb.ListChirpFlagsParams{
+		BeforeCreatedAt: arg.BeforeCreatedAt,
+		BeforeID: arg.BeforeID,
+		PageLimit: int64(arg.PageLimit),
+	})
+	flags := make([]database.ChirpFlag, 0, len(rows))
+	for _, r := range rows {
+		flags = append(flags, database.ChirpFlag(r))
+	}
+	return flags, err
+}
+
+func (s *SQLite) DeleteChirpFlag(ctx context.Context, id uuid.UUID) error {
+	return s.q.DeleteChirpFlag(ctx, id)
+}
+
+func (s *SQLite) CreateHeldChirp(ctx context.Context, arg database.CreateHeldChirpParams) (database.HeldChirp, error) {
+	held, err := s.q.CreateHeldChirp(ctx, sqlitedb.CreateHeldChirpParams{
+		ID: uuid.New(),
+		CreatedAt: now(),
+		UserID: arg.UserID,
+		Body: arg.Body,
+		InReplyTo: arg.InReplyTo,
+		QuoteOf: arg.QuoteOf,
+		Stage: arg.Stage,
+		Reason: arg.Reason,
+	})
+	return database.HeldChirp(held), sqliteErr(err)
+}
+
+func (s *SQLite) GetHeldChirp(ctx context.Context, id uuid.UUID) (database.HeldChirp, error) {
+	held, err := s.q.GetHeldChirp(ctx, id)
+	return database.HeldChirp(held), err
+}
+
+func (s *SQLite) ListHeldChirps(ctx context.Context, arg database.ListHeldChirpsParams) ([]database.HeldChirp, error) {
+	rows, err := s.q.ListHeldChirps(ctx, sqlitedb.ListHeldChirpsParams{
+		AfterCreatedAt: arg.AfterCreatedAt,
+		AfterID: arg.AfterID,
+		PageLimit: int64(arg.PageLimit),
+	})
+	held := make([]database.HeldChirp, 0, len(rows))
+	for _, r := range rows {
+		held = append(held, database.HeldChirp(r))
+	}
+	return held, err
+}
+
+func (s *SQLite) DeleteHeldChirp(ctx context.Context, id uuid.UUID) error {
+	return s.q.DeleteHeldChirp(ctx, id)
+}
+
+func (s *SQLite) ClaimHeldChirp(ctx context.Context, id uuid.UUID) (database.HeldChirp, error) {
+	held, err := s.q.ClaimHeldChirp(ctx, id)
+	return database.HeldChirp(held), err
+}
+
+func (s *SQLite) AddHeldChirpMedia(ctx context.Context, arg database.AddHeldChirpMediaParams) error {
+	err := s.q.AddHeldChirpMedia(ctx, sqlitedb.AddHeldChirpMediaParams{
+		HeldChirpID: arg.HeldChirpID,
+		MediaID: arg.MediaID,
+		Position: int64(arg.Position),
+	})
+	return sqliteErr(err)
+}
+
+func (s *SQLite) ListHeldChirpMedia(ctx context.Context, heldChirpID uuid.UUID) ([]database.HeldChirpMedium, error) {
+	rows, err := s.q.ListHeldChirpMedia(ctx, heldChirpID)
+	media := make([]database.HeldChirpMedium, 0, len(rows))
+	for _, r := range rows {
+		media = append(media, database.HeldChirpMedium{HeldChirpID: r.HeldChirpID, MediaID: r.MediaID, Position: int32(r.Position)})
+	}
+	return media, err
+}
+
+func (s *SQLite) CreateReport(ctx context.Context, arg database.CreateReportParams) (database.Report, error) {
+	report, err := s.q.CreateReport(ctx, sqlitedb.CreateReportParams{
+		ID: uuid.New(),
+		CreatedAt: now(),
+		ReporterID: arg.ReporterID,
+		UserID: arg.UserID,
+		ChirpID: arg.ChirpID,
+		Category: arg.Category,
+		Details: arg.Details,
+	})
+	return database.Report(report), sqliteErr(err)
+}
+
+func (s *SQLite) GetReport(ctx context.Context, id uuid.UUID) (database.Report, error) {
+	report, err := s.q.GetReport(ctx, id)
+	return database.Report(report), err
+}
+
+func sqliteReports(rows []sqlitedb.Report) []database.Report {
+	reports := make([]database.Report, 0, len(rows))
+	for _, r := range rows {
+		reports = append(reports, database.Report(r))
+	}
+	return reports
+}
+
+func (s *SQLite) ListReportsByStatus(ctx context.Context, arg database.ListReportsByStatusParams) ([]database.Report, error) {
+	rows, err := s.q.ListReportsByStatus(ctx, sqlitedb.ListReportsByStatusParams{
+		Status: arg.Status,
+		AfterCreatedAt: arg.AfterCreatedAt,
+		AfterID: arg.AfterID,
+		PageLimit: int64(arg.PageLimit),
+	})
+	return sqliteReports(rows), err
+}
+
+func (s *SQLite) ListUnresolvedReports(ctx context.Context, arg database.ListUnresolvedReportsParams) ([]database.Report, error) {
+	rows, err := s.q.ListUnresolvedReports(ctx, sqlitedb.ListUnresolvedReportsParams{
+		AfterCreatedAt: arg.AfterCreatedAt,
+		AfterID: arg.AfterID,
+		PageLimit: int64(arg.PageLimit),
+	})
+	return sqliteReports(rows), err
+}
+
+func (s *SQLite) ListReportsByReporter(ctx context.Context, arg database.ListReportsByReporterParams) ([]database.Report, error) {
+	rows, err := s.q.ListReportsByReporter(ctx, sqlitedb.ListReportsByReporterParams{
+		ReporterID: arg.ReporterID,
+		BeforeCreatedAt: arg.BeforeCreatedAt,
+		BeforeID: arg.BeforeID,
+		PageLimit: int64(arg.PageLimit),
+	})
+	return sqliteReports(rows), err
+}
+
+func (s *SQLite) ClaimReport(ctx context.Context, arg database.ClaimReportParams) (database.Report, error) {
+	report, err := s.q.ClaimReport(ctx, sqlitedb.ClaimReportParams{
+		ClaimedBy: arg.ClaimedBy,
+		ClaimedAt: sql.NullTime{Time: now(), Valid: true},
+		ID: arg.ID,
+	})
+	return database.Report(report), err
+}
+
+func (s *SQLite) ResolveReport(ctx context.Context, arg database.ResolveReportParams) (database.Report, error) {
+	report, err := s.q.ResolveReport(ctx, sqlitedb.ResolveReportParams{
+		ClaimedBy: arg.ClaimedBy,
+		ResolvedAt: sql.NullTime{Time: now(), Valid: true},
+		Resolution: arg.Resolution,
+		Note: arg.Note,
+		ID: arg.ID,
+	})
+	return database.Report(report), err
+}
+
+func (s *SQLite) CreateRoleChange(ctx context.Context, arg database.CreateRoleChangeParams) (database.RoleChange, error) {
+	change, err := s.q.CreateRoleChange(ctx, sqlitedb.CreateRoleChangeParams{
+		ID: uuid.New(),
+		CreatedAt: now(),
+		UserID: arg.UserID,
+		ChangedBy: arg.ChangedBy,
+		OldRole: arg.OldRole,
+		NewRole: arg.NewRole,
+		Reason: arg.Reason,
+	})
+	return database.RoleChange(change), sqliteErr(err)
+}
+
+func sqliteRoleChanges(rows []sqlitedb.RoleChange) []database.RoleChange {
+	changes := make([]database.RoleChange, 0, len(rows))
+	for _, r := range rows {
+		changes = append(changes, database.RoleChange(r))
+	}
+	return changes
+}
+
+func (s *SQLite) ListRoleChanges(ctx context.Context, arg database.ListRoleChangesParams) ([]database.RoleChange, error) {
+	rows, err := s.q.ListRoleChanges(ctx, sqlitedb.ListRoleChangesParams{
+		BeforeCreatedAt: arg.BeforeCreatedAt,
+		BeforeID: arg.BeforeID,
+		PageLimit: int64(arg.PageLimit),
+	})
+	return sqliteRoleChanges(rows), err
+}
+
+func (s *SQLite) ListUserRoleChanges(ctx context.Context, arg database.ListUserRoleChangesParams) ([]database.RoleChange, error) {
+	rows, err := s.q.ListUserRoleChanges(ctx, sqlitedb.ListUserRoleChangesParams{
+		UserID: arg.UserID,
+		BeforeCreatedAt: arg.BeforeCreatedAt,
+		BeforeID: arg.BeforeID,
+		PageLimit: int64(arg.PageLimit),
+	})
+	return sqliteRoleChanges(rows), err
+}
+
+func (s *SQLite) CreateUser(ctx context.Context, arg database.CreateUserParams) (database.User, error) {
+	t := now()
+	user, err := s.q.CreateUser(ctx, sqlitedb.CreateUserParams{
+		ID: uuid.New(),
+		CreatedAt: t,
+		UpdatedAt: t,
+		Email: arg.Email,
+		HashedPassword: arg.HashedPassword,
+		Handle: arg.Handle,
+	})
+	return database.User(user), sqliteErr(err)
+}
+
+func (s *SQLite) GetUserByEmail(ctx context.Context, email string) (database.User, error) {
+	user, err := s.q.GetUserByEmail(ctx, email)
+	return database.User(user), err
+}
+
+func (s *SQLite) GetUserByID(ctx context.Context, id uuid.UUID) (database.User, error) {
+	user, err := s.q.GetUserByID(ctx, id)
+	return database.User(user), err
+}
+
+// handle is a nullable column, so the generated code wants NullStrings
+func (s *SQLite) GetUsersByHandles(ctx context.Context, handles []string) ([]database.User, error) {
+	args := make([]sql.NullString, 0, len(handles))
+	for _, h := range handles {
+		args = append(args, sql.NullString{String: h, Valid: true})
+	}
+	rows, err := s.q.GetUsersByHandles(ctx, args)
+	users := make([]database.User, 0, len(rows))
+	for _, r := range rows {
+		users = append(users, database.User(r))
+	}
+	return users, err
+}
+
+func (s *SQLite) UpdateEmailAndPassword(ctx context.Context, arg database.UpdateEmailAndPasswordParams) (database.User, error) {
+	user, err := s.q.UpdateEmailAndPassword(ctx, sqlitedb.UpdateEmailAndPasswordParams{
+		Email: arg.Email,
+		HashedPassword: arg.HashedPassword,
+		UpdatedAt: now(),
+		ID: arg.ID,
+	})
+	return database.User(user), sqliteErr(err)
+}
+
+func (s *SQLite) UpdateHandle(ctx context.Context, arg database.UpdateHandleParams) (database.User, error) {
+	user, err := s.q.UpdateHandle(ctx, sqlitedb.UpdateHandleParams{
+		Handle: arg.Handle,
+		UpdatedAt: now(),
+		ID: arg.ID,
+	})
+	return database.User(user), sqliteErr(err)
+}
+
+func (s *SQLite) UpdateDMSetting(ctx context.Context, arg database.UpdateDMSettingParams) (database.User, error) {
+	user, err := s.q.UpdateDMSetting(ctx, sqlitedb.UpdateDMSettingParams{
+		DmsFromFollowersOnly: arg.DmsFromFollowersOnly,
+		UpdatedAt: now(),
+		ID: arg.ID,
+	})
+	return database.User(user), err
+}
+
+func (s *SQLite) UpgradeToRed(ctx context.Context, id uuid.UUID) error {
+	return s.q.UpgradeToRed(ctx, sqlitedb.UpgradeToRedParams{UpdatedAt: now(), ID: id})
+}
+
+func (s *SQLite) SoftDeleteUser(ctx context.Context, id uuid.UUID) (database.User, error) {
+	user, err := s.q.SoftDeleteUser(ctx, sqlitedb.SoftDeleteUserParams{
+		DeletedAt: sql.NullTime{Time: now(), Valid: true},
+		ID: id,
+	})
+	return database.User(user), err
+}
+
+func (s *SQLite) RestoreUser(ctx context.Context, id uuid.UUID) (database.User, error) {
+	user, err := s.q.RestoreUser(ctx, sqlitedb.RestoreUserParams{UpdatedAt: now(), ID: id})
+	return database.User(user), err
+}
+
+func (s *SQLite) SetUserStatus(ctx context.Context, arg database.SetUserStatusParams) (database.User, error) {
+	user, err := s.q.SetUserStatus(ctx, sqlitedb.SetUserStatusParams{
+		Status: arg.Status,
+		StatusReason: arg.StatusReason,
+		StatusUntil: arg.StatusUntil,
+		HideChirps: arg.HideChirps,
+		UpdatedAt: now(),
+		ID: arg.ID,
+	})
+	return database.User(user), sqliteErr(err)
+}
+
+func (s *SQLite) SetUserRole(ctx context.Context, arg database.SetUserRoleParams) (database.User, error) {
+	user, err := s.q.SetUserRole(ctx, sqlitedb.SetUserRoleParams{
+		Role: arg.Role,
+		UpdatedAt: now(),
+		ID: arg.ID,
+	})
+	return database.User(user), sqliteErr(err)
+}
+
+func (s *SQLite) ListPurgeableUsers(ctx context.Context, arg database.ListPurgeableUsersParams) ([]database.User, error) {
+	rows, err := s.q.ListPurgeableUsers(ctx, sqlitedb.ListPurgeableUsersParams{
+		DeletedBefore: arg.DeletedBefore,
+		PageLimit: int64(arg.PageLimit),
+	})
+	users := make([]database.User, 0, len(rows))
+	for _, r := range rows {
+		users = append(users, database.User(r))
+	}
+	return users, err
+}
+
+func (s *SQLite) CreatePlaceholderUser(ctx context.Context, id uuid.UUID) error {
+	t := now()
+	return s.q.CreatePlaceholderUser(ctx, sqlitedb.CreatePlaceholderUserParams{ID: id, CreatedAt: t, UpdatedAt: t})
+}
+
+func (s *SQLite) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	return s.q.DeleteUser(ctx, id)
+}
+
+func (s *SQLite) ResetUsers(ctx context.Context) error {
+	return s.q.ResetUsers(ctx)
+}
+
+func (s *SQLite) CreateToken(ctx context.Context, arg database.CreateTokenParams) (database.RefreshToken, error) {
+	t := now()
+	token, err := s.q.CreateToken(ctx, sqlitedb.CreateTokenParams{
+		Token: arg.Token,
+		CreatedAt: t,
+		UpdatedAt: t,
+		UserID: arg.UserID,
+		ExpiresAt: arg.ExpiresAt,
+	})
+	return database.RefreshToken(token), sqliteErr(err)
+}
+
+func (s *SQLite) GetUserFromRefreshToken(ctx context.Context, token string) (database.GetUserFromRefreshTokenRow, error) {
+	row, err := s.q.GetUserFromRefreshToken(ctx, token)
+	return database.GetUserFromRefreshTokenRow(row), err
+}
+
+func (s *SQLite) RevokeToken(ctx context.Context, token string) error {
+	t := now()
+	return s.q.RevokeToken(ctx, sqlitedb.RevokeTokenParams{
+		UpdatedAt: t,
+		RevokedAt: sql.NullTime{Time: t, Valid: true},
+		Token: token,
+	})
+}
+
+func (s *SQLite) RevokeUserTokens(ctx context.Context, userID uuid.UUID) error {
+	return s.q.RevokeUserTokens(ctx, sqlitedb.RevokeUserTokensParams{
+		RevokedAt: sql.NullTime{Time: now(), Valid: true},
+		UserID: userID,
+	})
+}
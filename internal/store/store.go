@@ -0,0 +1,195 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"internal/database"
+	"github.com/google/uuid"
+)
+
+// the stores backed by a database/sql connection pool can say how it's doing
+// (the memory store doesn't have one)
+type PoolStats interface {
+	Stats() sql.DBStats
+}
+
+// everything the handlers need from the database
+// (one method per sqlc query, so the generated *database.Queries already fits)
+type Store interface {
+	// InTx runs fn against a store where everything happens in one transaction,
+	// committed if fn returns nil and rolled back if it returns an error
+	// calling InTx again on the store fn gets just carries on in the same transaction
+	InTx(ctx context.Context, fn func(Store) error) error
+
+	CreateChirp(ctx context.Context, arg database.CreateChirpParams) (database.Chirp, error)
+	GetAllChirps(ctx context.Context) ([]database.Chirp, error)
+	GetChirpsByUser(ctx context.Context, userID uuid.UUID) ([]database.Chirp, error)
+	GetSingleChirp(ctx context.Context, id uuid.UUID) (database.Chirp, error)
+	GetChirpForUpdate(ctx context.Context, id uuid.UUID) (database.Chirp, error)
+	GetChirpsByIDs(ctx context.Context, ids []uuid.UUID) ([]database.Chirp, error)
+	DeleteSingleChirp(ctx context.Context, id uuid.UUID) error
+	SoftDeleteChirp(ctx context.Context, id uuid.UUID) error
+	TombstoneChirp(ctx context.Context, id uuid.UUID) error
+	RestoreChirp(ctx context.Context, id uuid.UUID) (database.Chirp, error)
+	SoftDeleteUserChirps(ctx context.Context, arg database.SoftDeleteUserChirpsParams) error
+	ListUnpurgedUserChirps(ctx context.Context, userID uuid.UUID) ([]database.Chirp, error)
+	ReassignTombstones(ctx context.Context, arg database.ReassignTombstonesParams) error
+	RestoreUserChirps(ctx context.Context, arg database.RestoreUserChirpsParams) error
+	ListPurgeableChirps(ctx context.Context, arg database.ListPurgeableChirpsParams) ([]database.Chirp, error)
+	EditChirp(ctx context.Context, arg database.EditChirpParams) (database.Chirp, error)
+	CreateChirpRevision(ctx context.Context, arg database.CreateChirpRevisionParams) error
+	ListChirpRevisions(ctx context.Context, chirpID uuid.UUID) ([]database.ChirpRevision, error)
+	DeleteChirpRevisions(ctx context.Context, chirpID uuid.UUID) error
+	ListChirpsAsc(ctx context.Context, arg database.ListChirpsAscParams) ([]database.ListChirpsAscRow, error)
+	ListChirpsDesc(ctx context.Context, arg database.ListChirpsDescParams) ([]database.ListChirpsDescRow, error)
+	ListChirpsByUserAsc(ctx context.Context, arg database.ListChirpsByUserAscParams) ([]database.ListChirpsByUserAscRow, error)
+	ListChirpsByUserDesc(ctx context.Context, arg database.ListChirpsByUserDescParams) ([]database.ListChirpsByUserDescRow, error)
+	SearchChirps(ctx context.Context, arg database.SearchChirpsParams) ([]database.SearchChirpsRow, error)
+	ListReplies(ctx context.Context, arg database.ListRepliesParams) ([]database.Chirp, error)
+	GetRepliesToChirps(ctx context.Context, arg database.GetRepliesToChirpsParams) ([]database.Chirp, error)
+	CountReplies(ctx context.Context, ids []uuid.UUID) ([]database.CountRepliesRow, error)
+
+	LikeChirp(ctx context.Context, arg database.LikeChirpParams) error
+	UnlikeChirp(ctx context.Context, arg database.UnlikeChirpParams) error
+	CountLikes(ctx context.Context, ids []uuid.UUID) ([]database.CountLikesRow, error)
+	GetLikedByUser(ctx context.Context, arg database.GetLikedByUserParams) ([]uuid.UUID, error)
+	ListUserLikes(ctx context.Context, arg database.ListUserLikesParams) ([]database.ListUserLikesRow, error)
+
+	Rechirp(ctx context.Context, arg database.RechirpParams) error
+	UndoRechirp(ctx context.Context, arg database.UndoRechirpParams) error
+	CountRechirps(ctx context.Context, ids []uuid.UUID) ([]database.CountRechirpsRow, error)
+
+	TagChirp(ctx context.Context, arg database.TagChirpParams) error
+	ClearHashtags(ctx context.Context, chirpID uuid.UUID) error
+	ListHashtagChirps(ctx context.Context, arg database.ListHashtagChirpsParams) ([]database.Chirp, error)
+	TrendingHashtags(ctx context.Context, arg database.TrendingHashtagsParams) ([]database.TrendingHashtagsRow, error)
+
+	FollowUser(ctx context.Context, arg database.FollowUserParams) error
+	GetFollow(ctx context.Context, arg database.GetFollowParams) (database.Follow, error)
+	UnfollowUser(ctx context.Context, arg database.UnfollowUserParams) error
+	ListFollowers(ctx context.Context, arg database.ListFollowersParams) ([]database.ListFollowersRow, error)
+	ListFollowing(ctx context.Context, arg database.ListFollowingParams) ([]database.ListFollowingRow, error)
+	ListTimeline(ctx context.Context, arg database.ListTimelineParams) ([]database.ListTimelineRow, error)
+
+	MentionUser(ctx context.Context, arg database.MentionUserParams) error
+	ClearMentions(ctx context.Context, chirpID uuid.UUID) error
+	GetMentionsForChirps(ctx context.Context, chirpIds []uuid.UUID) ([]database.GetMentionsForChirpsRow, error)
+
+	CreateNotification(ctx context.Context, arg database.CreateNotificationParams) error
+	CreateReportNotification(ctx context.Context, arg database.CreateReportNotificationParams) error
+	ListNotifications(ctx context.Context, arg database.ListNotificationsParams) ([]database.Notification, error)
+	ListUnreadNotifications(ctx context.Context, arg database.ListUnreadNotificationsParams) ([]database.Notification, error)
+	MarkNotificationsRead(ctx context.Context, arg database.MarkNotificationsReadParams) error
+	MarkAllNotificationsRead(ctx context.Context, userID uuid.UUID) error
+
+	CreateConversation(ctx context.Context, isGroup bool) (database.Conversation, error)
+	GetConversation(ctx context.Context, id uuid.UUID) (database.Conversation, error)
+	FindDirectConversation(ctx context.Context, arg database.FindDirectConversationParams) (database.Conversation, error)
+	TouchConversation(ctx context.Context, arg database.TouchConversationParams) error
+	AddParticipant(ctx context.Context, arg database.AddParticipantParams) error
+	GetParticipant(ctx context.Context, arg database.GetParticipantParams) (database.ConversationParticipant, error)
+	ListParticipants(ctx context.Context, conversationIds []uuid.UUID) ([]database.ConversationParticipant, error)
+	LeaveConversation(ctx context.Context, arg database.LeaveConversationParams) error
+	MarkConversationRead(ctx context.Context, arg database.MarkConversationReadParams) error
+	ListConversations(ctx context.Context, arg database.ListConversationsParams) ([]database.ListConversationsRow, error)
+	CreateMessage(ctx context.Context, arg database.CreateMessageParams) (database.Message, error)
+	ListMessages(ctx context.Context, arg database.ListMessagesParams) ([]database.Message, error)
+
+	CreateMediaUpload(ctx context.Context, arg database.CreateMediaUploadParams) (database.MediaUpload, error)
+	GetMediaUpload(ctx context.Context, id uuid.UUID) (database.MediaUpload, error)
+	AttachMediaUpload(ctx context.Context, arg database.AttachMediaUploadParams) (int64, error)
+	GetMediaForChirps(ctx context.Context, chirpIds []uuid.UUID) ([]database.MediaUpload, error)
+	ListOrphanedMedia(ctx context.Context, arg database.ListOrphanedMediaParams) ([]database.MediaUpload, error)
+	ListUserMedia(ctx context.Context, userID uuid.UUID) ([]database.MediaUpload, error)
+	DeleteMediaUpload(ctx context.Context, id uuid.UUID) error
+
+	UpsertFilterList(ctx context.Context, arg database.UpsertFilterListParams) (database.FilterList, error)
+	GetFilterList(ctx context.Context, name string) (database.FilterList, error)
+	ListFilterLists(ctx context.Context) ([]database.FilterList, error)
+	DeleteFilterList(ctx context.Context, name string) error
+	AddFilterWord(ctx context.Context, arg database.AddFilterWordParams) error
+	RemoveFilterWord(ctx context.Context, arg database.RemoveFilterWordParams) error
+	ClearFilterWords(ctx context.Context, listName string) error
+	ListFilterWords(ctx context.Context) ([]database.FilterWord, error)
+	CreateChirpFlag(ctx context.Context, arg database.CreateChirpFlagParams) error
+	ListChirpFlags(ctx context.Context, arg database.ListChirpFlagsParams) ([]database.ChirpFlag, error)
+	DeleteChirpFlag(ctx context.Context, id uuid.UUID) error
+
+	CreateHeldChirp(ctx context.Context, arg database.CreateHeldChirpParams) (database.HeldChirp, error)
+	GetHeldChirp(ctx context.Context, id uuid.UUID) (database.HeldChirp, error)
+	ListHeldChirps(ctx context.Context, arg database.ListHeldChirpsParams) ([]database.HeldChirp, error)
+	DeleteHeldChirp(ctx context.Context, id uuid.UUID) error
+	ClaimHeldChirp(ctx context.Context, id uuid.UUID) (database.HeldChirp, error)
+	AddHeldChirpMedia(ctx context.Context, arg database.AddHeldChirpMediaParams) error
+	ListHeldChirpMedia(ctx context.Context, heldChirpID uuid.UUID) ([]database.HeldChirpMedium, error)
+
+	CreateReport(ctx context.Context, arg database.CreateReportParams) (database.Report, error)
+	GetReport(ctx context.Context, id uuid.UUID) (database.Report, error)
+	ListReportsByStatus(ctx context.Context, arg database.ListReportsByStatusParams) ([]database.Report, error)
+	ListUnresolvedReports(ctx context.Context, arg database.ListUnresolvedReportsParams) ([]database.Report, error)
+	ListReportsByReporter(ctx context.Context, arg database.ListReportsByReporterParams) ([]database.Report, error)
+	ClaimReport(ctx context.Context, arg database.ClaimReportParams) (database.Report, error)
+	ResolveReport(ctx context.Context, arg database.ResolveReportParams) (database.Report, error)
+
+	CreateRoleChange(ctx context.Context, arg database.CreateRoleChangeParams) (database.RoleChange, error)
+	ListRoleChanges(ctx context.Context, arg database.ListRoleChangesParams) ([]database.RoleChange, error)
+	ListUserRoleChanges(ctx context.Context, arg database.ListUserRoleChangesParams) ([]database.RoleChange, error)
+
+	CreateUser(ctx context.Context, arg database.CreateUserParams) (database.User, error)
+	GetUserByEmail(ctx context.Context, email string) (database.User, error)
+	GetUserByID(ctx context.Context, id uuid.UUID) (database.User, error)
+	GetUsersByHandles(ctx context.Context, handles []string) ([]database.User, error)
+	UpdateEmailAndPassword(ctx context.Context, arg database.UpdateEmailAndPasswordParams) (database.User, error)
+	UpdateHandle(ctx context.Context, arg database.UpdateHandleParams) (database.User, error)
+	UpdateDMSetting(ctx context.Context, arg database.UpdateDMSettingParams) (database.User, error)
+	UpgradeToRed(ctx context.Context, id uuid.UUID) error
+	SoftDeleteUser(ctx context.Context, id uuid.UUID) (database.User, error)
+	RestoreUser(ctx context.Context, id uuid.UUID) (database.User, error)
+	SetUserStatus(ctx context.Context, arg database.SetUserStatusParams) (database.User, error)
+	SetUserRole(ctx context.Context, arg database.SetUserRoleParams) (database.User, error)
+	ListPurgeableUsers(ctx context.Context, arg database.ListPurgeableUsersParams) ([]database.User, error)
+	CreatePlaceholderUser(ctx context.Context, id uuid.UUID) error
+	DeleteUser(ctx context.Context, id uuid.UUID) error
+	ResetUsers(ctx context.Context) error
+
+	CreateToken(ctx context.Context, arg database.CreateTokenParams) (database.RefreshToken, error)
+	GetUserFromRefreshToken(ctx context.Context, token string) (database.GetUserFromRefreshTokenRow, error)
+	RevokeToken(ctx context.Context, token string) error
+	RevokeUserTokens(ctx context.Context, userID uuid.UUID) error
+}
+
+// runs fn in a transaction on db, for the stores backed by database/sql
+func runTx(ctx context.Context, db *sql.DB, fn func(*sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	// does nothing once it's committed
+	defer tx.Rollback()
+	err = fn(tx)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// the errors a non-Postgres store returns where Postgres would raise a constraint violation
+// (not-found is always sql.ErrNoRows, same as the generated code)
+// they have the same SQLSTATE codes as the Postgres ones, so callers can tell them apart the same way
+var (
+	ErrUniqueViolation error = &constraintError{state: "23505", msg: "duplicate key value violates unique constraint"}
+	ErrForeignKeyViolation error = &constraintError{state: "23503", msg: "insert or update violates foreign key constraint"}
+)
+
+type constraintError struct {
+	state string
+	msg string
+}
+
+func (e *constraintError) Error() string {
+	return e.msg
+}
+
+func (e *constraintError) SQLState() string {
+	return e.state
+}
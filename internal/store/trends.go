@@ -0,0 +1,46 @@
+package store
+
+import (
+	"internal/database"
+	"math"
+	"sort"
+	"time"
+)
+
+// one use of a hashtag, for the stores that score trends in Go
+type hashtagUse struct {
+	Tag string
+	CreatedAt time.Time
+}
+
+// the same scoring as the TrendingHashtags query: every use in the window counts for
+// exp(-age / decay), so a tag used a lot an hour ago beats one used a little more yesterday
+func scoreTrends(uses []hashtagUse, arg database.TrendingHashtagsParams) []database.TrendingHashtagsRow {
+	trends := map[string]*database.TrendingHashtagsRow{}
+	for _, u := range uses {
+		if u.CreatedAt.Before(arg.Since) {
+			continue
+		}
+		t, ok := trends[u.Tag]
+		if !ok {
+			t = &database.TrendingHashtagsRow{Tag: u.Tag}
+			trends[u.Tag] = t
+		}
+		t.Uses++
+		t.Score += math.Exp(-arg.Now.Sub(u.CreatedAt).Seconds() / arg.DecaySeconds)
+	}
+	rows := []database.TrendingHashtagsRow{}
+	for _, t := range trends {
+		rows = append(rows, *t)
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].Score == rows[j].Score {
+			return rows[i].Tag < rows[j].Tag
+		}
+		return rows[i].Score > rows[j].Score
+	})
+	if len(rows) > int(arg.PageLimit) {
+		rows = rows[:arg.PageLimit]
+	}
+	return rows
+}
@@ -0,0 +1,42 @@
+package store
+
+import (
+	"internal/database"
+	"testing"
+	"time"
+)
+
+func TestScoreTrends(t *testing.T) {
+	now := time.Date(2025, 1, 2, 12, 0, 0, 0, time.UTC)
+	uses := []hashtagUse{
+		// three uses yesterday
+		{Tag: "old", CreatedAt: now.Add(-20 * time.Hour)},
+		{Tag: "old", CreatedAt: now.Add(-20 * time.Hour)},
+		{Tag: "old", CreatedAt: now.Add(-20 * time.Hour)},
+		// two in the last hour
+		{Tag: "new", CreatedAt: now.Add(-30 * time.Minute)},
+		{Tag: "new", CreatedAt: now.Add(-10 * time.Minute)},
+		// and one that's out of the window entirely
+		{Tag: "ancient", CreatedAt: now.Add(-48 * time.Hour)},
+	}
+	rows := scoreTrends(uses, database.TrendingHashtagsParams{
+		Now: now,
+		DecaySeconds: (6 * time.Hour).Seconds(),
+		Since: now.Add(-24 * time.Hour),
+		PageLimit: 10,
+	})
+	if len(rows) != 2 {
+		t.Fatalf("Expected 2 trends, got %+v", rows)
+	}
+	if rows[0].Tag != "new" || rows[0].Uses != 2 {
+		t.Errorf("Recent uses should outweigh older ones, got %+v", rows)
+	}
+	if rows[1].Tag != "old" || rows[1].Uses != 3 {
+		t.Errorf("Expected old to come second with 3 uses, got %+v", rows[1])
+	}
+
+	rows = scoreTrends(uses, database.TrendingHashtagsParams{Now: now, DecaySeconds: 3600, Since: now.Add(-24 * time.Hour), PageLimit: 1})
+	if len(rows) != 1 {
+		t.Errorf("PageLimit wasn't applied, got %+v", rows)
+	}
+}
@@ -0,0 +1,3 @@
+module video
+
+go 1.24.1
@@ -0,0 +1,161 @@
+package video
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"time"
+)
+
+// the moov box is read into memory whole, and it's only metadata, so anything bigger than this is turned away
+const maxMoovSize = 16 << 20
+
+// an MP4 is a list of boxes, each a 32-bit size and a four letter type, and the duration
+// is in the movie header (mvhd) inside the moov box, which can be before or after the video data
+func mp4Duration(r *reader) (time.Duration, error) {
+	for {
+		header, err := r.read(8)
+		if err == io.EOF {
+			// got to the end without finding it
+			return 0, ErrUnknownDuration
+		}
+		if err != nil {
+			return 0, err
+		}
+		size := int64(binary.BigEndian.Uint32(header[0:4]))
+		kind := string(header[4:8])
+		headerSize := int64(8)
+		switch size {
+		case 0:
+			// the last box, which goes on to the end of the file
+			if kind != "moov" {
+				return 0, ErrUnknownDuration
+			}
+			data, err := io.ReadAll(io.LimitReader(r.r, maxMoovSize+1))
+			if err != nil {
+				return 0, err
+			}
+			if len(data) > maxMoovSize {
+				return 0, ErrMalformed
+			}
+			return moovDuration(data)
+		case 1:
+			// a 64-bit size follows
+			large, err := r.read(8)
+			if err != nil {
+				return 0, ErrMalformed
+			}
+			if binary.BigEndian.Uint64(large) > math.MaxInt64 {
+				return 0, ErrMalformed
+			}
+			size = int64(binary.BigEndian.Uint64(large))
+			headerSize = 16
+		}
+		if size < headerSize {
+			return 0, ErrMalformed
+		}
+		if kind != "moov" {
+			err = r.skip(size - headerSize)
+			if err != nil {
+				return 0, err
+			}
+			continue
+		}
+		if size-headerSize > maxMoovSize {
+			return 0, ErrMalformed
+		}
+		data, err := r.read(size - headerSize)
+		if err != nil {
+			return 0, ErrMalformed
+		}
+		return moovDuration(data)
+	}
+}
+
+// the boxes directly inside data, by type (only the first of each)
+func childBoxes(data []byte) (map[string][]byte, error) {
+	boxes := map[string][]byte{}
+	for len(data) > 0 {
+		if len(data) < 8 {
+			return nil, ErrMalformed
+		}
+		size := uint64(binary.BigEndian.Uint32(data[0:4]))
+		kind := string(data[4:8])
+		headerSize := uint64(8)
+		switch size {
+		case 0:
+			size = uint64(len(data))
+		case 1:
+			if len(data) < 16 {
+				return nil, ErrMalformed
+			}
+			size = binary.BigEndian.Uint64(data[8:16])
+			headerSize = 16
+		}
+		if size < headerSize || size > uint64(len(data)) {
+			return nil, ErrMalformed
+		}
+		if _, ok := boxes[kind]; !ok {
+			boxes[kind] = data[headerSize:size]
+		}
+		data = data[size:]
+	}
+	return boxes, nil
+}
+
+// mvhd has the duration in units of its timescale (so many per second)
+// fragmented MP4s can leave it at zero, with the real one in mvex's movie extends header (mehd) instead
+func moovDuration(moov []byte) (time.Duration, error) {
+	boxes, err := childBoxes(moov)
+	if err != nil {
+		return 0, err
+	}
+	mvhd, ok := boxes["mvhd"]
+	if !ok || len(mvhd) < 1 {
+		return 0, ErrMalformed
+	}
+	var timescale uint32
+	var duration uint64
+	unknown := uint64(math.MaxUint64)
+	// the first byte is the version, which decides how big the fields are, then there are 3 bytes of flags
+	if mvhd[0] == 1 {
+		if len(mvhd) < 32 {
+			return 0, ErrMalformed
+		}
+		timescale = binary.BigEndian.Uint32(mvhd[20:24])
+		duration = binary.BigEndian.Uint64(mvhd[24:32])
+	} else {
+		if len(mvhd) < 20 {
+			return 0, ErrMalformed
+		}
+		timescale = binary.BigEndian.Uint32(mvhd[12:16])
+		duration = uint64(binary.BigEndian.Uint32(mvhd[16:20]))
+		unknown = math.MaxUint32
+	}
+	if timescale == 0 {
+		return 0, ErrMalformed
+	}
+	if duration == 0 || duration == unknown {
+		duration = fragmentDuration(boxes["mvex"])
+	}
+	if duration == 0 {
+		return 0, ErrUnknownDuration
+	}
+	return seconds(float64(duration) / float64(timescale)), nil
+}
+
+// the duration in mvex's mehd box, or 0 if there isn't one
+func fragmentDuration(mvex []byte) uint64 {
+	boxes, err := childBoxes(mvex)
+	if err != nil {
+		return 0
+	}
+	mehd := boxes["mehd"]
+	if len(mehd) >= 12 && mehd[0] == 1 {
+		return binary.BigEndian.Uint64(mehd[4:12])
+	}
+	if len(mehd) >= 8 {
+		return uint64(binary.BigEndian.Uint32(mehd[4:8]))
+	}
+	return 0
+}
@@ -0,0 +1,85 @@
+package video
+
+import (
+	"errors"
+	"io"
+	"math"
+	"time"
+)
+
+// returned for videos that don't say how long they are anywhere that's looked
+var ErrUnknownDuration = errors.New("can't tell how long the video is")
+
+// returned for files that aren't laid out the way their container says they should be
+var ErrMalformed = errors.New("malformed video")
+
+// returned for content types Duration doesn't know how to read
+var ErrUnsupported = errors.New("unsupported video type")
+
+// whether Duration knows what to do with contentType
+func Supported(contentType string) bool {
+	return contentType == "video/mp4" || contentType == "video/webm"
+}
+
+// Duration reads how long a video is from its container, without decoding any of it
+// the whole file might have to be read to find out (an MP4 can keep it right at the end),
+// so if r is an io.Seeker the parts that don't matter are skipped over instead
+func Duration(r io.Reader, contentType string) (time.Duration, error) {
+	rd := &reader{r: r}
+	switch contentType {
+	case "video/mp4":
+		return mp4Duration(rd)
+	case "video/webm":
+		return webmDuration(rd)
+	}
+	return 0, ErrUnsupported
+}
+
+// keeps track of how far into the file it's got
+type reader struct {
+	r io.Reader
+	pos int64
+}
+
+// exactly n bytes, or ErrMalformed if the file ends first
+// an io.EOF right at the start is passed on, since that's usually just the end of the file
+func (r *reader) read(n int64) ([]byte, error) {
+	buf := make([]byte, n)
+	read, err := io.ReadFull(r.r, buf)
+	r.pos += int64(read)
+	if err == io.ErrUnexpectedEOF {
+		return nil, ErrMalformed
+	}
+	return buf, err
+}
+
+func (r *reader) skip(n int64) error {
+	if n < 0 {
+		return ErrMalformed
+	}
+	if s, ok := r.r.(io.Seeker); ok {
+		_, err := s.Seek(n, io.SeekCurrent)
+		if err != nil {
+			return err
+		}
+		r.pos += n
+		return nil
+	}
+	skipped, err := io.CopyN(io.Discard, r.r, n)
+	r.pos += skipped
+	if err == io.EOF {
+		return ErrMalformed
+	}
+	return err
+}
+
+// so a duration that's way out there doesn't wrap around to something short
+func seconds(s float64) time.Duration {
+	if math.IsNaN(s) || s < 0 {
+		return 0
+	}
+	if s >= float64(math.MaxInt64)/float64(time.Second) {
+		return time.Duration(math.MaxInt64)
+	}
+	return time.Duration(s * float64(time.Second))
+}
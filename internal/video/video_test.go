@@ -0,0 +1,146 @@
+package video
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"testing"
+	"time"
+)
+
+// an MP4 box
+func box(kind string, data ...[]byte) []byte {
+	body := bytes.Join(data, nil)
+	out := binary.BigEndian.AppendUint32(nil, uint32(8+len(body)))
+	return append(append(out, kind...), body...)
+}
+
+// a version 0 movie header, with everything that isn't the timescale and duration left zeroed
+func mvhd(timescale uint32, duration uint32) []byte {
+	body := make([]byte, 100)
+	binary.BigEndian.PutUint32(body[12:16], timescale)
+	binary.BigEndian.PutUint32(body[16:20], duration)
+	return box("mvhd", body)
+}
+
+// a version 1 one, with 64-bit times
+func mvhd64(timescale uint32, duration uint64) []byte {
+	body := make([]byte, 112)
+	body[0] = 1
+	binary.BigEndian.PutUint32(body[20:24], timescale)
+	binary.BigEndian.PutUint64(body[24:32], duration)
+	return box("mvhd", body)
+}
+
+func TestMP4Duration(t *testing.T) {
+	ftyp := box("ftyp", []byte("isom\x00\x00\x02\x00isomiso2mp41"))
+	mdat := box("mdat", make([]byte, 4096))
+	mehd := box("mehd", []byte{0, 0, 0, 0}, binary.BigEndian.AppendUint32(nil, 90_000*7))
+	cases := []struct {
+		name string
+		file []byte
+		duration time.Duration
+	}{
+		{name: "moov first", file: bytes.Join([][]byte{ftyp, box("moov", mvhd(1000, 12_500)), mdat}, nil), duration: 12500 * time.Millisecond},
+		// not "fast start", so it has to go past the video data to find it
+		{name: "moov last", file: bytes.Join([][]byte{ftyp, mdat, box("moov", mvhd(600, 600*90))}, nil), duration: 90 * time.Second},
+		{name: "version 1", file: bytes.Join([][]byte{ftyp, box("moov", mvhd64(48_000, 48_000*3))}, nil), duration: 3 * time.Second},
+		{name: "fragmented", file: bytes.Join([][]byte{ftyp, box("moov", mvhd(90_000, 0), box("mvex", mehd))}, nil), duration: 7 * time.Second},
+	}
+	for _, c := range cases {
+		// with and without being able to seek past the video data
+		for _, r := range []io.Reader{bytes.NewReader(c.file), io.MultiReader(bytes.NewReader(c.file))} {
+			d, err := Duration(r, "video/mp4")
+			if err != nil || d != c.duration {
+				t.Errorf("%s: Duration returned %v, %v, expected %v", c.name, d, err, c.duration)
+			}
+		}
+	}
+
+	_, err := Duration(bytes.NewReader(bytes.Join([][]byte{ftyp, mdat}, nil)), "video/mp4")
+	if !errors.Is(err, ErrUnknownDuration) {
+		t.Errorf("Expected ErrUnknownDuration without a moov box, got %v", err)
+	}
+	_, err = Duration(bytes.NewReader(bytes.Join([][]byte{ftyp, box("moov", mvhd(90_000, 0))}, nil)), "video/mp4")
+	if !errors.Is(err, ErrUnknownDuration) {
+		t.Errorf("Expected ErrUnknownDuration for a zero duration, got %v", err)
+	}
+	moov := box("moov", mvhd(1000, 5000))
+	_, err = Duration(bytes.NewReader(append(ftyp, moov[:len(moov)-10]...)), "video/mp4")
+	if !errors.Is(err, ErrMalformed) {
+		t.Errorf("Expected ErrMalformed for a cut off moov box, got %v", err)
+	}
+}
+
+// an EBML element, with an 8 byte size (or an unknown one)
+func ebml(id uint32, unknown bool, data ...[]byte) []byte {
+	body := bytes.Join(data, nil)
+	idBytes := binary.BigEndian.AppendUint32(nil, id)
+	for len(idBytes) > 1 && idBytes[0] == 0 {
+		idBytes = idBytes[1:]
+	}
+	sizeBytes := binary.BigEndian.AppendUint64(nil, uint64(len(body)))
+	sizeBytes[0] = 0x01
+	if unknown {
+		sizeBytes = []byte{0x01, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}
+	}
+	return append(append(idBytes, sizeBytes...), body...)
+}
+
+func sized(id uint32, data ...[]byte) []byte {
+	return ebml(id, false, data...)
+}
+
+func unsized(id uint32, data ...[]byte) []byte {
+	return ebml(id, true, data...)
+}
+
+// a block on track 1 at offset from its cluster's timecode, with a frame of junk
+func simpleBlock(offset int16) []byte {
+	return sized(idSimpleBlock, []byte{0x81}, binary.BigEndian.AppendUint16(nil, uint16(offset)), []byte{0x80}, make([]byte, 64))
+}
+
+func TestWebMDuration(t *testing.T) {
+	header := sized(idEBML, sized(0x4282, []byte("webm")))
+	scale := sized(idTimecodeScale, []byte{0x0F, 0x42, 0x40})
+	duration := sized(idDuration, binary.BigEndian.AppendUint64(nil, math.Float64bits(4500)))
+	tracks := sized(0x1654AE6B, make([]byte, 32))
+
+	// what a browser recording looks like: no duration, and a segment and clusters that don't say how big they are
+	recorded := bytes.Join([][]byte{header, unsized(idSegment,
+		sized(idInfo, scale),
+		tracks,
+		unsized(idCluster, sized(idTimecode, []byte{0}), simpleBlock(0), simpleBlock(33)),
+		unsized(idCluster, sized(idTimecode, []byte{0x0B, 0xB8}), simpleBlock(0), sized(idBlockGroup, sized(idBlock, []byte{0x81, 0x01, 0xF4, 0x00}, make([]byte, 16)))),
+	)}, nil)
+	cases := []struct {
+		name string
+		file []byte
+		duration time.Duration
+	}{
+		{name: "with a duration", file: bytes.Join([][]byte{header, sized(idSegment, sized(idInfo, scale, duration), tracks)}, nil), duration: 4500 * time.Millisecond},
+		{name: "recorded", file: recorded, duration: 3500 * time.Millisecond},
+		{name: "known sizes", file: bytes.Join([][]byte{header, sized(idSegment, sized(idInfo, scale), sized(idCluster, sized(idTimecode, []byte{0x07, 0xD0}), simpleBlock(-10)))}, nil), duration: 1990 * time.Millisecond},
+	}
+	for _, c := range cases {
+		d, err := Duration(bytes.NewReader(c.file), "video/webm")
+		if err != nil || d != c.duration {
+			t.Errorf("%s: Duration returned %v, %v, expected %v", c.name, d, err, c.duration)
+		}
+	}
+
+	_, err := Duration(bytes.NewReader(bytes.Join([][]byte{header, sized(idSegment, sized(idInfo, scale), tracks)}, nil)), "video/webm")
+	if !errors.Is(err, ErrUnknownDuration) {
+		t.Errorf("Expected ErrUnknownDuration without a duration or any blocks, got %v", err)
+	}
+	_, err = Duration(bytes.NewReader([]byte("not a webm at all")), "video/webm")
+	if !errors.Is(err, ErrMalformed) {
+		t.Errorf("Expected ErrMalformed for something that isn't EBML, got %v", err)
+	}
+	_, err = Duration(bytes.NewReader(recorded), "video/quicktime")
+	if !errors.Is(err, ErrUnsupported) {
+		t.Errorf("Expected ErrUnsupported, got %v", err)
+	}
+}
@@ -0,0 +1,363 @@
+package video
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"math/bits"
+	"time"
+)
+
+// the EBML element ids that matter here
+// see https://www.matroska.org/technical/elements.html
+const (
+	idEBML = 0x1A45DFA3
+	idSegment = 0x18538067
+	idInfo = 0x1549A966
+	idTimecodeScale = 0x2AD7B1
+	idDuration = 0x4489
+	idCluster = 0x1F43B675
+	idTimecode = 0xE7
+	idSimpleBlock = 0xA3
+	idBlockGroup = 0xA0
+	idBlock = 0xA1
+)
+
+// the other elements that can come straight inside a segment,
+// which is how the end of a cluster with an unknown size is spotted
+var segmentChildren = map[uint64]bool{
+	idInfo: true,
+	idCluster: true,
+	0x114D9B74: true, // SeekHead
+	0x1654AE6B: true, // Tracks
+	0x1C53BB6B: true, // Cues
+	0x1941A469: true, // Attachments
+	0x1043A770: true, // Chapters
+	0x1254C367: true, // Tags
+}
+
+// anything read into memory whole is small, so bigger than this means something's wrong
+const maxElementSize = 1 << 20
+
+// the timestamps are in units of this many nanoseconds unless Info says otherwise
+const defaultTimecodeScale = 1_000_000
+
+// an element's id and how big its data is (-1 for unknown, meaning it goes on until its parent does)
+type element struct {
+	id uint64
+	size int64
+}
+
+// a WebM is EBML, a binary XML-ish tree of elements, and the duration is in the segment's Info
+// live recordings (like the ones browsers make) often leave it out, in which case it's as far as
+// the last block's timestamp goes, which means reading through every cluster
+func webmDuration(r *reader) (time.Duration, error) {
+	header, err := r.element()
+	if err != nil || header.id != idEBML || header.size < 0 {
+		return 0, ErrMalformed
+	}
+	err = r.skip(header.size)
+	if err != nil {
+		return 0, err
+	}
+	segment, err := r.element()
+	if err != nil || segment.id != idSegment {
+		return 0, ErrMalformed
+	}
+	end := int64(-1)
+	if segment.size >= 0 {
+		end = r.pos + segment.size
+	}
+
+	scale := uint64(defaultTimecodeScale)
+	var last int64
+	seen := false
+	var pending *element
+	for end < 0 || r.pos < end {
+		var child element
+		if pending != nil {
+			child, pending = *pending, nil
+		} else {
+			child, err = r.element()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return 0, err
+			}
+		}
+		switch child.id {
+		case idInfo:
+			data, err := r.body(child)
+			if err != nil {
+				return 0, err
+			}
+			var duration float64
+			scale, duration, err = parseInfo(data)
+			if err != nil {
+				return 0, err
+			}
+			if duration > 0 {
+				return seconds(duration * float64(scale) / float64(time.Second)), nil
+			}
+		case idCluster:
+			var clusterLast int64
+			var found bool
+			clusterLast, found, pending, err = r.cluster(child)
+			if err != nil {
+				return 0, err
+			}
+			if found && (!seen || clusterLast > last) {
+				last = clusterLast
+				seen = true
+			}
+		default:
+			if child.size < 0 {
+				return 0, ErrMalformed
+			}
+			err = r.skip(child.size)
+			if err != nil {
+				return 0, err
+			}
+		}
+	}
+	if !seen {
+		return 0, ErrUnknownDuration
+	}
+	return seconds(float64(last) * float64(scale) / float64(time.Second)), nil
+}
+
+// reads through a cluster, returning the latest block timestamp in it
+// a cluster with an unknown size ends at the next element that belongs to the segment, which is handed back
+func (r *reader) cluster(cluster element) (int64, bool, *element, error) {
+	end := int64(-1)
+	if cluster.size >= 0 {
+		end = r.pos + cluster.size
+	}
+	var timecode int64
+	var last int64
+	found := false
+	for end < 0 || r.pos < end {
+		child, err := r.element()
+		if err == io.EOF && end < 0 {
+			break
+		}
+		if err != nil {
+			return 0, false, nil, ErrMalformed
+		}
+		if end < 0 && segmentChildren[child.id] {
+			return last, found, &child, nil
+		}
+		switch child.id {
+		case idTimecode:
+			data, err := r.body(child)
+			if err != nil {
+				return 0, false, nil, err
+			}
+			timecode = int64(readUint(data))
+		case idSimpleBlock, idBlockGroup:
+			var offset int64
+			var ok bool
+			if child.id == idSimpleBlock {
+				offset, err = r.blockTimecode(child)
+				ok = err == nil
+			} else {
+				offset, ok, err = r.blockGroup(child)
+			}
+			if err != nil {
+				return 0, false, nil, err
+			}
+			if ok && (!found || timecode+offset > last) {
+				last = timecode + offset
+				found = true
+			}
+		default:
+			if child.size < 0 {
+				return 0, false, nil, ErrMalformed
+			}
+			err = r.skip(child.size)
+			if err != nil {
+				return 0, false, nil, err
+			}
+		}
+	}
+	return last, found, nil, nil
+}
+
+// a block starts with its track number and then its timestamp, relative to its cluster's
+// the rest of it is the frame, which is skipped
+func (r *reader) blockTimecode(block element) (int64, error) {
+	if block.size < 0 {
+		return 0, ErrMalformed
+	}
+	start := r.pos
+	first, err := r.read(1)
+	if err != nil {
+		return 0, ErrMalformed
+	}
+	_, err = r.rest(first[0], 8)
+	if err != nil {
+		return 0, err
+	}
+	timecode, err := r.read(2)
+	if err != nil {
+		return 0, ErrMalformed
+	}
+	read := r.pos - start
+	if read > block.size {
+		return 0, ErrMalformed
+	}
+	err = r.skip(block.size - read)
+	if err != nil {
+		return 0, err
+	}
+	return int64(int16(binary.BigEndian.Uint16(timecode))), nil
+}
+
+// the timestamp of the block in a block group, which comes along with things like how long it lasts
+func (r *reader) blockGroup(group element) (int64, bool, error) {
+	if group.size < 0 {
+		return 0, false, ErrMalformed
+	}
+	end := r.pos + group.size
+	var offset int64
+	found := false
+	for r.pos < end {
+		child, err := r.element()
+		if err != nil || child.size < 0 {
+			return 0, false, ErrMalformed
+		}
+		if child.id == idBlock && !found {
+			offset, err = r.blockTimecode(child)
+			if err != nil {
+				return 0, false, err
+			}
+			found = true
+			continue
+		}
+		err = r.skip(child.size)
+		if err != nil {
+			return 0, false, err
+		}
+	}
+	return offset, found, nil
+}
+
+// the timecode scale and duration (in units of the scale) in an Info element's data
+func parseInfo(data []byte) (uint64, float64, error) {
+	scale := uint64(defaultTimecodeScale)
+	var duration float64
+	for len(data) > 0 {
+		id, n, ok := vint(data, true)
+		if !ok {
+			return 0, 0, ErrMalformed
+		}
+		size, m, ok := vint(data[n:], false)
+		if !ok || size > uint64(len(data)-n-m) {
+			return 0, 0, ErrMalformed
+		}
+		value := data[n+m : n+m+int(size)]
+		switch id {
+		case idTimecodeScale:
+			scale = readUint(value)
+		case idDuration:
+			switch len(value) {
+			case 4:
+				duration = float64(math.Float32frombits(binary.BigEndian.Uint32(value)))
+			case 8:
+				duration = math.Float64frombits(binary.BigEndian.Uint64(value))
+			}
+		}
+		data = data[n+m+int(size):]
+	}
+	if scale == 0 {
+		return 0, 0, ErrMalformed
+	}
+	return scale, duration, nil
+}
+
+// a big-endian unsigned integer of up to 8 bytes
+func readUint(data []byte) uint64 {
+	var v uint64
+	for _, b := range data {
+		v = v<<8 | uint64(b)
+	}
+	return v
+}
+
+// EBML's variable length integers: the number of leading zeros in the first byte says how many more bytes there are
+// ids keep that length marker and sizes don't, and a size that's all ones means unknown
+func vint(data []byte, id bool) (uint64, int, bool) {
+	if len(data) == 0 || data[0] == 0 {
+		return 0, 0, false
+	}
+	n := bits.LeadingZeros8(data[0]) + 1
+	if len(data) < n || (id && n > 4) {
+		return 0, 0, false
+	}
+	v := uint64(data[0])
+	if !id {
+		v &= 0xFF >> n
+	}
+	for _, b := range data[1:n] {
+		v = v<<8 | uint64(b)
+	}
+	return v, n, true
+}
+
+// the header of the next element
+func (r *reader) element() (element, error) {
+	first, err := r.read(1)
+	if err != nil {
+		return element{}, err
+	}
+	idBytes, err := r.rest(first[0], 4)
+	if err != nil {
+		return element{}, err
+	}
+	id, _, _ := vint(idBytes, true)
+	first, err = r.read(1)
+	if err != nil {
+		return element{}, ErrMalformed
+	}
+	sizeBytes, err := r.rest(first[0], 8)
+	if err != nil {
+		return element{}, err
+	}
+	size, n, _ := vint(sizeBytes, false)
+	if size == 1<<(7*n)-1 {
+		return element{id: id, size: -1}, nil
+	}
+	if size > math.MaxInt64 {
+		return element{}, ErrMalformed
+	}
+	return element{id: id, size: int64(size)}, nil
+}
+
+// the whole of a variable length integer that starts with first, up to max bytes long
+func (r *reader) rest(first byte, max int) ([]byte, error) {
+	if first == 0 {
+		return nil, ErrMalformed
+	}
+	n := bits.LeadingZeros8(first) + 1
+	if n > max {
+		return nil, ErrMalformed
+	}
+	more, err := r.read(int64(n - 1))
+	if err != nil {
+		return nil, ErrMalformed
+	}
+	return append([]byte{first}, more...), nil
+}
+
+// an element's data, for the small ones that are worth reading into memory
+func (r *reader) body(e element) ([]byte, error) {
+	if e.size < 0 || e.size > maxElementSize {
+		return nil, ErrMalformed
+	}
+	data, err := r.read(e.size)
+	if err != nil {
+		return nil, ErrMalformed
+	}
+	return data, nil
+}
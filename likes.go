@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"github.com/google/uuid"
+	"internal/database"
+	"net/http"
+)
+
+// like a chirp (liking it again does nothing)
+func likeChirp(wri http.ResponseWriter, req *http.Request, apiCfg apiConfig) {
+	user, err := authenticate(req, apiCfg)
+	if err != nil {
+		respondWithProblem(wri, errUnauthorized)
+		return
+	}
+	chirp, ok := getLiveChirp(wri, req, apiCfg)
+	if !ok {
+		return
+	}
+	err = apiCfg.dbQueries.LikeChirp(req.Context(), database.LikeChirpParams{UserID: user, ChirpID: chirp.ID})
+	if err != nil {
+		respondWithProblem(wri, fmt.Errorf("liking chirp: %w", err))
+		return
+	}
+	err = notify(req.Context(), apiCfg, chirp.UserID, user, notifyLike, uuid.NullUUID{UUID: chirp.ID, Valid: true})
+	if err != nil {
+		respondWithProblem(wri, fmt.Errorf("notifying user: %w", err))
+		return
+	}
+	wri.WriteHeader(204)
+}
+
+// unlike a chirp (unliking something you haven't liked does nothing)
+func unlikeChirp(wri http.ResponseWriter, req *http.Request, apiCfg apiConfig) {
+	user, err := authenticate(req, apiCfg)
+	if err != nil {
+		respondWithProblem(wri, errUnauthorized)
+		return
+	}
+	chirp, ok := getLiveChirp(wri, req, apiCfg)
+	if !ok {
+		return
+	}
+	err = apiCfg.dbQueries.UnlikeChirp(req.Context(), database.UnlikeChirpParams{UserID: user, ChirpID: chirp.ID})
+	if err != nil {
+		respondWithProblem(wri, fmt.Errorf("unliking chirp: %w", err))
+		return
+	}
+	wri.WriteHeader(204)
+}
+
+// get the chirps a user has liked, most recently liked first
+func getUserLikes(wri http.ResponseWriter, req *http.Request, apiCfg apiConfig) {
+	userID, err := uuid.Parse(req.PathValue("userID"))
+	if err != nil {
+		respondWithProblem(wri, errUserNotFound)
+		return
+	}
+	start, limit, err := parsePage(req, true)
+	if err != nil {
+		respondWithProblem(wri, err)
+		return
+	}
+	rows, err := apiCfg.dbQueries.ListUserLikes(req.Context(), database.ListUserLikesParams{
+		UserID: userID,
+		BeforeLikedAt: start.CreatedAt,
+		BeforeID: start.ID,
+		PageLimit: limit + 1,
+	})
+	if err != nil {
+		respondWithProblem(wri, fmt.Errorf("getting likes: %w", err))
+		return
+	}
+	// the cursor here is on when it was liked, not when it was chirped
+	if len(rows) > int(limit) {
+		rows = rows[:limit]
+		last := rows[len(rows)-1]
+		setNextLink(wri, req, cursor{CreatedAt: last.LikedAt, ID: last.Chirp.ID})
+	}
+	chirps := []database.Chirp{}
+	for _, r := range rows {
+		chirps = append(chirps, r.Chirp)
+	}
+	output, err := chirpResponses(req, apiCfg, chirps)
+	if err != nil {
+		respondWithProblem(wri, fmt.Errorf("getting likes: %w", err))
+		return
+	}
+	respondWithJSON(wri, 200, output)
+}
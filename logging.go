@@ -0,0 +1,121 @@
+package main
+
+import (
+	"github.com/google/uuid"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// the longest X-Request-ID that's passed along, rather than replaced with a new one
+const maxRequestIDLength = 128
+
+// logs every request once it's done, under an id that's sent back in X-Request-ID (and in error responses)
+// a request that comes in with its own X-Request-ID, say from a proxy, keeps it
+func (cfg *apiConfig) middlewareLogging(mux *http.ServeMux, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(wri http.ResponseWriter, req *http.Request) {
+		start := time.Now()
+		_, route := mux.Handler(req)
+		requestID := req.Header.Get("X-Request-ID")
+		if !validRequestID(requestID) {
+			requestID = uuid.New().String()
+		}
+		wri.Header().Set("X-Request-ID", requestID)
+		sw := &statusWriter{ResponseWriter: wri, requestID: requestID}
+		next.ServeHTTP(sw, req)
+		attrs := []any{
+			"request_id", requestID,
+			"method", req.Method,
+			"route", route,
+			"path", req.URL.Path,
+			"status", sw.Status(),
+			"bytes", sw.bytes,
+			"latency", time.Since(start),
+		}
+		if userID, ok := tokenUser(req, *cfg); ok {
+			attrs = append(attrs, "user_id", userID)
+		}
+		slog.Info("request", attrs...)
+	})
+}
+
+// ids from outside end up in the logs, so they're kept short and printable
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		if c <= ' ' || c > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// keeps track of what a handler sent back, for the logs and metrics
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+	bytes int
+	requestID string
+}
+
+// the statusWriter wri already is, or a new one around it
+// so the middleware can share one, and respondWithError can find the request id in it
+func wrapStatusWriter(wri http.ResponseWriter) *statusWriter {
+	if sw, ok := wri.(*statusWriter); ok {
+		return sw
+	}
+	return &statusWriter{ResponseWriter: wri}
+}
+
+// the statusWriter underneath wri, if there is one
+func findStatusWriter(wri http.ResponseWriter) *statusWriter {
+	for {
+		if sw, ok := wri.(*statusWriter); ok {
+			return sw
+		}
+		unwrapper, ok := wri.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return nil
+		}
+		wri = unwrapper.Unwrap()
+	}
+}
+
+// the id of the request being responded to through wri, or "" if it didn't come through middlewareLogging
+func responseRequestID(wri http.ResponseWriter) string {
+	if sw := findStatusWriter(wri); sw != nil {
+		return sw.requestID
+	}
+	return ""
+}
+
+func (sw *statusWriter) WriteHeader(code int) {
+	if sw.status == 0 {
+		sw.status = code
+	}
+	sw.ResponseWriter.WriteHeader(code)
+}
+
+func (sw *statusWriter) Write(data []byte) (int, error) {
+	if sw.status == 0 {
+		sw.status = 200
+	}
+	n, err := sw.ResponseWriter.Write(data)
+	sw.bytes += n
+	return n, err
+}
+
+// handlers that never write anything still send a 200
+func (sw *statusWriter) Status() int {
+	if sw.status == 0 {
+		return 200
+	}
+	return sw.status
+}
+
+// so http.ResponseController can get at the real ResponseWriter
+func (sw *statusWriter) Unwrap() http.ResponseWriter {
+	return sw.ResponseWriter
+}
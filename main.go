@@ -1,57 +1,260 @@
 package main
 
 import _ "github.com/lib/pq"
+import _ "modernc.org/sqlite"
 
 import (
+	"context"
 	"fmt"
-	"sync/atomic"
+	"log/slog"
 	"net/http"
 	"strings"
-	"slices"
-	"internal/database"
+	"internal/store"
+	"internal/migrate"
+	"internal/blobstore"
+	"internal/contentfilter"
+	"internal/moderation"
+	"internal/ratelimit"
 	"database/sql"
 	"os"
+	"time"
 	"github.com/joho/godotenv"
+	"github.com/google/uuid"
 )
 
 type apiConfig struct {
-	fileserverHits atomic.Int32
-	dbQueries *database.Queries
-	platform string
+	metrics *serverMetrics
+	metricsToken string
+	dbQueries store.Store
 	secret string
 	polka_key string
+	deletionGracePeriod time.Duration
+	blobs blobstore.BlobStore
+	mediaCache blobstore.BlobStore
+	filter *contentfilter.Filter
+	moderation *moderation.Pipeline
+	rateLimits ratelimit.Config
+	rateLimiter ratelimit.Backend
 }
 
 func main() {
 	godotenv.Load() // loads the .env file
+	// requests and server errors are logged to stdout as text, or as JSON with LOG_FORMAT=json
+	if os.Getenv("LOG_FORMAT") == "json" {
+		slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stdout, nil)))
+	} else {
+		slog.SetDefault(slog.New(slog.NewTextHandler(os.Stdout, nil)))
+	}
 	dbURL := os.Getenv("DB_URL")
-	db, err := sql.Open("postgres", dbURL)
+	dbQueries, migrator, err := openStore(dbURL)
 	if err != nil {
-		fmt.Println("Error opening the database")
+		fmt.Printf("Error opening the database: %v\n", err)
+		os.Exit(1)
+	}
+
+	// chirpy migrate up|down|status
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		err = migrateCommand(migrator, os.Args[2:])
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+	// don't serve anything against a schema that's behind
+	err = migrateOnStartup(migrator)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	// uploads are kept on disk, in MEDIA_DIR (or ./media if that's not set)
+	mediaDir := os.Getenv("MEDIA_DIR")
+	if mediaDir == "" {
+		mediaDir = "media"
+	}
+	blobs, err := blobstore.NewLocal(mediaDir)
+	if err != nil {
+		fmt.Printf("Error opening the media directory: %v\n", err)
+		os.Exit(1)
+	}
+	// resized images are made on demand and kept in MEDIA_CACHE_DIR (or ./media-cache)
+	// anything in there can be deleted, it'll just be made again
+	mediaCacheDir := os.Getenv("MEDIA_CACHE_DIR")
+	if mediaCacheDir == "" {
+		mediaCacheDir = "media-cache"
+	}
+	mediaCache, err := blobstore.NewLocal(mediaCacheDir)
+	if err != nil {
+		fmt.Printf("Error opening the media cache directory: %v\n", err)
+		os.Exit(1)
+	}
+
+	// deleted chirps and users can be restored for DELETION_GRACE_PERIOD (30 days if it's not set)
+	// after that the purge job gets rid of them for good
+	gracePeriod := defaultDeletionGracePeriod
+	if g := os.Getenv("DELETION_GRACE_PERIOD"); g != "" {
+		gracePeriod, err = time.ParseDuration(g)
+		if err != nil || gracePeriod < 0 {
+			fmt.Printf("Invalid DELETION_GRACE_PERIOD %q\n", g)
+			os.Exit(1)
+		}
 	}
-	dbQueries := database.New(db)
 
 	apiCfg := apiConfig{}
-	apiCfg.fileserverHits.Store(0)
+	apiCfg.metrics = newServerMetrics(dbQueries)
+	// METRICS_TOKEN keeps /metrics private to whatever scrapes it
+	apiCfg.metricsToken = os.Getenv("METRICS_TOKEN")
 	apiCfg.dbQueries = dbQueries
-	apiCfg.platform = os.Getenv("PLATFORM")
 	apiCfg.secret = os.Getenv("SECRET")
 	apiCfg.polka_key = os.Getenv("POLKA_KEY")
-	mux := http.NewServeMux()
-	// get number of page visits
-	mux.HandleFunc("GET /admin/metrics", func(wri http.ResponseWriter, req *http.Request){
-		respondWithString(wri, 200, fmt.Sprintf("<html><body><h1>Welcome, Chirpy Admin</h1><p>Chirpy has been visited %d times!</p></body></html>", apiCfg.fileserverHits.Load()))
-	})
-	// a dev endpoint to reset page visits and the database
-	mux.HandleFunc("POST /admin/reset", func(wri http.ResponseWriter, req *http.Request){
-		if apiCfg.platform == "dev" {
-			apiCfg.metricsReset()
-			apiCfg.dbQueries.ResetUsers(req.Context())
-			respondWithString(wri, 200, "Reset")
-		} else {
-			respondWithError(wri, 403, "Forbidden")
+	apiCfg.deletionGracePeriod = gracePeriod
+	apiCfg.blobs = blobs
+	apiCfg.mediaCache = mediaCache
+	// the content filter's word lists live in the database
+	// FILTER_FILE, if it's set, is a JSON file of lists that get written over the ones in there on startup
+	apiCfg.filter = contentfilter.New(nil)
+	if filterFile := os.Getenv("FILTER_FILE"); filterFile != "" {
+		err = seedFilterFile(context.Background(), apiCfg, filterFile)
+		if err != nil {
+			fmt.Printf("Error loading FILTER_FILE: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	err = loadFilter(context.Background(), apiCfg)
+	if err != nil {
+		fmt.Printf("Error loading the content filter: %v\n", err)
+		os.Exit(1)
+	}
+	// new chirps go through the moderation pipeline in MODERATION_FILE
+	// without one it's just the length limit and the content filter
+	apiCfg.moderation = moderation.Default(apiCfg.filter)
+	if moderationFile := os.Getenv("MODERATION_FILE"); moderationFile != "" {
+		apiCfg.moderation, err = moderation.LoadFile(moderationFile, apiCfg.filter)
+		if err != nil {
+			fmt.Printf("Error loading MODERATION_FILE: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	// requests are rate limited by the policies in RATE_LIMIT_FILE
+	// without one it's just logging in, signing up and posting chirps
+	apiCfg.rateLimits = ratelimit.Default()
+	if rateLimitFile := os.Getenv("RATE_LIMIT_FILE"); rateLimitFile != "" {
+		apiCfg.rateLimits, err = ratelimit.LoadFile(rateLimitFile)
+		if err != nil {
+			fmt.Printf("Error loading RATE_LIMIT_FILE: %v\n", err)
+			os.Exit(1)
 		}
+	}
+	// the buckets are kept in memory, unless RATE_LIMIT_BACKEND points at a Redis server that every server can share
+	apiCfg.rateLimiter, err = ratelimit.Open(os.Getenv("RATE_LIMIT_BACKEND"))
+	if err != nil {
+		fmt.Printf("Error opening RATE_LIMIT_BACKEND: %v\n", err)
+		os.Exit(1)
+	}
+	// ADMINS is a comma separated list of user ids that are made admins on startup, so there's someone to grant roles
+	admins := []uuid.UUID{}
+	for _, id := range strings.Split(os.Getenv("ADMINS"), ",") {
+		id = strings.TrimSpace(id)
+		if id == "" {
+			continue
+		}
+		admin, err := uuid.Parse(id)
+		if err != nil {
+			fmt.Printf("Invalid user id %q in ADMINS\n", id)
+			os.Exit(1)
+		}
+		admins = append(admins, admin)
+	}
+	err = grantAdmins(context.Background(), apiCfg, admins)
+	if err != nil {
+		fmt.Printf("Error granting ADMINS: %v\n", err)
+		os.Exit(1)
+	}
+	go collectOrphanedMediaForever(apiCfg)
+	go reloadFilterForever(apiCfg)
+	go purgeDeletedForever(apiCfg)
+	mux := http.NewServeMux()
+	// get number of page visits and the rest of the metrics (needs an admin)
+	mux.Handle("GET /admin/metrics", apiCfg.middlewareRequireRole(roleAdmin, func(wri http.ResponseWriter, req *http.Request) {
+		getAdminMetrics(wri, req, apiCfg)
+	}))
+	// the same metrics for Prometheus
+	mux.HandleFunc("GET /metrics", func(wri http.ResponseWriter, req *http.Request) {
+		getMetrics(wri, req, apiCfg)
 	})
+	// reset page visits and the database (needs an admin)
+	mux.Handle("POST /admin/reset", apiCfg.middlewareRequireRole(roleAdmin, func(wri http.ResponseWriter, req *http.Request) {
+		apiCfg.metricsReset()
+		apiCfg.dbQueries.ResetUsers(req.Context())
+		respondWithString(wri, 200, "Reset")
+	}))
+	// bring back deleted chirps and users (needs an admin)
+	mux.Handle("POST /admin/chirps/{chirpID}/restore", apiCfg.middlewareRequireRole(roleAdmin, func(wri http.ResponseWriter, req *http.Request) {
+		restoreChirp(wri, req, apiCfg)
+	}))
+	mux.Handle("POST /admin/users/{userID}/restore", apiCfg.middlewareRequireRole(roleAdmin, func(wri http.ResponseWriter, req *http.Request) {
+		restoreUser(wri, req, apiCfg)
+	}))
+	// edit the content filter's word lists (needs an admin)
+	mux.Handle("GET /admin/filter/lists", apiCfg.middlewareRequireRole(roleAdmin, func(wri http.ResponseWriter, req *http.Request) {
+		getFilterLists(wri, req, apiCfg)
+	}))
+	mux.Handle("PUT /admin/filter/lists/{name}", apiCfg.middlewareRequireRole(roleAdmin, func(wri http.ResponseWriter, req *http.Request) {
+		putFilterList(wri, req, apiCfg)
+	}))
+	mux.Handle("DELETE /admin/filter/lists/{name}", apiCfg.middlewareRequireRole(roleAdmin, func(wri http.ResponseWriter, req *http.Request) {
+		deleteFilterList(wri, req, apiCfg)
+	}))
+	mux.Handle("POST /admin/filter/lists/{name}/words", apiCfg.middlewareRequireRole(roleAdmin, func(wri http.ResponseWriter, req *http.Request) {
+		postFilterWords(wri, req, apiCfg)
+	}))
+	mux.Handle("DELETE /admin/filter/lists/{name}/words/{word}", apiCfg.middlewareRequireRole(roleAdmin, func(wri http.ResponseWriter, req *http.Request) {
+		deleteFilterWord(wri, req, apiCfg)
+	}))
+	// work through the chirps the moderation pipeline held and the ones the filter flagged (needs a moderator)
+	mux.Handle("GET /admin/moderation/queue", apiCfg.middlewareRequireRole(roleModerator, func(wri http.ResponseWriter, req *http.Request) {
+		getHeldChirps(wri, req, apiCfg)
+	}))
+	mux.Handle("POST /admin/moderation/queue/{heldID}/approve", apiCfg.middlewareRequireRole(roleModerator, func(wri http.ResponseWriter, req *http.Request) {
+		approveHeldChirp(wri, req, apiCfg)
+	}))
+	mux.Handle("POST /admin/moderation/queue/{heldID}/reject", apiCfg.middlewareRequireRole(roleModerator, func(wri http.ResponseWriter, req *http.Request) {
+		rejectHeldChirp(wri, req, apiCfg)
+	}))
+	mux.Handle("GET /admin/flags", apiCfg.middlewareRequireRole(roleModerator, func(wri http.ResponseWriter, req *http.Request) {
+		getChirpFlags(wri, req, apiCfg)
+	}))
+	mux.Handle("DELETE /admin/flags/{flagID}", apiCfg.middlewareRequireRole(roleModerator, func(wri http.ResponseWriter, req *http.Request) {
+		deleteChirpFlag(wri, req, apiCfg)
+	}))
+	// work through user reports (needs a moderator)
+	mux.Handle("GET /api/admin/reports", apiCfg.middlewareRequireRole(roleModerator, func(wri http.ResponseWriter, req *http.Request) {
+		getReportQueue(wri, req, apiCfg)
+	}))
+	mux.Handle("POST /api/admin/reports/{reportID}/claim", apiCfg.middlewareRequireRole(roleModerator, func(wri http.ResponseWriter, req *http.Request) {
+		claimReport(wri, req, apiCfg)
+	}))
+	mux.Handle("POST /api/admin/reports/{reportID}/resolve", apiCfg.middlewareRequireRole(roleModerator, func(wri http.ResponseWriter, req *http.Request) {
+		resolveReport(wri, req, apiCfg)
+	}))
+	// suspend and ban users (needs a moderator, or an admin to ban)
+	mux.Handle("GET /admin/users/{userID}/status", apiCfg.middlewareRequireRole(roleModerator, func(wri http.ResponseWriter, req *http.Request) {
+		getAccountStatus(wri, req, apiCfg)
+	}))
+	mux.Handle("PUT /admin/users/{userID}/status", apiCfg.middlewareRequireRole(roleModerator, func(wri http.ResponseWriter, req *http.Request) {
+		putAccountStatus(wri, req, apiCfg)
+	}))
+	// give users roles and take them away, and see who's done that (needs an admin)
+	mux.Handle("POST /admin/users/{userID}/roles", apiCfg.middlewareRequireRole(roleAdmin, func(wri http.ResponseWriter, req *http.Request) {
+		grantRole(wri, req, apiCfg)
+	}))
+	mux.Handle("DELETE /admin/users/{userID}/roles/{role}", apiCfg.middlewareRequireRole(roleAdmin, func(wri http.ResponseWriter, req *http.Request) {
+		revokeRole(wri, req, apiCfg)
+	}))
+	mux.Handle("GET /admin/roles/audit", apiCfg.middlewareRequireRole(roleAdmin, func(wri http.ResponseWriter, req *http.Request) {
+		getRoleChanges(wri, req, apiCfg)
+	}))
 	// get the health of the server
 	mux.HandleFunc("GET /api/healthz", func(wri http.ResponseWriter, req *http.Request) {
 		respondWithString(wri, 200, "OK")
@@ -60,15 +263,102 @@ func main() {
 	mux.HandleFunc("GET /api/chirps", func(wri http.ResponseWriter, req *http.Request) {
 		getChirps(wri, req, apiCfg)
 	})
+	mux.HandleFunc("GET /api/chirps/search", func(wri http.ResponseWriter, req *http.Request) {
+		searchChirps(wri, req, apiCfg)
+	})
 	mux.HandleFunc("GET /api/chirps/{chirpID}", func(wri http.ResponseWriter, req *http.Request) {
 		getChirpByID(wri, req, apiCfg)
 	})
+	mux.HandleFunc("GET /api/chirps/{chirpID}/thread", func(wri http.ResponseWriter, req *http.Request) {
+		getThread(wri, req, apiCfg)
+	})
 	mux.HandleFunc("POST /api/chirps", func(wri http.ResponseWriter, req *http.Request) {
 		postChirp(wri, req, apiCfg)
 	})
 	mux.HandleFunc("DELETE /api/chirps/{chirpID}", func(wri http.ResponseWriter, req *http.Request) {
 		deleteChirp(wri, req, apiCfg)
 	})
+	mux.HandleFunc("POST /api/chirps/{chirpID}/likes", func(wri http.ResponseWriter, req *http.Request) {
+		likeChirp(wri, req, apiCfg)
+	})
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}/likes", func(wri http.ResponseWriter, req *http.Request) {
+		unlikeChirp(wri, req, apiCfg)
+	})
+	mux.HandleFunc("GET /api/hashtags/{tag}/chirps", func(wri http.ResponseWriter, req *http.Request) {
+		getHashtagChirps(wri, req, apiCfg)
+	})
+	mux.HandleFunc("GET /api/trends", func(wri http.ResponseWriter, req *http.Request) {
+		getTrends(wri, req, apiCfg)
+	})
+	mux.HandleFunc("GET /api/notifications", func(wri http.ResponseWriter, req *http.Request) {
+		getNotifications(wri, req, apiCfg)
+	})
+	mux.HandleFunc("POST /api/notifications/read", func(wri http.ResponseWriter, req *http.Request) {
+		readNotifications(wri, req, apiCfg)
+	})
+	mux.HandleFunc("POST /api/reports", func(wri http.ResponseWriter, req *http.Request) {
+		postReport(wri, req, apiCfg)
+	})
+	mux.HandleFunc("GET /api/reports", func(wri http.ResponseWriter, req *http.Request) {
+		getMyReports(wri, req, apiCfg)
+	})
+	mux.HandleFunc("GET /api/reports/{reportID}", func(wri http.ResponseWriter, req *http.Request) {
+		getReport(wri, req, apiCfg)
+	})
+	mux.HandleFunc("GET /api/timeline", func(wri http.ResponseWriter, req *http.Request) {
+		getTimeline(wri, req, apiCfg)
+	})
+	mux.HandleFunc("POST /api/users/{userID}/follow", func(wri http.ResponseWriter, req *http.Request) {
+		followUser(wri, req, apiCfg)
+	})
+	mux.HandleFunc("DELETE /api/users/{userID}/follow", func(wri http.ResponseWriter, req *http.Request) {
+		unfollowUser(wri, req, apiCfg)
+	})
+	mux.HandleFunc("GET /api/users/{userID}/followers", func(wri http.ResponseWriter, req *http.Request) {
+		getFollowers(wri, req, apiCfg)
+	})
+	mux.HandleFunc("GET /api/users/{userID}/following", func(wri http.ResponseWriter, req *http.Request) {
+		getFollowing(wri, req, apiCfg)
+	})
+	mux.HandleFunc("POST /api/chirps/{chirpID}/rechirp", func(wri http.ResponseWriter, req *http.Request) {
+		rechirpChirp(wri, req, apiCfg)
+	})
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}/rechirp", func(wri http.ResponseWriter, req *http.Request) {
+		undoRechirp(wri, req, apiCfg)
+	})
+	mux.HandleFunc("PUT /api/chirps/{chirpID}", func(wri http.ResponseWriter, req *http.Request) {
+		putChirp(wri, req, apiCfg)
+	})
+	mux.HandleFunc("GET /api/chirps/{chirpID}/revisions", func(wri http.ResponseWriter, req *http.Request) {
+		getChirpRevisions(wri, req, apiCfg)
+	})
+	mux.HandleFunc("POST /api/media", func(wri http.ResponseWriter, req *http.Request) {
+		postMedia(wri, req, apiCfg)
+	})
+	mux.HandleFunc("GET /api/media/{mediaID}", func(wri http.ResponseWriter, req *http.Request) {
+		getMedia(wri, req, apiCfg)
+	})
+	mux.HandleFunc("GET /api/media/{mediaID}/thumbnail", func(wri http.ResponseWriter, req *http.Request) {
+		getMediaThumbnail(wri, req, apiCfg)
+	})
+	mux.HandleFunc("POST /api/conversations", func(wri http.ResponseWriter, req *http.Request) {
+		postConversation(wri, req, apiCfg)
+	})
+	mux.HandleFunc("GET /api/conversations", func(wri http.ResponseWriter, req *http.Request) {
+		getConversations(wri, req, apiCfg)
+	})
+	mux.HandleFunc("POST /api/conversations/{conversationID}/messages", func(wri http.ResponseWriter, req *http.Request) {
+		postMessage(wri, req, apiCfg)
+	})
+	mux.HandleFunc("GET /api/conversations/{conversationID}/messages", func(wri http.ResponseWriter, req *http.Request) {
+		getMessages(wri, req, apiCfg)
+	})
+	mux.HandleFunc("POST /api/conversations/{conversationID}/read", func(wri http.ResponseWriter, req *http.Request) {
+		readConversation(wri, req, apiCfg)
+	})
+	mux.HandleFunc("POST /api/conversations/{conversationID}/leave", func(wri http.ResponseWriter, req *http.Request) {
+		leaveConversation(wri, req, apiCfg)
+	})
 	
 	mux.HandleFunc("POST /api/users", func(wri http.ResponseWriter, req *http.Request) {
 		postUser(wri, req, apiCfg)
@@ -79,6 +369,12 @@ func main() {
 	mux.HandleFunc("PUT /api/users", func(wri http.ResponseWriter, req *http.Request) {
 		putUser(wri, req, apiCfg)
 	})
+	mux.HandleFunc("DELETE /api/users", func(wri http.ResponseWriter, req *http.Request) {
+		deleteUser(wri, req, apiCfg)
+	})
+	mux.HandleFunc("GET /api/users/{userID}/likes", func(wri http.ResponseWriter, req *http.Request) {
+		getUserLikes(wri, req, apiCfg)
+	})
 	
 	mux.HandleFunc("POST /api/refresh", func(wri http.ResponseWriter, req *http.Request) {
 		refresh(wri, req, apiCfg)
@@ -95,33 +391,74 @@ func main() {
 	mux.Handle("/app/", apiCfg.middlewareMetricsInc(http.StripPrefix("/app", http.FileServer(http.Dir(".")))))
 	
 	server := http.Server{
-		Handler: mux,
+		Handler: apiCfg.middlewareLogging(mux, apiCfg.middlewareMetrics(mux, apiCfg.middlewareRateLimit(mux))),
 		Addr: ":8080",
 	}
 	_ = server.ListenAndServe()
 }
 
+// picks a store based on DB_URL
+// "memory:" keeps everything in memory (handy for demos),
+// "sqlite:" or "file:" is a SQLite database file, and anything else is a Postgres URL
+// also returns the migrator for that database (nil for the memory store, which has no schema)
+func openStore(dbURL string) (store.Store, *migrate.Migrator, error) {
+	if strings.HasPrefix(dbURL, "memory:") {
+		return store.NewMemory(), nil, nil
+	}
+	if strings.HasPrefix(dbURL, "sqlite:") || strings.HasPrefix(dbURL, "file:") {
+		db, err := sql.Open("sqlite", sqliteDSN(dbURL))
+		if err != nil {
+			return nil, nil, err
+		}
+		// SQLite only lets one writer in at a time anyway, and this keeps :memory: databases from splitting
+		db.SetMaxOpenConns(1)
+		migrator, err := newMigrator(db, migrate.SQLite, sqliteMigrations, "sql/sqlite/schema")
+		if err != nil {
+			return nil, nil, err
+		}
+		return store.NewSQLite(db), migrator, nil
+	}
+	db, err := sql.Open("postgres", dbURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	migrator, err := newMigrator(db, migrate.Postgres, postgresMigrations, "sql/schema")
+	if err != nil {
+		return nil, nil, err
+	}
+	return store.NewPostgres(db), migrator, nil
+}
+
+// runs fn with a copy of apiCfg whose store is a transaction, so everything fn does to the database
+// is committed together if it returns nil and rolled back if it returns an error
+// fn has to do all its queries through the apiCfg it gets, since SQLite only has the one connection
+func inTx(ctx context.Context, apiCfg apiConfig, fn func(apiConfig) error) error {
+	return apiCfg.dbQueries.InTx(ctx, func(q store.Store) error {
+		apiCfg.dbQueries = q
+		return fn(apiCfg)
+	})
+}
+
+// turns a sqlite: or file: DB_URL into something the driver understands
+// foreign keys are off by default in SQLite, and the cascading deletes need them
+func sqliteDSN(dbURL string) string {
+	dsn := strings.TrimPrefix(dbURL, "sqlite:")
+	sep := "?"
+	if strings.Contains(dsn, "?") {
+		sep = "&"
+	}
+	return dsn + sep + "_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_time_format=sqlite"
+}
+
 // adds one to the metrics counter every time something on /app/ is accessed
 func (cfg *apiConfig) middlewareMetricsInc(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(wri http.ResponseWriter, res *http.Request) {
-		cfg.fileserverHits.Store(cfg.fileserverHits.Add(1))
+		cfg.metrics.fileserverHits.Inc()
 		next.ServeHTTP(wri, res) // ALWAYS continue the ServeHTTP chain.  Don't just send the plain Handler.
 	})
 }
 
 // resets the metrics counter to 0
 func (cfg *apiConfig) metricsReset() {
-	cfg.fileserverHits.Store(0)
+	cfg.metrics.fileserverHits.Reset()
 }
-
-// cleans a string to replace disallowed words with asterisks
-func profanityFilter(original string) string {
-	disallowed := []string{"kerfuffle", "sharbert", "fornax"} //I'm just doing what the lesson says.
-	tokenified := strings.Split(original, " ")
-	for i, _ := range tokenified {
-		if slices.Contains(disallowed, strings.ToLower(tokenified[i])) {
-			tokenified[i] = "****"
-		}
-	}
-	return strings.Join(tokenified, " ")
-}
\ No newline at end of file
@@ -0,0 +1,426 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"internal/blobstore"
+	"internal/database"
+	"internal/imaging"
+	"internal/video"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+)
+
+const maxImageSize = 5 << 20
+const maxVideoSize = 40 << 20
+const maxMediaPerChirp = 4
+
+// "short" videos, going by what the container says rather than how big the file is
+const maxVideoLength = 2 * time.Minute
+
+// uploads that haven't made it onto a chirp by then are cleaned up
+const orphanedMediaAge = 24 * time.Hour
+const orphanedMediaInterval = time.Hour
+
+// the widths images can be asked for with ?w=
+var mediaWidths = []int{160, 320, 640, 1280}
+
+// what can be uploaded (going by the file's contents, not what the client says it is) and how big it can be
+var mediaTypes = map[string]int64{
+	"image/jpeg": maxImageSize,
+	"image/png": maxImageSize,
+	"image/gif": maxImageSize,
+	"image/webp": maxImageSize,
+	"video/mp4": maxVideoSize,
+	"video/webm": maxVideoSize,
+}
+
+func mediaResponse(m database.MediaUpload) mediaParam {
+	res := mediaParam{
+		ID: m.ID,
+		CreatedAt: m.CreatedAt,
+		ContentType: m.ContentType,
+		Size: m.Size,
+		URL: "/api/media/" + m.ID.String(),
+		Width: m.Width,
+		Height: m.Height,
+		Blurhash: m.Blurhash,
+	}
+	if m.ThumbnailKey.Valid {
+		res.ThumbnailURL = res.URL + "/thumbnail"
+	}
+	return res
+}
+
+// upload an image or a short video, to attach to a chirp afterwards
+// the file is the "file" field of a multipart/form-data body
+func postMedia(wri http.ResponseWriter, req *http.Request, apiCfg apiConfig) {
+	user, err := authenticate(req, apiCfg)
+	if err != nil {
+		respondWithProblem(wri, errUnauthorized)
+		return
+	}
+	// leave a little room for the multipart headers around the file
+	req.Body = http.MaxBytesReader(wri, req.Body, maxVideoSize+(1<<20))
+	reader, err := req.MultipartReader()
+	if err != nil {
+		respondWithError(wri, 400, "Request must be multipart/form-data")
+		return
+	}
+	var part io.Reader
+	for part == nil {
+		p, err := reader.NextPart()
+		if err == io.EOF {
+			respondWithError(wri, 400, "Missing file")
+			return
+		}
+		if err != nil {
+			respondWithProblem(wri, errInvalidUpload.Wrap(err))
+			return
+		}
+		if p.FormName() == "file" {
+			part = p
+		}
+	}
+
+	// sniff the type from the first 512 bytes, which is all DetectContentType looks at
+	buffered := bufio.NewReaderSize(part, 512)
+	head, err := buffered.Peek(512)
+	if err != nil && err != io.EOF {
+		respondWithProblem(wri, errInvalidUpload.Wrap(err))
+		return
+	}
+	contentType := http.DetectContentType(head)
+	maxSize, ok := mediaTypes[contentType]
+	if !ok {
+		respondWithError(wri, 415, "Only JPEG, PNG, GIF and WebP images and MP4 and WebM videos can be uploaded")
+		return
+	}
+
+	// read one byte past the limit so going over it can be told apart from landing right on it
+	body := io.LimitReader(buffered, maxSize+1)
+	params := database.CreateMediaUploadParams{UserID: user, BlobKey: uuid.New().String(), ContentType: contentType}
+	var tooBig *http.MaxBytesError
+	if imaging.Supported(contentType) {
+		// images are small enough to clean up in memory before anything is stored
+		data, err := io.ReadAll(body)
+		if errors.As(err, &tooBig) || int64(len(data)) > maxSize {
+			respondWithError(wri, 413, fmt.Sprintf("File is too large, the limit for %s is %d MB", contentType, maxSize>>20))
+			return
+		}
+		if err != nil {
+			respondWithProblem(wri, errInvalidUpload.Wrap(err))
+			return
+		}
+		processed, err := imaging.Process(data, contentType)
+		if errors.Is(err, imaging.ErrTooLarge) {
+			respondWithError(wri, 400, fmt.Sprintf("Images can be at most %d megapixels", imaging.MaxPixels/1_000_000))
+			return
+		}
+		if err != nil {
+			respondWithProblem(wri, errInvalidImage.Wrap(err))
+			return
+		}
+		params.Size, err = apiCfg.blobs.Put(req.Context(), params.BlobKey, bytes.NewReader(processed.Data))
+		if err != nil {
+			respondWithProblem(wri, fmt.Errorf("storing upload: %w", err))
+			return
+		}
+		params.Width = int32(processed.Width)
+		params.Height = int32(processed.Height)
+		params.Blurhash = processed.Blurhash
+		if processed.Thumbnail != nil {
+			params.ThumbnailKey = sql.NullString{String: params.BlobKey + "-thumb", Valid: true}
+			_, err = apiCfg.blobs.Put(req.Context(), params.ThumbnailKey.String, bytes.NewReader(processed.Thumbnail))
+			if err != nil {
+				apiCfg.blobs.Delete(req.Context(), params.BlobKey)
+				respondWithProblem(wri, fmt.Errorf("storing upload: %w", err))
+				return
+			}
+		}
+	} else {
+		// videos go straight to the blob store as they come in
+		size, err := apiCfg.blobs.Put(req.Context(), params.BlobKey, body)
+		if errors.As(err, &tooBig) || (err == nil && size > maxSize) {
+			apiCfg.blobs.Delete(req.Context(), params.BlobKey)
+			respondWithError(wri, 413, fmt.Sprintf("File is too large, the limit for %s is %d MB", contentType, maxSize>>20))
+			return
+		}
+		if err != nil {
+			respondWithProblem(wri, fmt.Errorf("storing upload: %w", err))
+			return
+		}
+		params.Size = size
+	}
+	if params.Size == 0 {
+		deleteMediaFiles(req.Context(), apiCfg, database.MediaUpload{BlobKey: params.BlobKey, ThumbnailKey: params.ThumbnailKey})
+		respondWithError(wri, 400, "File is empty")
+		return
+	}
+	if video.Supported(contentType) {
+		// read back from the blob store, since MP4s can keep their duration right at the end
+		f, err := apiCfg.blobs.Open(req.Context(), params.BlobKey)
+		if err != nil {
+			apiCfg.blobs.Delete(req.Context(), params.BlobKey)
+			respondWithProblem(wri, fmt.Errorf("reading upload: %w", err))
+			return
+		}
+		length, err := video.Duration(f, contentType)
+		f.Close()
+		if err != nil {
+			apiCfg.blobs.Delete(req.Context(), params.BlobKey)
+			respondWithProblem(wri, errInvalidVideo.Wrap(err))
+			return
+		}
+		if length > maxVideoLength {
+			apiCfg.blobs.Delete(req.Context(), params.BlobKey)
+			respondWithError(wri, 400, fmt.Sprintf("Videos can be at most %d minutes long", int(maxVideoLength.Minutes())))
+			return
+		}
+	}
+	media, err := apiCfg.dbQueries.CreateMediaUpload(req.Context(), params)
+	if err != nil {
+		deleteMediaFiles(req.Context(), apiCfg, database.MediaUpload{BlobKey: params.BlobKey, ThumbnailKey: params.ThumbnailKey})
+		respondWithProblem(wri, fmt.Errorf("storing upload: %w", err))
+		return
+	}
+	respondWithJSON(wri, 201, mediaResponse(media))
+}
+
+// looks up the media in the path, responding with a 404 if it's not there (or its chirp has been deleted)
+func getVisibleMedia(wri http.ResponseWriter, req *http.Request, apiCfg apiConfig) (database.MediaUpload, bool) {
+	mediaID, err := uuid.Parse(req.PathValue("mediaID"))
+	if err != nil {
+		respondWithProblem(wri, errMediaNotFound)
+		return database.MediaUpload{}, false
+	}
+	media, err := apiCfg.dbQueries.GetMediaUpload(req.Context(), mediaID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithProblem(wri, errMediaNotFound)
+		return database.MediaUpload{}, false
+	}
+	if err != nil {
+		respondWithProblem(wri, fmt.Errorf("getting media: %w", err))
+		return database.MediaUpload{}, false
+	}
+	// until it's on a chirp, only whoever uploaded it gets to see it
+	if !media.ChirpID.Valid {
+		user, err := authenticate(req, apiCfg)
+		if err != nil || user != media.UserID {
+			respondWithProblem(wri, errMediaNotFound)
+			return database.MediaUpload{}, false
+		}
+	}
+	// media goes away with its chirp, even before it's been cleaned up
+	if media.ChirpID.Valid {
+		chirp, err := apiCfg.dbQueries.GetSingleChirp(req.Context(), media.ChirpID.UUID)
+		if err != nil || chirp.DeletedAt.Valid {
+			respondWithProblem(wri, errMediaNotFound)
+			return database.MediaUpload{}, false
+		}
+	}
+	return media, true
+}
+
+// serve an uploaded file, or with ?w= a copy of an image scaled down to that width
+func getMedia(wri http.ResponseWriter, req *http.Request, apiCfg apiConfig) {
+	media, ok := getVisibleMedia(wri, req, apiCfg)
+	if !ok {
+		return
+	}
+	widthParam := req.URL.Query().Get("w")
+	if widthParam == "" {
+		serveBlob(wri, req, apiCfg.blobs, media.BlobKey, media.ContentType)
+		return
+	}
+	// only a few widths are allowed so the cache can't be filled with every number there is
+	width, err := strconv.Atoi(widthParam)
+	if err != nil || !slices.Contains(mediaWidths, width) {
+		respondWithError(wri, 400, fmt.Sprintf("w must be one of %v", mediaWidths))
+		return
+	}
+	// anything that can't be resized (or is already narrow enough) is just served as is
+	if !imaging.Resizable(media.ContentType) || width >= int(media.Width) {
+		serveBlob(wri, req, apiCfg.blobs, media.BlobKey, media.ContentType)
+		return
+	}
+	key := variantKey(media, width)
+	cached, err := apiCfg.mediaCache.Open(req.Context(), key)
+	if err == nil {
+		cached.Close()
+	} else if errors.Is(err, blobstore.ErrNotFound) {
+		err = resizeMedia(req.Context(), apiCfg, media, width)
+	}
+	if err != nil {
+		respondWithProblem(wri, fmt.Errorf("resizing media: %w", err))
+		return
+	}
+	serveBlob(wri, req, apiCfg.mediaCache, key, imaging.VariantType(media.ContentType))
+}
+
+// serve an image's square thumbnail
+func getMediaThumbnail(wri http.ResponseWriter, req *http.Request, apiCfg apiConfig) {
+	media, ok := getVisibleMedia(wri, req, apiCfg)
+	if !ok {
+		return
+	}
+	if !media.ThumbnailKey.Valid {
+		respondWithError(wri, 404, "This media doesn't have a thumbnail")
+		return
+	}
+	serveBlob(wri, req, apiCfg.blobs, media.ThumbnailKey.String, imaging.VariantType(media.ContentType))
+}
+
+// where the copy of media scaled to width is kept in the cache
+func variantKey(media database.MediaUpload, width int) string {
+	return fmt.Sprintf("%s-w%d", media.BlobKey, width)
+}
+
+// makes the copy of media scaled to width and puts it in the cache
+func resizeMedia(ctx context.Context, apiCfg apiConfig, media database.MediaUpload, width int) error {
+	blob, err := apiCfg.blobs.Open(ctx, media.BlobKey)
+	if err != nil {
+		return err
+	}
+	defer blob.Close()
+	data, err := io.ReadAll(blob)
+	if err != nil {
+		return err
+	}
+	resized, err := imaging.Resize(data, media.ContentType, width)
+	if err != nil {
+		return err
+	}
+	_, err = apiCfg.mediaCache.Put(ctx, variantKey(media, width), bytes.NewReader(resized))
+	return err
+}
+
+func serveBlob(wri http.ResponseWriter, req *http.Request, blobs blobstore.BlobStore, key string, contentType string) {
+	blob, err := blobs.Open(req.Context(), key)
+	if errors.Is(err, blobstore.ErrNotFound) {
+		respondWithProblem(wri, errMediaNotFound)
+		return
+	}
+	if err != nil {
+		respondWithProblem(wri, fmt.Errorf("getting media: %w", err))
+		return
+	}
+	defer blob.Close()
+	wri.Header().Set("Content-Type", contentType)
+	wri.Header().Set("X-Content-Type-Options", "nosniff")
+	// a media id (and width) always points at the same file
+	wri.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	wri.WriteHeader(200)
+	io.Copy(wri, blob)
+}
+
+// deletes an upload's file along with its thumbnail and anything in the cache made from it
+func deleteMediaFiles(ctx context.Context, apiCfg apiConfig, media database.MediaUpload) error {
+	for _, width := range mediaWidths {
+		err := apiCfg.mediaCache.Delete(ctx, variantKey(media, width))
+		if err != nil {
+			return err
+		}
+	}
+	if media.ThumbnailKey.Valid {
+		err := apiCfg.blobs.Delete(ctx, media.ThumbnailKey.String)
+		if err != nil {
+			return err
+		}
+	}
+	return apiCfg.blobs.Delete(ctx, media.BlobKey)
+}
+
+// checks the media_ids on a new chirp, responding with a 400 unless they're all the user's own unattached uploads
+func validMedia(wri http.ResponseWriter, req *http.Request, apiCfg apiConfig, ids []uuid.UUID, user uuid.UUID) bool {
+	if len(ids) > maxMediaPerChirp {
+		respondWithError(wri, 400, fmt.Sprintf("A chirp can have at most %d media_ids", maxMediaPerChirp))
+		return false
+	}
+	for i, id := range ids {
+		for _, earlier := range ids[:i] {
+			if earlier == id {
+				respondWithError(wri, 400, "media_ids can't have the same upload twice")
+				return false
+			}
+		}
+		media, err := apiCfg.dbQueries.GetMediaUpload(req.Context(), id)
+		if err != nil || media.UserID != user || media.ChirpID.Valid {
+			respondWithError(wri, 400, "media_ids must be your own uploads that aren't on another chirp")
+			return false
+		}
+	}
+	return true
+}
+
+// attaches the uploads to the chirp in the order they were given
+// validMedia has already checked they're free, but another chirp could have taken one since,
+// in which case this fails so the chirp isn't posted without it
+func attachMedia(ctx context.Context, apiCfg apiConfig, chirp database.Chirp, ids []uuid.UUID) error {
+	for i, id := range ids {
+		attached, err := apiCfg.dbQueries.AttachMediaUpload(ctx, database.AttachMediaUploadParams{
+			ChirpID: uuid.NullUUID{UUID: chirp.ID, Valid: true},
+			Position: int32(i),
+			ID: id,
+		})
+		if err != nil {
+			return err
+		}
+		if attached == 0 {
+			return errMediaTaken
+		}
+	}
+	return nil
+}
+
+// deletes the uploads (and all their files) that were never attached to a chirp, or whose chirp was deleted,
+// as long as they're older than before
+// the files go first, so if that fails the row is still there to try again next time
+func collectOrphanedMedia(ctx context.Context, apiCfg apiConfig, before time.Time) (int, error) {
+	const pageSize = 100
+	collected := 0
+	for {
+		orphans, err := apiCfg.dbQueries.ListOrphanedMedia(ctx, database.ListOrphanedMediaParams{CreatedBefore: before, PageLimit: pageSize})
+		if err != nil {
+			return collected, err
+		}
+		for _, media := range orphans {
+			err = deleteMediaFiles(ctx, apiCfg, media)
+			if err != nil {
+				return collected, err
+			}
+			err = apiCfg.dbQueries.DeleteMediaUpload(ctx, media.ID)
+			if err != nil {
+				return collected, err
+			}
+			collected++
+		}
+		if len(orphans) < pageSize {
+			return collected, nil
+		}
+	}
+}
+
+// runs collectOrphanedMedia every orphanedMediaInterval for as long as the server's up
+func collectOrphanedMediaForever(apiCfg apiConfig) {
+	ticker := time.NewTicker(orphanedMediaInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		collected, err := collectOrphanedMedia(context.Background(), apiCfg, time.Now().UTC().Add(-orphanedMediaAge))
+		if err != nil {
+			slog.Error("collecting orphaned media failed", "error", err)
+		}
+		if collected > 0 {
+			slog.Info("collected orphaned media", "uploads", collected)
+		}
+	}
+}
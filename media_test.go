@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"internal/auth"
+	"internal/database"
+	"internal/store"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAttachMediaTaken(t *testing.T) {
+	ctx := context.Background()
+	apiCfg := apiConfig{dbQueries: store.NewMemory()}
+	user, _ := apiCfg.dbQueries.CreateUser(ctx, database.CreateUserParams{Email: "user@example.com"})
+	upload, _ := apiCfg.dbQueries.CreateMediaUpload(ctx, database.CreateMediaUploadParams{UserID: user.ID, BlobKey: "a", ContentType: "image/png", Size: 10})
+	first, _ := apiCfg.dbQueries.CreateChirp(ctx, database.CreateChirpParams{Body: "first", UserID: user.ID})
+	second, _ := apiCfg.dbQueries.CreateChirp(ctx, database.CreateChirpParams{Body: "second", UserID: user.ID})
+
+	err := attachMedia(ctx, apiCfg, first, []uuid.UUID{upload.ID})
+	if err != nil {
+		t.Fatalf("Error in attachMedia: %v", err)
+	}
+	// the one that loses the race finds out, rather than going up without it
+	err = attachMedia(ctx, apiCfg, second, []uuid.UUID{upload.ID})
+	if !errors.Is(err, errMediaTaken) {
+		t.Errorf("Expected errMediaTaken for an upload that's already attached, got %v", err)
+	}
+}
+
+func TestUnattachedMediaVisibility(t *testing.T) {
+	ctx := context.Background()
+	apiCfg := apiConfig{dbQueries: store.NewMemory(), secret: "secret"}
+	uploader, _ := apiCfg.dbQueries.CreateUser(ctx, database.CreateUserParams{Email: "uploader@example.com"})
+	other, _ := apiCfg.dbQueries.CreateUser(ctx, database.CreateUserParams{Email: "other@example.com"})
+	upload, _ := apiCfg.dbQueries.CreateMediaUpload(ctx, database.CreateMediaUploadParams{UserID: uploader.ID, BlobKey: "a", ContentType: "image/png", Size: 10})
+	visibleTo := func(user *database.User) bool {
+		req := httptest.NewRequest("GET", "/api/media/"+upload.ID.String(), nil)
+		req.SetPathValue("mediaID", upload.ID.String())
+		if user != nil {
+			token, _ := auth.MakeJWT(user.ID, user.Role, apiCfg.secret, time.Hour)
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		_, ok := getVisibleMedia(httptest.NewRecorder(), req, apiCfg)
+		return ok
+	}
+
+	if !visibleTo(&uploader) {
+		t.Errorf("Expected the uploader to see their unattached upload")
+	}
+	if visibleTo(&other) || visibleTo(nil) {
+		t.Errorf("Expected nobody else to see an unattached upload")
+	}
+	chirp, _ := apiCfg.dbQueries.CreateChirp(ctx, database.CreateChirpParams{Body: "look", UserID: uploader.ID})
+	attachMedia(ctx, apiCfg, chirp, []uuid.UUID{upload.ID})
+	if !visibleTo(nil) {
+		t.Errorf("Expected anyone to see an upload once it's on a chirp")
+	}
+}
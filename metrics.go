@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"internal/auth"
+	"internal/metrics"
+	"internal/store"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// bcrypt is slow on purpose, so its buckets go a lot higher than the ones for requests
+var bcryptBuckets = []float64{.01, .025, .05, .1, .25, .5, 1, 2.5}
+
+// everything chirpy counts, all in one registry
+// /metrics serves it to Prometheus, and /admin/metrics shows it to people
+type serverMetrics struct {
+	registry *metrics.Registry
+	requests *metrics.Counter
+	requestDuration *metrics.Histogram
+	inFlight *metrics.Gauge
+	fileserverHits *metrics.Counter
+	bcryptDuration *metrics.Histogram
+	chirpsCreated *metrics.Counter
+	loginFailures *metrics.Counter
+}
+
+func newServerMetrics(dbQueries store.Store) *serverMetrics {
+	r := metrics.NewRegistry()
+	m := &serverMetrics{
+		registry: r,
+		requests: r.Counter("chirpy_http_requests_total", "HTTP requests served, by route and status code.", "route", "code"),
+		requestDuration: r.Histogram("chirpy_http_request_duration_seconds", "How long HTTP requests took, by route and status code.", metrics.DefaultBuckets, "route", "code"),
+		inFlight: r.Gauge("chirpy_http_requests_in_flight", "HTTP requests being served right now."),
+		fileserverHits: r.Counter("chirpy_fileserver_hits_total", "Requests for the pages under /app/."),
+		bcryptDuration: r.Histogram("chirpy_bcrypt_duration_seconds", "How long hashing and checking passwords took.", bcryptBuckets, "op"),
+		chirpsCreated: r.Counter("chirpy_chirps_created_total", "Chirps that went up, including held ones once they're approved."),
+		loginFailures: r.Counter("chirpy_login_failures_total", "Failed logins, by why they failed.", "reason"),
+	}
+	// the memory store has no connection pool to report on
+	if pool, ok := dbQueries.(store.PoolStats); ok {
+		r.GaugeFunc("chirpy_db_max_open_connections", "The most connections the pool will open.", func() float64 {
+			return float64(pool.Stats().MaxOpenConnections)
+		})
+		r.GaugeFunc("chirpy_db_open_connections", "Connections the pool has open, in use or idle.", func() float64 {
+			return float64(pool.Stats().OpenConnections)
+		})
+		r.GaugeFunc("chirpy_db_in_use_connections", "Connections being used right now.", func() float64 {
+			return float64(pool.Stats().InUse)
+		})
+		r.GaugeFunc("chirpy_db_idle_connections", "Connections sitting idle.", func() float64 {
+			return float64(pool.Stats().Idle)
+		})
+		r.CounterFunc("chirpy_db_wait_count_total", "Times a query had to wait for a connection.", func() float64 {
+			return float64(pool.Stats().WaitCount)
+		})
+		r.CounterFunc("chirpy_db_wait_duration_seconds_total", "Time spent waiting for connections.", func() float64 {
+			return pool.Stats().WaitDuration.Seconds()
+		})
+	}
+	return m
+}
+
+// counts every request by the route it matched (not its path, which would make a series for every chirp id)
+// requests that didn't match a route count as "unmatched"
+func (cfg *apiConfig) middlewareMetrics(mux *http.ServeMux, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(wri http.ResponseWriter, req *http.Request) {
+		_, route := mux.Handler(req)
+		if route == "" {
+			route = "unmatched"
+		}
+		cfg.metrics.inFlight.Inc()
+		defer cfg.metrics.inFlight.Dec()
+		start := time.Now()
+		sw := wrapStatusWriter(wri)
+		next.ServeHTTP(sw, req)
+		code := strconv.Itoa(sw.Status())
+		cfg.metrics.requests.Inc(route, code)
+		cfg.metrics.requestDuration.Observe(time.Since(start).Seconds(), route, code)
+	})
+}
+
+// auth.HashPassword, timed
+func hashPassword(apiCfg apiConfig, password string) (string, error) {
+	start := time.Now()
+	defer func() { apiCfg.metrics.bcryptDuration.Observe(time.Since(start).Seconds(), "hash") }()
+	return auth.HashPassword(password)
+}
+
+// auth.CheckPasswordHash, timed
+func checkPassword(apiCfg apiConfig, password string, hash string) error {
+	start := time.Now()
+	defer func() { apiCfg.metrics.bcryptDuration.Observe(time.Since(start).Seconds(), "check") }()
+	return auth.CheckPasswordHash(password, hash)
+}
+
+// every metric in the Prometheus text format
+// if METRICS_TOKEN is set, it has to be sent as a bearer token
+func getMetrics(wri http.ResponseWriter, req *http.Request, apiCfg apiConfig) {
+	if apiCfg.metricsToken != "" {
+		token, err := auth.GetBearerToken(req.Header)
+		if err != nil || token != apiCfg.metricsToken {
+			respondWithProblem(wri, errUnauthorized)
+			return
+		}
+	}
+	wri.Header().Set("Content-Type", metrics.ContentType)
+	wri.WriteHeader(200)
+	apiCfg.metrics.registry.WriteText(wri)
+}
+
+// the same metrics as /metrics, as a page for people to read
+func getAdminMetrics(wri http.ResponseWriter, req *http.Request, apiCfg apiConfig) {
+	page := strings.Builder{}
+	page.WriteString("<html><body><h1>Welcome, Chirpy Admin</h1>")
+	page.WriteString(fmt.Sprintf("<p>Chirpy has been visited %d times!</p>", int64(apiCfg.metrics.fileserverHits.Value())))
+	for _, f := range apiCfg.metrics.registry.Gather() {
+		page.WriteString(fmt.Sprintf("<h2>%s</h2><p>%s</p>", html.EscapeString(f.Name), html.EscapeString(f.Help)))
+		if len(f.Samples) == 0 {
+			page.WriteString("<p>Nothing yet.</p>")
+			continue
+		}
+		page.WriteString("<table>")
+		for _, s := range f.Samples {
+			labels := []string{}
+			for _, l := range s.Labels {
+				labels = append(labels, fmt.Sprintf("%s=%s", l.Name, l.Value))
+			}
+			page.WriteString(fmt.Sprintf("<tr><td>%s</td><td>%s</td><td>%s</td></tr>", html.EscapeString(s.Name), html.EscapeString(strings.Join(labels, " ")), strconv.FormatFloat(s.Value, 'g', -1, 64)))
+		}
+		page.WriteString("</table>")
+	}
+	page.WriteString("</body></html>")
+	wri.Header().Set("Content-Type", "text/html; charset=utf-8")
+	wri.WriteHeader(200)
+	wri.Write([]byte(page.String()))
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"internal/migrate"
+	"io/fs"
+	"os"
+)
+
+// the goose migrations get built right into the binary
+//go:embed sql/schema/*.sql
+var postgresMigrations embed.FS
+
+//go:embed sql/sqlite/schema/*.sql
+var sqliteMigrations embed.FS
+
+// gets a migrator for the migrations in one of the embedded directories
+func newMigrator(db *sql.DB, dialect migrate.Dialect, migrations embed.FS, dir string) (*migrate.Migrator, error) {
+	sub, err := fs.Sub(migrations, dir)
+	if err != nil {
+		return nil, err
+	}
+	return migrate.New(db, dialect, sub)
+}
+
+// applies any pending migrations, then makes sure nothing is still pending
+// set AUTO_MIGRATE=false to only do the check (and run `chirpy migrate up` yourself)
+func migrateOnStartup(migrator *migrate.Migrator) error {
+	if migrator == nil {
+		return nil
+	}
+	ctx := context.Background()
+	if os.Getenv("AUTO_MIGRATE") != "false" {
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			return fmt.Errorf("Error migrating the database: %v", err)
+		}
+		for _, m := range applied {
+			fmt.Printf("Applied migration %v\n", m.Name)
+		}
+	}
+	pending, err := migrator.Pending(ctx)
+	if err != nil {
+		return fmt.Errorf("Error checking migrations: %v", err)
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("The database schema is behind (%d pending, starting with %v).  Run `chirpy migrate up` first.", len(pending), pending[0].Name)
+	}
+	return nil
+}
+
+// handles `chirpy migrate up|down|status`
+func migrateCommand(migrator *migrate.Migrator, args []string) error {
+	if migrator == nil {
+		return fmt.Errorf("The in-memory store doesn't have anything to migrate")
+	}
+	if len(args) != 1 {
+		return fmt.Errorf("Usage: chirpy migrate up|down|status")
+	}
+	ctx := context.Background()
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			fmt.Println("Already up to date")
+		}
+		for _, m := range applied {
+			fmt.Printf("Applied migration %v\n", m.Name)
+		}
+	case "down":
+		m, found, err := migrator.Down(ctx)
+		if err != nil {
+			return err
+		}
+		if !found {
+			fmt.Println("Nothing to roll back")
+			return nil
+		}
+		fmt.Printf("Rolled back migration %v\n", m.Name)
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%-30v %v\n", "Applied At", "Migration")
+		for _, s := range statuses {
+			appliedAt := "Pending"
+			if s.Applied {
+				appliedAt = s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%-30v %v\n", appliedAt, s.Migration.Name)
+		}
+	default:
+		return fmt.Errorf("Unknown migrate command %q, expected up, down or status", args[0])
+	}
+	return nil
+}
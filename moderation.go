@@ -0,0 +1,263 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"internal/contentfilter"
+	"internal/database"
+	"internal/moderation"
+	"net/http"
+)
+
+// runs a chirp through the moderation pipeline, responding with an error if it's rejected
+func moderateChirp(wri http.ResponseWriter, req *http.Request, apiCfg apiConfig, user uuid.UUID, body string) (moderation.Result, bool) {
+	author, err := apiCfg.dbQueries.GetUserByID(req.Context(), user)
+	if err != nil {
+		respondWithProblem(wri, fmt.Errorf("getting user: %w", err))
+		return moderation.Result{}, false
+	}
+	res, err := apiCfg.moderation.Run(req.Context(), moderation.Chirp{Body: body, AuthorCreatedAt: author.CreatedAt})
+	if err != nil {
+		respondWithProblem(wri, fmt.Errorf("moderating chirp: %w", err))
+		return moderation.Result{}, false
+	}
+	if res.Decision == moderation.Reject {
+		respondWithProblem(wri, errChirpRejected(res.Reason))
+		return moderation.Result{}, false
+	}
+	return res, true
+}
+
+// creates a chirp and does everything that comes with one going up:
+// attaching its media, tagging it, flagging whatever the content filter matched,
+// and letting whoever it mentions or replies to know
+// it's all one transaction, so a chirp is never left half published (and a retry doesn't make two)
+func publishChirp(ctx context.Context, apiCfg apiConfig, params database.CreateChirpParams, mediaIDs []uuid.UUID, flagged []contentfilter.Match) (database.Chirp, error) {
+	var chirp database.Chirp
+	err := inTx(ctx, apiCfg, func(apiCfg apiConfig) error {
+		var err error
+		chirp, err = apiCfg.dbQueries.CreateChirp(ctx, params)
+		if err != nil {
+			return err
+		}
+		err = attachMedia(ctx, apiCfg, chirp, mediaIDs)
+		if err != nil {
+			return fmt.Errorf("attaching media: %w", err)
+		}
+		err = tagChirp(ctx, apiCfg, chirp)
+		if err != nil {
+			return fmt.Errorf("tagging chirp: %w", err)
+		}
+		err = flagChirp(ctx, apiCfg, chirp.ID, flagged)
+		if err != nil {
+			return fmt.Errorf("flagging chirp: %w", err)
+		}
+		err = mentionUsers(ctx, apiCfg, chirp)
+		if err != nil {
+			return fmt.Errorf("mentioning users: %w", err)
+		}
+		if chirp.InReplyTo.Valid {
+			parent, err := apiCfg.dbQueries.GetSingleChirp(ctx, chirp.InReplyTo.UUID)
+			if err != nil {
+				return fmt.Errorf("getting parent chirp: %w", err)
+			}
+			err = notify(ctx, apiCfg, parent.UserID, chirp.UserID, notifyReply, uuid.NullUUID{UUID: chirp.ID, Valid: true})
+			if err != nil {
+				return fmt.Errorf("notifying user: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return database.Chirp{}, err
+	}
+	apiCfg.metrics.chirpsCreated.Inc()
+	return chirp, nil
+}
+
+// puts a chirp in the review queue, along with the media it'll have once it's approved
+func holdChirp(ctx context.Context, apiCfg apiConfig, params database.CreateHeldChirpParams, mediaIDs []uuid.UUID) (heldChirpParam, error) {
+	var held database.HeldChirp
+	err := inTx(ctx, apiCfg, func(apiCfg apiConfig) error {
+		var err error
+		held, err = apiCfg.dbQueries.CreateHeldChirp(ctx, params)
+		if err != nil {
+			return err
+		}
+		for i, id := range mediaIDs {
+			err = apiCfg.dbQueries.AddHeldChirpMedia(ctx, database.AddHeldChirpMediaParams{HeldChirpID: held.ID, MediaID: id, Position: int32(i)})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return heldChirpParam{}, err
+	}
+	return heldChirpResponse(held, mediaIDs), nil
+}
+
+func heldChirpResponse(held database.HeldChirp, mediaIDs []uuid.UUID) heldChirpParam {
+	res := heldChirpParam{
+		ID: held.ID,
+		CreatedAt: held.CreatedAt,
+		UserID: held.UserID,
+		Body: held.Body,
+		MediaIDs: mediaIDs,
+		Stage: held.Stage,
+		Reason: held.Reason,
+	}
+	if held.InReplyTo.Valid {
+		inReplyTo := held.InReplyTo.UUID
+		res.InReplyTo = &inReplyTo
+	}
+	if held.QuoteOf.Valid {
+		quoteOf := held.QuoteOf.UUID
+		res.QuoteOf = &quoteOf
+	}
+	return res
+}
+
+func heldMediaIDs(ctx context.Context, apiCfg apiConfig, held database.HeldChirp) ([]uuid.UUID, error) {
+	media, err := apiCfg.dbQueries.ListHeldChirpMedia(ctx, held.ID)
+	if err != nil {
+		return nil, err
+	}
+	ids := []uuid.UUID{}
+	for _, m := range media {
+		ids = append(ids, m.MediaID)
+	}
+	return ids, nil
+}
+
+// the held chirp in the path, responding with an error if it isn't there
+func getPathHeldChirp(wri http.ResponseWriter, req *http.Request, apiCfg apiConfig) (database.HeldChirp, bool) {
+	heldID, err := uuid.Parse(req.PathValue("heldID"))
+	if err != nil {
+		respondWithProblem(wri, errHeldChirpNotFound)
+		return database.HeldChirp{}, false
+	}
+	held, err := apiCfg.dbQueries.GetHeldChirp(req.Context(), heldID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithProblem(wri, errHeldChirpNotFound)
+		return database.HeldChirp{}, false
+	}
+	if err != nil {
+		respondWithProblem(wri, fmt.Errorf("getting held chirp: %w", err))
+		return database.HeldChirp{}, false
+	}
+	return held, true
+}
+
+// the review queue, oldest first
+func getHeldChirps(wri http.ResponseWriter, req *http.Request, apiCfg apiConfig) {
+	start, limit, err := parsePage(req, false)
+	if err != nil {
+		respondWithProblem(wri, err)
+		return
+	}
+	rows, err := apiCfg.dbQueries.ListHeldChirps(req.Context(), database.ListHeldChirpsParams{
+		AfterCreatedAt: start.CreatedAt,
+		AfterID: start.ID,
+		PageLimit: limit + 1,
+	})
+	if err != nil {
+		respondWithProblem(wri, fmt.Errorf("getting held chirps: %w", err))
+		return
+	}
+	if len(rows) > int(limit) {
+		rows = rows[:limit]
+		last := rows[len(rows)-1]
+		setNextLink(wri, req, cursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+	output := []heldChirpParam{}
+	for _, h := range rows {
+		mediaIDs, err := heldMediaIDs(req.Context(), apiCfg, h)
+		if err != nil {
+			respondWithProblem(wri, fmt.Errorf("getting held chirps: %w", err))
+			return
+		}
+		output = append(output, heldChirpResponse(h, mediaIDs))
+	}
+	respondWithJSON(wri, 200, output)
+}
+
+// publishes a held chirp, as if it had just been posted
+func approveHeldChirp(wri http.ResponseWriter, req *http.Request, apiCfg apiConfig) {
+	held, ok := getPathHeldChirp(wri, req, apiCfg)
+	if !ok {
+		return
+	}
+	author, err := apiCfg.dbQueries.GetUserByID(req.Context(), held.UserID)
+	if err != nil {
+		respondWithProblem(wri, fmt.Errorf("getting user: %w", err))
+		return
+	}
+	if author.DeletedAt.Valid {
+		respondWithProblem(wri, errHeldAuthorDeleted)
+		return
+	}
+	// a purged parent takes its held replies with it, but one that's only deleted doesn't
+	if held.InReplyTo.Valid {
+		parent, err := apiCfg.dbQueries.GetSingleChirp(req.Context(), held.InReplyTo.UUID)
+		if err != nil {
+			respondWithProblem(wri, fmt.Errorf("getting parent chirp: %w", err))
+			return
+		}
+		if parent.DeletedAt.Valid {
+			respondWithProblem(wri, errParentDeleted)
+			return
+		}
+	}
+	// claiming it and publishing it happen together, so a second approval at the same time
+	// finds it already gone rather than publishing it again
+	var chirp database.Chirp
+	err = inTx(req.Context(), apiCfg, func(apiCfg apiConfig) error {
+		// its media goes with it, so that has to be got first
+		mediaIDs, err := heldMediaIDs(req.Context(), apiCfg, held)
+		if err != nil {
+			return fmt.Errorf("getting held chirp: %w", err)
+		}
+		claimed, err := apiCfg.dbQueries.ClaimHeldChirp(req.Context(), held.ID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return errHeldChirpNotFound.Wrap(err)
+		}
+		if err != nil {
+			return fmt.Errorf("claiming held chirp: %w", err)
+		}
+		chirp, err = publishChirp(req.Context(), apiCfg, database.CreateChirpParams{Body: claimed.Body, UserID: claimed.UserID, InReplyTo: claimed.InReplyTo, QuoteOf: claimed.QuoteOf}, mediaIDs, nil)
+		if err != nil {
+			return fmt.Errorf("creating chirp: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		respondWithProblem(wri, err)
+		return
+	}
+	resBody, err := chirpResponses(req, apiCfg, []database.Chirp{chirp})
+	if err != nil {
+		respondWithProblem(wri, fmt.Errorf("creating chirp: %w", err))
+		return
+	}
+	respondWithJSON(wri, 201, resBody[0])
+}
+
+// throws a held chirp away
+// its media is left unattached, so the orphaned media collection gets it
+func rejectHeldChirp(wri http.ResponseWriter, req *http.Request, apiCfg apiConfig) {
+	held, ok := getPathHeldChirp(wri, req, apiCfg)
+	if !ok {
+		return
+	}
+	err := apiCfg.dbQueries.DeleteHeldChirp(req.Context(), held.ID)
+	if err != nil {
+		respondWithProblem(wri, fmt.Errorf("deleting held chirp: %w", err))
+		return
+	}
+	wri.WriteHeader(204)
+}
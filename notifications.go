@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+	"internal/apierr"
+	"internal/database"
+	"internal/mentions"
+	"net/http"
+)
+
+// what a notification is about
+const (
+	notifyMention = "mention"
+	notifyReply = "reply"
+	notifyLike = "like"
+	notifyFollow = "follow"
+	// one of your reports was resolved
+	notifyReportResolved = "report_resolved"
+)
+
+// tells user that actor did something (the store drops repeats, and nobody gets notified about themselves)
+func notify(ctx context.Context, apiCfg apiConfig, user uuid.UUID, actor uuid.UUID, kind string, chirpID uuid.NullUUID) error {
+	if user == actor {
+		return nil
+	}
+	return apiCfg.dbQueries.CreateNotification(ctx, database.CreateNotificationParams{
+		UserID: user,
+		ActorID: actor,
+		Kind: kind,
+		ChirpID: chirpID,
+	})
+}
+
+// resolves the @handles in a new chirp, saves them and notifies whoever was mentioned
+// handles that don't belong to anyone are left as plain text
+func mentionUsers(ctx context.Context, apiCfg apiConfig, chirp database.Chirp) error {
+	handles := mentions.Extract(chirp.Body)
+	if len(handles) == 0 {
+		return nil
+	}
+	users, err := apiCfg.dbQueries.GetUsersByHandles(ctx, handles)
+	if err != nil {
+		return err
+	}
+	for _, u := range users {
+		if u.DeletedAt.Valid {
+			continue
+		}
+		err = apiCfg.dbQueries.MentionUser(ctx, database.MentionUserParams{ChirpID: chirp.ID, UserID: u.ID})
+		if err != nil {
+			return err
+		}
+		err = notify(ctx, apiCfg, u.ID, chirp.UserID, notifyMention, uuid.NullUUID{UUID: chirp.ID, Valid: true})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checks a handle someone's asking for, responding with an error if it's no good
+func validHandle(wri http.ResponseWriter, req *http.Request, apiCfg apiConfig, handle string, user uuid.UUID) (sql.NullString, bool) {
+	normalized, ok := mentions.Normalize(handle)
+	if !ok {
+		msg := fmt.Sprintf("Handles must be 1 to %d letters, numbers or underscores", mentions.MaxLength)
+		respondWithProblem(wri, apierr.Validation("invalid_handle", msg, apierr.FieldError{Field: "handle", Code: "invalid", Message: msg}))
+		return sql.NullString{}, false
+	}
+	taken, err := apiCfg.dbQueries.GetUsersByHandles(req.Context(), []string{normalized})
+	if err != nil {
+		respondWithProblem(wri, fmt.Errorf("checking handle: %w", err))
+		return sql.NullString{}, false
+	}
+	if len(taken) > 0 && taken[0].ID != user {
+		respondWithProblem(wri, errHandleTaken)
+		return sql.NullString{}, false
+	}
+	return sql.NullString{String: normalized, Valid: true}, true
+}
+
+// get the logged in user's notifications, newest first
+// ?unread=true leaves out the ones that have been read
+func getNotifications(wri http.ResponseWriter, req *http.Request, apiCfg apiConfig) {
+	user, err := authenticate(req, apiCfg)
+	if err != nil {
+		respondWithProblem(wri, errUnauthorized)
+		return
+	}
+	start, limit, err := parsePage(req, true)
+	if err != nil {
+		respondWithProblem(wri, err)
+		return
+	}
+	var rows []database.Notification
+	switch req.URL.Query().Get("unread") {
+	case "", "false":
+		rows, err = apiCfg.dbQueries.ListNotifications(req.Context(), database.ListNotificationsParams{
+			UserID: user,
+			BeforeCreatedAt: start.CreatedAt,
+			BeforeID: start.ID,
+			PageLimit: limit + 1,
+		})
+	case "true":
+		rows, err = apiCfg.dbQueries.ListUnreadNotifications(req.Context(), database.ListUnreadNotificationsParams{
+			UserID: user,
+			BeforeCreatedAt: start.CreatedAt,
+			BeforeID: start.ID,
+			PageLimit: limit + 1,
+		})
+	default:
+		respondWithError(wri, 400, "unread must be true or false")
+		return
+	}
+	if err != nil {
+		respondWithProblem(wri, fmt.Errorf("getting notifications: %w", err))
+		return
+	}
+	if len(rows) > int(limit) {
+		rows = rows[:limit]
+		last := rows[len(rows)-1]
+		setNextLink(wri, req, cursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+	output := []notificationParam{}
+	for _, n := range rows {
+		res := notificationParam{
+			ID: n.ID,
+			CreatedAt: n.CreatedAt,
+			Kind: n.Kind,
+			ActorID: n.ActorID,
+			Read: n.ReadAt.Valid,
+		}
+		if n.ChirpID.Valid {
+			chirpID := n.ChirpID.UUID
+			res.ChirpID = &chirpID
+		}
+		if n.ReportID.Valid {
+			reportID := n.ReportID.UUID
+			res.ReportID = &reportID
+		}
+		output = append(output, res)
+	}
+	respondWithJSON(wri, 200, output)
+}
+
+// mark notifications as read, or all of them if no ids are given
+func readNotifications(wri http.ResponseWriter, req *http.Request, apiCfg apiConfig) {
+	type reqParam struct {
+		IDs []uuid.UUID `json:"ids"`
+	}
+	user, err := authenticate(req, apiCfg)
+	if err != nil {
+		respondWithProblem(wri, errUnauthorized)
+		return
+	}
+
+	// an empty body is fine, that's the same as marking everything
+	reqBody := reqParam{}
+	if req.ContentLength != 0 {
+		err = json.NewDecoder(req.Body).Decode(&reqBody)
+		if err != nil {
+			respondWithProblem(wri, errInvalidBody.Wrap(err))
+			return
+		}
+	}
+	if len(reqBody.IDs) == 0 {
+		err = apiCfg.dbQueries.MarkAllNotificationsRead(req.Context(), user)
+	} else {
+		err = apiCfg.dbQueries.MarkNotificationsRead(req.Context(), database.MarkNotificationsReadParams{UserID: user, Ids: reqBody.IDs})
+	}
+	if err != nil {
+		respondWithProblem(wri, fmt.Errorf("marking notifications read: %w", err))
+		return
+	}
+	wri.WriteHeader(204)
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"github.com/google/uuid"
+	"internal/apierr"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultPageLimit = 50
+const maxPageLimit = 100
+
+// a position in a list ordered by (created_at, id)
+type cursor struct {
+	CreatedAt time.Time
+	ID uuid.UUID
+}
+
+// where an ascending list starts (before everything)
+var firstCursor = cursor{}
+
+// where a descending list starts (after everything)
+var lastCursor = cursor{
+	CreatedAt: time.Date(9999, 12, 31, 23, 59, 59, 0, time.UTC),
+	ID: uuid.Max,
+}
+
+// cursors are opaque to clients, so just base64 the position
+func (c cursor) encode() string {
+	raw := c.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + c.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// read a cursor back out of ?cursor=
+func decodeCursor(encoded string) (cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return cursor{}, err
+	}
+	createdAt, id, found := strings.Cut(string(raw), "|")
+	if !found {
+		return cursor{}, fmt.Errorf("malformed cursor")
+	}
+	c := cursor{}
+	c.CreatedAt, err = time.Parse(time.RFC3339Nano, createdAt)
+	if err != nil {
+		return cursor{}, err
+	}
+	c.ID, err = uuid.Parse(id)
+	if err != nil {
+		return cursor{}, err
+	}
+	return c, nil
+}
+
+// reads ?limit= and ?cursor= from a request
+// no cursor means start from the beginning (or the end, if it's sorted newest first)
+func parsePage(req *http.Request, desc bool) (cursor, int32, error) {
+	limit := defaultPageLimit
+	if l := req.URL.Query().Get("limit"); l != "" {
+		var err error
+		limit, err = strconv.Atoi(l)
+		if err != nil || limit < 1 || limit > maxPageLimit {
+			msg := fmt.Sprintf("limit must be between 1 and %d", maxPageLimit)
+			return cursor{}, 0, apierr.Validation("invalid_page", msg, apierr.FieldError{Field: "limit", Code: "out_of_range", Message: msg})
+		}
+	}
+	start := firstCursor
+	if desc {
+		start = lastCursor
+	}
+	if c := req.URL.Query().Get("cursor"); c != "" {
+		var err error
+		start, err = decodeCursor(c)
+		if err != nil {
+			return cursor{}, 0, apierr.Validation("invalid_page", "Invalid cursor", apierr.FieldError{Field: "cursor", Code: "invalid", Message: "Invalid cursor"})
+		}
+	}
+	return start, int32(limit), nil
+}
+
+// points the Link header at the next page, keeping the rest of the query as it was
+func setNextLink(wri http.ResponseWriter, req *http.Request, next cursor) {
+	query := req.URL.Query()
+	query.Set("cursor", next.encode())
+	nextURL := *req.URL
+	nextURL.RawQuery = query.Encode()
+	wri.Header().Set("Link", fmt.Sprintf("<%v>; rel=\"next\"", nextURL.RequestURI()))
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// wraps the whole mux, so every request is limited by the policy for the route it matched
+// requests with a valid JWT are counted against the user, and everything else against the IP it came from
+func (cfg *apiConfig) middlewareRateLimit(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(wri http.ResponseWriter, req *http.Request) {
+		_, route := mux.Handler(req)
+		policy, ok := cfg.rateLimits.Policy(route)
+		if !ok {
+			mux.ServeHTTP(wri, req)
+			return
+		}
+		res, err := cfg.rateLimiter.Take(req.Context(), route+" "+rateLimitKey(req, *cfg), policy)
+		if err != nil {
+			// better to let everyone through than no one
+			slog.Error("rate limit check failed", "request_id", responseRequestID(wri), "route", route, "error", err)
+			mux.ServeHTTP(wri, req)
+			return
+		}
+		wri.Header().Set("RateLimit-Limit", fmt.Sprint(res.Limit))
+		wri.Header().Set("RateLimit-Remaining", fmt.Sprint(res.Remaining))
+		wri.Header().Set("RateLimit-Reset", fmt.Sprint(ceilSeconds(res.Reset)))
+		wri.Header().Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", policy.Rate, ceilSeconds(policy.Per)))
+		if !res.Allowed {
+			wri.Header().Set("Retry-After", fmt.Sprint(ceilSeconds(res.RetryAfter)))
+			respondWithError(wri, 429, "Too many requests, try again later")
+			return
+		}
+		mux.ServeHTTP(wri, req)
+	})
+}
+
+// who a request is counted against: "user:<id>" or "ip:<address>"
+func rateLimitKey(req *http.Request, apiCfg apiConfig) string {
+	if userID, ok := tokenUser(req, apiCfg); ok {
+		return "user:" + userID.String()
+	}
+	return "ip:" + clientIP(req, apiCfg.rateLimits.TrustForwardedFor)
+}
+
+// the address a request came from
+// behind a proxy that's the last address in X-Forwarded-For, the one the proxy added itself
+// (anything before it came from the client, who could have made it up)
+func clientIP(req *http.Request, trustForwardedFor bool) string {
+	// proxies can add their own header rather than adding to the last one
+	if forwarded := strings.Join(req.Header.Values("X-Forwarded-For"), ","); trustForwardedFor && forwarded != "" {
+		addrs := strings.Split(forwarded, ",")
+		return strings.TrimSpace(addrs[len(addrs)-1])
+	}
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return host
+}
+
+// the headers count in whole seconds, rounding up so clients don't come back too early
+func ceilSeconds(d time.Duration) int64 {
+	return int64(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"internal/database"
+	"net/http"
+)
+
+// rechirp a chirp onto your own timeline (rechirping it again does nothing)
+func rechirpChirp(wri http.ResponseWriter, req *http.Request, apiCfg apiConfig) {
+	user, err := authenticate(req, apiCfg)
+	if err != nil {
+		respondWithProblem(wri, errUnauthorized)
+		return
+	}
+	chirp, ok := getLiveChirp(wri, req, apiCfg)
+	if !ok {
+		return
+	}
+	err = apiCfg.dbQueries.Rechirp(req.Context(), database.RechirpParams{UserID: user, ChirpID: chirp.ID})
+	if err != nil {
+		respondWithProblem(wri, fmt.Errorf("rechirping chirp: %w", err))
+		return
+	}
+	wri.WriteHeader(204)
+}
+
+// take a rechirp back (undoing one you haven't made does nothing)
+func undoRechirp(wri http.ResponseWriter, req *http.Request, apiCfg apiConfig) {
+	user, err := authenticate(req, apiCfg)
+	if err != nil {
+		respondWithProblem(wri, errUnauthorized)
+		return
+	}
+	chirp, ok := getLiveChirp(wri, req, apiCfg)
+	if !ok {
+		return
+	}
+	err = apiCfg.dbQueries.UndoRechirp(req.Context(), database.UndoRechirpParams{UserID: user, ChirpID: chirp.ID})
+	if err != nil {
+		respondWithProblem(wri, fmt.Errorf("undoing rechirp: %w", err))
+		return
+	}
+	wri.WriteHeader(204)
+}
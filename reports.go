@@ -0,0 +1,411 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"internal/apierr"
+	"internal/database"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+var reportCategories = []string{"spam", "harassment", "hate", "violence", "sexual", "self_harm", "impersonation", "other"}
+
+const maxReportDetails = 500
+
+// how long a suspend resolution lasts when it doesn't say
+const defaultSuspendDays = 7
+
+// reporters only get to see where their report is at, not who has it or what they wrote about it
+func reportResponse(report database.Report, moderator bool) reportParam {
+	res := reportParam{
+		ID: report.ID,
+		CreatedAt: report.CreatedAt,
+		UpdatedAt: report.UpdatedAt,
+		ReporterID: report.ReporterID,
+		UserID: report.UserID,
+		Category: report.Category,
+		Details: report.Details,
+		Status: report.Status,
+	}
+	if report.ChirpID.Valid {
+		chirpID := report.ChirpID.UUID
+		res.ChirpID = &chirpID
+	}
+	if report.ClaimedAt.Valid {
+		claimedAt := report.ClaimedAt.Time
+		res.ClaimedAt = &claimedAt
+	}
+	if report.ResolvedAt.Valid {
+		resolvedAt := report.ResolvedAt.Time
+		res.ResolvedAt = &resolvedAt
+	}
+	if report.Resolution.Valid {
+		resolution := report.Resolution.String
+		res.Resolution = &resolution
+	}
+	if moderator {
+		if report.ClaimedBy.Valid {
+			claimedBy := report.ClaimedBy.UUID
+			res.ClaimedBy = &claimedBy
+		}
+		res.Note = report.Note
+	}
+	return res
+}
+
+// the report in the path, responding with an error if it isn't there
+func getPathReport(wri http.ResponseWriter, req *http.Request, apiCfg apiConfig) (database.Report, bool) {
+	reportID, err := uuid.Parse(req.PathValue("reportID"))
+	if err != nil {
+		respondWithProblem(wri, errReportNotFound)
+		return database.Report{}, false
+	}
+	report, err := apiCfg.dbQueries.GetReport(req.Context(), reportID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithProblem(wri, errReportNotFound)
+		return database.Report{}, false
+	}
+	if err != nil {
+		respondWithProblem(wri, fmt.Errorf("getting report: %w", err))
+		return database.Report{}, false
+	}
+	return report, true
+}
+
+// report a chirp or a user
+// a chirp report is against the chirp's author too, so suspending them is an option
+func postReport(wri http.ResponseWriter, req *http.Request, apiCfg apiConfig) {
+	reporter, err := authenticate(req, apiCfg)
+	if err != nil {
+		respondWithProblem(wri, errUnauthorized)
+		return
+	}
+	reqBody := struct {
+		ChirpID *uuid.UUID `json:"chirp_id"`
+		UserID *uuid.UUID `json:"user_id"`
+		Category string `json:"category"`
+		Details string `json:"details"`
+	}{}
+	err = json.NewDecoder(req.Body).Decode(&reqBody)
+	if err != nil {
+		respondWithProblem(wri, errInvalidBody.Wrap(err))
+		return
+	}
+	if (reqBody.ChirpID == nil) == (reqBody.UserID == nil) {
+		msg := "Report needs either a chirp_id or a user_id"
+		respondWithProblem(wri, apierr.Validation("invalid_report", msg,
+			apierr.FieldError{Field: "chirp_id", Code: "exactly_one", Message: msg},
+			apierr.FieldError{Field: "user_id", Code: "exactly_one", Message: msg},
+		))
+		return
+	}
+	if !slices.Contains(reportCategories, reqBody.Category) {
+		msg := fmt.Sprintf("Category should be one of %s", strings.Join(reportCategories, ", "))
+		respondWithProblem(wri, apierr.Validation("invalid_report", msg, apierr.FieldError{Field: "category", Code: "invalid", Message: msg}))
+		return
+	}
+	if utf8.RuneCountInString(reqBody.Details) > maxReportDetails {
+		msg := fmt.Sprintf("Details can't be more than %d characters", maxReportDetails)
+		respondWithProblem(wri, apierr.Validation("invalid_report", msg, apierr.FieldError{Field: "details", Code: "too_long", Message: msg}))
+		return
+	}
+	params := database.CreateReportParams{
+		ReporterID: reporter,
+		Category: reqBody.Category,
+		Details: reqBody.Details,
+	}
+	if reqBody.ChirpID != nil {
+		chirp, err := apiCfg.dbQueries.GetSingleChirp(req.Context(), *reqBody.ChirpID)
+		if errors.Is(err, sql.ErrNoRows) || (err == nil && chirp.DeletedAt.Valid) {
+			respondWithProblem(wri, errChirpNotFound)
+			return
+		}
+		if err != nil {
+			respondWithProblem(wri, fmt.Errorf("getting chirp: %w", err))
+			return
+		}
+		params.UserID = chirp.UserID
+		params.ChirpID = uuid.NullUUID{UUID: chirp.ID, Valid: true}
+	} else {
+		user, err := apiCfg.dbQueries.GetUserByID(req.Context(), *reqBody.UserID)
+		if errors.Is(err, sql.ErrNoRows) || (err == nil && user.DeletedAt.Valid) {
+			respondWithProblem(wri, errUserNotFound)
+			return
+		}
+		if err != nil {
+			respondWithProblem(wri, fmt.Errorf("getting user: %w", err))
+			return
+		}
+		params.UserID = user.ID
+	}
+	if params.UserID == reporter {
+		respondWithProblem(wri, errSelfReport)
+		return
+	}
+	report, err := apiCfg.dbQueries.CreateReport(req.Context(), params)
+	if err != nil {
+		respondWithProblem(wri, fmt.Errorf("creating report: %w", err))
+		return
+	}
+	respondWithJSON(wri, 201, reportResponse(report, false))
+}
+
+// the reports you've made, newest first
+func getMyReports(wri http.ResponseWriter, req *http.Request, apiCfg apiConfig) {
+	user, err := authenticate(req, apiCfg)
+	if err != nil {
+		respondWithProblem(wri, errUnauthorized)
+		return
+	}
+	start, limit, err := parsePage(req, true)
+	if err != nil {
+		respondWithProblem(wri, err)
+		return
+	}
+	rows, err := apiCfg.dbQueries.ListReportsByReporter(req.Context(), database.ListReportsByReporterParams{
+		ReporterID: user,
+		BeforeCreatedAt: start.CreatedAt,
+		BeforeID: start.ID,
+		PageLimit: limit + 1,
+	})
+	if err != nil {
+		respondWithProblem(wri, fmt.Errorf("getting reports: %w", err))
+		return
+	}
+	if len(rows) > int(limit) {
+		rows = rows[:limit]
+		last := rows[len(rows)-1]
+		setNextLink(wri, req, cursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+	output := []reportParam{}
+	for _, r := range rows {
+		output = append(output, reportResponse(r, false))
+	}
+	respondWithJSON(wri, 200, output)
+}
+
+// a single report, for whoever made it or a moderator
+func getReport(wri http.ResponseWriter, req *http.Request, apiCfg apiConfig) {
+	user, role, err := authenticateRole(req, apiCfg)
+	if err != nil {
+		respondWithProblem(wri, errUnauthorized)
+		return
+	}
+	report, ok := getPathReport(wri, req, apiCfg)
+	if !ok {
+		return
+	}
+	moderator := hasRole(role, roleModerator)
+	// anyone else doesn't get to know it exists
+	if report.ReporterID != user && !moderator {
+		respondWithProblem(wri, errReportNotFound)
+		return
+	}
+	respondWithJSON(wri, 200, reportResponse(report, moderator))
+}
+
+// the moderator queue, oldest first
+// ?status=open, claimed or resolved, or everything that isn't resolved yet if it's left out
+func getReportQueue(wri http.ResponseWriter, req *http.Request, apiCfg apiConfig) {
+	start, limit, err := parsePage(req, false)
+	if err != nil {
+		respondWithProblem(wri, err)
+		return
+	}
+	var rows []database.Report
+	switch status := req.URL.Query().Get("status"); status {
+	case "":
+		rows, err = apiCfg.dbQueries.ListUnresolvedReports(req.Context(), database.ListUnresolvedReportsParams{
+			AfterCreatedAt: start.CreatedAt,
+			AfterID: start.ID,
+			PageLimit: limit + 1,
+		})
+	case "open", "claimed", "resolved":
+		rows, err = apiCfg.dbQueries.ListReportsByStatus(req.Context(), database.ListReportsByStatusParams{
+			Status: status,
+			AfterCreatedAt: start.CreatedAt,
+			AfterID: start.ID,
+			PageLimit: limit + 1,
+		})
+	default:
+		msg := "status must be open, claimed or resolved"
+		respondWithProblem(wri, apierr.Validation("invalid_status", msg, apierr.FieldError{Field: "status", Code: "invalid", Message: msg}))
+		return
+	}
+	if err != nil {
+		respondWithProblem(wri, fmt.Errorf("getting reports: %w", err))
+		return
+	}
+	if len(rows) > int(limit) {
+		rows = rows[:limit]
+		last := rows[len(rows)-1]
+		setNextLink(wri, req, cursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+	output := []reportParam{}
+	for _, r := range rows {
+		output = append(output, reportResponse(r, true))
+	}
+	respondWithJSON(wri, 200, output)
+}
+
+// take a report off the queue so no one else works on it
+// claiming one you've already claimed does nothing
+func claimReport(wri http.ResponseWriter, req *http.Request, apiCfg apiConfig) {
+	moderator := requestUser(req)
+	report, ok := getPathReport(wri, req, apiCfg)
+	if !ok {
+		return
+	}
+	if report.Status == "claimed" && report.ClaimedBy.Valid && report.ClaimedBy.UUID == moderator {
+		respondWithJSON(wri, 200, reportResponse(report, true))
+		return
+	}
+	claimed, err := apiCfg.dbQueries.ClaimReport(req.Context(), database.ClaimReportParams{
+		ClaimedBy: uuid.NullUUID{UUID: moderator, Valid: true},
+		ID: report.ID,
+	})
+	// someone else got to it first, and whether they claimed it or resolved it decides what to say
+	if errors.Is(err, sql.ErrNoRows) {
+		report, err = apiCfg.dbQueries.GetReport(req.Context(), report.ID)
+		if err == nil && report.Status == "resolved" {
+			respondWithProblem(wri, errReportResolved)
+		} else {
+			respondWithProblem(wri, errReportClaimed)
+		}
+		return
+	}
+	if err != nil {
+		respondWithProblem(wri, fmt.Errorf("claiming report: %w", err))
+		return
+	}
+	respondWithJSON(wri, 200, reportResponse(claimed, true))
+}
+
+// close a report, doing whatever the resolution says:
+// dismiss does nothing, remove deletes the reported chirp and suspend locks its author out for a while
+// the reporter gets a notification either way
+func resolveReport(wri http.ResponseWriter, req *http.Request, apiCfg apiConfig) {
+	moderator := requestUser(req)
+	reqBody := struct {
+		Resolution string `json:"resolution"`
+		Note string `json:"note"`
+		SuspendDays int `json:"suspend_days"`
+	}{}
+	err := json.NewDecoder(req.Body).Decode(&reqBody)
+	if err != nil {
+		respondWithProblem(wri, errInvalidBody.Wrap(err))
+		return
+	}
+	report, ok := getPathReport(wri, req, apiCfg)
+	if !ok {
+		return
+	}
+	if report.Status == "resolved" {
+		respondWithProblem(wri, errReportResolved)
+		return
+	}
+	if report.Status == "claimed" && report.ClaimedBy.Valid && report.ClaimedBy.UUID != moderator {
+		respondWithProblem(wri, errReportClaimed)
+		return
+	}
+	// everything that can be checked up front is, so the transaction only has to do things
+	switch reqBody.Resolution {
+	case "dismiss":
+	case "remove":
+		if !report.ChirpID.Valid {
+			msg := "Only chirp reports can be resolved by removing the chirp"
+			respondWithProblem(wri, apierr.Validation("invalid_resolution", msg, apierr.FieldError{Field: "resolution", Code: "not_a_chirp_report", Message: msg}))
+			return
+		}
+	case "suspend":
+		if reqBody.SuspendDays < 0 || reqBody.SuspendDays > maxSuspendDays {
+			msg := fmt.Sprintf("suspend_days must be between 0 and %d", maxSuspendDays)
+			respondWithProblem(wri, apierr.Validation("invalid_suspension", msg, apierr.FieldError{Field: "suspend_days", Code: "out_of_range", Message: msg}))
+			return
+		}
+		if reqBody.SuspendDays == 0 {
+			reqBody.SuspendDays = defaultSuspendDays
+		}
+		reported, err := apiCfg.dbQueries.GetUserByID(req.Context(), report.UserID)
+		if err != nil {
+			respondWithProblem(wri, fmt.Errorf("getting user: %w", err))
+			return
+		}
+		if hasRole(reported.Role, roleModerator) && !hasRole(requestRole(req), roleAdmin) {
+			respondWithProblem(wri, errSuspendModerator)
+			return
+		}
+	default:
+		msg := "resolution must be dismiss, remove or suspend"
+		respondWithProblem(wri, apierr.Validation("invalid_resolution", msg, apierr.FieldError{Field: "resolution", Code: "invalid", Message: msg}))
+		return
+	}
+
+	// resolving it comes first, so when two moderators resolve it at once only one of them gets to act on it,
+	// and the action and the reporter's notification go with it or not at all
+	err = inTx(req.Context(), apiCfg, func(apiCfg apiConfig) error {
+		resolved, err := apiCfg.dbQueries.ResolveReport(req.Context(), database.ResolveReportParams{
+			ClaimedBy: uuid.NullUUID{UUID: moderator, Valid: true},
+			Resolution: sql.NullString{String: reqBody.Resolution, Valid: true},
+			Note: reqBody.Note,
+			ID: report.ID,
+		})
+		// someone else resolved or claimed it since it was looked up
+		if errors.Is(err, sql.ErrNoRows) {
+			current, getErr := apiCfg.dbQueries.GetReport(req.Context(), report.ID)
+			if getErr == nil && current.Status == "claimed" {
+				return errReportClaimed.Wrap(err)
+			}
+			return errReportResolved.Wrap(err)
+		}
+		if err != nil {
+			return fmt.Errorf("resolving report: %w", err)
+		}
+		report = resolved
+		switch reqBody.Resolution {
+		case "remove":
+			err = apiCfg.dbQueries.SoftDeleteChirp(req.Context(), report.ChirpID.UUID)
+			if err != nil {
+				return fmt.Errorf("deleting chirp: %w", err)
+			}
+		case "suspend":
+			reported, err := apiCfg.dbQueries.GetUserByID(req.Context(), report.UserID)
+			if err != nil {
+				return fmt.Errorf("getting user: %w", err)
+			}
+			// a suspension shouldn't cut a ban or a longer suspension short
+			until := time.Now().UTC().AddDate(0, 0, reqBody.SuspendDays)
+			status := accountStatus(reported)
+			longer := status == statusSuspended && (!reported.StatusUntil.Valid || reported.StatusUntil.Time.After(until))
+			if status != statusBanned && !longer {
+				_, err = setAccountStatus(req.Context(), apiCfg, reported.ID, statusSuspended, fmt.Sprintf("Reported for %s", report.Category), until, false)
+				if err != nil {
+					return fmt.Errorf("suspending user: %w", err)
+				}
+			}
+		}
+		err = apiCfg.dbQueries.CreateReportNotification(req.Context(), database.CreateReportNotificationParams{
+			UserID: report.ReporterID,
+			// it's the moderator who did something, not whoever was reported
+			ActorID: moderator,
+			Kind: notifyReportResolved,
+			ReportID: uuid.NullUUID{UUID: report.ID, Valid: true},
+		})
+		if err != nil {
+			return fmt.Errorf("notifying reporter: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		respondWithProblem(wri, err)
+		return
+	}
+	respondWithJSON(wri, 200, reportResponse(report, true))
+}
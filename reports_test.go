@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"internal/database"
+	"internal/store"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// resolves report as moderator, returning the status code
+func resolveReportAs(apiCfg apiConfig, moderator database.User, report database.Report, body string) int {
+	req := httptest.NewRequest("POST", "/admin/reports/"+report.ID.String()+"/resolve", strings.NewReader(body))
+	req.SetPathValue("reportID", report.ID.String())
+	req = req.WithContext(context.WithValue(req.Context(), requestClaimsKey{}, requestClaims{user: moderator.ID, role: roleModerator}))
+	wri := httptest.NewRecorder()
+	resolveReport(wri, req, apiCfg)
+	return wri.Code
+}
+
+func TestResolveReport(t *testing.T) {
+	ctx := context.Background()
+	apiCfg := apiConfig{dbQueries: store.NewMemory()}
+	moderator, _ := apiCfg.dbQueries.CreateUser(ctx, database.CreateUserParams{Email: "moderator@example.com"})
+	other, _ := apiCfg.dbQueries.CreateUser(ctx, database.CreateUserParams{Email: "other@example.com"})
+	reporter, _ := apiCfg.dbQueries.CreateUser(ctx, database.CreateUserParams{Email: "reporter@example.com"})
+	reported, _ := apiCfg.dbQueries.CreateUser(ctx, database.CreateUserParams{Email: "reported@example.com"})
+	report, _ := apiCfg.dbQueries.CreateReport(ctx, database.CreateReportParams{ReporterID: reporter.ID, UserID: reported.ID, Category: "spam"})
+
+	// they're already suspended for longer than this would
+	longer := time.Now().UTC().AddDate(0, 0, 30).Truncate(time.Second)
+	apiCfg.dbQueries.SetUserStatus(ctx, database.SetUserStatusParams{Status: statusSuspended, StatusUntil: sql.NullTime{Time: longer, Valid: true}, ID: reported.ID})
+	if code := resolveReportAs(apiCfg, moderator, report, `{"resolution": "suspend", "suspend_days": 7}`); code != 200 {
+		t.Fatalf("Expected a 200 for resolving the report, got %d", code)
+	}
+	user, _ := apiCfg.dbQueries.GetUserByID(ctx, reported.ID)
+	if !user.StatusUntil.Time.Equal(longer) {
+		t.Errorf("Expected the suspension to still end at %v, got %v", longer, user.StatusUntil.Time)
+	}
+	notifications, _ := apiCfg.dbQueries.ListNotifications(ctx, database.ListNotificationsParams{UserID: reporter.ID, BeforeCreatedAt: lastCursor.CreatedAt, BeforeID: lastCursor.ID, PageLimit: 10})
+	if len(notifications) != 1 {
+		t.Errorf("Expected the reporter to get 1 notification, got %d", len(notifications))
+	}
+
+	// the second moderator to get to it doesn't get to act on it as well
+	if code := resolveReportAs(apiCfg, other, report, `{"resolution": "suspend", "suspend_days": 365}`); code != 409 {
+		t.Errorf("Expected a 409 for resolving it again, got %d", code)
+	}
+	user, _ = apiCfg.dbQueries.GetUserByID(ctx, reported.ID)
+	if !user.StatusUntil.Time.Equal(longer) {
+		t.Errorf("Expected the second resolution not to change the suspension, got %v", user.StatusUntil.Time)
+	}
+}
+
+// claims report as moderator, returning the status code and the error code if there is one
+func claimReportAs(apiCfg apiConfig, moderator database.User, report database.Report) (int, string) {
+	req := httptest.NewRequest("POST", "/admin/reports/"+report.ID.String()+"/claim", nil)
+	req.SetPathValue("reportID", report.ID.String())
+	req = req.WithContext(context.WithValue(req.Context(), requestClaimsKey{}, requestClaims{user: moderator.ID, role: roleModerator}))
+	wri := httptest.NewRecorder()
+	claimReport(wri, req, apiCfg)
+	problem := struct {
+		Code string `json:"code"`
+	}{}
+	json.Unmarshal(wri.Body.Bytes(), &problem)
+	return wri.Code, problem.Code
+}
+
+func TestClaimReportConflicts(t *testing.T) {
+	ctx := context.Background()
+	apiCfg := apiConfig{dbQueries: store.NewMemory()}
+	moderator, _ := apiCfg.dbQueries.CreateUser(ctx, database.CreateUserParams{Email: "moderator@example.com"})
+	other, _ := apiCfg.dbQueries.CreateUser(ctx, database.CreateUserParams{Email: "other@example.com"})
+	reporter, _ := apiCfg.dbQueries.CreateUser(ctx, database.CreateUserParams{Email: "reporter@example.com"})
+	report, _ := apiCfg.dbQueries.CreateReport(ctx, database.CreateReportParams{ReporterID: reporter.ID, UserID: other.ID, Category: "spam"})
+
+	if code, _ := claimReportAs(apiCfg, moderator, report); code != 200 {
+		t.Fatalf("Expected a 200 for claiming the report, got %d", code)
+	}
+	if code, errCode := claimReportAs(apiCfg, other, report); code != 409 || errCode != "report_claimed" {
+		t.Errorf("Expected a 409 report_claimed for claiming someone else's report, got %d %q", code, errCode)
+	}
+	resolveReportAs(apiCfg, moderator, report, `{"resolution": "dismiss"}`)
+	if code, errCode := claimReportAs(apiCfg, other, report); code != 409 || errCode != "report_resolved" {
+		t.Errorf("Expected a 409 report_resolved for claiming a resolved report, got %d %q", code, errCode)
+	}
+}
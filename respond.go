@@ -3,7 +3,11 @@ package main
 import (
 	"net/http"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"internal/apierr"
+	"log/slog"
+	"strings"
 )
 
 // sends a string response
@@ -17,7 +21,7 @@ func respondWithString(wri http.ResponseWriter, code int, msg string) {
 func respondWithJSON(wri http.ResponseWriter, code int, payload interface{}) {
 	dat, err := json.Marshal(payload)
 	if err != nil {
-		respondWithError(wri, 500, fmt.Sprintf("Error marshalling response: %v", err))
+		respondWithProblem(wri, fmt.Errorf("marshalling response: %w", err))
 		return
 	}
 	wri.Header().Set("Content-Type", "application/json")
@@ -25,14 +29,61 @@ func respondWithJSON(wri http.ResponseWriter, code int, payload interface{}) {
 	wri.Write(dat)
 }
 
-// sends an error response
+// sends an error response with just a status code and a message for the client
+// server errors' messages tend to have the cause in them, so those are logged and the client gets a generic one
 func respondWithError(wri http.ResponseWriter, code int, msg string) {
-	type errorResp struct {
+	if code >= 500 {
+		respondWithProblem(wri, apierr.Internal(errors.New(msg)))
+		return
+	}
+	writeProblem(wri, code, statusCode(code), msg, nil)
+}
+
+// sends err as an error response, working out the status code and what to say from what kind of error it is
+// (see apierr.From) and logging the cause of anything that's the server's fault
+func respondWithProblem(wri http.ResponseWriter, err error) {
+	e := apierr.From(err)
+	status := e.Kind.Status()
+	if status >= 500 {
+		slog.Error("server error", "request_id", responseRequestID(wri), "status", status, "error", err)
+	} else if e.Err != nil {
+		// the client only gets the message, so whatever was underneath it goes in the logs
+		slog.Info("request error", "request_id", responseRequestID(wri), "status", status, "code", e.Code, "error", err)
+	}
+	writeProblem(wri, status, e.Code, e.Message, e.Fields)
+}
+
+// a stable code for errors that only have a status code, like "not_found" or "too_many_requests"
+func statusCode(status int) string {
+	return strings.ToLower(strings.ReplaceAll(http.StatusText(status), " ", "_"))
+}
+
+// every error goes out as RFC 7807 problem details, along with a code clients can check for
+// and the request's id so it can be found in the logs
+// error has the message in it too, as it's where it's always been
+func writeProblem(wri http.ResponseWriter, status int, code string, msg string, fields []apierr.FieldError) {
+	type problemResp struct {
+		Type string `json:"type"`
+		Title string `json:"title"`
+		Status int `json:"status"`
+		Detail string `json:"detail"`
+		Code string `json:"code"`
+		RequestID string `json:"request_id,omitempty"`
+		Errors []apierr.FieldError `json:"errors,omitempty"`
 		Error string `json:"error"`
 	}
-	res := errorResp{Error: msg}
+	res := problemResp{
+		Type: "about:blank",
+		Title: http.StatusText(status),
+		Status: status,
+		Detail: msg,
+		Code: code,
+		RequestID: responseRequestID(wri),
+		Errors: fields,
+		Error: msg,
+	}
 	ret, _ := json.Marshal(res)
-	wri.Header().Set("Content-Type", "application/json")
-	wri.WriteHeader(code)
+	wri.Header().Set("Content-Type", "application/problem+json")
+	wri.WriteHeader(status)
 	wri.Write(ret)
-}
\ No newline at end of file
+}
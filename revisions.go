@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"internal/database"
+	"internal/moderation"
+	"net/http"
+	"time"
+)
+
+// how long after posting a chirp can still be edited
+const editWindow = 15 * time.Minute
+const chirpyRedEditWindow = time.Hour
+
+// edit a chirp's body
+// the version being replaced is kept in the chirp's revisions, and its hashtags and mentions are worked out again
+func putChirp(wri http.ResponseWriter, req *http.Request, apiCfg apiConfig) {
+	type reqParam struct {
+		Body string `json:"body"`
+	}
+	user, err := authenticate(req, apiCfg)
+	if err != nil {
+		respondWithProblem(wri, errUnauthorized)
+		return
+	}
+	chirp, ok := getLiveChirp(wri, req, apiCfg)
+	if !ok {
+		return
+	}
+	if chirp.UserID != user {
+		respondWithError(wri, 403, "You can only edit your own chirps")
+		return
+	}
+	author, err := apiCfg.dbQueries.GetUserByID(req.Context(), user)
+	if err != nil {
+		respondWithProblem(wri, fmt.Errorf("getting user: %w", err))
+		return
+	}
+	window := editWindow
+	if author.IsChirpyRed {
+		window = chirpyRedEditWindow
+	}
+	if time.Since(chirp.CreatedAt) > window {
+		respondWithError(wri, 403, fmt.Sprintf("Chirps can only be edited for %v after they're posted", window))
+		return
+	}
+
+	decoder := json.NewDecoder(req.Body)
+	reqBody := reqParam{}
+	err = decoder.Decode(&reqBody)
+	if err != nil {
+		respondWithProblem(wri, errInvalidBody.Wrap(err))
+		return
+	}
+	res, ok := moderateChirp(wri, req, apiCfg, user, reqBody.Body)
+	if !ok {
+		return
+	}
+	// there's no queue for edits, so one that would be held can't be made at all
+	if res.Decision == moderation.Hold {
+		respondWithError(wri, 400, fmt.Sprintf("Edit can't be made without a moderator reviewing it: %s", res.Reason))
+		return
+	}
+	body := res.Body
+
+	// the old version's saved in the same transaction as the edit, so the history always matches the chirp
+	// and the chirp's locked and read again first, so two edits at once each save what the other replaced
+	err = inTx(req.Context(), apiCfg, func(apiCfg apiConfig) error {
+		current, err := apiCfg.dbQueries.GetChirpForUpdate(req.Context(), chirp.ID)
+		if err != nil {
+			return fmt.Errorf("editing chirp: %w", err)
+		}
+		if current.DeletedAt.Valid {
+			return errChirpNotFound
+		}
+		chirp = current
+		// saving the same thing again doesn't count as an edit
+		if body == chirp.Body {
+			return nil
+		}
+		versionAt := chirp.CreatedAt
+		if chirp.EditedAt.Valid {
+			versionAt = chirp.EditedAt.Time
+		}
+		err = apiCfg.dbQueries.CreateChirpRevision(req.Context(), database.CreateChirpRevisionParams{ChirpID: chirp.ID, Body: chirp.Body, CreatedAt: versionAt})
+		if err != nil {
+			return fmt.Errorf("editing chirp: %w", err)
+		}
+		chirp, err = apiCfg.dbQueries.EditChirp(req.Context(), database.EditChirpParams{ID: chirp.ID, Body: body})
+		if err != nil {
+			return fmt.Errorf("editing chirp: %w", err)
+		}
+		err = flagChirp(req.Context(), apiCfg, chirp.ID, res.Flags)
+		if err != nil {
+			return fmt.Errorf("flagging chirp: %w", err)
+		}
+		err = apiCfg.dbQueries.ClearHashtags(req.Context(), chirp.ID)
+		if err == nil {
+			err = tagChirp(req.Context(), apiCfg, chirp)
+		}
+		if err != nil {
+			return fmt.Errorf("tagging chirp: %w", err)
+		}
+		// notifications dedupe, so only people newly mentioned hear about it
+		err = apiCfg.dbQueries.ClearMentions(req.Context(), chirp.ID)
+		if err == nil {
+			err = mentionUsers(req.Context(), apiCfg, chirp)
+		}
+		if err != nil {
+			return fmt.Errorf("mentioning users: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		respondWithProblem(wri, err)
+		return
+	}
+	resBody, err := chirpResponses(req, apiCfg, []database.Chirp{chirp})
+	if err != nil {
+		respondWithProblem(wri, fmt.Errorf("editing chirp: %w", err))
+		return
+	}
+	respondWithJSON(wri, 200, resBody[0])
+}
+
+// get every version of a chirp, oldest first, ending with the current one
+func getChirpRevisions(wri http.ResponseWriter, req *http.Request, apiCfg apiConfig) {
+	chirp, ok := getLiveChirp(wri, req, apiCfg)
+	if !ok {
+		return
+	}
+	revisions, err := apiCfg.dbQueries.ListChirpRevisions(req.Context(), chirp.ID)
+	if err != nil {
+		respondWithProblem(wri, fmt.Errorf("getting revisions: %w", err))
+		return
+	}
+	output := []revisionParam{}
+	for _, r := range revisions {
+		replacedAt := r.ReplacedAt
+		output = append(output, revisionParam{Body: r.Body, CreatedAt: r.CreatedAt, ReplacedAt: &replacedAt})
+	}
+	current := revisionParam{Body: chirp.Body, CreatedAt: chirp.CreatedAt}
+	if chirp.EditedAt.Valid {
+		current.CreatedAt = chirp.EditedAt.Time
+	}
+	output = append(output, current)
+	respondWithJSON(wri, 200, output)
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"internal/database"
+	"net/http"
+	"slices"
+)
+
+// each role can do everything the ones before it can
+const (
+	roleUser = "user"
+	roleModerator = "moderator"
+	roleAdmin = "admin"
+)
+
+var roles = []string{roleUser, roleModerator, roleAdmin}
+
+// whether role is at least as powerful as need
+// anything that isn't a known role (like the empty role in old tokens) is just a user
+func hasRole(role string, need string) bool {
+	return max(slices.Index(roles, role), 0) >= slices.Index(roles, need)
+}
+
+// the key the middleware stores who's making the request under in its context
+type requestClaimsKey struct{}
+
+type requestClaims struct {
+	user uuid.UUID
+	role string
+}
+
+// the user whose JWT got the request through middlewareRequireRole
+func requestUser(req *http.Request) uuid.UUID {
+	claims, _ := req.Context().Value(requestClaimsKey{}).(requestClaims)
+	return claims.user
+}
+
+// and their role
+func requestRole(req *http.Request) string {
+	claims, _ := req.Context().Value(requestClaimsKey{}).(requestClaims)
+	return claims.role
+}
+
+// only lets requests through if their user has at least role
+// the role is looked up every time, so a change takes effect straight away
+func (cfg *apiConfig) middlewareRequireRole(role string, next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(wri http.ResponseWriter, req *http.Request) {
+		user, userRole, err := authenticateRole(req, *cfg)
+		if err != nil {
+			respondWithProblem(wri, errUnauthorized)
+			return
+		}
+		if !hasRole(userRole, role) {
+			respondWithProblem(wri, errForbidden)
+			return
+		}
+		next(wri, req.WithContext(context.WithValue(req.Context(), requestClaimsKey{}, requestClaims{user: user, role: userRole})))
+	})
+}
+
+// changes a user's role, keeping a record of who did it and why
+// changedBy is empty for changes the server makes itself
+// setting the role they already have does nothing
+func setRole(ctx context.Context, apiCfg apiConfig, user database.User, role string, changedBy uuid.NullUUID, reason string) (database.User, error) {
+	if user.Role == role {
+		return user, nil
+	}
+	updated, err := apiCfg.dbQueries.SetUserRole(ctx, database.SetUserRoleParams{Role: role, ID: user.ID})
+	if err != nil {
+		return database.User{}, err
+	}
+	_, err = apiCfg.dbQueries.CreateRoleChange(ctx, database.CreateRoleChangeParams{
+		UserID: user.ID,
+		ChangedBy: changedBy,
+		OldRole: user.Role,
+		NewRole: role,
+		Reason: reason,
+	})
+	if err != nil {
+		return database.User{}, fmt.Errorf("recording role change: %w", err)
+	}
+	return updated, nil
+}
+
+// makes sure everyone in ADMINS is an admin
+// users that don't exist (yet) are skipped
+func grantAdmins(ctx context.Context, apiCfg apiConfig, ids []uuid.UUID) error {
+	for _, id := range ids {
+		user, err := apiCfg.dbQueries.GetUserByID(ctx, id)
+		if errors.Is(err, sql.ErrNoRows) {
+			fmt.Printf("ADMINS: there's no user %v, skipping them\n", id)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		_, err = setRole(ctx, apiCfg, user, roleAdmin, uuid.NullUUID{}, "listed in ADMINS")
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// give a user a role, replacing the one they had
+func grantRole(wri http.ResponseWriter, req *http.Request, apiCfg apiConfig) {
+	reqBody := struct {
+		Role string `json:"role"`
+		Reason string `json:"reason"`
+	}{}
+	err := json.NewDecoder(req.Body).Decode(&reqBody)
+	if err != nil {
+		respondWithProblem(wri, errInvalidBody.Wrap(err))
+		return
+	}
+	if !slices.Contains(roles, reqBody.Role) {
+		respondWithError(wri, 400, "role must be user, moderator or admin")
+		return
+	}
+	user, ok := getPathUser(wri, req, apiCfg)
+	if !ok {
+		return
+	}
+	changeRole(wri, req, apiCfg, user, reqBody.Role, reqBody.Reason)
+}
+
+// take a role away from a user, leaving them a plain user
+// revoking a role they don't have does nothing
+func revokeRole(wri http.ResponseWriter, req *http.Request, apiCfg apiConfig) {
+	role := req.PathValue("role")
+	if !slices.Contains(roles, role) {
+		respondWithError(wri, 404, "Role not found")
+		return
+	}
+	user, ok := getPathUser(wri, req, apiCfg)
+	if !ok {
+		return
+	}
+	if user.Role != role {
+		respondWithJSON(wri, 200, userRoleParam{UserID: user.ID, Role: user.Role})
+		return
+	}
+	changeRole(wri, req, apiCfg, user, roleUser, req.URL.Query().Get("reason"))
+}
+
+// sets the role for grantRole and revokeRole, as the admin making the request
+func changeRole(wri http.ResponseWriter, req *http.Request, apiCfg apiConfig, user database.User, role string, reason string) {
+	admin := requestUser(req)
+	// so the last admin can't lock everyone out
+	if user.ID == admin {
+		respondWithError(wri, 400, "You can't change your own role")
+		return
+	}
+	user, err := setRole(req.Context(), apiCfg, user, role, uuid.NullUUID{UUID: admin, Valid: true}, reason)
+	if err != nil {
+		respondWithProblem(wri, fmt.Errorf("changing role: %w", err))
+		return
+	}
+	respondWithJSON(wri, 200, userRoleParam{UserID: user.ID, Role: user.Role})
+}
+
+// every role change, newest first
+// ?user_id= narrows it down to one user's
+func getRoleChanges(wri http.ResponseWriter, req *http.Request, apiCfg apiConfig) {
+	start, limit, err := parsePage(req, true)
+	if err != nil {
+		respondWithProblem(wri, err)
+		return
+	}
+	var rows []database.RoleChange
+	if userIDString := req.URL.Query().Get("user_id"); userIDString != "" {
+		userID, parseErr := uuid.Parse(userIDString)
+		if parseErr != nil {
+			respondWithError(wri, 400, "Invalid user_id")
+			return
+		}
+		rows, err = apiCfg.dbQueries.ListUserRoleChanges(req.Context(), database.ListUserRoleChangesParams{
+			UserID: userID,
+			BeforeCreatedAt: start.CreatedAt,
+			BeforeID: start.ID,
+			PageLimit: limit + 1,
+		})
+	} else {
+		rows, err = apiCfg.dbQueries.ListRoleChanges(req.Context(), database.ListRoleChangesParams{
+			BeforeCreatedAt: start.CreatedAt,
+			BeforeID: start.ID,
+			PageLimit: limit + 1,
+		})
+	}
+	if err != nil {
+		respondWithProblem(wri, fmt.Errorf("getting role changes: %w", err))
+		return
+	}
+	if len(rows) > int(limit) {
+		rows = rows[:limit]
+		last := rows[len(rows)-1]
+		setNextLink(wri, req, cursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+	output := []roleChangeParam{}
+	for _, c := range rows {
+		res := roleChangeParam{
+			ID: c.ID,
+			CreatedAt: c.CreatedAt,
+			UserID: c.UserID,
+			OldRole: c.OldRole,
+			NewRole: c.NewRole,
+			Reason: c.Reason,
+		}
+		if c.ChangedBy.Valid {
+			changedBy := c.ChangedBy.UUID
+			res.ChangedBy = &changedBy
+		}
+		output = append(output, res)
+	}
+	respondWithJSON(wri, 200, output)
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"internal/auth"
+	"internal/database"
+	"internal/store"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRequireRoleDemoted(t *testing.T) {
+	ctx := context.Background()
+	apiCfg := &apiConfig{dbQueries: store.NewMemory(), secret: "secret"}
+	admin, _ := apiCfg.dbQueries.CreateUser(ctx, database.CreateUserParams{Email: "admin@example.com"})
+	apiCfg.dbQueries.SetUserRole(ctx, database.SetUserRoleParams{Role: roleAdmin, ID: admin.ID})
+	token, err := auth.MakeJWT(admin.ID, roleAdmin, apiCfg.secret, time.Hour)
+	if err != nil {
+		t.Fatalf("Error in MakeJWT: %v", err)
+	}
+	handler := apiCfg.middlewareRequireRole(roleAdmin, func(wri http.ResponseWriter, req *http.Request) {
+		wri.WriteHeader(204)
+	})
+	request := func() int {
+		req := httptest.NewRequest("GET", "/admin/roles/audit", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		wri := httptest.NewRecorder()
+		handler.ServeHTTP(wri, req)
+		return wri.Code
+	}
+
+	if code := request(); code != 204 {
+		t.Errorf("Expected an admin to get through, got %d", code)
+	}
+	// their token still says admin, but they aren't one any more
+	apiCfg.dbQueries.SetUserRole(ctx, database.SetUserRoleParams{Role: roleUser, ID: admin.ID})
+	if code := request(); code != 403 {
+		t.Errorf("Expected a demoted admin to get a 403, got %d", code)
+	}
+}
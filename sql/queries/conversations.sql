@@ -0,0 +1,91 @@
+-- name: CreateConversation :one
+INSERT INTO conversations (id, created_at, updated_at, is_group)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    $1
+)
+RETURNING *;
+
+-- name: GetConversation :one
+SELECT * FROM conversations
+WHERE id = $1;
+
+-- the one-to-one conversation two people are both still in, if there is one
+-- name: FindDirectConversation :one
+SELECT conversations.* FROM conversations
+JOIN conversation_participants a ON a.conversation_id = conversations.id
+JOIN conversation_participants b ON b.conversation_id = conversations.id
+WHERE conversations.is_group = false
+AND a.user_id = sqlc.arg(user_id) AND a.left_at IS NULL
+AND b.user_id = sqlc.arg(other_id) AND b.left_at IS NULL
+ORDER BY conversations.created_at
+LIMIT 1;
+
+-- name: TouchConversation :exec
+UPDATE conversations
+SET updated_at = $2
+WHERE id = $1;
+
+-- name: AddParticipant :exec
+INSERT INTO conversation_participants (conversation_id, user_id, joined_at)
+VALUES (
+    $1,
+    $2,
+    NOW()
+)
+ON CONFLICT DO NOTHING;
+
+-- name: GetParticipant :one
+SELECT * FROM conversation_participants
+WHERE conversation_id = $1 AND user_id = $2;
+
+-- name: ListParticipants :many
+SELECT * FROM conversation_participants
+WHERE conversation_id = ANY(sqlc.arg(conversation_ids)::uuid[])
+ORDER BY joined_at, user_id;
+
+-- name: LeaveConversation :exec
+UPDATE conversation_participants
+SET left_at = NOW()
+WHERE conversation_id = $1 AND user_id = $2
+AND left_at IS NULL;
+
+-- name: MarkConversationRead :exec
+UPDATE conversation_participants
+SET last_read_at = $3
+WHERE conversation_id = $1 AND user_id = $2;
+
+-- the conversations someone's still in, most recently active first, each with how many messages they haven't read
+-- name: ListConversations :many
+SELECT sqlc.embed(conversations),
+    (SELECT COUNT(*) FROM messages
+    WHERE messages.conversation_id = conversations.id
+    AND messages.sender_id <> conversation_participants.user_id
+    AND (conversation_participants.last_read_at IS NULL OR messages.created_at > conversation_participants.last_read_at)) AS unread_count
+FROM conversation_participants
+JOIN conversations ON conversations.id = conversation_participants.conversation_id
+WHERE conversation_participants.user_id = sqlc.arg(user_id)
+AND conversation_participants.left_at IS NULL
+AND (conversations.updated_at, conversations.id) < (sqlc.arg(before_updated_at)::timestamp, sqlc.arg(before_id)::uuid)
+ORDER BY conversations.updated_at DESC, conversations.id DESC
+LIMIT sqlc.arg(page_limit);
+
+-- name: CreateMessage :one
+INSERT INTO messages (id, created_at, conversation_id, sender_id, body)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    $1,
+    $2,
+    $3
+)
+RETURNING *;
+
+-- name: ListMessages :many
+SELECT * FROM messages
+WHERE conversation_id = sqlc.arg(conversation_id)
+AND (created_at, id) < (sqlc.arg(before_created_at)::timestamp, sqlc.arg(before_id)::uuid)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(page_limit);
\ No newline at end of file
@@ -0,0 +1,67 @@
+-- creates the list, or changes the action of the one that's already there
+-- name: UpsertFilterList :one
+INSERT INTO filter_lists (name, created_at, updated_at, action)
+VALUES (
+    $1,
+    NOW(),
+    NOW(),
+    $2
+)
+ON CONFLICT (name) DO UPDATE
+SET action = EXCLUDED.action, updated_at = EXCLUDED.updated_at
+RETURNING *;
+
+-- name: GetFilterList :one
+SELECT * FROM filter_lists
+WHERE name = $1;
+
+-- name: ListFilterLists :many
+SELECT * FROM filter_lists
+ORDER BY name;
+
+-- name: DeleteFilterList :exec
+DELETE FROM filter_lists
+WHERE name = $1;
+
+-- name: AddFilterWord :exec
+INSERT INTO filter_words (list_name, word, created_at)
+VALUES (
+    $1,
+    $2,
+    NOW()
+)
+ON CONFLICT DO NOTHING;
+
+-- name: RemoveFilterWord :exec
+DELETE FROM filter_words
+WHERE list_name = $1 AND word = $2;
+
+-- name: ClearFilterWords :exec
+DELETE FROM filter_words
+WHERE list_name = $1;
+
+-- every word on every list
+-- name: ListFilterWords :many
+SELECT * FROM filter_words
+ORDER BY list_name, word;
+
+-- name: CreateChirpFlag :exec
+INSERT INTO chirp_flags (id, created_at, chirp_id, list_name, word)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    $1,
+    $2,
+    $3
+);
+
+-- newest first
+-- name: ListChirpFlags :many
+SELECT * FROM chirp_flags
+WHERE (created_at, id) < (sqlc.arg(before_created_at)::timestamp, sqlc.arg(before_id)::uuid)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(page_limit);
+
+-- name: DeleteChirpFlag :exec
+DELETE FROM chirp_flags
+WHERE id = $1;
@@ -0,0 +1,69 @@
+-- name: FollowUser :exec
+INSERT INTO follows (follower_id, followee_id, created_at)
+VALUES (
+    $1,
+    $2,
+    NOW()
+)
+ON CONFLICT DO NOTHING;
+
+-- name: UnfollowUser :exec
+DELETE FROM follows
+WHERE follower_id = $1 AND followee_id = $2;
+
+-- name: GetFollow :one
+SELECT * FROM follows
+WHERE follower_id = $1 AND followee_id = $2;
+
+-- deleted users are left out of both lists
+-- name: ListFollowers :many
+SELECT follows.follower_id AS user_id, follows.created_at AS followed_at FROM follows
+JOIN users ON users.id = follows.follower_id
+WHERE follows.followee_id = sqlc.arg(user_id)
+AND users.deleted_at IS NULL
+AND (follows.created_at, follows.follower_id) < (sqlc.arg(before_followed_at)::timestamp, sqlc.arg(before_id)::uuid)
+ORDER BY follows.created_at DESC, follows.follower_id DESC
+LIMIT sqlc.arg(page_limit);
+
+-- name: ListFollowing :many
+SELECT follows.followee_id AS user_id, follows.created_at AS followed_at FROM follows
+JOIN users ON users.id = follows.followee_id
+WHERE follows.follower_id = sqlc.arg(user_id)
+AND users.deleted_at IS NULL
+AND (follows.created_at, follows.followee_id) < (sqlc.arg(before_followed_at)::timestamp, sqlc.arg(before_id)::uuid)
+ORDER BY follows.created_at DESC, follows.followee_id DESC
+LIMIT sqlc.arg(page_limit);
+
+-- the home timeline, newest first, shaped like ListChirpsDesc
+-- rather than gathering everything everyone followed has ever posted and sorting it, each author
+-- only hands over their newest page_limit entries before the cursor (straight off the
+-- (user_id, created_at, id) indexes), so it stays quick when someone follows thousands of accounts
+-- name: ListTimeline :many
+SELECT sqlc.embed(chirps), entries.entry_id, entries.entry_at, entries.rechirped_by
+FROM (
+    SELECT followee_id AS author_id FROM follows
+    WHERE follower_id = sqlc.arg(user_id)
+    UNION ALL
+    SELECT sqlc.arg(user_id)::uuid
+) AS authors
+CROSS JOIN LATERAL (
+    (SELECT r.id AS entry_id, r.created_at AS entry_at, r.chirp_id, r.user_id AS rechirped_by
+    FROM rechirps r
+    JOIN chirps rc ON rc.id = r.chirp_id
+    WHERE r.user_id = authors.author_id
+    AND rc.deleted_at IS NULL
+    AND (r.created_at, r.id) < (sqlc.arg(before_created_at)::timestamp, sqlc.arg(before_id)::uuid)
+    ORDER BY r.created_at DESC, r.id DESC
+    LIMIT sqlc.arg(page_limit))
+    UNION ALL
+    (SELECT c.id, c.created_at, c.id, NULL
+    FROM chirps c
+    WHERE c.user_id = authors.author_id
+    AND c.deleted_at IS NULL
+    AND (c.created_at, c.id) < (sqlc.arg(before_created_at)::timestamp, sqlc.arg(before_id)::uuid)
+    ORDER BY c.created_at DESC, c.id DESC
+    LIMIT sqlc.arg(page_limit))
+) AS entries
+JOIN chirps ON chirps.id = entries.chirp_id
+ORDER BY entries.entry_at DESC, entries.entry_id DESC
+LIMIT sqlc.arg(page_limit);
\ No newline at end of file
@@ -0,0 +1,35 @@
+-- name: TagChirp :exec
+INSERT INTO chirp_hashtags (chirp_id, tag, created_at)
+VALUES (
+    $1,
+    $2,
+    $3
+)
+ON CONFLICT DO NOTHING;
+
+-- name: ListHashtagChirps :many
+SELECT chirps.* FROM chirp_hashtags
+JOIN chirps ON chirps.id = chirp_hashtags.chirp_id
+WHERE chirp_hashtags.tag = sqlc.arg(tag)
+AND chirps.deleted_at IS NULL
+AND (chirp_hashtags.created_at, chirp_hashtags.chirp_id) < (sqlc.arg(before_created_at)::timestamp, sqlc.arg(before_id)::uuid)
+ORDER BY chirp_hashtags.created_at DESC, chirp_hashtags.chirp_id DESC
+LIMIT sqlc.arg(page_limit);
+
+-- every use counts for less the older it is, falling off by a factor of e every decay_seconds
+-- deleted chirps are left out, so taking a chirp down takes its tags out of the trends with it
+-- name: TrendingHashtags :many
+SELECT chirp_hashtags.tag,
+    COUNT(*) AS uses,
+    SUM(EXP(-EXTRACT(EPOCH FROM (sqlc.arg(now)::timestamp - chirp_hashtags.created_at)) / sqlc.arg(decay_seconds)::float8))::float8 AS score
+FROM chirp_hashtags
+JOIN chirps ON chirps.id = chirp_hashtags.chirp_id
+WHERE chirp_hashtags.created_at >= sqlc.arg(since)::timestamp
+AND chirps.deleted_at IS NULL
+GROUP BY chirp_hashtags.tag
+ORDER BY score DESC, chirp_hashtags.tag
+LIMIT sqlc.arg(page_limit);
+
+-- name: ClearHashtags :exec
+DELETE FROM chirp_hashtags
+WHERE chirp_id = $1;
\ No newline at end of file
@@ -0,0 +1,47 @@
+-- name: CreateHeldChirp :one
+INSERT INTO held_chirps (id, created_at, user_id, body, in_reply_to, quote_of, stage, reason)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6
+)
+RETURNING *;
+
+-- name: GetHeldChirp :one
+SELECT * FROM held_chirps
+WHERE id = $1;
+
+-- oldest first, so the queue is worked through in order
+-- name: ListHeldChirps :many
+SELECT * FROM held_chirps
+WHERE (created_at, id) > (sqlc.arg(after_created_at)::timestamp, sqlc.arg(after_id)::uuid)
+ORDER BY created_at, id
+LIMIT sqlc.arg(page_limit);
+
+-- name: DeleteHeldChirp :exec
+DELETE FROM held_chirps
+WHERE id = $1;
+
+-- deletes it and hands it back, so only one approval can ever get it
+-- name: ClaimHeldChirp :one
+DELETE FROM held_chirps
+WHERE id = $1
+RETURNING *;
+
+-- name: AddHeldChirpMedia :exec
+INSERT INTO held_chirp_media (held_chirp_id, media_id, position)
+VALUES (
+    $1,
+    $2,
+    $3
+);
+
+-- name: ListHeldChirpMedia :many
+SELECT * FROM held_chirp_media
+WHERE held_chirp_id = $1
+ORDER BY position;
@@ -0,0 +1,16 @@
+-- name: MentionUser :exec
+INSERT INTO chirp_mentions (chirp_id, user_id)
+VALUES (
+    $1,
+    $2
+)
+ON CONFLICT DO NOTHING;
+
+-- name: GetMentionsForChirps :many
+SELECT chirp_mentions.chirp_id, users.id AS user_id, users.handle FROM chirp_mentions
+JOIN users ON users.id = chirp_mentions.user_id
+WHERE chirp_mentions.chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[]);
+
+-- name: ClearMentions :exec
+DELETE FROM chirp_mentions
+WHERE chirp_id = $1;
\ No newline at end of file
@@ -0,0 +1,57 @@
+-- name: CreateNotification :exec
+INSERT INTO notifications (id, created_at, user_id, actor_id, kind, chirp_id)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4
+)
+ON CONFLICT DO NOTHING;
+
+-- notifications about a report point at it rather than at a chirp
+-- name: CreateReportNotification :exec
+INSERT INTO notifications (id, created_at, user_id, actor_id, kind, report_id)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4
+)
+ON CONFLICT DO NOTHING;
+
+-- notifications about a chirp that's since become a tombstone are left out, same as ones that cascaded away
+-- name: ListNotifications :many
+SELECT notifications.* FROM notifications
+LEFT JOIN chirps ON chirps.id = notifications.chirp_id
+WHERE notifications.user_id = sqlc.arg(user_id)
+AND chirps.deleted_at IS NULL
+AND (notifications.created_at, notifications.id) < (sqlc.arg(before_created_at)::timestamp, sqlc.arg(before_id)::uuid)
+ORDER BY notifications.created_at DESC, notifications.id DESC
+LIMIT sqlc.arg(page_limit);
+
+-- name: ListUnreadNotifications :many
+SELECT notifications.* FROM notifications
+LEFT JOIN chirps ON chirps.id = notifications.chirp_id
+WHERE notifications.user_id = sqlc.arg(user_id)
+AND notifications.read_at IS NULL
+AND chirps.deleted_at IS NULL
+AND (notifications.created_at, notifications.id) < (sqlc.arg(before_created_at)::timestamp, sqlc.arg(before_id)::uuid)
+ORDER BY notifications.created_at DESC, notifications.id DESC
+LIMIT sqlc.arg(page_limit);
+
+-- name: MarkNotificationsRead :exec
+UPDATE notifications
+SET read_at = NOW()
+WHERE user_id = sqlc.arg(user_id)
+AND id = ANY(sqlc.arg(ids)::uuid[])
+AND read_at IS NULL;
+
+-- name: MarkAllNotificationsRead :exec
+UPDATE notifications
+SET read_at = NOW()
+WHERE user_id = $1
+AND read_at IS NULL;
\ No newline at end of file
@@ -0,0 +1,18 @@
+-- name: Rechirp :exec
+INSERT INTO rechirps (id, created_at, user_id, chirp_id)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    $1,
+    $2
+)
+ON CONFLICT DO NOTHING;
+
+-- name: UndoRechirp :exec
+DELETE FROM rechirps
+WHERE user_id = $1 AND chirp_id = $2;
+
+-- name: CountRechirps :many
+SELECT chirp_id, COUNT(*) AS rechirp_count FROM rechirps
+WHERE chirp_id = ANY(sqlc.arg(ids)::uuid[])
+GROUP BY chirp_id;
\ No newline at end of file
@@ -0,0 +1,57 @@
+-- name: CreateReport :one
+INSERT INTO reports (id, created_at, updated_at, reporter_id, user_id, chirp_id, category, details)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4,
+    $5
+)
+RETURNING *;
+
+-- name: GetReport :one
+SELECT * FROM reports
+WHERE id = $1;
+
+-- oldest first, so the queue is worked through in order
+-- name: ListReportsByStatus :many
+SELECT * FROM reports
+WHERE status = sqlc.arg(status)
+AND (created_at, id) > (sqlc.arg(after_created_at)::timestamp, sqlc.arg(after_id)::uuid)
+ORDER BY created_at, id
+LIMIT sqlc.arg(page_limit);
+
+-- everything that's open or claimed, oldest first
+-- name: ListUnresolvedReports :many
+SELECT * FROM reports
+WHERE status <> 'resolved'
+AND (created_at, id) > (sqlc.arg(after_created_at)::timestamp, sqlc.arg(after_id)::uuid)
+ORDER BY created_at, id
+LIMIT sqlc.arg(page_limit);
+
+-- the reports a user has made, newest first
+-- name: ListReportsByReporter :many
+SELECT * FROM reports
+WHERE reporter_id = sqlc.arg(reporter_id)
+AND (created_at, id) < (sqlc.arg(before_created_at)::timestamp, sqlc.arg(before_id)::uuid)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(page_limit);
+
+-- only open reports can be claimed, so two moderators can't both end up with one
+-- name: ClaimReport :one
+UPDATE reports
+SET status = 'claimed', claimed_by = sqlc.arg(claimed_by), claimed_at = NOW(), updated_at = NOW()
+WHERE id = sqlc.arg(id) AND status = 'open'
+RETURNING *;
+
+-- whoever resolves a report ends up with it claimed, if it wasn't already
+-- name: ResolveReport :one
+UPDATE reports
+SET status = 'resolved', claimed_by = sqlc.arg(claimed_by), claimed_at = COALESCE(claimed_at, NOW()),
+resolved_at = NOW(), updated_at = NOW(), resolution = sqlc.arg(resolution), note = sqlc.arg(note)
+WHERE id = sqlc.arg(id) AND status <> 'resolved'
+AND (status <> 'claimed' OR claimed_by = sqlc.arg(claimed_by))
+RETURNING *;
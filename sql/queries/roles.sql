@@ -0,0 +1,26 @@
+-- name: CreateRoleChange :one
+INSERT INTO role_changes (id, created_at, user_id, changed_by, old_role, new_role, reason)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4,
+    $5
+)
+RETURNING *;
+
+-- newest first
+-- name: ListRoleChanges :many
+SELECT * FROM role_changes
+WHERE (created_at, id) < (sqlc.arg(before_created_at)::timestamp, sqlc.arg(before_id)::uuid)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(page_limit);
+
+-- name: ListUserRoleChanges :many
+SELECT * FROM role_changes
+WHERE user_id = sqlc.arg(user_id)
+AND (created_at, id) < (sqlc.arg(before_created_at)::timestamp, sqlc.arg(before_id)::uuid)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(page_limit);
@@ -1,11 +1,12 @@
 -- name: CreateUser :one
-INSERT INTO users(id, created_at, updated_at, email, hashed_password)
+INSERT INTO users(id, created_at, updated_at, email, hashed_password, handle)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
-    $2
+    $2,
+    $3
 )
 RETURNING *;
 
@@ -16,13 +17,77 @@ DELETE FROM users;
 SELECT * FROM users
 WHERE email = $1;
 
+-- name: GetUserByID :one
+SELECT * FROM users
+WHERE id = $1;
+
 -- name: UpdateEmailAndPassword :one
 UPDATE users
 SET email = $2, hashed_password = $3, updated_at = NOW()
 WHERE id = $1
 RETURNING *;
 
+-- name: UpdateHandle :one
+UPDATE users
+SET handle = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: UpdateDMSetting :one
+UPDATE users
+SET dms_from_followers_only = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: GetUsersByHandles :many
+SELECT * FROM users
+WHERE handle = ANY(sqlc.arg(handles)::text[]);
+
 -- name: UpgradeToRed :exec
 UPDATE users
 SET is_chirpy_red = true, updated_at = NOW()
-WHERE id = $1;
\ No newline at end of file
+WHERE id = $1;
+
+-- name: SoftDeleteUser :one
+UPDATE users
+SET deleted_at = NOW(), updated_at = NOW()
+WHERE id = $1 AND deleted_at IS NULL
+RETURNING *;
+
+-- name: RestoreUser :one
+UPDATE users
+SET deleted_at = NULL, updated_at = NOW()
+WHERE id = $1 AND deleted_at IS NOT NULL
+RETURNING *;
+
+-- name: ListPurgeableUsers :many
+SELECT * FROM users
+WHERE deleted_at < sqlc.arg(deleted_before)
+ORDER BY deleted_at
+LIMIT sqlc.arg(page_limit);
+
+-- who purged users' tombstones belong to, made the first time it's needed
+-- it's banned and has no password, so nobody can log in as it
+-- name: CreatePlaceholderUser :exec
+INSERT INTO users(id, created_at, updated_at, email, status, status_reason)
+VALUES ($1, NOW(), NOW(), '(deleted)', 'banned', 'This account has been deleted')
+ON CONFLICT (id) DO NOTHING;
+
+-- everything of theirs cascades away with them
+-- name: DeleteUser :exec
+DELETE FROM users
+WHERE id = $1;
+
+-- status_until is NULL for a suspension or ban that doesn't end
+-- name: SetUserStatus :one
+UPDATE users
+SET status = sqlc.arg(status), status_reason = sqlc.arg(status_reason), status_until = sqlc.arg(status_until),
+hide_chirps = sqlc.arg(hide_chirps), updated_at = NOW()
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: SetUserRole :one
+UPDATE users
+SET role = sqlc.arg(role), updated_at = NOW()
+WHERE id = sqlc.arg(id)
+RETURNING *;
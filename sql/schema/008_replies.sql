@@ -0,0 +1,13 @@
+-- +goose Up
+ALTER TABLE chirps
+ADD COLUMN in_reply_to UUID REFERENCES chirps(id) ON DELETE SET NULL;
+ALTER TABLE chirps
+ADD COLUMN deleted_at TIMESTAMP;
+CREATE INDEX chirps_in_reply_to_idx ON chirps (in_reply_to, created_at, id);
+
+-- +goose Down
+DROP INDEX chirps_in_reply_to_idx;
+ALTER TABLE chirps
+DROP COLUMN deleted_at;
+ALTER TABLE chirps
+DROP COLUMN in_reply_to;
\ No newline at end of file
@@ -0,0 +1,23 @@
+-- +goose Up
+-- no foreign key on quote_of, a quote outlives the chirp it quotes (which then shows up as unavailable)
+ALTER TABLE chirps
+ADD COLUMN quote_of UUID;
+CREATE TABLE rechirps (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL,
+    chirp_id UUID NOT NULL,
+    UNIQUE (user_id, chirp_id),
+    FOREIGN KEY (user_id)
+    REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (chirp_id)
+    REFERENCES chirps(id) ON DELETE CASCADE
+);
+CREATE INDEX rechirps_created_at_id_idx ON rechirps (created_at, id);
+CREATE INDEX rechirps_user_id_created_at_id_idx ON rechirps (user_id, created_at, id);
+CREATE INDEX rechirps_chirp_id_idx ON rechirps (chirp_id);
+
+-- +goose Down
+DROP TABLE rechirps;
+ALTER TABLE chirps
+DROP COLUMN quote_of;
\ No newline at end of file
@@ -0,0 +1,21 @@
+-- +goose Up
+-- handles are optional so existing users carry on as they were, they just can't be mentioned until they pick one
+ALTER TABLE users
+ADD COLUMN handle TEXT;
+CREATE UNIQUE INDEX users_handle_idx ON users (handle);
+CREATE TABLE chirp_mentions (
+    chirp_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    PRIMARY KEY (chirp_id, user_id),
+    FOREIGN KEY (chirp_id)
+    REFERENCES chirps(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id)
+    REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE INDEX chirp_mentions_user_id_idx ON chirp_mentions (user_id);
+
+-- +goose Down
+DROP TABLE chirp_mentions;
+DROP INDEX users_handle_idx;
+ALTER TABLE users
+DROP COLUMN handle;
\ No newline at end of file
@@ -0,0 +1,21 @@
+-- +goose Up
+-- edited_at stays NULL until the first edit, which is how responses know to say it's been edited
+ALTER TABLE chirps
+ADD COLUMN edited_at TIMESTAMP;
+-- every version a chirp had before its current one
+-- created_at is when that version went up (the chirp's created_at, or the edit that made it), replaced_at is when it was edited away
+CREATE TABLE chirp_revisions (
+    id UUID PRIMARY KEY,
+    chirp_id UUID NOT NULL,
+    body TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    replaced_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (chirp_id)
+    REFERENCES chirps(id) ON DELETE CASCADE
+);
+CREATE INDEX chirp_revisions_chirp_id_replaced_at_idx ON chirp_revisions (chirp_id, replaced_at);
+
+-- +goose Down
+DROP TABLE chirp_revisions;
+ALTER TABLE chirps
+DROP COLUMN edited_at;
@@ -0,0 +1,40 @@
+-- +goose Up
+-- the word lists the content filter checks chirps against
+-- action is what happens to a chirp with one of the list's words in it: mask, reject or flag
+CREATE TABLE filter_lists (
+    name TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    action TEXT NOT NULL
+);
+CREATE TABLE filter_words (
+    list_name TEXT NOT NULL,
+    word TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (list_name, word),
+    FOREIGN KEY (list_name)
+    REFERENCES filter_lists(name) ON DELETE CASCADE
+);
+-- chirps that matched a flag list, waiting for a moderator to look at them
+-- list_name and word are copied rather than referenced, so editing the lists doesn't lose any flags
+CREATE TABLE chirp_flags (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    chirp_id UUID NOT NULL,
+    list_name TEXT NOT NULL,
+    word TEXT NOT NULL,
+    FOREIGN KEY (chirp_id)
+    REFERENCES chirps(id) ON DELETE CASCADE
+);
+CREATE INDEX chirp_flags_created_at_idx ON chirp_flags (created_at, id);
+CREATE INDEX chirp_flags_chirp_id_idx ON chirp_flags (chirp_id);
+-- the words the old hard-coded filter masked
+INSERT INTO filter_lists (name, created_at, updated_at, action)
+VALUES ('default', NOW(), NOW(), 'mask');
+INSERT INTO filter_words (list_name, word, created_at)
+VALUES ('default', 'kerfuffle', NOW()), ('default', 'sharbert', NOW()), ('default', 'fornax', NOW());
+
+-- +goose Down
+DROP TABLE chirp_flags;
+DROP TABLE filter_words;
+DROP TABLE filter_lists;
@@ -0,0 +1,28 @@
+-- +goose Up
+-- users are active, suspended or banned, with a reason they're shown when they try to log in
+-- status_until is when a suspension or ban ends (NULL for never), after which they're active again
+-- hide_chirps keeps their chirps out of the chirp listings until then
+ALTER TABLE users
+ADD COLUMN status TEXT NOT NULL DEFAULT 'active',
+ADD COLUMN status_reason TEXT NOT NULL DEFAULT '',
+ADD COLUMN status_until TIMESTAMP,
+ADD COLUMN hide_chirps BOOLEAN NOT NULL DEFAULT false;
+-- suspensions from reports carry over (ones that have already ended just read as active)
+UPDATE users
+SET status = 'suspended', status_until = suspended_until
+WHERE suspended_until IS NOT NULL;
+ALTER TABLE users
+DROP COLUMN suspended_until;
+
+-- +goose Down
+-- suspended_until can't say never, so bans and open-ended suspensions go on as long as it can
+ALTER TABLE users
+ADD COLUMN suspended_until TIMESTAMP;
+UPDATE users
+SET suspended_until = COALESCE(status_until, '9999-12-31')
+WHERE status <> 'active';
+ALTER TABLE users
+DROP COLUMN hide_chirps,
+DROP COLUMN status_until,
+DROP COLUMN status_reason,
+DROP COLUMN status;
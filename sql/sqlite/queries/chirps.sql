@@ -0,0 +1,210 @@
+-- name: CreateChirp :one
+INSERT INTO chirps(id, created_at, updated_at, body, user_id, in_reply_to, quote_of)
+VALUES (
+    sqlc.arg(id),
+    sqlc.arg(created_at),
+    sqlc.arg(updated_at),
+    sqlc.arg(body),
+    sqlc.arg(user_id),
+    sqlc.arg(in_reply_to),
+    sqlc.arg(quote_of)
+)
+RETURNING *;
+
+-- name: GetAllChirps :many
+SELECT * FROM chirps
+WHERE deleted_at IS NULL
+ORDER BY created_at;
+
+-- name: GetChirpsByUser :many
+SELECT * FROM chirps
+WHERE user_id = sqlc.arg(user_id)
+AND deleted_at IS NULL
+ORDER BY created_at;
+
+-- name: GetSingleChirp :one
+SELECT * FROM chirps
+WHERE id = sqlc.arg(id);
+
+-- SQLite doesn't have FOR UPDATE, but there's only the one connection, so a transaction has it to itself anyway
+-- name: GetChirpForUpdate :one
+SELECT * FROM chirps
+WHERE id = sqlc.arg(id);
+
+-- name: GetChirpsByIDs :many
+SELECT * FROM chirps
+WHERE id IN (sqlc.slice(ids));
+
+-- name: DeleteSingleChirp :exec
+DELETE FROM chirps
+WHERE id = sqlc.arg(id);
+
+-- name: SoftDeleteChirp :exec
+UPDATE chirps
+SET deleted_at = sqlc.arg(deleted_at), updated_at = sqlc.arg(deleted_at)
+WHERE id = sqlc.arg(id) AND deleted_at IS NULL;
+
+-- blanks the body for good, the row is only kept so replies still have something to hang off
+-- name: TombstoneChirp :exec
+UPDATE chirps
+SET body = '', purged_at = sqlc.arg(purged_at), updated_at = sqlc.arg(updated_at), deleted_at = COALESCE(deleted_at, sqlc.arg(purged_at))
+WHERE id = sqlc.arg(id);
+
+-- only works until the chirp's been purged
+-- name: RestoreChirp :one
+UPDATE chirps
+SET deleted_at = NULL, updated_at = sqlc.arg(updated_at)
+WHERE id = sqlc.arg(id) AND deleted_at IS NOT NULL AND purged_at IS NULL
+RETURNING *;
+
+-- deleting a user deletes their chirps at the same moment, so restoring them can tell those apart
+-- from ones they deleted themselves
+-- name: SoftDeleteUserChirps :exec
+UPDATE chirps
+SET deleted_at = sqlc.arg(deleted_at), updated_at = sqlc.arg(deleted_at)
+WHERE user_id = sqlc.arg(user_id) AND deleted_at IS NULL;
+
+-- name: RestoreUserChirps :exec
+UPDATE chirps
+SET deleted_at = NULL, updated_at = sqlc.arg(updated_at)
+WHERE user_id = sqlc.arg(user_id) AND deleted_at = sqlc.arg(deleted_at) AND purged_at IS NULL;
+
+-- name: ListPurgeableChirps :many
+SELECT * FROM chirps
+WHERE deleted_at < sqlc.arg(deleted_before) AND purged_at IS NULL
+ORDER BY deleted_at
+LIMIT sqlc.arg(page_limit);
+
+-- newest first, so replies are purged before what they're replying to
+-- name: ListUnpurgedUserChirps :many
+SELECT * FROM chirps
+WHERE user_id = sqlc.arg(user_id) AND purged_at IS NULL
+ORDER BY created_at DESC, id DESC;
+
+-- a purged user's tombstones would cascade away with them, so they're handed over to the placeholder user first
+-- name: ReassignTombstones :exec
+UPDATE chirps
+SET user_id = sqlc.arg(new_user_id), updated_at = sqlc.arg(updated_at)
+WHERE user_id = sqlc.arg(user_id) AND purged_at IS NOT NULL;
+
+-- tombstones can't be edited
+-- name: EditChirp :one
+UPDATE chirps
+SET body = sqlc.arg(body), updated_at = sqlc.arg(edited_at), edited_at = sqlc.arg(edited_at)
+WHERE id = sqlc.arg(id) AND deleted_at IS NULL
+RETURNING *;
+
+-- the timeline queries mix in rechirps, so each entry is either a chirp or someone rechirping one
+-- (rechirped_by is NULL for plain chirps, and the entry id is the rechirp's own id otherwise)
+-- chirps from users with hide_chirps set are left out until their suspension or ban ends
+-- name: ListChirpsAsc :many
+SELECT sqlc.embed(chirps), entries.entry_id, entries.entry_at, entries.rechirped_by
+FROM (
+    SELECT r.id AS entry_id, r.created_at AS entry_at, r.chirp_id, r.user_id AS rechirped_by
+    FROM rechirps r
+    UNION ALL
+    SELECT c.id, c.created_at, c.id, NULL
+    FROM chirps c
+) AS entries
+JOIN chirps ON chirps.id = entries.chirp_id
+WHERE chirps.deleted_at IS NULL
+AND (entries.entry_at > sqlc.arg(after_created_at)
+OR (entries.entry_at = sqlc.arg(after_created_at) AND entries.entry_id > sqlc.arg(after_id)))
+AND chirps.user_id NOT IN (
+    SELECT id FROM users
+    WHERE hide_chirps AND (status_until IS NULL OR status_until > sqlc.arg(now))
+)
+ORDER BY entries.entry_at, entries.entry_id
+LIMIT sqlc.arg(page_limit);
+
+-- name: ListChirpsDesc :many
+SELECT sqlc.embed(chirps), entries.entry_id, entries.entry_at, entries.rechirped_by
+FROM (
+    SELECT r.id AS entry_id, r.created_at AS entry_at, r.chirp_id, r.user_id AS rechirped_by
+    FROM rechirps r
+    UNION ALL
+    SELECT c.id, c.created_at, c.id, NULL
+    FROM chirps c
+) AS entries
+JOIN chirps ON chirps.id = entries.chirp_id
+WHERE chirps.deleted_at IS NULL
+AND (entries.entry_at < sqlc.arg(before_created_at)
+OR (entries.entry_at = sqlc.arg(before_created_at) AND entries.entry_id < sqlc.arg(before_id)))
+AND chirps.user_id NOT IN (
+    SELECT id FROM users
+    WHERE hide_chirps AND (status_until IS NULL OR status_until > sqlc.arg(now))
+)
+ORDER BY entries.entry_at DESC, entries.entry_id DESC
+LIMIT sqlc.arg(page_limit);
+
+-- name: ListChirpsByUserAsc :many
+SELECT sqlc.embed(chirps), entries.entry_id, entries.entry_at, entries.rechirped_by
+FROM (
+    SELECT r.id AS entry_id, r.created_at AS entry_at, r.chirp_id, r.user_id AS rechirped_by
+    FROM rechirps r
+    WHERE r.user_id = sqlc.arg(user_id)
+    UNION ALL
+    SELECT c.id, c.created_at, c.id, NULL
+    FROM chirps c
+    WHERE c.user_id = sqlc.arg(user_id)
+) AS entries
+JOIN chirps ON chirps.id = entries.chirp_id
+WHERE chirps.deleted_at IS NULL
+AND (entries.entry_at > sqlc.arg(after_created_at)
+OR (entries.entry_at = sqlc.arg(after_created_at) AND entries.entry_id > sqlc.arg(after_id)))
+AND chirps.user_id NOT IN (
+    SELECT id FROM users
+    WHERE hide_chirps AND (status_until IS NULL OR status_until > sqlc.arg(now))
+)
+ORDER BY entries.entry_at, entries.entry_id
+LIMIT sqlc.arg(page_limit);
+
+-- name: ListChirpsByUserDesc :many
+SELECT sqlc.embed(chirps), entries.entry_id, entries.entry_at, entries.rechirped_by
+FROM (
+    SELECT r.id AS entry_id, r.created_at AS entry_at, r.chirp_id, r.user_id AS rechirped_by
+    FROM rechirps r
+    WHERE r.user_id = sqlc.arg(user_id)
+    UNION ALL
+    SELECT c.id, c.created_at, c.id, NULL
+    FROM chirps c
+    WHERE c.user_id = sqlc.arg(user_id)
+) AS entries
+JOIN chirps ON chirps.id = entries.chirp_id
+WHERE chirps.deleted_at IS NULL
+AND (entries.entry_at < sqlc.arg(before_created_at)
+OR (entries.entry_at = sqlc.arg(before_created_at) AND entries.entry_id < sqlc.arg(before_id)))
+AND chirps.user_id NOT IN (
+    SELECT id FROM users
+    WHERE hide_chirps AND (status_until IS NULL OR status_until > sqlc.arg(now))
+)
+ORDER BY entries.entry_at DESC, entries.entry_id DESC
+LIMIT sqlc.arg(page_limit);
+
+-- name: SearchChirpsCandidates :many
+SELECT * FROM chirps
+WHERE body LIKE sqlc.arg(pattern) ESCAPE '\'
+AND deleted_at IS NULL
+AND created_at >= sqlc.arg(since)
+AND created_at < sqlc.arg(until)
+ORDER BY created_at DESC, id DESC;
+
+-- name: ListReplies :many
+SELECT * FROM chirps
+WHERE in_reply_to = sqlc.arg(in_reply_to)
+AND (created_at > sqlc.arg(after_created_at)
+OR (created_at = sqlc.arg(after_created_at) AND id > sqlc.arg(after_id)))
+ORDER BY created_at, id
+LIMIT sqlc.arg(page_limit);
+
+-- name: GetRepliesToChirps :many
+SELECT * FROM chirps
+WHERE in_reply_to IN (sqlc.slice(parent_ids))
+ORDER BY created_at, id
+LIMIT sqlc.arg(page_limit);
+
+-- name: CountReplies :many
+SELECT in_reply_to, COUNT(*) AS reply_count FROM chirps
+WHERE in_reply_to IN (sqlc.slice(ids))
+AND deleted_at IS NULL
+GROUP BY in_reply_to;
\ No newline at end of file
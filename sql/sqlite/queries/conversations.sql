@@ -0,0 +1,93 @@
+-- name: CreateConversation :one
+INSERT INTO conversations (id, created_at, updated_at, is_group)
+VALUES (
+    sqlc.arg(id),
+    sqlc.arg(created_at),
+    sqlc.arg(updated_at),
+    sqlc.arg(is_group)
+)
+RETURNING *;
+
+-- name: GetConversation :one
+SELECT * FROM conversations
+WHERE id = sqlc.arg(id);
+
+-- the one-to-one conversation two people are both still in, if there is one
+-- name: FindDirectConversation :one
+SELECT conversations.* FROM conversations
+JOIN conversation_participants a ON a.conversation_id = conversations.id
+JOIN conversation_participants b ON b.conversation_id = conversations.id
+WHERE conversations.is_group = false
+AND a.user_id = sqlc.arg(user_id) AND a.left_at IS NULL
+AND b.user_id = sqlc.arg(other_id) AND b.left_at IS NULL
+ORDER BY conversations.created_at
+LIMIT 1;
+
+-- name: TouchConversation :exec
+UPDATE conversations
+SET updated_at = sqlc.arg(updated_at)
+WHERE id = sqlc.arg(id);
+
+-- name: AddParticipant :exec
+INSERT INTO conversation_participants (conversation_id, user_id, joined_at)
+VALUES (
+    sqlc.arg(conversation_id),
+    sqlc.arg(user_id),
+    sqlc.arg(joined_at)
+)
+ON CONFLICT DO NOTHING;
+
+-- name: GetParticipant :one
+SELECT * FROM conversation_participants
+WHERE conversation_id = sqlc.arg(conversation_id) AND user_id = sqlc.arg(user_id);
+
+-- name: ListParticipants :many
+SELECT * FROM conversation_participants
+WHERE conversation_id IN (sqlc.slice(conversation_ids))
+ORDER BY joined_at, user_id;
+
+-- name: LeaveConversation :exec
+UPDATE conversation_participants
+SET left_at = sqlc.arg(left_at)
+WHERE conversation_id = sqlc.arg(conversation_id) AND user_id = sqlc.arg(user_id)
+AND left_at IS NULL;
+
+-- name: MarkConversationRead :exec
+UPDATE conversation_participants
+SET last_read_at = sqlc.arg(last_read_at)
+WHERE conversation_id = sqlc.arg(conversation_id) AND user_id = sqlc.arg(user_id);
+
+-- the conversations someone's still in, most recently active first, each with how many messages they haven't read
+-- name: ListConversations :many
+SELECT sqlc.embed(conversations),
+    (SELECT COUNT(*) FROM messages
+    WHERE messages.conversation_id = conversations.id
+    AND messages.sender_id <> conversation_participants.user_id
+    AND (conversation_participants.last_read_at IS NULL OR messages.created_at > conversation_participants.last_read_at)) AS unread_count
+FROM conversation_participants
+JOIN conversations ON conversations.id = conversation_participants.conversation_id
+WHERE conversation_participants.user_id = sqlc.arg(user_id)
+AND conversation_participants.left_at IS NULL
+AND (conversations.updated_at < sqlc.arg(before_updated_at)
+OR (conversations.updated_at = sqlc.arg(before_updated_at) AND conversations.id < sqlc.arg(before_id)))
+ORDER BY conversations.updated_at DESC, conversations.id DESC
+LIMIT sqlc.arg(page_limit);
+
+-- name: CreateMessage :one
+INSERT INTO messages (id, created_at, conversation_id, sender_id, body)
+VALUES (
+    sqlc.arg(id),
+    sqlc.arg(created_at),
+    sqlc.arg(conversation_id),
+    sqlc.arg(sender_id),
+    sqlc.arg(body)
+)
+RETURNING *;
+
+-- name: ListMessages :many
+SELECT * FROM messages
+WHERE conversation_id = sqlc.arg(conversation_id)
+AND (created_at < sqlc.arg(before_created_at)
+OR (created_at = sqlc.arg(before_created_at) AND id < sqlc.arg(before_id)))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(page_limit);
\ No newline at end of file
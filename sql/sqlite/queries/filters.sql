@@ -0,0 +1,68 @@
+-- creates the list, or changes the action of the one that's already there
+-- name: UpsertFilterList :one
+INSERT INTO filter_lists (name, created_at, updated_at, action)
+VALUES (
+    sqlc.arg(name),
+    sqlc.arg(created_at),
+    sqlc.arg(created_at),
+    sqlc.arg(action)
+)
+ON CONFLICT (name) DO UPDATE
+SET action = excluded.action, updated_at = excluded.updated_at
+RETURNING *;
+
+-- name: GetFilterList :one
+SELECT * FROM filter_lists
+WHERE name = sqlc.arg(name);
+
+-- name: ListFilterLists :many
+SELECT * FROM filter_lists
+ORDER BY name;
+
+-- name: DeleteFilterList :exec
+DELETE FROM filter_lists
+WHERE name = sqlc.arg(name);
+
+-- name: AddFilterWord :exec
+INSERT INTO filter_words (list_name, word, created_at)
+VALUES (
+    sqlc.arg(list_name),
+    sqlc.arg(word),
+    sqlc.arg(created_at)
+)
+ON CONFLICT DO NOTHING;
+
+-- name: RemoveFilterWord :exec
+DELETE FROM filter_words
+WHERE list_name = sqlc.arg(list_name) AND word = sqlc.arg(word);
+
+-- name: ClearFilterWords :exec
+DELETE FROM filter_words
+WHERE list_name = sqlc.arg(list_name);
+
+-- every word on every list
+-- name: ListFilterWords :many
+SELECT * FROM filter_words
+ORDER BY list_name, word;
+
+-- name: CreateChirpFlag :exec
+INSERT INTO chirp_flags (id, created_at, chirp_id, list_name, word)
+VALUES (
+    sqlc.arg(id),
+    sqlc.arg(created_at),
+    sqlc.arg(chirp_id),
+    sqlc.arg(list_name),
+    sqlc.arg(word)
+);
+
+-- newest first
+-- name: ListChirpFlags :many
+SELECT * FROM chirp_flags
+WHERE (created_at < sqlc.arg(before_created_at)
+OR (created_at = sqlc.arg(before_created_at) AND id < sqlc.arg(before_id)))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(page_limit);
+
+-- name: DeleteChirpFlag :exec
+DELETE FROM chirp_flags
+WHERE id = sqlc.arg(id);
@@ -0,0 +1,61 @@
+-- name: FollowUser :exec
+INSERT INTO follows (follower_id, followee_id, created_at)
+VALUES (
+    sqlc.arg(follower_id),
+    sqlc.arg(followee_id),
+    sqlc.arg(created_at)
+)
+ON CONFLICT DO NOTHING;
+
+-- name: UnfollowUser :exec
+DELETE FROM follows
+WHERE follower_id = sqlc.arg(follower_id) AND followee_id = sqlc.arg(followee_id);
+
+-- name: GetFollow :one
+SELECT * FROM follows
+WHERE follower_id = sqlc.arg(follower_id) AND followee_id = sqlc.arg(followee_id);
+
+-- deleted users are left out of both lists
+-- name: ListFollowers :many
+SELECT follows.follower_id AS user_id, follows.created_at AS followed_at FROM follows
+JOIN users ON users.id = follows.follower_id
+WHERE follows.followee_id = sqlc.arg(user_id)
+AND users.deleted_at IS NULL
+AND (follows.created_at < sqlc.arg(before_followed_at)
+OR (follows.created_at = sqlc.arg(before_followed_at) AND follows.follower_id < sqlc.arg(before_id)))
+ORDER BY follows.created_at DESC, follows.follower_id DESC
+LIMIT sqlc.arg(page_limit);
+
+-- name: ListFollowing :many
+SELECT follows.followee_id AS user_id, follows.created_at AS followed_at FROM follows
+JOIN users ON users.id = follows.followee_id
+WHERE follows.follower_id = sqlc.arg(user_id)
+AND users.deleted_at IS NULL
+AND (follows.created_at < sqlc.arg(before_followed_at)
+OR (follows.created_at = sqlc.arg(before_followed_at) AND follows.followee_id < sqlc.arg(before_id)))
+ORDER BY follows.created_at DESC, follows.followee_id DESC
+LIMIT sqlc.arg(page_limit);
+
+-- SQLite has no LATERAL, so this is the plain version of the Postgres query
+-- the cursor is checked inside each branch so the (user_id, created_at, id) indexes still do the work
+-- name: ListTimeline :many
+SELECT sqlc.embed(chirps), entries.entry_id, entries.entry_at, entries.rechirped_by
+FROM (
+    SELECT r.id AS entry_id, r.created_at AS entry_at, r.chirp_id, r.user_id AS rechirped_by
+    FROM rechirps r
+    WHERE (r.user_id = sqlc.arg(user_id)
+    OR r.user_id IN (SELECT followee_id FROM follows WHERE follower_id = sqlc.arg(user_id)))
+    AND (r.created_at < sqlc.arg(before_created_at)
+    OR (r.created_at = sqlc.arg(before_created_at) AND r.id < sqlc.arg(before_id)))
+    UNION ALL
+    SELECT c.id, c.created_at, c.id, NULL
+    FROM chirps c
+    WHERE (c.user_id = sqlc.arg(user_id)
+    OR c.user_id IN (SELECT followee_id FROM follows WHERE follower_id = sqlc.arg(user_id)))
+    AND (c.created_at < sqlc.arg(before_created_at)
+    OR (c.created_at = sqlc.arg(before_created_at) AND c.id < sqlc.arg(before_id)))
+) AS entries
+JOIN chirps ON chirps.id = entries.chirp_id
+WHERE chirps.deleted_at IS NULL
+ORDER BY entries.entry_at DESC, entries.entry_id DESC
+LIMIT sqlc.arg(page_limit);
\ No newline at end of file
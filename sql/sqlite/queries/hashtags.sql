@@ -0,0 +1,30 @@
+-- name: TagChirp :exec
+INSERT INTO chirp_hashtags (chirp_id, tag, created_at)
+VALUES (
+    sqlc.arg(chirp_id),
+    sqlc.arg(tag),
+    sqlc.arg(created_at)
+)
+ON CONFLICT DO NOTHING;
+
+-- name: ListHashtagChirps :many
+SELECT chirps.* FROM chirp_hashtags
+JOIN chirps ON chirps.id = chirp_hashtags.chirp_id
+WHERE chirp_hashtags.tag = sqlc.arg(tag)
+AND chirps.deleted_at IS NULL
+AND (chirp_hashtags.created_at < sqlc.arg(before_created_at)
+OR (chirp_hashtags.created_at = sqlc.arg(before_created_at) AND chirp_hashtags.chirp_id < sqlc.arg(before_id)))
+ORDER BY chirp_hashtags.created_at DESC, chirp_hashtags.chirp_id DESC
+LIMIT sqlc.arg(page_limit);
+
+-- SQLite doesn't always have exp(), so this just hands back every use in the window and trends.go scores them
+-- name: ListHashtagUses :many
+SELECT chirp_hashtags.tag, chirp_hashtags.created_at
+FROM chirp_hashtags
+JOIN chirps ON chirps.id = chirp_hashtags.chirp_id
+WHERE chirp_hashtags.created_at >= sqlc.arg(since)
+AND chirps.deleted_at IS NULL;
+
+-- name: ClearHashtags :exec
+DELETE FROM chirp_hashtags
+WHERE chirp_id = sqlc.arg(chirp_id);
\ No newline at end of file
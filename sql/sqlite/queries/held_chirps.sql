@@ -0,0 +1,48 @@
+-- name: CreateHeldChirp :one
+INSERT INTO held_chirps (id, created_at, user_id, body, in_reply_to, quote_of, stage, reason)
+VALUES (
+    sqlc.arg(id),
+    sqlc.arg(created_at),
+    sqlc.arg(user_id),
+    sqlc.arg(body),
+    sqlc.arg(in_reply_to),
+    sqlc.arg(quote_of),
+    sqlc.arg(stage),
+    sqlc.arg(reason)
+)
+RETURNING *;
+
+-- name: GetHeldChirp :one
+SELECT * FROM held_chirps
+WHERE id = sqlc.arg(id);
+
+-- oldest first, so the queue is worked through in order
+-- name: ListHeldChirps :many
+SELECT * FROM held_chirps
+WHERE (created_at > sqlc.arg(after_created_at)
+OR (created_at = sqlc.arg(after_created_at) AND id > sqlc.arg(after_id)))
+ORDER BY created_at, id
+LIMIT sqlc.arg(page_limit);
+
+-- name: DeleteHeldChirp :exec
+DELETE FROM held_chirps
+WHERE id = sqlc.arg(id);
+
+-- deletes it and hands it back, so only one approval can ever get it
+-- name: ClaimHeldChirp :one
+DELETE FROM held_chirps
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: AddHeldChirpMedia :exec
+INSERT INTO held_chirp_media (held_chirp_id, media_id, position)
+VALUES (
+    sqlc.arg(held_chirp_id),
+    sqlc.arg(media_id),
+    sqlc.arg(position)
+);
+
+-- name: ListHeldChirpMedia :many
+SELECT * FROM held_chirp_media
+WHERE held_chirp_id = sqlc.arg(held_chirp_id)
+ORDER BY position;
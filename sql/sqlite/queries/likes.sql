@@ -0,0 +1,32 @@
+-- name: LikeChirp :exec
+INSERT INTO likes (user_id, chirp_id, created_at)
+VALUES (
+    sqlc.arg(user_id),
+    sqlc.arg(chirp_id),
+    sqlc.arg(created_at)
+)
+ON CONFLICT DO NOTHING;
+
+-- name: UnlikeChirp :exec
+DELETE FROM likes
+WHERE user_id = sqlc.arg(user_id) AND chirp_id = sqlc.arg(chirp_id);
+
+-- name: CountLikes :many
+SELECT chirp_id, COUNT(*) AS like_count FROM likes
+WHERE chirp_id IN (sqlc.slice(ids))
+GROUP BY chirp_id;
+
+-- name: GetLikedByUser :many
+SELECT chirp_id FROM likes
+WHERE user_id = sqlc.arg(user_id)
+AND chirp_id IN (sqlc.slice(chirp_ids));
+
+-- name: ListUserLikes :many
+SELECT sqlc.embed(chirps), likes.created_at AS liked_at FROM likes
+JOIN chirps ON chirps.id = likes.chirp_id
+WHERE likes.user_id = sqlc.arg(user_id)
+AND chirps.deleted_at IS NULL
+AND (likes.created_at < sqlc.arg(before_liked_at)
+OR (likes.created_at = sqlc.arg(before_liked_at) AND likes.chirp_id < sqlc.arg(before_id)))
+ORDER BY likes.created_at DESC, likes.chirp_id DESC
+LIMIT sqlc.arg(page_limit);
\ No newline at end of file
@@ -0,0 +1,50 @@
+-- name: CreateMediaUpload :one
+INSERT INTO media_uploads (id, created_at, user_id, blob_key, content_type, size, width, height, blurhash, thumbnail_key)
+VALUES (
+    sqlc.arg(id),
+    sqlc.arg(created_at),
+    sqlc.arg(user_id),
+    sqlc.arg(blob_key),
+    sqlc.arg(content_type),
+    sqlc.arg(size),
+    sqlc.arg(width),
+    sqlc.arg(height),
+    sqlc.arg(blurhash),
+    sqlc.arg(thumbnail_key)
+)
+RETURNING *;
+
+-- name: GetMediaUpload :one
+SELECT * FROM media_uploads
+WHERE id = sqlc.arg(id);
+
+-- only claims uploads that aren't already on a chirp
+-- nothing's updated if it's already on a chirp, which is how racing for an upload is caught
+-- name: AttachMediaUpload :execrows
+UPDATE media_uploads
+SET chirp_id = sqlc.arg(chirp_id), position = sqlc.arg(position)
+WHERE id = sqlc.arg(id) AND chirp_id IS NULL;
+
+-- name: GetMediaForChirps :many
+SELECT * FROM media_uploads
+WHERE chirp_id IN (sqlc.slice(chirp_ids))
+ORDER BY chirp_id, position;
+
+-- uploads nobody attached (or whose chirp was purged) once they're old enough
+-- media on chirps that are only deleted is kept, in case they're restored, and so is media on held chirps
+-- name: ListOrphanedMedia :many
+SELECT * FROM media_uploads
+WHERE created_at < sqlc.arg(created_before)
+AND (chirp_id IS NULL OR chirp_id IN (SELECT id FROM chirps WHERE purged_at IS NOT NULL))
+AND id NOT IN (SELECT media_id FROM held_chirp_media)
+ORDER BY created_at
+LIMIT sqlc.arg(page_limit);
+
+-- everything a user uploaded, so the files can go before the user does
+-- name: ListUserMedia :many
+SELECT * FROM media_uploads
+WHERE user_id = sqlc.arg(user_id);
+
+-- name: DeleteMediaUpload :exec
+DELETE FROM media_uploads
+WHERE id = sqlc.arg(id);
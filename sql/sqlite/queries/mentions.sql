@@ -0,0 +1,16 @@
+-- name: MentionUser :exec
+INSERT INTO chirp_mentions (chirp_id, user_id)
+VALUES (
+    sqlc.arg(chirp_id),
+    sqlc.arg(user_id)
+)
+ON CONFLICT DO NOTHING;
+
+-- name: GetMentionsForChirps :many
+SELECT chirp_mentions.chirp_id, users.id AS user_id, users.handle FROM chirp_mentions
+JOIN users ON users.id = chirp_mentions.user_id
+WHERE chirp_mentions.chirp_id IN (sqlc.slice(chirp_ids));
+
+-- name: ClearMentions :exec
+DELETE FROM chirp_mentions
+WHERE chirp_id = sqlc.arg(chirp_id);
\ No newline at end of file
@@ -0,0 +1,59 @@
+-- name: CreateNotification :exec
+INSERT INTO notifications (id, created_at, user_id, actor_id, kind, chirp_id)
+VALUES (
+    sqlc.arg(id),
+    sqlc.arg(created_at),
+    sqlc.arg(user_id),
+    sqlc.arg(actor_id),
+    sqlc.arg(kind),
+    sqlc.arg(chirp_id)
+)
+ON CONFLICT DO NOTHING;
+
+-- notifications about a report point at it rather than at a chirp
+-- name: CreateReportNotification :exec
+INSERT INTO notifications (id, created_at, user_id, actor_id, kind, report_id)
+VALUES (
+    sqlc.arg(id),
+    sqlc.arg(created_at),
+    sqlc.arg(user_id),
+    sqlc.arg(actor_id),
+    sqlc.arg(kind),
+    sqlc.arg(report_id)
+)
+ON CONFLICT DO NOTHING;
+
+-- notifications about a chirp that's since become a tombstone are left out, same as ones that cascaded away
+-- name: ListNotifications :many
+SELECT notifications.* FROM notifications
+LEFT JOIN chirps ON chirps.id = notifications.chirp_id
+WHERE notifications.user_id = sqlc.arg(user_id)
+AND chirps.deleted_at IS NULL
+AND (notifications.created_at < sqlc.arg(before_created_at)
+OR (notifications.created_at = sqlc.arg(before_created_at) AND notifications.id < sqlc.arg(before_id)))
+ORDER BY notifications.created_at DESC, notifications.id DESC
+LIMIT sqlc.arg(page_limit);
+
+-- name: ListUnreadNotifications :many
+SELECT notifications.* FROM notifications
+LEFT JOIN chirps ON chirps.id = notifications.chirp_id
+WHERE notifications.user_id = sqlc.arg(user_id)
+AND notifications.read_at IS NULL
+AND chirps.deleted_at IS NULL
+AND (notifications.created_at < sqlc.arg(before_created_at)
+OR (notifications.created_at = sqlc.arg(before_created_at) AND notifications.id < sqlc.arg(before_id)))
+ORDER BY notifications.created_at DESC, notifications.id DESC
+LIMIT sqlc.arg(page_limit);
+
+-- name: MarkNotificationsRead :exec
+UPDATE notifications
+SET read_at = sqlc.arg(read_at)
+WHERE user_id = sqlc.arg(user_id)
+AND id IN (sqlc.slice(ids))
+AND read_at IS NULL;
+
+-- name: MarkAllNotificationsRead :exec
+UPDATE notifications
+SET read_at = sqlc.arg(read_at)
+WHERE user_id = sqlc.arg(user_id)
+AND read_at IS NULL;
\ No newline at end of file
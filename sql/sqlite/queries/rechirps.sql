@@ -0,0 +1,18 @@
+-- name: Rechirp :exec
+INSERT INTO rechirps (id, created_at, user_id, chirp_id)
+VALUES (
+    sqlc.arg(id),
+    sqlc.arg(created_at),
+    sqlc.arg(user_id),
+    sqlc.arg(chirp_id)
+)
+ON CONFLICT DO NOTHING;
+
+-- name: UndoRechirp :exec
+DELETE FROM rechirps
+WHERE user_id = sqlc.arg(user_id) AND chirp_id = sqlc.arg(chirp_id);
+
+-- name: CountRechirps :many
+SELECT chirp_id, COUNT(*) AS rechirp_count FROM rechirps
+WHERE chirp_id IN (sqlc.slice(ids))
+GROUP BY chirp_id;
\ No newline at end of file
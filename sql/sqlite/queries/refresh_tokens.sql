@@ -0,0 +1,24 @@
+-- name: CreateToken :one
+INSERT INTO refresh_tokens (token, created_at, updated_at, user_id, expires_at)
+VALUES (
+    sqlc.arg(token),
+    sqlc.arg(created_at),
+    sqlc.arg(updated_at),
+    sqlc.arg(user_id),
+    sqlc.arg(expires_at)
+)
+RETURNING *;
+
+-- name: GetUserFromRefreshToken :one
+SELECT user_id, expires_at, revoked_at FROM refresh_tokens
+WHERE token = sqlc.arg(token);
+
+-- name: RevokeToken :exec
+UPDATE refresh_tokens
+SET updated_at = sqlc.arg(updated_at), revoked_at = sqlc.arg(revoked_at)
+WHERE token = sqlc.arg(token);
+
+-- name: RevokeUserTokens :exec
+UPDATE refresh_tokens
+SET updated_at = sqlc.arg(revoked_at), revoked_at = sqlc.arg(revoked_at)
+WHERE user_id = sqlc.arg(user_id) AND revoked_at IS NULL;
\ No newline at end of file
@@ -0,0 +1,19 @@
+-- name: CreateChirpRevision :exec
+INSERT INTO chirp_revisions (id, chirp_id, body, created_at, replaced_at)
+VALUES (
+    sqlc.arg(id),
+    sqlc.arg(chirp_id),
+    sqlc.arg(body),
+    sqlc.arg(created_at),
+    sqlc.arg(replaced_at)
+);
+
+-- oldest first
+-- name: ListChirpRevisions :many
+SELECT * FROM chirp_revisions
+WHERE chirp_id = sqlc.arg(chirp_id)
+ORDER BY replaced_at, id;
+
+-- name: DeleteChirpRevisions :exec
+DELETE FROM chirp_revisions
+WHERE chirp_id = sqlc.arg(chirp_id);
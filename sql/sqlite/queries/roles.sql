@@ -0,0 +1,28 @@
+-- name: CreateRoleChange :one
+INSERT INTO role_changes (id, created_at, user_id, changed_by, old_role, new_role, reason)
+VALUES (
+    sqlc.arg(id),
+    sqlc.arg(created_at),
+    sqlc.arg(user_id),
+    sqlc.arg(changed_by),
+    sqlc.arg(old_role),
+    sqlc.arg(new_role),
+    sqlc.arg(reason)
+)
+RETURNING *;
+
+-- newest first
+-- name: ListRoleChanges :many
+SELECT * FROM role_changes
+WHERE created_at < sqlc.arg(before_created_at)
+OR (created_at = sqlc.arg(before_created_at) AND id < sqlc.arg(before_id))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(page_limit);
+
+-- name: ListUserRoleChanges :many
+SELECT * FROM role_changes
+WHERE user_id = sqlc.arg(user_id)
+AND (created_at < sqlc.arg(before_created_at)
+OR (created_at = sqlc.arg(before_created_at) AND id < sqlc.arg(before_id)))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(page_limit);
@@ -0,0 +1,93 @@
+-- name: CreateUser :one
+INSERT INTO users(id, created_at, updated_at, email, hashed_password, handle)
+VALUES (
+    sqlc.arg(id),
+    sqlc.arg(created_at),
+    sqlc.arg(updated_at),
+    sqlc.arg(email),
+    sqlc.arg(hashed_password),
+    sqlc.arg(handle)
+)
+RETURNING *;
+
+-- name: ResetUsers :exec
+DELETE FROM users;
+
+-- name: GetUserByEmail :one
+SELECT * FROM users
+WHERE email = sqlc.arg(email);
+
+-- name: GetUserByID :one
+SELECT * FROM users
+WHERE id = sqlc.arg(id);
+
+-- name: UpdateEmailAndPassword :one
+UPDATE users
+SET email = sqlc.arg(email), hashed_password = sqlc.arg(hashed_password), updated_at = sqlc.arg(updated_at)
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: UpdateHandle :one
+UPDATE users
+SET handle = sqlc.arg(handle), updated_at = sqlc.arg(updated_at)
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: UpdateDMSetting :one
+UPDATE users
+SET dms_from_followers_only = sqlc.arg(dms_from_followers_only), updated_at = sqlc.arg(updated_at)
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: GetUsersByHandles :many
+SELECT * FROM users
+WHERE handle IN (sqlc.slice(handles));
+
+-- name: UpgradeToRed :exec
+UPDATE users
+SET is_chirpy_red = true, updated_at = sqlc.arg(updated_at)
+WHERE id = sqlc.arg(id);
+
+-- name: SoftDeleteUser :one
+UPDATE users
+SET deleted_at = sqlc.arg(deleted_at), updated_at = sqlc.arg(deleted_at)
+WHERE id = sqlc.arg(id) AND deleted_at IS NULL
+RETURNING *;
+
+-- name: RestoreUser :one
+UPDATE users
+SET deleted_at = NULL, updated_at = sqlc.arg(updated_at)
+WHERE id = sqlc.arg(id) AND deleted_at IS NOT NULL
+RETURNING *;
+
+-- name: ListPurgeableUsers :many
+SELECT * FROM users
+WHERE deleted_at < sqlc.arg(deleted_before)
+ORDER BY deleted_at
+LIMIT sqlc.arg(page_limit);
+
+-- who purged users' tombstones belong to, made the first time it's needed
+-- it's banned and has no password, so nobody can log in as it
+-- name: CreatePlaceholderUser :exec
+INSERT INTO users(id, created_at, updated_at, email, status, status_reason)
+VALUES (sqlc.arg(id), sqlc.arg(created_at), sqlc.arg(updated_at), '(deleted)', 'banned', 'This account has been deleted')
+ON CONFLICT (id) DO NOTHING;
+
+-- everything of theirs cascades away with them
+-- name: DeleteUser :exec
+DELETE FROM users
+WHERE id = sqlc.arg(id);
+
+-- status_until is NULL for a suspension or ban that doesn't end
+-- name: SetUserStatus :one
+UPDATE users
+SET status = sqlc.arg(status), status_reason = sqlc.arg(status_reason), status_until = sqlc.arg(status_until),
+hide_chirps = sqlc.arg(hide_chirps), updated_at = sqlc.arg(updated_at)
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: SetUserRole :one
+UPDATE users
+SET role = sqlc.arg(role), updated_at = sqlc.arg(updated_at)
+WHERE id = sqlc.arg(id)
+RETURNING *;
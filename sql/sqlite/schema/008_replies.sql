@@ -0,0 +1,25 @@
+-- +goose Up
+ALTER TABLE chirps
+ADD COLUMN in_reply_to TEXT REFERENCES chirps(id) ON DELETE SET NULL;
+ALTER TABLE chirps
+ADD COLUMN deleted_at TIMESTAMP;
+CREATE INDEX chirps_in_reply_to_idx ON chirps (in_reply_to, created_at, id);
+
+-- +goose Down
+-- SQLite can't drop a column with a foreign key on it, so rebuild the table without them
+DROP INDEX chirps_in_reply_to_idx;
+CREATE TABLE chirps_old (
+    id TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    body TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    FOREIGN KEY (user_id)
+    REFERENCES users(id) ON DELETE CASCADE
+);
+INSERT INTO chirps_old (id, created_at, updated_at, body, user_id)
+SELECT id, created_at, updated_at, body, user_id FROM chirps;
+DROP TABLE chirps;
+ALTER TABLE chirps_old RENAME TO chirps;
+CREATE INDEX chirps_created_at_id_idx ON chirps (created_at, id);
+CREATE INDEX chirps_user_id_created_at_id_idx ON chirps (user_id, created_at, id);
\ No newline at end of file
@@ -0,0 +1,16 @@
+-- +goose Up
+CREATE TABLE likes (
+    user_id TEXT NOT NULL,
+    chirp_id TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (user_id, chirp_id),
+    FOREIGN KEY (user_id)
+    REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (chirp_id)
+    REFERENCES chirps(id) ON DELETE CASCADE
+);
+CREATE INDEX likes_chirp_id_idx ON likes (chirp_id);
+CREATE INDEX likes_user_id_created_at_idx ON likes (user_id, created_at, chirp_id);
+
+-- +goose Down
+DROP TABLE likes;
\ No newline at end of file
@@ -0,0 +1,16 @@
+-- +goose Up
+CREATE TABLE follows (
+    follower_id TEXT NOT NULL,
+    followee_id TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (follower_id, followee_id),
+    FOREIGN KEY (follower_id)
+    REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (followee_id)
+    REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE INDEX follows_follower_id_created_at_idx ON follows (follower_id, created_at, followee_id);
+CREATE INDEX follows_followee_id_created_at_idx ON follows (followee_id, created_at, follower_id);
+
+-- +goose Down
+DROP TABLE follows;
\ No newline at end of file
@@ -0,0 +1,26 @@
+-- +goose Up
+-- chirp_id is whatever the notification is about (the reply, the chirp that was liked, and so on)
+-- so it goes away with the chirp, and follows don't have one
+CREATE TABLE notifications (
+    id TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    user_id TEXT NOT NULL,
+    actor_id TEXT NOT NULL,
+    kind TEXT NOT NULL,
+    chirp_id TEXT,
+    read_at TIMESTAMP,
+    FOREIGN KEY (user_id)
+    REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (actor_id)
+    REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (chirp_id)
+    REFERENCES chirps(id) ON DELETE CASCADE
+);
+-- one notification per thing that happened, so liking something twice doesn't notify twice
+-- (NULLs never clash in a unique index, hence the COALESCE for follows)
+CREATE UNIQUE INDEX notifications_dedupe_idx ON notifications (user_id, actor_id, kind, COALESCE(chirp_id, ''));
+CREATE INDEX notifications_user_id_created_at_idx ON notifications (user_id, created_at, id);
+CREATE INDEX notifications_chirp_id_idx ON notifications (chirp_id);
+
+-- +goose Down
+DROP TABLE notifications;
\ No newline at end of file
@@ -0,0 +1,44 @@
+-- +goose Up
+ALTER TABLE users
+ADD COLUMN dms_from_followers_only BOOLEAN NOT NULL DEFAULT false;
+-- updated_at moves with every message, so the newest conversations sort first
+CREATE TABLE conversations (
+    id TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    is_group BOOLEAN NOT NULL
+);
+CREATE INDEX conversations_updated_at_id_idx ON conversations (updated_at, id);
+-- leaving a conversation keeps the row around (with left_at set) so the others can see who was there
+CREATE TABLE conversation_participants (
+    conversation_id TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    joined_at TIMESTAMP NOT NULL,
+    last_read_at TIMESTAMP,
+    left_at TIMESTAMP,
+    PRIMARY KEY (conversation_id, user_id),
+    FOREIGN KEY (conversation_id)
+    REFERENCES conversations(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id)
+    REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE INDEX conversation_participants_user_id_idx ON conversation_participants (user_id);
+CREATE TABLE messages (
+    id TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    conversation_id TEXT NOT NULL,
+    sender_id TEXT NOT NULL,
+    body TEXT NOT NULL,
+    FOREIGN KEY (conversation_id)
+    REFERENCES conversations(id) ON DELETE CASCADE,
+    FOREIGN KEY (sender_id)
+    REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE INDEX messages_conversation_id_created_at_id_idx ON messages (conversation_id, created_at, id);
+
+-- +goose Down
+DROP TABLE messages;
+DROP TABLE conversation_participants;
+DROP TABLE conversations;
+ALTER TABLE users
+DROP COLUMN dms_from_followers_only;
\ No newline at end of file
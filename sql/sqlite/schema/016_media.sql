@@ -0,0 +1,22 @@
+-- +goose Up
+-- uploads start out unattached (chirp_id is NULL) until a chirp claims them
+-- the file itself lives in the blob store under blob_key
+CREATE TABLE media_uploads (
+    id TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    user_id TEXT NOT NULL,
+    blob_key TEXT NOT NULL,
+    content_type TEXT NOT NULL,
+    size INTEGER NOT NULL,
+    chirp_id TEXT,
+    position INTEGER NOT NULL DEFAULT 0,
+    FOREIGN KEY (user_id)
+    REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (chirp_id)
+    REFERENCES chirps(id) ON DELETE SET NULL
+);
+CREATE INDEX media_uploads_chirp_id_idx ON media_uploads (chirp_id);
+CREATE INDEX media_uploads_created_at_idx ON media_uploads (created_at);
+
+-- +goose Down
+DROP TABLE media_uploads;
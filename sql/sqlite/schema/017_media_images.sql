@@ -0,0 +1,20 @@
+-- +goose Up
+-- filled in for images, videos are left at 0 and ''
+ALTER TABLE media_uploads
+ADD COLUMN width INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE media_uploads
+ADD COLUMN height INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE media_uploads
+ADD COLUMN blurhash TEXT NOT NULL DEFAULT '';
+ALTER TABLE media_uploads
+ADD COLUMN thumbnail_key TEXT;
+
+-- +goose Down
+ALTER TABLE media_uploads
+DROP COLUMN thumbnail_key;
+ALTER TABLE media_uploads
+DROP COLUMN blurhash;
+ALTER TABLE media_uploads
+DROP COLUMN height;
+ALTER TABLE media_uploads
+DROP COLUMN width;
@@ -0,0 +1,23 @@
+-- +goose Up
+-- deleted users (and deleted chirps) stick around for a grace period so they can be restored,
+-- then the purge job takes them out for good
+ALTER TABLE users
+ADD COLUMN deleted_at TIMESTAMP;
+CREATE INDEX users_deleted_at_idx ON users (deleted_at);
+-- a chirp's body is kept while it's only deleted, and blanked once it's purged
+-- (purged chirps with replies stay behind as tombstones, the rest are deleted outright)
+ALTER TABLE chirps
+ADD COLUMN purged_at TIMESTAMP;
+CREATE INDEX chirps_deleted_at_idx ON chirps (deleted_at);
+-- tombstones from before this migration already lost their bodies
+UPDATE chirps
+SET purged_at = deleted_at
+WHERE deleted_at IS NOT NULL;
+
+-- +goose Down
+DROP INDEX chirps_deleted_at_idx;
+ALTER TABLE chirps
+DROP COLUMN purged_at;
+DROP INDEX users_deleted_at_idx;
+ALTER TABLE users
+DROP COLUMN deleted_at;
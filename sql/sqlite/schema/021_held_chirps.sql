@@ -0,0 +1,36 @@
+-- +goose Up
+-- chirps the moderation pipeline held for review, waiting for a moderator to approve or reject them
+-- they only become real chirps once they're approved, so nothing else has to know they exist
+-- stage and reason are what held them
+CREATE TABLE held_chirps (
+    id TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    user_id TEXT NOT NULL,
+    body TEXT NOT NULL,
+    in_reply_to TEXT,
+    quote_of TEXT,
+    stage TEXT NOT NULL,
+    reason TEXT NOT NULL,
+    FOREIGN KEY (user_id)
+    REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (in_reply_to)
+    REFERENCES chirps(id) ON DELETE CASCADE
+);
+CREATE INDEX held_chirps_created_at_idx ON held_chirps (created_at, id);
+-- the uploads a held chirp will have once it's approved
+-- they're kept out of the orphaned media collection until then
+CREATE TABLE held_chirp_media (
+    held_chirp_id TEXT NOT NULL,
+    media_id TEXT NOT NULL,
+    position INTEGER NOT NULL,
+    PRIMARY KEY (held_chirp_id, media_id),
+    FOREIGN KEY (held_chirp_id)
+    REFERENCES held_chirps(id) ON DELETE CASCADE,
+    FOREIGN KEY (media_id)
+    REFERENCES media_uploads(id) ON DELETE CASCADE
+);
+CREATE INDEX held_chirp_media_media_id_idx ON held_chirp_media (media_id);
+
+-- +goose Down
+DROP TABLE held_chirp_media;
+DROP TABLE held_chirps;
@@ -0,0 +1,52 @@
+-- +goose Up
+-- reports users make about chirps and accounts, for moderators to work through
+-- user_id is always the account being reported (the author, for a chirp), so resolving either kind works the same way
+-- status goes from open to claimed (a moderator's looking at it) to resolved
+CREATE TABLE reports (
+    id TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    reporter_id TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    chirp_id TEXT,
+    category TEXT NOT NULL,
+    details TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'open',
+    claimed_by TEXT,
+    claimed_at TIMESTAMP,
+    resolved_at TIMESTAMP,
+    resolution TEXT,
+    note TEXT NOT NULL DEFAULT '',
+    FOREIGN KEY (reporter_id)
+    REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id)
+    REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (chirp_id)
+    REFERENCES chirps(id) ON DELETE CASCADE,
+    FOREIGN KEY (claimed_by)
+    REFERENCES users(id) ON DELETE SET NULL
+);
+CREATE INDEX reports_status_created_at_idx ON reports (status, created_at, id);
+CREATE INDEX reports_reporter_id_created_at_idx ON reports (reporter_id, created_at, id);
+-- suspended users can't log in until suspended_until
+ALTER TABLE users
+ADD COLUMN suspended_until TIMESTAMP;
+-- notifications about a report (that it's been resolved) point at it
+-- and it's part of what makes a notification unique, since they don't have a chirp_id
+-- (no foreign key here, since SQLite can't drop a column that has one, so a notification
+-- about a report on a chirp that's since been purged is left pointing at nothing)
+ALTER TABLE notifications
+ADD COLUMN report_id TEXT;
+DROP INDEX notifications_dedupe_idx;
+CREATE UNIQUE INDEX notifications_dedupe_idx ON notifications (user_id, actor_id, kind, COALESCE(chirp_id, ''), COALESCE(report_id, ''));
+
+-- +goose Down
+DROP INDEX notifications_dedupe_idx;
+DELETE FROM notifications
+WHERE report_id IS NOT NULL;
+CREATE UNIQUE INDEX notifications_dedupe_idx ON notifications (user_id, actor_id, kind, COALESCE(chirp_id, ''));
+ALTER TABLE notifications
+DROP COLUMN report_id;
+ALTER TABLE users
+DROP COLUMN suspended_until;
+DROP TABLE reports;
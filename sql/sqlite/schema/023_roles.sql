@@ -0,0 +1,26 @@
+-- +goose Up
+-- every user has one role, and each one can do everything the ones before it can: user, moderator, admin
+ALTER TABLE users
+ADD COLUMN role TEXT NOT NULL DEFAULT 'user';
+-- the audit trail of every role change
+-- changed_by is NULL when it was done from ADMINS on startup (or by someone who's since been purged)
+CREATE TABLE role_changes (
+    id TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    user_id TEXT NOT NULL,
+    changed_by TEXT,
+    old_role TEXT NOT NULL,
+    new_role TEXT NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    FOREIGN KEY (user_id)
+    REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (changed_by)
+    REFERENCES users(id) ON DELETE SET NULL
+);
+CREATE INDEX role_changes_created_at_idx ON role_changes (created_at, id);
+CREATE INDEX role_changes_user_id_created_at_idx ON role_changes (user_id, created_at, id);
+
+-- +goose Down
+DROP TABLE role_changes;
+ALTER TABLE users
+DROP COLUMN role;
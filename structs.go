@@ -10,15 +10,200 @@ type userParam struct {
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 	Email string `json:"email"`
+	Handle string `json:"handle,omitempty"`
 	IsChirpyRed bool `json:"is_chirpy_red"`
+	DMsFromFollowersOnly bool `json:"dms_from_followers_only"`
+	Role string `json:"role,omitempty"`
 	Token string `json:"token"`
 	RefreshToken string `json:"refresh_token"`
 }
 
+// a user mentioned in a chirp
+type mediaParam struct {
+	ID uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	ContentType string `json:"content_type"`
+	Size int64 `json:"size"`
+	URL string `json:"url"`
+	Width int32 `json:"width,omitempty"`
+	Height int32 `json:"height,omitempty"`
+	Blurhash string `json:"blurhash,omitempty"`
+	ThumbnailURL string `json:"thumbnail_url,omitempty"`
+}
+
+type mentionParam struct {
+	UserID uuid.UUID `json:"user_id"`
+	Handle string `json:"handle"`
+}
+
+type notificationParam struct {
+	ID uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	Kind string `json:"kind"`
+	ActorID uuid.UUID `json:"actor_id"`
+	ChirpID *uuid.UUID `json:"chirp_id"`
+	ReportID *uuid.UUID `json:"report_id"`
+	Read bool `json:"read"`
+}
+
+// someone on either end of a follow, and when they followed
+type followParam struct {
+	UserID uuid.UUID `json:"user_id"`
+	FollowedAt time.Time `json:"followed_at"`
+}
+
 type chirpParam struct {
 	ID uuid.UUID `json:"id"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 	Body string `json:"body"`
 	UserID uuid.UUID `json:"user_id"`
-}
\ No newline at end of file
+	InReplyTo *uuid.UUID `json:"in_reply_to"`
+	ReplyCount int64 `json:"reply_count"`
+	LikeCount int64 `json:"like_count"`
+	LikedByMe bool `json:"liked_by_me"`
+	Mentions []mentionParam `json:"mentions"`
+	Media []mediaParam `json:"media"`
+	QuoteOf *uuid.UUID `json:"quote_of"`
+	QuotedChirp *quotedChirpParam `json:"quoted_chirp,omitempty"`
+	RechirpCount int64 `json:"rechirp_count"`
+	RechirpedBy *uuid.UUID `json:"rechirped_by,omitempty"`
+	RechirpedAt *time.Time `json:"rechirped_at,omitempty"`
+	Deleted bool `json:"deleted,omitempty"`
+	Edited bool `json:"edited"`
+	EditedAt *time.Time `json:"edited_at,omitempty"`
+}
+
+// if the quoted chirp is gone, only the id and unavailable are sent
+type quotedChirpParam struct {
+	ID uuid.UUID `json:"id"`
+	Unavailable bool `json:"unavailable"`
+	*chirpParam
+}
+
+type searchResultParam struct {
+	chirpParam
+	Snippet string `json:"snippet"`
+}
+
+type trendParam struct {
+	Tag string `json:"tag"`
+	Uses int64 `json:"uses"`
+	Score float64 `json:"score"`
+}
+
+type threadNodeParam struct {
+	chirpParam
+	Replies []threadNodeParam `json:"replies"`
+}
+
+type threadParam struct {
+	Ancestors []chirpParam `json:"ancestors"`
+	Chirp chirpParam `json:"chirp"`
+	Replies []threadNodeParam `json:"replies"`
+}
+type conversationParam struct {
+	ID uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	IsGroup bool `json:"is_group"`
+	Participants []participantParam `json:"participants"`
+	UnreadCount int64 `json:"unread_count"`
+}
+
+type participantParam struct {
+	UserID uuid.UUID `json:"user_id"`
+	JoinedAt time.Time `json:"joined_at"`
+	LastReadAt *time.Time `json:"last_read_at"`
+	Left bool `json:"left"`
+}
+
+type messageParam struct {
+	ID uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	ConversationID uuid.UUID `json:"conversation_id"`
+	SenderID uuid.UUID `json:"sender_id"`
+	Body string `json:"body"`
+}
+
+// replaced_at is null for the current version
+type revisionParam struct {
+	Body string `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+	ReplacedAt *time.Time `json:"replaced_at"`
+}
+
+type filterListParam struct {
+	Name string `json:"name"`
+	Action string `json:"action"`
+	Words []string `json:"words"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// a chirp that had a word from a flag list in it
+type chirpFlagParam struct {
+	ID uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	ChirpID uuid.UUID `json:"chirp_id"`
+	List string `json:"list"`
+	Word string `json:"word"`
+}
+
+// a chirp waiting in the review queue
+// stage and reason are what held it
+type heldChirpParam struct {
+	ID uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UserID uuid.UUID `json:"user_id"`
+	Body string `json:"body"`
+	InReplyTo *uuid.UUID `json:"in_reply_to"`
+	QuoteOf *uuid.UUID `json:"quote_of"`
+	MediaIDs []uuid.UUID `json:"media_ids"`
+	Stage string `json:"stage"`
+	Reason string `json:"reason"`
+}
+
+// claimed_by and note are only shown to moderators
+type reportParam struct {
+	ID uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	ReporterID uuid.UUID `json:"reporter_id"`
+	UserID uuid.UUID `json:"user_id"`
+	ChirpID *uuid.UUID `json:"chirp_id"`
+	Category string `json:"category"`
+	Details string `json:"details"`
+	Status string `json:"status"`
+	ClaimedBy *uuid.UUID `json:"claimed_by,omitempty"`
+	ClaimedAt *time.Time `json:"claimed_at"`
+	ResolvedAt *time.Time `json:"resolved_at"`
+	Resolution *string `json:"resolution"`
+	Note string `json:"note,omitempty"`
+}
+
+type userRoleParam struct {
+	UserID uuid.UUID `json:"user_id"`
+	Role string `json:"role"`
+}
+
+// an entry in the role audit trail
+// changed_by is null for changes made from ADMINS
+type roleChangeParam struct {
+	ID uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UserID uuid.UUID `json:"user_id"`
+	ChangedBy *uuid.UUID `json:"changed_by"`
+	OldRole string `json:"old_role"`
+	NewRole string `json:"new_role"`
+	Reason string `json:"reason"`
+}
+
+// reason, until and hide_chirps are only there while a user is suspended or banned
+type accountStatusParam struct {
+	UserID uuid.UUID `json:"user_id"`
+	Status string `json:"status"`
+	Reason string `json:"reason,omitempty"`
+	Until *time.Time `json:"until,omitempty"`
+	HideChirps bool `json:"hide_chirps,omitempty"`
+}
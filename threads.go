@@ -0,0 +1,128 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"internal/database"
+	"net/http"
+	"strconv"
+)
+
+const defaultThreadDepth = 3
+const maxThreadDepth = 10
+
+// how many nested replies to load per level, so one busy thread can't load the whole table
+const maxThreadLevelSize = 500
+
+// how far up a reply chain to walk before giving up
+const maxAncestors = 1000
+
+// get a chirp with everything above it and a page of the replies below it
+// the direct replies are paginated like GET /api/chirps (oldest first), and each one
+// comes with its own replies nested up to ?depth= levels deep
+func getThread(wri http.ResponseWriter, req *http.Request, apiCfg apiConfig) {
+	chirpID, _ := uuid.Parse(req.PathValue("chirpID"))
+	chirp, err := apiCfg.dbQueries.GetSingleChirp(req.Context(), chirpID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithProblem(wri, errChirpNotFound)
+		} else {
+			respondWithProblem(wri, fmt.Errorf("getting chirp: %w", err))
+		}
+		return
+	}
+	depth := defaultThreadDepth
+	if d := req.URL.Query().Get("depth"); d != "" {
+		depth, err = strconv.Atoi(d)
+		if err != nil || depth < 1 || depth > maxThreadDepth {
+			respondWithError(wri, 400, fmt.Sprintf("depth must be between 1 and %d", maxThreadDepth))
+			return
+		}
+	}
+	start, limit, err := parsePage(req, false)
+	if err != nil {
+		respondWithProblem(wri, err)
+		return
+	}
+
+	// walk up to the root
+	ancestors := []database.Chirp{}
+	for parent := chirp.InReplyTo; parent.Valid && len(ancestors) < maxAncestors; {
+		p, err := apiCfg.dbQueries.GetSingleChirp(req.Context(), parent.UUID)
+		if err != nil {
+			respondWithProblem(wri, fmt.Errorf("getting thread: %w", err))
+			return
+		}
+		ancestors = append([]database.Chirp{p}, ancestors...)
+		parent = p.InReplyTo
+	}
+
+	// one page of direct replies
+	replies, err := apiCfg.dbQueries.ListReplies(req.Context(), database.ListRepliesParams{
+		InReplyTo: uuid.NullUUID{UUID: chirp.ID, Valid: true},
+		AfterCreatedAt: start.CreatedAt,
+		AfterID: start.ID,
+		PageLimit: limit + 1,
+	})
+	if err != nil {
+		respondWithProblem(wri, fmt.Errorf("getting thread: %w", err))
+		return
+	}
+	if len(replies) > int(limit) {
+		replies = replies[:limit]
+		last := replies[len(replies)-1]
+		setNextLink(wri, req, cursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+
+	// then their replies, a level at a time
+	descendants := []database.Chirp{}
+	level := replies
+	for i := 1; i < depth && len(level) > 0; i++ {
+		parents := []uuid.UUID{}
+		for _, c := range level {
+			parents = append(parents, c.ID)
+		}
+		level, err = apiCfg.dbQueries.GetRepliesToChirps(req.Context(), database.GetRepliesToChirpsParams{
+			ParentIds: parents,
+			PageLimit: maxThreadLevelSize,
+		})
+		if err != nil {
+			respondWithProblem(wri, fmt.Errorf("getting thread: %w", err))
+			return
+		}
+		descendants = append(descendants, level...)
+	}
+
+	// fill in the counts for everything in one go, then put the tree together
+	all := append(append(append([]database.Chirp{}, ancestors...), chirp), replies...)
+	all = append(all, descendants...)
+	responses, err := chirpResponses(req, apiCfg, all)
+	if err != nil {
+		respondWithProblem(wri, fmt.Errorf("getting thread: %w", err))
+		return
+	}
+	children := map[uuid.UUID][]chirpParam{}
+	for _, r := range responses[len(ancestors)+1+len(replies):] {
+		children[*r.InReplyTo] = append(children[*r.InReplyTo], r)
+	}
+	resBody := threadParam{
+		Ancestors: responses[:len(ancestors)],
+		Chirp: responses[len(ancestors)],
+		Replies: threadNodes(responses[len(ancestors)+1:len(ancestors)+1+len(replies)], children),
+	}
+	respondWithJSON(wri, 200, resBody)
+}
+
+// nests the replies under each chirp
+func threadNodes(chirps []chirpParam, children map[uuid.UUID][]chirpParam) []threadNodeParam {
+	nodes := []threadNodeParam{}
+	for _, c := range chirps {
+		nodes = append(nodes, threadNodeParam{
+			chirpParam: c,
+			Replies: threadNodes(children[c.ID], children),
+		})
+	}
+	return nodes
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"internal/auth"
+	"internal/database"
+	"internal/store"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// sends body to PUT /api/users as user, returning the status code
+func putUserAs(t *testing.T, apiCfg apiConfig, user database.User, body string) int {
+	token, err := auth.MakeJWT(user.ID, user.Role, apiCfg.secret, time.Hour)
+	if err != nil {
+		t.Fatalf("Error in MakeJWT: %v", err)
+	}
+	req := httptest.NewRequest("PUT", "/api/users", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	wri := httptest.NewRecorder()
+	putUser(wri, req, apiCfg)
+	return wri.Code
+}
+
+func TestPutUserHandleOnly(t *testing.T) {
+	ctx := context.Background()
+	apiCfg := apiConfig{dbQueries: store.NewMemory(), secret: "secret"}
+	user, _ := apiCfg.dbQueries.CreateUser(ctx, database.CreateUserParams{Email: "user@example.com", HashedPassword: "hash"})
+
+	if code := putUserAs(t, apiCfg, user, `{"handle": "chirper"}`); code != 200 {
+		t.Fatalf("Expected a 200 for changing the handle, got %d", code)
+	}
+	updated, _ := apiCfg.dbQueries.GetUserByID(ctx, user.ID)
+	if updated.Handle.String != "chirper" || updated.Email != user.Email || updated.HashedPassword != user.HashedPassword {
+		t.Errorf("Expected only the handle to change, got %+v", updated)
+	}
+
+	// a handle that's turned away doesn't change anything else either
+	if code := putUserAs(t, apiCfg, user, `{"email": "new@example.com", "handle": "not a handle!"}`); code != 400 {
+		t.Fatalf("Expected a 400 for an invalid handle, got %d", code)
+	}
+	updated, _ = apiCfg.dbQueries.GetUserByID(ctx, user.ID)
+	if updated.Email != user.Email || updated.Handle.String != "chirper" {
+		t.Errorf("Expected nothing to change after an invalid handle, got %+v", updated)
+	}
+}
+
+func TestPutUserDMSettingOnly(t *testing.T) {
+	ctx := context.Background()
+	apiCfg := apiConfig{dbQueries: store.NewMemory(), secret: "secret"}
+	user, _ := apiCfg.dbQueries.CreateUser(ctx, database.CreateUserParams{Email: "user@example.com", HashedPassword: "hash"})
+
+	if code := putUserAs(t, apiCfg, user, `{"dms_from_followers_only": true}`); code != 200 {
+		t.Fatalf("Expected a 200 for changing the DM setting, got %d", code)
+	}
+	updated, _ := apiCfg.dbQueries.GetUserByID(ctx, user.ID)
+	if !updated.DmsFromFollowersOnly {
+		t.Errorf("Expected DMs to be from followers only")
+	}
+	if updated.Email != user.Email || updated.HashedPassword != user.HashedPassword {
+		t.Errorf("Expected the email and password to stay %q and %q, got %q and %q", user.Email, user.HashedPassword, updated.Email, updated.HashedPassword)
+	}
+}